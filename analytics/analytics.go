@@ -0,0 +1,129 @@
+package analytics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	"github.com/rs/zerolog"
+)
+
+type counterKey struct {
+	owner   string
+	project string
+	env     string
+	param   string
+	variant string
+	hour    time.Time
+}
+
+type counterValue struct {
+	count int64
+	last  time.Time
+}
+
+// Counter counts evaluations served to clients per parameter, environment, variant and hour.
+// Counts are aggregated in memory and added to storage counters every interval, so evaluation
+// requests never wait for storage. Counts that failed to be stored are kept for the next flush;
+// a partially applied flush is retried as a whole, so storage errors may overcount.
+type Counter struct {
+	Storage  storage.DataStorage
+	Interval time.Duration
+	Log      zerolog.Logger
+
+	mu     sync.Mutex
+	counts map[counterKey]*counterValue
+}
+
+// Record counts evaluations of the environment parameters
+func (c *Counter) Record(owner, project, env string, evals []*api.Evaluation) {
+	if len(evals) == 0 {
+		return
+	}
+	now := util.Now()
+	hour := now.UTC().Truncate(time.Hour)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = map[counterKey]*counterValue{}
+	}
+	for _, ev := range evals {
+		key := counterKey{owner, project, env, ev.Code, ev.Variant, hour}
+		v := c.counts[key]
+		if v == nil {
+			v = &counterValue{}
+			c.counts[key] = v
+		}
+		v.count++
+		v.last = now
+	}
+}
+
+// Run flushes counts every interval until context is done and once more then. Storage has to
+// stay open until Run returns, otherwise counts recorded after the last flush are lost.
+func (c *Counter) Run(ctx context.Context) {
+	c.Log.Info().Str("interval", c.Interval.String()).Msg("Evaluation counter started")
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			c.Flush()
+			c.Log.Info().Msg("Evaluation counter stopped")
+			return
+		case <-ticker.C:
+			c.Flush()
+		}
+	}
+}
+
+// Flush adds counts recorded since the previous flush to storage
+func (c *Counter) Flush() {
+	c.mu.Lock()
+	pending := c.counts
+	c.counts = nil
+	c.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	list := make([]*domain.EvaluationCount, 0, len(pending))
+	for k, v := range pending {
+		list = append(list, &domain.EvaluationCount{
+			Owner:       k.owner,
+			Project:     k.project,
+			Environment: k.env,
+			Parameter:   k.param,
+			Variant:     k.variant,
+			Hour:        k.hour,
+			Count:       v.count,
+			Last:        v.last,
+		})
+	}
+	if err := c.Storage.EvaluationCounts().Add(list); err != nil {
+		c.Log.Error().Err(err).Int("counters", len(list)).Msg("Can't store evaluation counters")
+		c.restore(pending)
+		return
+	}
+	c.Log.Debug().Int("counters", len(list)).Msg("Evaluation counters stored")
+}
+
+// restore returns counts that were not stored back for the next flush
+func (c *Counter) restore(pending map[counterKey]*counterValue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = pending
+		return
+	}
+	for k, v := range pending {
+		if cur := c.counts[k]; cur != nil {
+			cur.count += v.count
+		} else {
+			c.counts[k] = v
+		}
+	}
+}
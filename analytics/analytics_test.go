@@ -0,0 +1,102 @@
+package analytics_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/analytics"
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+var logger = log.Output(zerolog.ConsoleWriter{
+	Out:     os.Stdout,
+	NoColor: true,
+}).Level(zerolog.DebugLevel)
+
+// sinkStorage keeps added counters in memory and fails while err is set
+type sinkStorage struct {
+	storage.DataStorage
+	counts []*domain.EvaluationCount
+	err    error
+}
+
+func (s *sinkStorage) EvaluationCounts() storage.EvaluationCountSink {
+	return s
+}
+
+func (s *sinkStorage) Add(counts []*domain.EvaluationCount) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.counts = append(s.counts, counts...)
+	return nil
+}
+
+func (s *sinkStorage) total(param, variant string) int64 {
+	var n int64
+	for _, c := range s.counts {
+		if c.Parameter == param && c.Variant == variant {
+			n += c.Count
+		}
+	}
+	return n
+}
+
+func TestCounter(t *testing.T) {
+	assert := asserts.New(t)
+	db := &sinkStorage{}
+	counter := &analytics.Counter{Storage: db, Interval: time.Minute, Log: logger}
+
+	evals := []*api.Evaluation{
+		{Code: "flag", Value: true, Reason: api.EvaluationReasonValue},
+		{Code: "color", Value: "red", Variant: "red", Reason: api.EvaluationReasonVariant},
+	}
+
+	t.Run("aggregate", func(t *testing.T) {
+		counter.Record("ow1", "proj1", "prod", evals)
+		counter.Record("ow1", "proj1", "prod", evals)
+		counter.Record("ow1", "proj1", "prod", evals[1:])
+		counter.Flush()
+		assert.Equal(2, len(db.counts))
+		assert.Equal(int64(2), db.total("flag", ""))
+		assert.Equal(int64(3), db.total("color", "red"))
+		for _, c := range db.counts {
+			assert.Equal("ow1", c.Owner)
+			assert.Equal("proj1", c.Project)
+			assert.Equal("prod", c.Environment)
+			assert.Equal(c.Hour, c.Hour.Truncate(time.Hour))
+			assert.Equal(c.Hour, c.Last.UTC().Truncate(time.Hour))
+		}
+	})
+
+	t.Run("nothing to flush", func(t *testing.T) {
+		counter.Flush()
+		assert.Equal(2, len(db.counts))
+	})
+
+	t.Run("keep on error", func(t *testing.T) {
+		db.err = errors.New("storage is down")
+		counter.Record("ow1", "proj1", "prod", evals[:1])
+		counter.Flush()
+		db.err = nil
+		counter.Record("ow1", "proj1", "prod", evals[:1])
+		counter.Flush()
+		assert.Equal(int64(4), db.total("flag", ""))
+	})
+
+	t.Run("flush on stop", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		counter.Record("ow1", "proj1", "prod", evals[:1])
+		cancel()
+		counter.Run(ctx)
+		assert.Equal(int64(5), db.total("flag", ""))
+	})
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Toggly/core/domain"
 )
@@ -12,11 +13,34 @@ var (
 	ErrProjectNotFound = errors.New("Project not found")
 	// ErrProjectNotEmpty error
 	ErrProjectNotEmpty = errors.New("Project not empty")
+	// ErrProjectExists error
+	ErrProjectExists = errors.New("Project already exists")
+	// ErrProjectProtected error
+	ErrProjectProtected = errors.New("Project has protected environments")
+	// ErrEnvironmentNotFound error
+	ErrEnvironmentNotFound = errors.New("Environment not found")
+	// ErrEnvironmentExists error
+	ErrEnvironmentExists = errors.New("Environment already exists")
+	// ErrParameterNotFound error
+	ErrParameterNotFound = errors.New("Parameter not found")
+	// ErrParameterExists error
+	ErrParameterExists = errors.New("Parameter already exists")
+	// ErrParameterInUse error
+	ErrParameterInUse = errors.New("Parameter is a prerequisite of other parameters")
+	// ErrVersionConflict error
+	ErrVersionConflict = errors.New("Version conflict")
 )
 
-// ErrBadRequest type
+// FieldError describes an invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ErrBadRequest type. Fields list invalid request fields, if known.
 type ErrBadRequest struct {
 	Description string
+	Fields      []*FieldError
 }
 
 func (e *ErrBadRequest) Error() string {
@@ -26,18 +50,24 @@ func (e *ErrBadRequest) Error() string {
 // TogglyAPI interface
 type TogglyAPI interface {
 	ForOwner(owner string) OwnerAPI
+	Organizations() OrganizationAPI
+	RateLimits() RateLimitAPI
 }
 
-// OwnerAPI interface
+// OwnerAPI interface. As returns API acting on behalf of the principal.
 type OwnerAPI interface {
+	As(principal string) OwnerAPI
 	Projects() ProjectAPI
 }
 
-// ProjectInfo type
+// ProjectInfo type. Non-zero Version is the expected version of the project being updated.
+// Environments are created together with a new project; nil means server defaults.
 type ProjectInfo struct {
-	Code        string
-	Description string
-	Status      string
+	Code         string
+	Description  string
+	Status       string
+	Version      int
+	Environments []*EnvironmentInfo
 }
 
 // ProjectAPI interface
@@ -46,17 +76,32 @@ type ProjectAPI interface {
 	Get(code string) (*domain.Project, error)
 	Create(info *ProjectInfo) (*domain.Project, error)
 	Update(info *ProjectInfo) (*domain.Project, error)
-	Delete(code string) error
+	Delete(code string, version int, cascade bool) error
 	For(code string) ForProjectAPI
 }
 
-// ForProjectAPI interface
+// ForProjectAPI interface. StaleParameters reports parameters of all project environments
+// which were not evaluated or have served the same value for the given number of days
+// or are past their expiry date. DependencyGraph returns prerequisites of all project parameters.
 type ForProjectAPI interface {
 	Environments() EnvironmentAPI
+	Diff(source, target string) (*EnvironmentDiff, error)
+	Promote(source, target string, params []string) (*EnvironmentDiff, error)
+	ChangeRequests() ChangeRequestAPI
+	ScheduledChanges() ScheduledChangeAPI
+	Webhooks() WebhookAPI
+	StaleParameters(days int) ([]*StaleParameter, error)
+	DependencyGraph() (*DependencyGraph, error)
 }
 
-// EnvironmentInfo type
-type EnvironmentInfo struct{}
+// EnvironmentInfo type. Non-zero Version is the expected version of the environment being updated.
+type EnvironmentInfo struct {
+	Code        string
+	Description string
+	Protected   bool
+	Disabled    bool
+	Version     int
+}
 
 // EnvironmentAPI interface
 type EnvironmentAPI interface {
@@ -64,13 +109,18 @@ type EnvironmentAPI interface {
 	Get(code string) (*domain.Environment, error)
 	Create(info *EnvironmentInfo) (*domain.Environment, error)
 	Update(info *EnvironmentInfo) (*domain.Environment, error)
-	Delete(code string) error
+	Delete(code string, version int) error
+	For(code string) ForEnvironmentAPI
 }
 
-// ForEnvironmentAPI interface
+// ForEnvironmentAPI interface. Evaluate returns values served to the evaluation context
+// identified by key for the given parameters or for all environment parameters if none given.
+// EvaluationSeries returns hourly evaluation counts of the parameter.
 type ForEnvironmentAPI interface {
-	Groups() GroupAPI
 	Parameters() ParameterAPI
+	Changesets() ChangesetAPI
+	Evaluate(key string, codes ...string) ([]*Evaluation, error)
+	EvaluationSeries(code string, from, to time.Time) (*EvaluationSeries, error)
 }
 
 // GroupInfo type
@@ -89,15 +139,44 @@ type ForGroupAPI interface {
 	Parameters() ParameterAPI
 }
 
-// ParameterInfo type
-type ParameterInfo struct{}
+// ParameterInfo type. Non-zero Version is the expected version of the parameter being updated.
+type ParameterInfo struct {
+	Code          string
+	Description   string
+	Type          string
+	Value         interface{}
+	OffValue      interface{}
+	AllowedValues []interface{}
+	Constraints   *domain.Constraints
+	Variants      []*domain.Variant
+	Prerequisites []*domain.Prerequisite
+	Expires       *time.Time
+	Version       int
+}
+
+// NewParameterInfo returns info describing existing parameter
+func NewParameterInfo(p *domain.Parameter) *ParameterInfo {
+	return &ParameterInfo{
+		Code:          p.Code,
+		Description:   p.Description,
+		Type:          p.Type,
+		Value:         p.Value,
+		OffValue:      p.OffValue,
+		AllowedValues: p.AllowedValues,
+		Constraints:   p.Constraints,
+		Variants:      p.Variants,
+		Prerequisites: p.Prerequisites,
+		Expires:       p.Expires,
+		Version:       p.Version,
+	}
+}
 
 // ParameterAPI interface
 type ParameterAPI interface {
 	List() ([]*domain.Parameter, error)
-	Get(code string) ([]*domain.Parameter, error)
+	Get(code string) (*domain.Parameter, error)
 	GetBatch(code ...string) ([]*domain.Parameter, error)
-	Create(param *ParameterInfo) (domain.Parameter, error)
-	Update(param *ParameterInfo) (domain.Parameter, error)
-	Delete(code string) error
+	Create(info *ParameterInfo) (*domain.Parameter, error)
+	Update(info *ParameterInfo) (*domain.Parameter, error)
+	Delete(code string, version int) error
 }
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Toggly/core/domain"
+)
+
+var (
+	// ErrChangeRequestNotFound error
+	ErrChangeRequestNotFound = errors.New("Change request not found")
+	// ErrChangeRequestClosed error
+	ErrChangeRequestClosed = errors.New("Change request is not pending")
+	// ErrApprovalNotAllowed error
+	ErrApprovalNotAllowed = errors.New("Approval not allowed")
+	// ErrPrincipalRequired error
+	ErrPrincipalRequired = errors.New("Principal required to request changes of protected environment")
+	// ErrPrincipalNotVerified error
+	ErrPrincipalNotVerified = errors.New("Principal must be verified by client certificate to review changes")
+)
+
+// ErrApprovalRequired is returned when a change of a protected environment
+// is submitted as a change request instead of being applied
+type ErrApprovalRequired struct {
+	ChangeRequest *domain.ChangeRequest
+}
+
+func (e *ErrApprovalRequired) Error() string {
+	return fmt.Sprintf("Approval required: change request %s", e.ChangeRequest.ID)
+}
+
+// ErrChangeRequestFailed is returned when an approved change request can't be applied.
+// The change request is kept with the failed status.
+type ErrChangeRequestFailed struct {
+	ChangeRequest *domain.ChangeRequest
+	Err           error
+}
+
+func (e *ErrChangeRequestFailed) Error() string {
+	return fmt.Sprintf("Change request %s failed: %s", e.ChangeRequest.ID, e.Err)
+}
+
+// ChangeRequestAPI interface. Approvals and comments are made on behalf of the API principal,
+// which is taken as given, so callers must pass only authenticated principals.
+type ChangeRequestAPI interface {
+	List(status string) ([]*domain.ChangeRequest, error)
+	Get(id string) (*domain.ChangeRequest, error)
+	Approve(id, comment string) (*domain.ChangeRequest, error)
+	Reject(id, comment string) (*domain.ChangeRequest, error)
+	Comment(id, text string) (*domain.ChangeRequest, error)
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/Toggly/core/domain"
+)
+
+var (
+	// ErrChangesetNotFound error
+	ErrChangesetNotFound = errors.New("Changeset not found")
+)
+
+// ChangesetInfo type. Every change creates, updates or deletes an environment parameter
+// and every parameter can be changed once. Non-zero Parameter.Version of updates and deletes
+// is the expected version of the parameter. Deletes use Parameter.Code and Parameter.Version only.
+type ChangesetInfo struct {
+	Description string
+	Changes     []*ChangeInfo
+}
+
+// ChangeInfo is a change of a single parameter. Action is one of domain.ChangeActionCreate,
+// domain.ChangeActionUpdate and domain.ChangeActionDelete.
+type ChangeInfo struct {
+	Action    string
+	Parameter *ParameterInfo
+}
+
+// ChangesetAPI interface. Apply validates all changes together, so they may depend on each other,
+// and applies them to the environment at once. List returns latest changesets, newest first.
+type ChangesetAPI interface {
+	List() ([]*domain.Changeset, error)
+	Get(id string) (*domain.Changeset, error)
+	Apply(info *ChangesetInfo) (*domain.Changeset, error)
+}
@@ -0,0 +1,22 @@
+package api
+
+// DependencyGraph describes prerequisites of project parameters. Nodes list all parameters
+// of all project environments and every edge links a parameter to its prerequisite.
+type DependencyGraph struct {
+	Nodes []*DependencyNode `json:"nodes"`
+	Edges []*DependencyEdge `json:"edges"`
+}
+
+// DependencyNode is a parameter of the environment
+type DependencyNode struct {
+	Environment string `json:"environment"`
+	Parameter   string `json:"parameter"`
+}
+
+// DependencyEdge means Parameter requires Prerequisite to serve Value
+type DependencyEdge struct {
+	Environment  string      `json:"environment"`
+	Parameter    string      `json:"parameter"`
+	Prerequisite string      `json:"prerequisite"`
+	Value        interface{} `json:"value"`
+}
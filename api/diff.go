@@ -0,0 +1,40 @@
+package api
+
+import "github.com/Toggly/core/domain"
+
+// Parameter change actions
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// FieldChange describes a parameter field which differs between environments
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Source interface{} `json:"source"`
+	Target interface{} `json:"target"`
+}
+
+// ParameterChange describes what promotion does with a target environment parameter:
+// adds a parameter existing in source only, removes a parameter existing in target only
+// or changes fields of a parameter existing in both.
+// ChangeRequest is set when promotion to a protected environment awaits approval,
+// all changes of a promotion share the same change request.
+type ParameterChange struct {
+	Code          string            `json:"code"`
+	Action        string            `json:"action"`
+	Fields        []*FieldChange    `json:"fields,omitempty"`
+	Source        *domain.Parameter `json:"source,omitempty"`
+	Target        *domain.Parameter `json:"target,omitempty"`
+	ChangeRequest string            `json:"change_request,omitempty"`
+}
+
+// EnvironmentDiff type. Changeset is the ID of the changeset a promotion is applied with.
+type EnvironmentDiff struct {
+	Project   string             `json:"project"`
+	Source    string             `json:"source"`
+	Target    string             `json:"target"`
+	Changes   []*ParameterChange `json:"changes"`
+	Changeset string             `json:"changeset,omitempty"`
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/util"
+)
+
+const (
+	// defaultSeriesPeriod is used when the series start is not specified
+	defaultSeriesPeriod = 24 * time.Hour
+	// maxSeriesPeriod limits the number of hours in one series
+	maxSeriesPeriod = 90 * 24 * time.Hour
+)
+
+func (a *forEnvironmentAPI) EvaluationSeries(code string, from, to time.Time) (*api.EvaluationSeries, error) {
+	if _, err := a.Parameters().Get(code); err != nil {
+		return nil, err
+	}
+	if to.IsZero() {
+		to = util.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultSeriesPeriod)
+	}
+	// the period is widened to whole hours
+	from = from.UTC().Truncate(time.Hour)
+	if t := to.UTC().Truncate(time.Hour); t.Before(to) {
+		to = t.Add(time.Hour)
+	} else {
+		to = t
+	}
+	if !from.Before(to) {
+		return nil, &api.ErrBadRequest{Description: "Series start must be before its end"}
+	}
+	if to.Sub(from) > maxSeriesPeriod {
+		return nil, &api.ErrBadRequest{Description: fmt.Sprintf("Series period can't be longer than %d days", maxSeriesPeriod/(24*time.Hour))}
+	}
+	counts, err := a.storage.ForOwner(a.owner).Projects().For(a.project).Environments().For(a.env).EvaluationCounts().List(code, from, to)
+	if err != nil {
+		return nil, err
+	}
+	series := &api.EvaluationSeries{
+		Parameter:   code,
+		Environment: a.env,
+		From:        from,
+		To:          to,
+		Variants:    map[string]int64{},
+		Points:      make([]*api.EvaluationPoint, 0, len(counts)),
+	}
+	for _, c := range counts {
+		series.Total += c.Count
+		series.Variants[c.Variant] += c.Count
+		series.Points = append(series.Points, &api.EvaluationPoint{
+			Hour:    c.Hour.UTC(),
+			Variant: c.Variant,
+			Count:   c.Count,
+		})
+	}
+	return series, nil
+}
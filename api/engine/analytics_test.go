@@ -0,0 +1,83 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/analytics"
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/util"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIEvaluationSeries(t *testing.T) {
+
+	assert := asserts.New(t)
+	db := getDB()
+	counter := &analytics.Counter{Storage: db, Interval: time.Minute, Log: logger}
+	e := engine.NewTogglyAPI(db, &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "prod"}},
+		Evaluations:         counter,
+	}, logger)
+	pApi := e.ForOwner("ow1").Projects()
+	envAPI := pApi.For("proj1").Environments().For("prod")
+
+	beforeTest()
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+	_, err = envAPI.Parameters().Create(&api.ParameterInfo{
+		Code:  "color",
+		Type:  domain.ParameterTypeString,
+		Value: "white",
+		Variants: []*domain.Variant{
+			&domain.Variant{Name: "red", Value: "red", Weight: 1},
+			&domain.Variant{Name: "blue", Value: "blue", Weight: 1},
+		},
+	})
+	assert.Nil(err)
+
+	t.Run("count", func(t *testing.T) {
+		variants := map[string]int64{}
+		for _, key := range []string{"user-1", "user-2", "user-3", "user-4", ""} {
+			res, err := envAPI.Evaluate(key, "color")
+			assert.Nil(err)
+			variants[res[0].Variant]++
+		}
+		counter.Flush()
+		series, err := envAPI.EvaluationSeries("color", time.Time{}, time.Time{})
+		assert.Nil(err)
+		assert.Equal(int64(5), series.Total)
+		assert.Equal(variants, series.Variants)
+		assert.Equal(len(variants), len(series.Points))
+		assert.Equal(util.Now().UTC().Truncate(time.Hour), series.Points[0].Hour)
+		assert.True(series.To.Sub(series.From) >= 24*time.Hour)
+	})
+
+	t.Run("empty period", func(t *testing.T) {
+		to := util.Now().Add(-48 * time.Hour)
+		series, err := envAPI.EvaluationSeries("color", to.Add(-time.Hour), to)
+		assert.Nil(err)
+		assert.Equal(int64(0), series.Total)
+		assert.Equal(0, len(series.Points))
+	})
+
+	t.Run("bad period", func(t *testing.T) {
+		now := util.Now()
+		_, err := envAPI.EvaluationSeries("color", now, now.Add(-2*time.Hour))
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+		_, err = envAPI.EvaluationSeries("color", now.Add(-365*24*time.Hour), now)
+		_, ok = err.(*api.ErrBadRequest)
+		assert.True(ok)
+	})
+
+	t.Run("unknown parameter", func(t *testing.T) {
+		_, err := envAPI.EvaluationSeries("size", time.Time{}, time.Time{})
+		assert.Equal(api.ErrParameterNotFound, err)
+	})
+
+	afterTest()
+}
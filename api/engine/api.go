@@ -1,38 +1,128 @@
 package engine
 
 import (
+	"fmt"
+	"regexp"
+
 	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
 	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
 	"github.com/rs/zerolog"
 )
 
-// NewTogglyAPI returns api engine
-func NewTogglyAPI(storage storage.DataStorage, log zerolog.Logger) api.TogglyAPI {
+// Config defines api engine settings
+type Config struct {
+	// DefaultEnvironments are created with every new project
+	// unless the create request specifies its own set
+	DefaultEnvironments []*api.EnvironmentInfo
+	// ApprovalsRequired is the number of approvals a change request needs
+	// before a change of a protected environment is applied. Zero disables approvals.
+	ApprovalsRequired int
+	// Events receives configuration change events. Nil disables publishing.
+	Events api.EventPublisher
+	// PrivateWebhooks allows webhook URLs of loopback, private and link-local addresses
+	PrivateWebhooks bool
+	// Evaluations receives evaluations served to clients. Nil disables counting.
+	Evaluations api.EvaluationRecorder
+	// Organizations requires owners to be registered organizations and principals
+	// to be their members. Owners are not validated if unset.
+	Organizations bool
+}
+
+// NewTogglyAPI returns api engine. Nil config means default settings.
+func NewTogglyAPI(storage storage.DataStorage, cfg *Config, log zerolog.Logger) api.TogglyAPI {
+	if cfg == nil {
+		cfg = &Config{}
+	}
 	return &engine{
 		storage: storage,
+		cfg:     cfg,
 		log:     log,
 	}
 }
 
 type engine struct {
 	storage storage.DataStorage
+	cfg     *Config
 	log     zerolog.Logger
 }
 
 func (e *engine) ForOwner(owner string) api.OwnerAPI {
 	return &ownerAPI{
 		owner:   owner,
+		storage: e.storage,
+		cfg:     e.cfg,
+		log:     e.log,
+	}
+}
+
+func (e *engine) Organizations() api.OrganizationAPI {
+	return &organizationAPI{
+		storage: e.storage,
+		cfg:     e.cfg,
+		log:     e.log,
+	}
+}
+
+func (e *engine) RateLimits() api.RateLimitAPI {
+	return &rateLimitAPI{
 		storage: e.storage,
 		log:     e.log,
 	}
 }
 
 type ownerAPI struct {
-	owner   string
-	storage storage.DataStorage
-	log     zerolog.Logger
+	owner     string
+	principal string
+	storage   storage.DataStorage
+	cfg       *Config
+	log       zerolog.Logger
+}
+
+func (o *ownerAPI) As(principal string) api.OwnerAPI {
+	a := *o
+	a.principal = principal
+	return &a
 }
 
 func (o *ownerAPI) Projects() api.ProjectAPI {
 	return &projectAPI{*o}
 }
+
+// publish sends configuration change event to the configured publisher
+func (o *ownerAPI) publish(typ, project, env, param string, data interface{}) {
+	if o.cfg.Events == nil {
+		return
+	}
+	o.cfg.Events.Publish(&domain.Event{
+		ID:          util.NewID(),
+		Type:        typ,
+		Owner:       o.owner,
+		Project:     project,
+		Environment: env,
+		Parameter:   param,
+		Principal:   o.principal,
+		Date:        util.Now(),
+		Data:        data,
+	})
+}
+
+// codePattern lists characters allowed in entity codes. Codes are parts of storage keys
+// and request paths, so separators and control characters are refused.
+var codePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// checkCode returns bad request error if the entity code is empty or doesn't match codePattern
+func checkCode(entity, code string) error {
+	if code == "" {
+		return &api.ErrBadRequest{
+			Description: entity + " code not specified",
+		}
+	}
+	if !codePattern.MatchString(code) {
+		return &api.ErrBadRequest{
+			Description: fmt.Sprintf("%s code %q can contain only letters, digits, `_`, `.` and `-`", entity, code),
+		}
+	}
+	return nil
+}
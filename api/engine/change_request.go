@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+)
+
+type changeRequestAPI struct {
+	ownerAPI
+	project string
+}
+
+func (a *changeRequestAPI) s() storage.ChangeRequestStorage {
+	return a.storage.ForOwner(a.owner).Projects().For(a.project).ChangeRequests()
+}
+
+// requestChange stores the change of the protected environment as a pending change request.
+// Requests must carry a principal, otherwise nobody could be told apart from the requester on approval.
+func (o *ownerAPI) requestChange(project, env string, cr *domain.ChangeRequest) error {
+	if o.principal == "" {
+		return api.ErrPrincipalRequired
+	}
+	cr.ID = util.NewID()
+	cr.Owner = o.owner
+	cr.Project = project
+	cr.Environment = env
+	cr.Requester = o.principal
+	cr.Status = domain.ChangeRequestStatusPending
+	cr.Approvals = []*domain.ChangeComment{}
+	cr.Comments = []*domain.ChangeComment{}
+	cr.RegDate = util.Now()
+	if err := o.storage.ForOwner(o.owner).Projects().For(project).ChangeRequests().Save(cr); err != nil {
+		return err
+	}
+	o.log.Info().Str("id", cr.ID).Str("env", env).Str("param", cr.ParameterCode).Msgf("Change %s submitted for approval", cr.Action)
+	return &api.ErrApprovalRequired{ChangeRequest: cr}
+}
+
+func (a *changeRequestAPI) List(status string) ([]*domain.ChangeRequest, error) {
+	if _, err := a.storage.ForOwner(a.owner).Projects().Get(a.project); err != nil {
+		return nil, projectError(err)
+	}
+	return a.s().List(status)
+}
+
+func (a *changeRequestAPI) Get(id string) (*domain.ChangeRequest, error) {
+	if _, err := a.storage.ForOwner(a.owner).Projects().Get(a.project); err != nil {
+		return nil, projectError(err)
+	}
+	cr, err := a.s().Get(id)
+	if err != nil {
+		return nil, changeRequestError(err)
+	}
+	return cr, nil
+}
+
+// pending returns change request which can be approved or rejected
+func (a *changeRequestAPI) pending(id string) (*domain.ChangeRequest, error) {
+	if a.principal == "" {
+		return nil, api.ErrApprovalNotAllowed
+	}
+	cr, err := a.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if cr.Status != domain.ChangeRequestStatusPending {
+		return nil, api.ErrChangeRequestClosed
+	}
+	return cr, nil
+}
+
+func (a *changeRequestAPI) comment(text string) *domain.ChangeComment {
+	return &domain.ChangeComment{
+		Principal: a.principal,
+		Text:      text,
+		Date:      util.Now(),
+	}
+}
+
+func (a *changeRequestAPI) Approve(id, comment string) (*domain.ChangeRequest, error) {
+	cr, err := a.pending(id)
+	if err != nil {
+		return nil, err
+	}
+	if cr.Requester == a.principal {
+		return nil, api.ErrApprovalNotAllowed
+	}
+	for _, ap := range cr.Approvals {
+		if ap.Principal == a.principal {
+			return nil, api.ErrApprovalNotAllowed
+		}
+	}
+	cr.Approvals = append(cr.Approvals, a.comment(comment))
+	if len(cr.Approvals) < a.cfg.ApprovalsRequired {
+		if err = a.s().Update(cr); err != nil {
+			return nil, changeRequestError(err)
+		}
+		return cr, nil
+	}
+	// Close the request first so concurrent approvals can't apply it twice
+	cr.Status = domain.ChangeRequestStatusApplied
+	if err = a.s().Update(cr); err != nil {
+		return nil, changeRequestError(err)
+	}
+	if err = a.apply(cr); err != nil {
+		a.log.Error().Err(err).Str("id", cr.ID).Msg("Can't apply change request")
+		cr.Status = domain.ChangeRequestStatusFailed
+		cr.Error = err.Error()
+		if uErr := a.s().Update(cr); uErr != nil {
+			return nil, changeRequestError(uErr)
+		}
+		return nil, &api.ErrChangeRequestFailed{ChangeRequest: cr, Err: err}
+	}
+	return cr, nil
+}
+
+// apply executes the approved change
+func (a *changeRequestAPI) apply(cr *domain.ChangeRequest) (err error) {
+	pAPI := &parameterAPI{ownerAPI: a.ownerAPI, project: a.project, env: cr.Environment, approved: true}
+	switch cr.Action {
+	case domain.ChangeActionCreate:
+		_, err = pAPI.Create(parameterInfo(cr.Parameter, 0))
+	case domain.ChangeActionUpdate:
+		_, err = pAPI.Update(parameterInfo(cr.Parameter, cr.BaseVersion))
+	case domain.ChangeActionDelete:
+		err = pAPI.Delete(cr.ParameterCode, cr.BaseVersion)
+	case domain.ChangeActionChangeset:
+		csAPI := &changesetAPI{ownerAPI: a.ownerAPI, project: a.project, env: cr.Environment, approved: true}
+		_, err = csAPI.Apply(changesetInfo(cr.Changeset))
+	case domain.ChangeActionEnvironmentUpdate:
+		eAPI := &environmentAPI{ownerAPI: a.ownerAPI, project: a.project, approved: true}
+		_, err = eAPI.Update(environmentInfo(cr.EnvironmentState, cr.BaseVersion))
+	case domain.ChangeActionEnvironmentDelete:
+		eAPI := &environmentAPI{ownerAPI: a.ownerAPI, project: a.project, approved: true}
+		err = eAPI.Delete(cr.Environment, cr.BaseVersion)
+	}
+	return err
+}
+
+func (a *changeRequestAPI) Reject(id, comment string) (*domain.ChangeRequest, error) {
+	cr, err := a.pending(id)
+	if err != nil {
+		return nil, err
+	}
+	cr.Status = domain.ChangeRequestStatusRejected
+	cr.Comments = append(cr.Comments, a.comment(comment))
+	if err = a.s().Update(cr); err != nil {
+		return nil, changeRequestError(err)
+	}
+	return cr, nil
+}
+
+func (a *changeRequestAPI) Comment(id, text string) (*domain.ChangeRequest, error) {
+	if a.principal == "" {
+		return nil, api.ErrApprovalNotAllowed
+	}
+	if text == "" {
+		return nil, &api.ErrBadRequest{
+			Description: "Comment text not specified",
+		}
+	}
+	cr, err := a.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	cr.Comments = append(cr.Comments, a.comment(text))
+	if err = a.s().Update(cr); err != nil {
+		return nil, changeRequestError(err)
+	}
+	return cr, nil
+}
+
+func changeRequestError(err error) error {
+	switch err {
+	case storage.ErrNotFound:
+		return api.ErrChangeRequestNotFound
+	case storage.ErrVersionConflict:
+		return api.ErrVersionConflict
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIChangeRequest(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{
+			&api.EnvironmentInfo{Code: "prod", Protected: true},
+		},
+		ApprovalsRequired: 2,
+	}, logger)
+	owner := e.ForOwner("ow1")
+	params := func(principal string) api.ParameterAPI {
+		return owner.As(principal).Projects().For("proj1").Environments().For("prod").Parameters()
+	}
+	changes := func(principal string) api.ChangeRequestAPI {
+		return owner.As(principal).Projects().For("proj1").ChangeRequests()
+	}
+
+	beforeTest()
+
+	_, err := owner.Projects().Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+
+	var id string
+
+	t.Run("submit", func(t *testing.T) {
+		_, err := params("alice").Create(&api.ParameterInfo{Code: "p1", Type: domain.ParameterTypeBool, Value: true})
+		e, ok := err.(*api.ErrApprovalRequired)
+		assert.True(ok)
+		assert.Equal("alice", e.ChangeRequest.Requester)
+		assert.Equal(domain.ChangeRequestStatusPending, e.ChangeRequest.Status)
+		id = e.ChangeRequest.ID
+		_, err = params("alice").Get("p1")
+		assert.Equal(api.ErrParameterNotFound, err)
+		list, err := changes("bob").List(domain.ChangeRequestStatusPending)
+		assert.Nil(err)
+		assert.Len(list, 1)
+	})
+
+	t.Run("self approval", func(t *testing.T) {
+		_, err := changes("alice").Approve(id, "")
+		assert.Equal(api.ErrApprovalNotAllowed, err)
+		_, err = changes("").Approve(id, "")
+		assert.Equal(api.ErrApprovalNotAllowed, err)
+	})
+
+	t.Run("comment", func(t *testing.T) {
+		cr, err := changes("bob").Comment(id, "Looks good")
+		assert.Nil(err)
+		assert.Len(cr.Comments, 1)
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		cr, err := changes("bob").Approve(id, "")
+		assert.Nil(err)
+		assert.Equal(domain.ChangeRequestStatusPending, cr.Status)
+		_, err = changes("bob").Approve(id, "")
+		assert.Equal(api.ErrApprovalNotAllowed, err)
+		cr, err = changes("carol").Approve(id, "")
+		assert.Nil(err)
+		assert.Equal(domain.ChangeRequestStatusApplied, cr.Status)
+		p, err := params("alice").Get("p1")
+		assert.Nil(err)
+		assert.Equal(true, p.Value)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		_, err := changes("dave").Approve(id, "")
+		assert.Equal(api.ErrChangeRequestClosed, err)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		err := params("alice").Delete("p1", 0)
+		e, ok := err.(*api.ErrApprovalRequired)
+		assert.True(ok)
+		cr, err := changes("bob").Reject(e.ChangeRequest.ID, "Still needed")
+		assert.Nil(err)
+		assert.Equal(domain.ChangeRequestStatusRejected, cr.Status)
+		_, err = params("alice").Get("p1")
+		assert.Nil(err)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		var ids []string
+		for i := 0; i < 2; i++ {
+			err := params("alice").Delete("p1", 0)
+			e, ok := err.(*api.ErrApprovalRequired)
+			assert.True(ok)
+			ids = append(ids, e.ChangeRequest.ID)
+		}
+		for _, id := range ids {
+			_, err := changes("bob").Approve(id, "")
+			assert.Nil(err)
+		}
+		cr, err := changes("carol").Approve(ids[0], "")
+		assert.Nil(err)
+		assert.Equal(domain.ChangeRequestStatusApplied, cr.Status)
+		_, err = changes("carol").Approve(ids[1], "")
+		e, ok := err.(*api.ErrChangeRequestFailed)
+		if assert.True(ok) {
+			assert.Equal(api.ErrParameterNotFound, e.Err)
+		}
+		cr, err = changes("bob").Get(ids[1])
+		assert.Nil(err)
+		assert.Equal(domain.ChangeRequestStatusFailed, cr.Status)
+		assert.Equal(api.ErrParameterNotFound.Error(), cr.Error)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := changes("bob").Get("none")
+		assert.Equal(api.ErrChangeRequestNotFound, err)
+	})
+
+	t.Run("no principal", func(t *testing.T) {
+		_, err := params("").Create(&api.ParameterInfo{Code: "p2", Type: domain.ParameterTypeBool, Value: true})
+		assert.Equal(api.ErrPrincipalRequired, err)
+		list, err := changes("bob").List(domain.ChangeRequestStatusPending)
+		assert.Nil(err)
+		assert.Len(list, 0)
+	})
+
+	envs := func(principal string) api.EnvironmentAPI {
+		return owner.As(principal).Projects().For("proj1").Environments()
+	}
+
+	t.Run("cascade protected project", func(t *testing.T) {
+		err := owner.Projects().Delete("proj1", 0, true)
+		assert.Equal(api.ErrProjectProtected, err)
+	})
+
+	t.Run("update protected environment", func(t *testing.T) {
+		env, err := envs("alice").Update(&api.EnvironmentInfo{Code: "prod", Description: "Production", Protected: true})
+		assert.Nil(err)
+		assert.Equal("Production", env.Description)
+		_, err = envs("alice").Update(&api.EnvironmentInfo{Code: "prod", Description: "Production"})
+		e, ok := err.(*api.ErrApprovalRequired)
+		assert.True(ok)
+		assert.Equal(domain.ChangeActionEnvironmentUpdate, e.ChangeRequest.Action)
+		env, err = envs("alice").Get("prod")
+		assert.Nil(err)
+		assert.True(env.Protected)
+		_, err = changes("bob").Approve(e.ChangeRequest.ID, "")
+		assert.Nil(err)
+		cr, err := changes("carol").Approve(e.ChangeRequest.ID, "")
+		assert.Nil(err)
+		assert.Equal(domain.ChangeRequestStatusApplied, cr.Status)
+		env, err = envs("alice").Get("prod")
+		assert.Nil(err)
+		assert.False(env.Protected)
+	})
+
+	t.Run("delete protected environment", func(t *testing.T) {
+		_, err := envs("alice").Create(&api.EnvironmentInfo{Code: "stage", Protected: true})
+		assert.Nil(err)
+		err = envs("").Delete("stage", 0)
+		assert.Equal(api.ErrPrincipalRequired, err)
+		err = envs("alice").Delete("stage", 0)
+		e, ok := err.(*api.ErrApprovalRequired)
+		assert.True(ok)
+		assert.Equal(domain.ChangeActionEnvironmentDelete, e.ChangeRequest.Action)
+		_, err = envs("alice").Get("stage")
+		assert.Nil(err)
+		_, err = changes("bob").Approve(e.ChangeRequest.ID, "")
+		assert.Nil(err)
+		cr, err := changes("carol").Approve(e.ChangeRequest.ID, "")
+		assert.Nil(err)
+		assert.Equal(domain.ChangeRequestStatusApplied, cr.Status)
+		_, err = envs("alice").Get("stage")
+		assert.Equal(api.ErrEnvironmentNotFound, err)
+	})
+
+	afterTest()
+}
@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+)
+
+// changesetLogSize limits the number of changesets returned by List
+const changesetLogSize = 100
+
+type changesetAPI struct {
+	ownerAPI
+	project string
+	env     string
+	// approved is set when applying an approved change request
+	approved bool
+}
+
+func (a *changesetAPI) s() storage.ChangesetStorage {
+	return a.storage.ForOwner(a.owner).Projects().For(a.project).Environments().For(a.env).Changesets()
+}
+
+func (a *changesetAPI) parameters() *parameterAPI {
+	return &parameterAPI{ownerAPI: a.ownerAPI, project: a.project, env: a.env, approved: a.approved}
+}
+
+func (a *changesetAPI) List() ([]*domain.Changeset, error) {
+	if err := a.parameters().checkEnvironment(); err != nil {
+		return nil, err
+	}
+	return a.s().List(changesetLogSize)
+}
+
+func (a *changesetAPI) Get(id string) (*domain.Changeset, error) {
+	if err := a.parameters().checkEnvironment(); err != nil {
+		return nil, err
+	}
+	cs, err := a.s().Get(id)
+	if err != nil {
+		return nil, changesetError(err)
+	}
+	return cs, nil
+}
+
+func (a *changesetAPI) Apply(info *api.ChangesetInfo) (*domain.Changeset, error) {
+	pAPI := a.parameters()
+	cs, err := a.newChangeset(pAPI, info)
+	if err != nil {
+		return nil, err
+	}
+	approval, err := pAPI.needsApproval()
+	if err != nil {
+		return nil, err
+	}
+	if approval {
+		return nil, pAPI.submitRequest(&domain.ChangeRequest{Action: domain.ChangeActionChangeset, Changeset: cs})
+	}
+	if err = a.s().Apply(cs); err != nil {
+		if e, ok := err.(*storage.ErrPartialChangeset); ok {
+			a.log.Error().Err(e.Err).Str("id", cs.ID).Str("env", a.env).Strs("params", e.Parameters).Msg("Changeset left partially applied")
+		}
+		return nil, parameterError(err)
+	}
+	a.log.Info().Str("id", cs.ID).Str("env", a.env).Int("changes", len(cs.Changes)).Msg("Changeset applied")
+	a.publish(domain.EventChangesetApplied, a.project, a.env, "", cs)
+	return cs, nil
+}
+
+// newChangeset validates changes against the environment state they lead to
+// and builds the changeset entity
+func (a *changesetAPI) newChangeset(pAPI *parameterAPI, info *api.ChangesetInfo) (*domain.Changeset, error) {
+	if len(info.Changes) == 0 {
+		return nil, &api.ErrBadRequest{Description: "Changeset has no changes"}
+	}
+	current, err := pAPI.List()
+	if err != nil {
+		return nil, err
+	}
+	state := make(map[string]*domain.Parameter, len(current))
+	order := make([]string, 0, len(current))
+	for _, p := range current {
+		state[p.Code] = p
+		order = append(order, p.Code)
+	}
+	var errs fieldErrors
+	items := make([]*domain.ChangesetItem, 0, len(info.Changes))
+	// touched maps changed parameter codes to change indexes
+	touched := make(map[string]int, len(info.Changes))
+	for i, c := range info.Changes {
+		field := fmt.Sprintf("changes[%d]", i)
+		if c.Parameter == nil || c.Parameter.Code == "" {
+			errs.add(field+".parameter.code", "Parameter code not specified")
+			continue
+		}
+		code := c.Parameter.Code
+		if _, ok := touched[code]; ok {
+			errs.add(field+".parameter.code", "Parameter `%s` is changed twice", code)
+			continue
+		}
+		touched[code] = i
+		old := state[code]
+		item := &domain.ChangesetItem{Action: c.Action, ParameterCode: code}
+		switch c.Action {
+		case domain.ChangeActionCreate:
+			if old != nil {
+				errs.add(field+".parameter.code", "Parameter `%s` already exists", code)
+				continue
+			}
+			order = append(order, code)
+		case domain.ChangeActionUpdate, domain.ChangeActionDelete:
+			if old == nil {
+				errs.add(field+".parameter.code", "Parameter `%s` not found", code)
+				continue
+			}
+			if c.Parameter.Version != 0 && c.Parameter.Version != old.Version {
+				return nil, api.ErrVersionConflict
+			}
+			item.BaseVersion = old.Version
+		default:
+			errs.add(field+".action", "Action can be one of `%s`, `%s`, `%s`",
+				domain.ChangeActionCreate, domain.ChangeActionUpdate, domain.ChangeActionDelete)
+			continue
+		}
+		if c.Action == domain.ChangeActionDelete {
+			delete(state, code)
+		} else {
+			p, err := pAPI.buildParameter(c.Parameter)
+			if err != nil {
+				if !errs.addNested(field+".parameter", err) {
+					return nil, err
+				}
+				continue
+			}
+			item.Parameter = p
+			state[code] = p
+		}
+		items = append(items, item)
+	}
+	if err = errs.err("Wrong changeset"); err != nil {
+		return nil, err
+	}
+	final := make([]*domain.Parameter, 0, len(state))
+	for _, code := range order {
+		if p, ok := state[code]; ok {
+			final = append(final, p)
+		}
+	}
+	// Prerequisites are checked for changed parameters and for parameters depending on them
+	for _, p := range final {
+		if i, ok := touched[p.Code]; ok {
+			list, err := checkPrerequisites(p.Code, p.Prerequisites, final)
+			if err != nil {
+				errs.addNested(fmt.Sprintf("changes[%d].parameter", i), err)
+				continue
+			}
+			p.Prerequisites = list
+			continue
+		}
+		for _, pr := range p.Prerequisites {
+			i, ok := touched[pr.Parameter]
+			if !ok {
+				continue
+			}
+			if _, err := checkPrerequisites(p.Code, p.Prerequisites, final); err != nil {
+				errs.add(fmt.Sprintf("changes[%d]", i), "Prerequisites of `%s` are broken: %s", p.Code, fieldMessages(err))
+			}
+			break
+		}
+	}
+	if err = errs.err("Wrong changeset"); err != nil {
+		return nil, err
+	}
+	return &domain.Changeset{
+		ID:          util.NewID(),
+		Owner:       a.owner,
+		Project:     a.project,
+		Environment: a.env,
+		Description: info.Description,
+		Principal:   a.principal,
+		Changes:     items,
+		RegDate:     util.Now(),
+	}, nil
+}
+
+// fieldMessages joins messages of bad request fields
+func fieldMessages(err error) string {
+	br, ok := err.(*api.ErrBadRequest)
+	if !ok || len(br.Fields) == 0 {
+		return err.Error()
+	}
+	msg := br.Fields[0].Message
+	for _, f := range br.Fields[1:] {
+		msg += "; " + f.Message
+	}
+	return msg
+}
+
+// changesetInfo returns info describing existing changeset
+func changesetInfo(cs *domain.Changeset) *api.ChangesetInfo {
+	info := &api.ChangesetInfo{Description: cs.Description}
+	for _, item := range cs.Changes {
+		change := &api.ChangeInfo{Action: item.Action}
+		if item.Parameter != nil {
+			change.Parameter = parameterInfo(item.Parameter, item.BaseVersion)
+		} else {
+			change.Parameter = &api.ParameterInfo{Code: item.ParameterCode, Version: item.BaseVersion}
+		}
+		info.Changes = append(info.Changes, change)
+	}
+	return info
+}
+
+func changesetError(err error) error {
+	switch err {
+	case storage.ErrNotFound:
+		return api.ErrChangesetNotFound
+	}
+	return err
+}
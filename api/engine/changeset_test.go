@@ -0,0 +1,171 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIChangeset(t *testing.T) {
+
+	assert := asserts.New(t)
+	events := &eventRecorder{}
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "dev"}},
+		Events:              events,
+	}, logger)
+	pApi := e.ForOwner("ow1").As("alice").Projects()
+	envAPI := pApi.For("proj1").Environments().For("dev")
+	params := envAPI.Parameters()
+	csAPI := envAPI.Changesets()
+
+	beforeTest()
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+	_, err = params.Create(&api.ParameterInfo{Code: "legacy", Type: domain.ParameterTypeBool, Value: true})
+	assert.Nil(err)
+
+	t.Run("apply", func(t *testing.T) {
+		events.events = nil
+		cs, err := csAPI.Apply(&api.ChangesetInfo{
+			Description: "new checkout",
+			Changes: []*api.ChangeInfo{
+				{Action: domain.ChangeActionCreate, Parameter: &api.ParameterInfo{
+					Code: "checkout", Type: domain.ParameterTypeBool, Value: true,
+					Prerequisites: []*domain.Prerequisite{{Parameter: "infra", Value: true}},
+				}},
+				{Action: domain.ChangeActionCreate, Parameter: &api.ParameterInfo{Code: "infra", Type: domain.ParameterTypeBool, Value: true}},
+				{Action: domain.ChangeActionDelete, Parameter: &api.ParameterInfo{Code: "legacy", Version: 1}},
+			},
+		})
+		assert.Nil(err)
+		assert.Equal(3, len(cs.Changes))
+		assert.Equal("alice", cs.Principal)
+		list, err := params.List()
+		assert.Nil(err)
+		assert.Equal(2, len(list))
+		for _, p := range list {
+			assert.Equal(1, p.Version)
+		}
+		if assert.Equal(1, len(events.events)) {
+			assert.Equal(domain.EventChangesetApplied, events.events[0].Type)
+		}
+	})
+
+	t.Run("all or nothing", func(t *testing.T) {
+		_, err := csAPI.Apply(&api.ChangesetInfo{
+			Changes: []*api.ChangeInfo{
+				{Action: domain.ChangeActionUpdate, Parameter: &api.ParameterInfo{Code: "infra", Type: domain.ParameterTypeBool, Value: false}},
+				{Action: domain.ChangeActionCreate, Parameter: &api.ParameterInfo{Code: "limit", Type: domain.ParameterTypeInt, Value: "many"}},
+			},
+		})
+		e, ok := err.(*api.ErrBadRequest)
+		if assert.True(ok) && assert.Equal(1, len(e.Fields)) {
+			assert.Equal("changes[1].parameter.value", e.Fields[0].Field)
+		}
+		p, err := params.Get("infra")
+		assert.Nil(err)
+		assert.Equal(true, p.Value)
+		_, err = params.Get("limit")
+		assert.Equal(api.ErrParameterNotFound, err)
+	})
+
+	t.Run("wrong changes", func(t *testing.T) {
+		for _, changes := range [][]*api.ChangeInfo{
+			{},
+			{{Action: "rename", Parameter: &api.ParameterInfo{Code: "infra"}}},
+			{{Action: domain.ChangeActionCreate, Parameter: &api.ParameterInfo{Code: "infra", Type: domain.ParameterTypeBool, Value: true}}},
+			{{Action: domain.ChangeActionDelete, Parameter: &api.ParameterInfo{Code: "missing"}}},
+			{{Action: domain.ChangeActionDelete, Parameter: &api.ParameterInfo{Code: "infra"}}},
+			{
+				{Action: domain.ChangeActionDelete, Parameter: &api.ParameterInfo{Code: "checkout"}},
+				{Action: domain.ChangeActionDelete, Parameter: &api.ParameterInfo{Code: "checkout"}},
+			},
+		} {
+			_, err := csAPI.Apply(&api.ChangesetInfo{Changes: changes})
+			_, ok := err.(*api.ErrBadRequest)
+			assert.True(ok)
+		}
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		_, err := csAPI.Apply(&api.ChangesetInfo{
+			Changes: []*api.ChangeInfo{
+				{Action: domain.ChangeActionUpdate, Parameter: &api.ParameterInfo{Code: "infra", Type: domain.ParameterTypeBool, Value: false, Version: 5}},
+			},
+		})
+		assert.Equal(api.ErrVersionConflict, err)
+	})
+
+	t.Run("delete dependent together", func(t *testing.T) {
+		_, err := csAPI.Apply(&api.ChangesetInfo{
+			Changes: []*api.ChangeInfo{
+				{Action: domain.ChangeActionDelete, Parameter: &api.ParameterInfo{Code: "infra"}},
+				{Action: domain.ChangeActionDelete, Parameter: &api.ParameterInfo{Code: "checkout"}},
+			},
+		})
+		assert.Nil(err)
+		list, err := params.List()
+		assert.Nil(err)
+		assert.Equal(0, len(list))
+	})
+
+	t.Run("log", func(t *testing.T) {
+		list, err := csAPI.List()
+		assert.Nil(err)
+		// Changesets applied within the same millisecond have no defined order
+		if assert.Equal(2, len(list)) {
+			descriptions := make([]string, 0, len(list))
+			for _, item := range list {
+				cs, err := csAPI.Get(item.ID)
+				assert.Nil(err)
+				descriptions = append(descriptions, cs.Description)
+			}
+			assert.Contains(descriptions, "new checkout")
+		}
+		_, err = csAPI.Get("missing")
+		assert.Equal(api.ErrChangesetNotFound, err)
+	})
+
+	afterTest()
+}
+
+func TestAPIChangesetApproval(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "prod", Protected: true}},
+		ApprovalsRequired:   1,
+	}, logger)
+	owner := e.ForOwner("ow1")
+	envAPI := owner.As("alice").Projects().For("proj1").Environments().For("prod")
+
+	beforeTest()
+
+	_, err := owner.Projects().Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+
+	_, err = envAPI.Changesets().Apply(&api.ChangesetInfo{
+		Changes: []*api.ChangeInfo{
+			{Action: domain.ChangeActionCreate, Parameter: &api.ParameterInfo{Code: "a", Type: domain.ParameterTypeBool, Value: true}},
+			{Action: domain.ChangeActionCreate, Parameter: &api.ParameterInfo{Code: "b", Type: domain.ParameterTypeBool, Value: true}},
+		},
+	})
+	approval, ok := err.(*api.ErrApprovalRequired)
+	if !assert.True(ok) {
+		return
+	}
+	assert.Equal(domain.ChangeActionChangeset, approval.ChangeRequest.Action)
+	cr, err := owner.As("bob").Projects().For("proj1").ChangeRequests().Approve(approval.ChangeRequest.ID, "")
+	assert.Nil(err)
+	assert.Equal(domain.ChangeRequestStatusApplied, cr.Status)
+	list, err := envAPI.Parameters().List()
+	assert.Nil(err)
+	assert.Equal(2, len(list))
+
+	afterTest()
+}
@@ -0,0 +1,283 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// fieldErrors collects invalid request fields
+type fieldErrors []*api.FieldError
+
+func (e *fieldErrors) add(field, format string, args ...interface{}) {
+	*e = append(*e, &api.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// addErr adds field error describing err
+func (e *fieldErrors) addErr(field string, err error) {
+	if br, ok := err.(*api.ErrBadRequest); ok {
+		e.add(field, "%s", br.Description)
+		return
+	}
+	e.add(field, "%s", err.Error())
+}
+
+// addNested adds field errors of the bad request under the prefix.
+// It reports false if err is not a bad request.
+func (e *fieldErrors) addNested(prefix string, err error) bool {
+	br, ok := err.(*api.ErrBadRequest)
+	if !ok {
+		return false
+	}
+	if len(br.Fields) == 0 {
+		e.add(prefix, "%s", br.Description)
+	}
+	for _, f := range br.Fields {
+		e.add(prefix+"."+f.Field, "%s", f.Message)
+	}
+	return true
+}
+
+// err returns bad request error listing collected fields or nil if there are none
+func (e fieldErrors) err(description string) error {
+	if len(e) == 0 {
+		return nil
+	}
+	return &api.ErrBadRequest{Description: description, Fields: e}
+}
+
+// valueChecker verifies values against parameter allowed values and constraints
+type valueChecker struct {
+	typ     string
+	allowed []interface{}
+	c       *domain.Constraints
+	pattern *regexp.Regexp
+	schema  *gojsonschema.Schema
+}
+
+// newValueChecker validates constraints for the parameter type. Allowed values must be normalized.
+func newValueChecker(typ string, allowed []interface{}, c *domain.Constraints) (*valueChecker, fieldErrors) {
+	vc := &valueChecker{typ: typ, allowed: allowed, c: c}
+	var errs fieldErrors
+	if c == nil {
+		return vc, errs
+	}
+	numeric := typ == domain.ParameterTypeInt || typ == domain.ParameterTypeFloat
+	textual := typ == domain.ParameterTypeString || typ == domain.ParameterTypeStringList
+	if (c.Min != nil || c.Max != nil) && !numeric {
+		errs.add("constraints.min", "Range is supported by `%s` and `%s` parameters only", domain.ParameterTypeInt, domain.ParameterTypeFloat)
+	}
+	if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+		errs.add("constraints.max", "Max is less than min")
+	}
+	if (c.MinLength != nil || c.MaxLength != nil || c.Pattern != "") && !textual {
+		errs.add("constraints.pattern", "Length and pattern are supported by `%s` and `%s` parameters only", domain.ParameterTypeString, domain.ParameterTypeStringList)
+	}
+	if c.MinLength != nil && *c.MinLength < 0 {
+		errs.add("constraints.min_length", "Length can't be negative")
+	}
+	if c.MaxLength != nil && *c.MaxLength < 0 {
+		errs.add("constraints.max_length", "Length can't be negative")
+	}
+	if c.MinLength != nil && c.MaxLength != nil && *c.MinLength > *c.MaxLength {
+		errs.add("constraints.max_length", "Max length is less than min length")
+	}
+	if c.Pattern != "" {
+		re, err := compiled.pattern(c.Pattern)
+		if err != nil {
+			errs.add("constraints.pattern", "Wrong pattern: %s", err)
+		}
+		vc.pattern = re
+	}
+	if c.Schema != "" {
+		if typ != domain.ParameterTypeJSON {
+			errs.add("constraints.schema", "Schema is supported by `%s` parameters only", domain.ParameterTypeJSON)
+		} else if schema, err := compiled.schema(c.Schema); err != nil {
+			errs.add("constraints.schema", "%s", err)
+		} else {
+			vc.schema = schema
+		}
+	}
+	return vc, errs
+}
+
+// maxCompiled limits the number of patterns and schemas kept compiled
+const maxCompiled = 1024
+
+// compiledCache keeps compiled patterns and schemas of parameter constraints, so checking values
+// of the same parameter doesn't compile them again. The cache is dropped when it's full.
+type compiledCache struct {
+	mu       sync.Mutex
+	patterns map[string]*regexp.Regexp
+	schemas  map[domain.JSONValue]*gojsonschema.Schema
+}
+
+var compiled = &compiledCache{}
+
+// pattern returns compiled pattern matching whole values
+func (c *compiledCache) pattern(pattern string) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	re, ok := c.patterns[pattern]
+	c.mu.Unlock()
+	if ok {
+		return re, nil
+	}
+	// The pattern alone is checked first, so it can't close the anchoring group
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.patterns == nil || len(c.patterns) >= maxCompiled {
+		c.patterns = map[string]*regexp.Regexp{}
+	}
+	c.patterns[pattern] = re
+	return re, nil
+}
+
+// schema returns compiled schema
+func (c *compiledCache) schema(value domain.JSONValue) (*gojsonschema.Schema, error) {
+	c.mu.Lock()
+	schema, ok := c.schemas[value]
+	c.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+	schema, err := compileSchema(value)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.schemas == nil || len(c.schemas) >= maxCompiled {
+		c.schemas = map[domain.JSONValue]*gojsonschema.Schema{}
+	}
+	c.schemas[value] = schema
+	return schema, nil
+}
+
+// compileSchema compiles JSON Schema. Only local references are allowed, so the server
+// never fetches schemas from the network or the file system.
+func compileSchema(value domain.JSONValue) (*gojsonschema.Schema, error) {
+	canonical, ok := normalizeJSON(value)
+	if !ok || !strings.HasPrefix(string(canonical), "{") {
+		return nil, fmt.Errorf("Schema is not a JSON object")
+	}
+	var doc interface{}
+	if err := json.Unmarshal([]byte(canonical), &doc); err != nil {
+		return nil, err
+	}
+	if ref := remoteRef(doc); ref != "" {
+		return nil, fmt.Errorf("Schema reference `%s` is not local", ref)
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(string(canonical)))
+	if err != nil {
+		return nil, fmt.Errorf("Wrong schema: %s", err)
+	}
+	return schema, nil
+}
+
+// remoteRef returns the first $ref not pointing into the document itself
+func remoteRef(doc interface{}) string {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if ref, ok := item.(string); key == "$ref" && ok && !strings.HasPrefix(ref, "#") {
+				return ref
+			}
+			if ref := remoteRef(item); ref != "" {
+				return ref
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if ref := remoteRef(item); ref != "" {
+				return ref
+			}
+		}
+	}
+	return ""
+}
+
+// check verifies normalized value and adds violations to errs
+func (vc *valueChecker) check(field string, value interface{}, errs *fieldErrors) {
+	if len(vc.allowed) > 0 && !vc.isAllowed(value) {
+		errs.add(field, "Value `%v` is not allowed", value)
+	}
+	if vc.c == nil {
+		return
+	}
+	switch v := value.(type) {
+	case int64:
+		vc.checkRange(field, float64(v), errs)
+	case float64:
+		vc.checkRange(field, v, errs)
+	case string:
+		if vc.typ == domain.ParameterTypeString {
+			vc.checkString(field, v, errs)
+		}
+	case []string:
+		for i, item := range v {
+			vc.checkString(fmt.Sprintf("%s[%d]", field, i), item, errs)
+		}
+	case domain.JSONValue:
+		vc.checkSchema(field, v, errs)
+	}
+}
+
+func (vc *valueChecker) isAllowed(value interface{}) bool {
+	for _, av := range vc.allowed {
+		if reflect.DeepEqual(av, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (vc *valueChecker) checkRange(field string, v float64, errs *fieldErrors) {
+	if vc.c.Min != nil && v < *vc.c.Min {
+		errs.add(field, "Value %v is less than %v", v, *vc.c.Min)
+	}
+	if vc.c.Max != nil && v > *vc.c.Max {
+		errs.add(field, "Value %v is greater than %v", v, *vc.c.Max)
+	}
+}
+
+func (vc *valueChecker) checkString(field string, v string, errs *fieldErrors) {
+	length := utf8.RuneCountInString(v)
+	if vc.c.MinLength != nil && length < *vc.c.MinLength {
+		errs.add(field, "Value is shorter than %d characters", *vc.c.MinLength)
+	}
+	if vc.c.MaxLength != nil && length > *vc.c.MaxLength {
+		errs.add(field, "Value is longer than %d characters", *vc.c.MaxLength)
+	}
+	if vc.pattern != nil && !vc.pattern.MatchString(v) {
+		errs.add(field, "Value `%s` does not match pattern `%s`", v, vc.c.Pattern)
+	}
+}
+
+func (vc *valueChecker) checkSchema(field string, v domain.JSONValue, errs *fieldErrors) {
+	if vc.schema == nil {
+		return
+	}
+	res, err := vc.schema.Validate(gojsonschema.NewStringLoader(string(v)))
+	if err != nil {
+		errs.add(field, "%s", err)
+		return
+	}
+	for _, e := range res.Errors() {
+		errs.add(field, "%s: %s", e.Field(), e.Description())
+	}
+}
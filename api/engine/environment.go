@@ -1,15 +1,182 @@
 package engine
 
 import (
+	"github.com/Toggly/core/api"
 	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
 )
 
-type environmentAPI struct{}
+type environmentAPI struct {
+	ownerAPI
+	project string
+	// approved is set when applying an approved change request
+	approved bool
+}
+
+func (a *environmentAPI) s() storage.EnvironmentStorage {
+	return a.storage.ForOwner(a.owner).Projects().For(a.project).Environments()
+}
+
+// needsApproval checks if changing protection or deleting the environment
+// must be submitted as a change request
+func (a *environmentAPI) needsApproval(env *domain.Environment) bool {
+	return env.Protected && a.cfg.ApprovalsRequired > 0 && !a.approved
+}
+
+// checkProject verifies environments parent project exists
+func (a *environmentAPI) checkProject() error {
+	_, err := a.storage.ForOwner(a.owner).Projects().Get(a.project)
+	return projectError(err)
+}
 
 func (a *environmentAPI) List() ([]*domain.Environment, error) {
-	return nil, nil
+	if err := a.checkProject(); err != nil {
+		return nil, err
+	}
+	return a.s().List()
 }
 
 func (a *environmentAPI) Get(code string) (*domain.Environment, error) {
-	return nil, nil
+	if err := a.checkProject(); err != nil {
+		return nil, err
+	}
+	env, err := a.s().Get(code)
+	if err != nil {
+		return nil, environmentError(err)
+	}
+	return env, nil
+}
+
+func checkEnvironmentParams(code string) error {
+	return checkCode("Environment", code)
+}
+
+func (a *environmentAPI) Create(info *api.EnvironmentInfo) (*domain.Environment, error) {
+	env, err := a.create(info)
+	if err != nil {
+		return nil, err
+	}
+	a.publish(domain.EventEnvironmentCreated, a.project, env.Code, "", env)
+	return env, nil
+}
+
+// create saves the environment without publishing the creation event
+func (a *environmentAPI) create(info *api.EnvironmentInfo) (*domain.Environment, error) {
+	if err := checkEnvironmentParams(info.Code); err != nil {
+		return nil, err
+	}
+	if err := a.checkProject(); err != nil {
+		return nil, err
+	}
+	env := &domain.Environment{
+		Code:        info.Code,
+		Owner:       a.owner,
+		Project:     a.project,
+		Description: info.Description,
+		Protected:   info.Protected,
+		Disabled:    info.Disabled,
+		RegDate:     util.Now(),
+	}
+	if err := a.s().Save(env); err != nil {
+		return nil, environmentError(err)
+	}
+	return env, nil
+}
+
+func (a *environmentAPI) Update(info *api.EnvironmentInfo) (*domain.Environment, error) {
+	if err := checkEnvironmentParams(info.Code); err != nil {
+		return nil, err
+	}
+	env, err := a.Get(info.Code)
+	if err != nil {
+		return nil, err
+	}
+	if info.Version != 0 && info.Version != env.Version {
+		return nil, api.ErrVersionConflict
+	}
+	newEnv := &domain.Environment{
+		Code:        info.Code,
+		Owner:       a.owner,
+		Project:     a.project,
+		Description: info.Description,
+		Protected:   info.Protected,
+		Disabled:    info.Disabled,
+		RegDate:     env.RegDate,
+		Version:     env.Version,
+	}
+	if a.needsApproval(env) && (newEnv.Protected != env.Protected || newEnv.Disabled != env.Disabled) {
+		return nil, a.requestChange(a.project, env.Code, &domain.ChangeRequest{
+			Action:           domain.ChangeActionEnvironmentUpdate,
+			EnvironmentState: newEnv,
+			BaseVersion:      env.Version,
+		})
+	}
+	if err = a.s().Update(newEnv); err != nil {
+		return nil, environmentError(err)
+	}
+	a.publish(domain.EventEnvironmentUpdated, a.project, newEnv.Code, "", newEnv)
+	return newEnv, nil
+}
+
+func (a *environmentAPI) Delete(code string, version int) error {
+	env, err := a.Get(code)
+	if err != nil {
+		return err
+	}
+	if a.needsApproval(env) {
+		if version != 0 && version != env.Version {
+			return api.ErrVersionConflict
+		}
+		return a.requestChange(a.project, code, &domain.ChangeRequest{
+			Action:      domain.ChangeActionEnvironmentDelete,
+			BaseVersion: env.Version,
+		})
+	}
+	if err := a.s().Delete(code, version); err != nil {
+		return environmentError(err)
+	}
+	a.publish(domain.EventEnvironmentDeleted, a.project, code, "", nil)
+	return nil
+}
+
+func (a *environmentAPI) For(code string) api.ForEnvironmentAPI {
+	return &forEnvironmentAPI{a.ownerAPI, a.project, code}
+}
+
+type forEnvironmentAPI struct {
+	ownerAPI
+	project string
+	env     string
+}
+
+func (a *forEnvironmentAPI) Parameters() api.ParameterAPI {
+	return &parameterAPI{ownerAPI: a.ownerAPI, project: a.project, env: a.env}
+}
+
+func (a *forEnvironmentAPI) Changesets() api.ChangesetAPI {
+	return &changesetAPI{ownerAPI: a.ownerAPI, project: a.project, env: a.env}
+}
+
+func environmentInfo(env *domain.Environment, version int) *api.EnvironmentInfo {
+	return &api.EnvironmentInfo{
+		Code:        env.Code,
+		Description: env.Description,
+		Protected:   env.Protected,
+		Disabled:    env.Disabled,
+		Version:     version,
+	}
+}
+
+func environmentError(err error) error {
+	if _, ok := err.(*storage.ErrUniqueIndex); ok {
+		return api.ErrEnvironmentExists
+	}
+	switch err {
+	case storage.ErrNotFound:
+		return api.ErrEnvironmentNotFound
+	case storage.ErrVersionConflict:
+		return api.ErrVersionConflict
+	}
+	return err
 }
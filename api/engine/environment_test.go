@@ -0,0 +1,76 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIEnvironment(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), nil, logger)
+	pApi := e.ForOwner("ow1").Projects()
+	eApi := pApi.For("proj1").Environments()
+
+	beforeTest()
+
+	t.Run("project not found", func(t *testing.T) {
+		_, err := eApi.List()
+		assert.Equal(api.ErrProjectNotFound, err)
+		_, err = eApi.Create(&api.EnvironmentInfo{Code: "env1"})
+		assert.Equal(api.ErrProjectNotFound, err)
+	})
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+
+	t.Run("get not found", func(t *testing.T) {
+		env, err := eApi.Get("env1")
+		assert.Nil(env)
+		assert.Equal(api.ErrEnvironmentNotFound, err)
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		for _, info := range []*api.EnvironmentInfo{{}, {Code: "env\x001"}} {
+			_, err := eApi.Create(info)
+			_, ok := err.(*api.ErrBadRequest)
+			assert.True(ok)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		env, err := eApi.Create(&api.EnvironmentInfo{Code: "env1", Description: "Env 1"})
+		assert.Nil(err)
+		assert.Equal("env1", env.Code)
+		assert.Equal("proj1", env.Project)
+		assert.Equal("ow1", env.Owner)
+		assert.Equal(1, env.Version)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		env, err := eApi.Update(&api.EnvironmentInfo{Code: "env1", Protected: true, Version: 1})
+		assert.Nil(err)
+		assert.True(env.Protected)
+		assert.Equal(2, env.Version)
+		_, err = eApi.Update(&api.EnvironmentInfo{Code: "env1", Version: 1})
+		assert.Equal(api.ErrVersionConflict, err)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list, err := eApi.List()
+		assert.Nil(err)
+		assert.Len(list, 1)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(api.ErrVersionConflict, eApi.Delete("env1", 1))
+		assert.Nil(eApi.Delete("env1", 2))
+		assert.Equal(api.ErrEnvironmentNotFound, eApi.Delete("env1", 0))
+	})
+
+	afterTest()
+}
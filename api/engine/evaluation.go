@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"reflect"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+)
+
+// Evaluate reads the project, the environment and its parameters once each,
+// straight from storage, as evaluations are served far more often than anything else
+func (a *forEnvironmentAPI) Evaluate(key string, codes ...string) ([]*api.Evaluation, error) {
+	projects := a.storage.ForOwner(a.owner).Projects()
+	proj, err := projects.Get(a.project)
+	if err != nil {
+		return nil, projectError(err)
+	}
+	envs := projects.For(a.project).Environments()
+	env, err := envs.Get(a.env)
+	if err != nil {
+		return nil, environmentError(err)
+	}
+	// all parameters are loaded, since prerequisites of the requested ones are evaluated too
+	list, err := envs.For(a.env).Parameters().List()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range list {
+		normalizeParameter(p)
+	}
+	off := ""
+	switch {
+	case proj.Status == domain.ProjectStatusDisabled:
+		off = api.EvaluationReasonProjectDisabled
+	case env.Disabled:
+		off = api.EvaluationReasonEnvironmentDisabled
+	}
+	ev := newEvaluator(list, key, off)
+	wanted := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		wanted[code] = true
+	}
+	res := make([]*api.Evaluation, 0, len(list))
+	for _, p := range list {
+		if len(codes) == 0 || wanted[p.Code] {
+			res = append(res, ev.evaluate(p))
+		}
+	}
+	if a.cfg.Evaluations != nil {
+		a.cfg.Evaluations.Record(a.owner, a.project, a.env, res)
+	}
+	return res, nil
+}
+
+// evaluator computes values served to the evaluation context by environment parameters.
+// Non-empty off reason means all parameters are switched off.
+type evaluator struct {
+	params map[string]*domain.Parameter
+	key    string
+	off    string
+	done   map[string]*api.Evaluation
+}
+
+func newEvaluator(list []*domain.Parameter, key, off string) *evaluator {
+	params := make(map[string]*domain.Parameter, len(list))
+	for _, p := range list {
+		params[p.Code] = p
+	}
+	return &evaluator{params: params, key: key, off: off, done: map[string]*api.Evaluation{}}
+}
+
+// evaluate returns parameter value served to the context. Every parameter is evaluated once,
+// so prerequisites shared by several parameters are not evaluated again.
+func (e *evaluator) evaluate(p *domain.Parameter) *api.Evaluation {
+	if ev, ok := e.done[p.Code]; ok {
+		return ev
+	}
+	// a parameter reached again through its own prerequisites fails them
+	e.done[p.Code] = &api.Evaluation{
+		Code:   p.Code,
+		Type:   p.Type,
+		Value:  offValue(p),
+		Reason: api.EvaluationReasonPrerequisiteFailed,
+	}
+	ev := &api.Evaluation{
+		Code:   p.Code,
+		Type:   p.Type,
+		Value:  p.Value,
+		Reason: api.EvaluationReasonValue,
+	}
+	switch {
+	case e.off != "":
+		ev.Value = offValue(p)
+		ev.Reason = e.off
+	case !e.prerequisitesMet(p):
+		ev.Value = offValue(p)
+		ev.Reason = api.EvaluationReasonPrerequisiteFailed
+	case e.key != "" && len(p.Variants) > 0:
+		if v := assignVariant(p, e.key); v != nil {
+			ev.Value = v.Value
+			ev.Variant = v.Name
+			ev.Reason = api.EvaluationReasonVariant
+		}
+	}
+	e.done[p.Code] = ev
+	return ev
+}
+
+// prerequisitesMet checks if every prerequisite parameter serves the required value
+func (e *evaluator) prerequisitesMet(p *domain.Parameter) bool {
+	for _, pr := range p.Prerequisites {
+		target, ok := e.params[pr.Parameter]
+		if !ok {
+			return false
+		}
+		required, err := normalizeValue(target.Type, pr.Value)
+		if err != nil || !reflect.DeepEqual(e.evaluate(target).Value, required) {
+			return false
+		}
+	}
+	return true
+}
+
+// assignVariant picks parameter variant for the context key. The same key always
+// gets the same variant while variant weights stay the same.
+func assignVariant(p *domain.Parameter, key string) *domain.Variant {
+	total := 0
+	for _, v := range p.Variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(p.Code + "/" + key))
+	bucket := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	for _, v := range p.Variants {
+		if bucket < v.Weight {
+			return v
+		}
+		bucket -= v.Weight
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package engine_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIEvaluation(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "prod"}},
+	}, logger)
+	pApi := e.ForOwner("ow1").Projects()
+	envAPI := pApi.For("proj1").Environments().For("prod")
+
+	beforeTest()
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+	_, err = envAPI.Parameters().Create(&api.ParameterInfo{Code: "flag", Type: domain.ParameterTypeBool, Value: true})
+	assert.Nil(err)
+	_, err = envAPI.Parameters().Create(&api.ParameterInfo{
+		Code: "limit", Type: domain.ParameterTypeInt, Value: 100, OffValue: 10,
+	})
+	assert.Nil(err)
+
+	t.Run("bad off value", func(t *testing.T) {
+		_, err := envAPI.Parameters().Create(&api.ParameterInfo{
+			Code: "other", Type: domain.ParameterTypeBool, Value: true, OffValue: "off",
+		})
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+	})
+
+	t.Run("active", func(t *testing.T) {
+		res, err := envAPI.Evaluate("", "flag")
+		assert.Nil(err)
+		assert.Equal(1, len(res))
+		assert.Equal(true, res[0].Value)
+		assert.Equal(api.EvaluationReasonValue, res[0].Reason)
+	})
+
+	t.Run("environment disabled", func(t *testing.T) {
+		_, err := pApi.For("proj1").Environments().Update(&api.EnvironmentInfo{Code: "prod", Disabled: true})
+		assert.Nil(err)
+		res, err := envAPI.Evaluate("")
+		assert.Nil(err)
+		assert.Equal(2, len(res))
+		values := map[string]interface{}{}
+		for _, ev := range res {
+			values[ev.Code] = ev.Value
+			assert.Equal(api.EvaluationReasonEnvironmentDisabled, ev.Reason)
+		}
+		assert.Equal(false, values["flag"])
+		assert.Equal(int64(10), values["limit"])
+		_, err = pApi.For("proj1").Environments().Update(&api.EnvironmentInfo{Code: "prod"})
+		assert.Nil(err)
+	})
+
+	t.Run("project disabled", func(t *testing.T) {
+		_, err := pApi.Update(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusDisabled})
+		assert.Nil(err)
+		res, err := envAPI.Evaluate("", "limit")
+		assert.Nil(err)
+		assert.Equal(int64(10), res[0].Value)
+		assert.Equal(api.EvaluationReasonProjectDisabled, res[0].Reason)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := pApi.For("proj1").Environments().For("none").Evaluate("")
+		assert.Equal(api.ErrEnvironmentNotFound, err)
+	})
+
+	afterTest()
+}
+
+func TestAPIEvaluationVariants(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "prod"}},
+	}, logger)
+	pApi := e.ForOwner("ow1").Projects()
+	envAPI := pApi.For("proj1").Environments().For("prod")
+
+	beforeTest()
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+
+	t.Run("bad variants", func(t *testing.T) {
+		tt := [][]*domain.Variant{
+			{{Name: "", Value: "a", Weight: 1}},
+			{{Name: "a", Value: "a", Weight: 1}, {Name: "a", Value: "b", Weight: 1}},
+			{{Name: "a", Value: "a", Weight: -1}, {Name: "b", Value: "b", Weight: 2}},
+			{{Name: "a", Value: "a", Weight: 0}},
+			{{Name: "a", Value: 1, Weight: 1}},
+			{{Name: "a", Value: "c", Weight: 1}},
+		}
+		for _, tc := range tt {
+			_, err := envAPI.Parameters().Create(&api.ParameterInfo{
+				Code: "color", Type: domain.ParameterTypeString, Value: "a",
+				AllowedValues: []interface{}{"a", "b"}, Variants: tc,
+			})
+			e, ok := err.(*api.ErrBadRequest)
+			assert.True(ok)
+			assert.NotEmpty(e.Fields)
+		}
+	})
+
+	_, err = envAPI.Parameters().Create(&api.ParameterInfo{
+		Code: "color", Type: domain.ParameterTypeString, Value: "grey",
+		Variants: []*domain.Variant{
+			{Name: "control", Value: "grey", Weight: 50},
+			{Name: "blue", Value: "blue", Weight: 25},
+			{Name: "green", Value: "green", Weight: 25},
+		},
+	})
+	assert.Nil(err)
+
+	t.Run("deterministic", func(t *testing.T) {
+		res, err := envAPI.Evaluate("user-1", "color")
+		assert.Nil(err)
+		assert.Equal(api.EvaluationReasonVariant, res[0].Reason)
+		assert.NotEmpty(res[0].Variant)
+		for i := 0; i < 5; i++ {
+			again, err := envAPI.Evaluate("user-1", "color")
+			assert.Nil(err)
+			assert.Equal(res[0].Variant, again[0].Variant)
+		}
+	})
+
+	t.Run("distribution", func(t *testing.T) {
+		counts := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			res, err := envAPI.Evaluate(fmt.Sprintf("user-%d", i), "color")
+			assert.Nil(err)
+			counts[res[0].Variant]++
+		}
+		assert.InDelta(500, counts["control"], 60)
+		assert.InDelta(250, counts["blue"], 60)
+		assert.InDelta(250, counts["green"], 60)
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		res, err := envAPI.Evaluate("", "color")
+		assert.Nil(err)
+		assert.Equal("grey", res[0].Value)
+		assert.Empty(res[0].Variant)
+	})
+
+	t.Run("weights editable", func(t *testing.T) {
+		_, err := envAPI.Parameters().Update(&api.ParameterInfo{
+			Code: "color", Type: domain.ParameterTypeString, Value: "grey",
+			Variants: []*domain.Variant{
+				{Name: "control", Value: "grey", Weight: 0},
+				{Name: "blue", Value: "blue", Weight: 100},
+			},
+		})
+		assert.Nil(err)
+		res, err := envAPI.Evaluate("user-1", "color")
+		assert.Nil(err)
+		assert.Equal("blue", res[0].Variant)
+	})
+
+	afterTest()
+}
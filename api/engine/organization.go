@@ -0,0 +1,358 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	"github.com/rs/zerolog"
+)
+
+type organizationAPI struct {
+	principal string
+	storage   storage.DataStorage
+	cfg       *Config
+	log       zerolog.Logger
+}
+
+func (a *organizationAPI) s() storage.OrganizationStorage {
+	return a.storage.Organizations()
+}
+
+func (a *organizationAPI) As(principal string) api.OrganizationAPI {
+	o := *a
+	o.principal = principal
+	return &o
+}
+
+func (a *organizationAPI) List() ([]*domain.Organization, error) {
+	if a.principal == "" {
+		return nil, api.ErrAccessDenied
+	}
+	return a.s().List(a.principal)
+}
+
+// get returns organization the principal is a member of together with the membership
+func (a *organizationAPI) get(id string) (*domain.Organization, *domain.Member, error) {
+	org, err := a.s().Get(id)
+	if err != nil {
+		return nil, nil, organizationError(err)
+	}
+	member := org.Member(a.principal)
+	if member == nil || a.principal == "" {
+		return nil, nil, api.ErrAccessDenied
+	}
+	return org, member, nil
+}
+
+func (a *organizationAPI) Get(id string) (*domain.Organization, error) {
+	org, _, err := a.get(id)
+	return org, err
+}
+
+// manage returns organization the principal can manage
+func (a *organizationAPI) manage(id string, version int) (*domain.Organization, *domain.Member, error) {
+	org, member, err := a.get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if member.Role != domain.MemberRoleOwner && member.Role != domain.MemberRoleAdmin {
+		return nil, nil, api.ErrAccessDenied
+	}
+	if version != 0 && version != org.Version {
+		return nil, nil, api.ErrVersionConflict
+	}
+	return org, member, nil
+}
+
+func (a *organizationAPI) update(org *domain.Organization) (*domain.Organization, error) {
+	if err := a.s().Update(org); err != nil {
+		return nil, organizationError(err)
+	}
+	return org, nil
+}
+
+func checkOrganizationParams(name string) error {
+	if name == "" {
+		return &api.ErrBadRequest{
+			Description: "Organization name not specified",
+		}
+	}
+	return nil
+}
+
+func (a *organizationAPI) Create(info *api.OrganizationInfo) (*domain.Organization, error) {
+	if err := checkOrganizationParams(info.Name); err != nil {
+		return nil, err
+	}
+	if a.principal == "" {
+		return nil, api.ErrAccessDenied
+	}
+	org := &domain.Organization{
+		ID:          util.NewID(),
+		Name:        info.Name,
+		Description: info.Description,
+		Members:     []*domain.Member{{Principal: a.principal, Role: domain.MemberRoleOwner}},
+		Teams:       []*domain.Team{},
+		RegDate:     util.Now(),
+	}
+	if err := a.s().Save(org); err != nil {
+		return nil, err
+	}
+	a.log.Info().Str("id", org.ID).Str("principal", a.principal).Msg("Organization created")
+	return org, nil
+}
+
+func (a *organizationAPI) Update(info *api.OrganizationInfo) (*domain.Organization, error) {
+	if err := checkOrganizationParams(info.Name); err != nil {
+		return nil, err
+	}
+	org, _, err := a.manage(info.ID, info.Version)
+	if err != nil {
+		return nil, err
+	}
+	org.Name = info.Name
+	org.Description = info.Description
+	return a.update(org)
+}
+
+func (a *organizationAPI) Delete(id string, version int) error {
+	_, member, err := a.manage(id, version)
+	if err != nil {
+		return err
+	}
+	if member.Role != domain.MemberRoleOwner {
+		return api.ErrAccessDenied
+	}
+	projects, err := a.storage.ForOwner(id).Projects().List()
+	if err != nil {
+		return err
+	}
+	if len(projects) > 0 {
+		return api.ErrOrganizationNotEmpty
+	}
+	if err := a.s().Delete(id, version); err != nil {
+		return organizationError(err)
+	}
+	a.log.Info().Str("id", id).Str("principal", a.principal).Msg("Organization deleted")
+	return nil
+}
+
+func checkMemberRole(role string) error {
+	switch role {
+	case domain.MemberRoleOwner, domain.MemberRoleAdmin, domain.MemberRoleMember:
+		return nil
+	}
+	return &api.ErrBadRequest{
+		Description: fmt.Sprintf("Member role can be one of `%s`, `%s`, `%s`",
+			domain.MemberRoleOwner, domain.MemberRoleAdmin, domain.MemberRoleMember),
+	}
+}
+
+// ownersLeft returns the number of organization owners except the principal
+func ownersLeft(org *domain.Organization, principal string) int {
+	n := 0
+	for _, m := range org.Members {
+		if m.Role == domain.MemberRoleOwner && m.Principal != principal {
+			n++
+		}
+	}
+	return n
+}
+
+// checkOwnerChange verifies only owners change owners and the organization keeps one
+func checkOwnerChange(org *domain.Organization, actor *domain.Member, target *domain.Member) error {
+	if target == nil || target.Role != domain.MemberRoleOwner {
+		return nil
+	}
+	if actor.Role != domain.MemberRoleOwner {
+		return api.ErrAccessDenied
+	}
+	if ownersLeft(org, target.Principal) == 0 {
+		return &api.ErrBadRequest{Description: "Organization must have an owner"}
+	}
+	return nil
+}
+
+func (a *organizationAPI) SetMember(id, principal, role string) (*domain.Organization, error) {
+	if principal == "" {
+		return nil, &api.ErrBadRequest{Description: "Member principal not specified"}
+	}
+	if err := checkMemberRole(role); err != nil {
+		return nil, err
+	}
+	org, actor, err := a.manage(id, 0)
+	if err != nil {
+		return nil, err
+	}
+	if role == domain.MemberRoleOwner && actor.Role != domain.MemberRoleOwner {
+		return nil, api.ErrAccessDenied
+	}
+	member := org.Member(principal)
+	if member != nil && member.Role != role {
+		if err := checkOwnerChange(org, actor, member); err != nil {
+			return nil, err
+		}
+	}
+	if member == nil {
+		org.Members = append(org.Members, &domain.Member{Principal: principal, Role: role})
+	} else {
+		member.Role = role
+	}
+	return a.update(org)
+}
+
+// RemoveMember removes the member from the organization and its teams.
+// Any member can leave the organization.
+func (a *organizationAPI) RemoveMember(id, principal string) (*domain.Organization, error) {
+	var org *domain.Organization
+	var actor *domain.Member
+	var err error
+	if principal == a.principal {
+		org, actor, err = a.get(id)
+	} else {
+		org, actor, err = a.manage(id, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	member := org.Member(principal)
+	if member == nil {
+		return nil, &api.ErrBadRequest{Description: fmt.Sprintf("Principal `%s` is not a member", principal)}
+	}
+	if err := checkOwnerChange(org, actor, member); err != nil {
+		return nil, err
+	}
+	members := make([]*domain.Member, 0, len(org.Members))
+	for _, m := range org.Members {
+		if m.Principal != principal {
+			members = append(members, m)
+		}
+	}
+	org.Members = members
+	for _, t := range org.Teams {
+		t.Members = without(t.Members, principal)
+	}
+	return a.update(org)
+}
+
+// without returns list items not equal to the value
+func without(list []string, value string) []string {
+	res := make([]string, 0, len(list))
+	for _, item := range list {
+		if item != value {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func (a *organizationAPI) SaveTeam(id string, info *api.TeamInfo) (*domain.Organization, error) {
+	if err := checkCode("Team", info.Code); err != nil {
+		return nil, err
+	}
+	org, _, err := a.manage(id, 0)
+	if err != nil {
+		return nil, err
+	}
+	var errs fieldErrors
+	members := make([]string, 0, len(info.Members))
+	seen := make(map[string]bool, len(info.Members))
+	for i, principal := range info.Members {
+		switch {
+		case org.Member(principal) == nil:
+			errs.add(fmt.Sprintf("members[%d]", i), "Principal `%s` is not a member", principal)
+		case !seen[principal]:
+			seen[principal] = true
+			members = append(members, principal)
+		}
+	}
+	projects := make([]string, 0, len(info.Projects))
+	granted := make(map[string]bool, len(info.Projects))
+	for i, code := range info.Projects {
+		if _, err := a.storage.ForOwner(id).Projects().Get(code); err != nil {
+			if err != storage.ErrNotFound {
+				return nil, err
+			}
+			errs.add(fmt.Sprintf("projects[%d]", i), "Project `%s` not found", code)
+			continue
+		}
+		if !granted[code] {
+			granted[code] = true
+			projects = append(projects, code)
+		}
+	}
+	if err := errs.err("Wrong team"); err != nil {
+		return nil, err
+	}
+	team := org.Team(info.Code)
+	if team == nil {
+		team = &domain.Team{Code: info.Code}
+		org.Teams = append(org.Teams, team)
+	}
+	team.Description = info.Description
+	team.Members = members
+	team.Projects = projects
+	return a.update(org)
+}
+
+func (a *organizationAPI) DeleteTeam(id, code string) (*domain.Organization, error) {
+	org, _, err := a.manage(id, 0)
+	if err != nil {
+		return nil, err
+	}
+	if org.Team(code) == nil {
+		return nil, api.ErrTeamNotFound
+	}
+	teams := make([]*domain.Team, 0, len(org.Teams))
+	for _, t := range org.Teams {
+		if t.Code != code {
+			teams = append(teams, t)
+		}
+	}
+	org.Teams = teams
+	return a.update(org)
+}
+
+func (a *organizationAPI) Authorize(owner, project string) error {
+	if !a.cfg.Organizations {
+		return nil
+	}
+	org, member, err := a.get(owner)
+	if err != nil {
+		return err
+	}
+	if project == "" || member.Role == domain.MemberRoleOwner || member.Role == domain.MemberRoleAdmin {
+		return nil
+	}
+	if project == api.AllProjects {
+		return api.ErrAccessDenied
+	}
+	for _, t := range org.Teams {
+		if contains(t.Members, a.principal) && contains(t.Projects, project) {
+			return nil
+		}
+	}
+	return api.ErrAccessDenied
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func organizationError(err error) error {
+	switch err {
+	case storage.ErrNotFound:
+		return api.ErrOrganizationNotFound
+	case storage.ErrVersionConflict:
+		return api.ErrVersionConflict
+	}
+	return err
+}
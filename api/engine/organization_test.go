@@ -0,0 +1,131 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIOrganization(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{Organizations: true}, logger)
+	alice := e.Organizations().As("alice")
+	bob := e.Organizations().As("bob")
+	carol := e.Organizations().As("carol")
+
+	beforeTest()
+
+	var org *domain.Organization
+
+	t.Run("create", func(t *testing.T) {
+		_, err := alice.Create(&api.OrganizationInfo{})
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+		_, err = e.Organizations().Create(&api.OrganizationInfo{Name: "Acme"})
+		assert.Equal(api.ErrAccessDenied, err)
+
+		org, err = alice.Create(&api.OrganizationInfo{Name: "Acme"})
+		assert.Nil(err)
+		assert.NotEmpty(org.ID)
+		assert.Equal(1, org.Version)
+		if assert.Equal(1, len(org.Members)) {
+			assert.Equal(domain.MemberRoleOwner, org.Members[0].Role)
+		}
+		list, err := alice.List()
+		assert.Nil(err)
+		assert.Equal(1, len(list))
+		list, err = bob.List()
+		assert.Nil(err)
+		assert.Equal(0, len(list))
+		_, err = bob.Get(org.ID)
+		assert.Equal(api.ErrAccessDenied, err)
+		_, err = alice.Get("missing")
+		assert.Equal(api.ErrOrganizationNotFound, err)
+	})
+
+	_, err := e.ForOwner(org.ID).Projects().Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+	_, err = e.ForOwner(org.ID).Projects().Create(&api.ProjectInfo{Code: "proj2", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+
+	t.Run("members", func(t *testing.T) {
+		_, err := alice.SetMember(org.ID, "bob", "guest")
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+		_, err = bob.SetMember(org.ID, "bob", domain.MemberRoleAdmin)
+		assert.Equal(api.ErrAccessDenied, err)
+
+		res, err := alice.SetMember(org.ID, "bob", domain.MemberRoleAdmin)
+		assert.Nil(err)
+		assert.Equal(2, len(res.Members))
+		_, err = bob.SetMember(org.ID, "carol", domain.MemberRoleMember)
+		assert.Nil(err)
+		_, err = bob.SetMember(org.ID, "carol", domain.MemberRoleOwner)
+		assert.Equal(api.ErrAccessDenied, err)
+		_, err = bob.SetMember(org.ID, "alice", domain.MemberRoleMember)
+		assert.Equal(api.ErrAccessDenied, err)
+		_, err = alice.SetMember(org.ID, "alice", domain.MemberRoleMember)
+		_, ok = err.(*api.ErrBadRequest)
+		assert.True(ok, "last owner can't be downgraded")
+		_, err = alice.RemoveMember(org.ID, "alice")
+		_, ok = err.(*api.ErrBadRequest)
+		assert.True(ok, "last owner can't leave")
+	})
+
+	t.Run("teams", func(t *testing.T) {
+		_, err := carol.SaveTeam(org.ID, &api.TeamInfo{Code: "checkout"})
+		assert.Equal(api.ErrAccessDenied, err)
+		_, err = bob.SaveTeam(org.ID, &api.TeamInfo{Code: "checkout", Members: []string{"dave"}, Projects: []string{"proj3"}})
+		br, ok := err.(*api.ErrBadRequest)
+		if assert.True(ok) {
+			assert.Equal(2, len(br.Fields))
+		}
+		res, err := bob.SaveTeam(org.ID, &api.TeamInfo{Code: "checkout", Members: []string{"carol"}, Projects: []string{"proj1"}})
+		assert.Nil(err)
+		if assert.Equal(1, len(res.Teams)) {
+			assert.Equal([]string{"carol"}, res.Teams[0].Members)
+		}
+		_, err = bob.DeleteTeam(org.ID, "missing")
+		assert.Equal(api.ErrTeamNotFound, err)
+	})
+
+	t.Run("authorize", func(t *testing.T) {
+		assert.Nil(carol.Authorize(org.ID, ""))
+		assert.Nil(carol.Authorize(org.ID, "proj1"))
+		assert.Equal(api.ErrAccessDenied, carol.Authorize(org.ID, "proj2"))
+		assert.Equal(api.ErrAccessDenied, carol.Authorize(org.ID, api.AllProjects))
+		assert.Nil(bob.Authorize(org.ID, "proj2"))
+		assert.Nil(bob.Authorize(org.ID, api.AllProjects))
+		assert.Equal(api.ErrAccessDenied, e.Organizations().As("dave").Authorize(org.ID, ""))
+		assert.Equal(api.ErrOrganizationNotFound, carol.Authorize("ow1", ""))
+
+		free := engine.NewTogglyAPI(getDB(), nil, logger).Organizations().As("dave")
+		assert.Nil(free.Authorize("ow1", "proj1"))
+	})
+
+	t.Run("leave", func(t *testing.T) {
+		res, err := carol.RemoveMember(org.ID, "carol")
+		assert.Nil(err)
+		assert.Equal(2, len(res.Members))
+		assert.Equal(0, len(res.Teams[0].Members))
+		assert.Equal(api.ErrAccessDenied, carol.Authorize(org.ID, ""))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(api.ErrAccessDenied, bob.Delete(org.ID, 0))
+		assert.Equal(api.ErrVersionConflict, alice.Delete(org.ID, 1))
+		assert.Equal(api.ErrOrganizationNotEmpty, alice.Delete(org.ID, 0))
+		for _, code := range []string{"proj1", "proj2"} {
+			assert.Nil(e.ForOwner(org.ID).Projects().Delete(code, 0, true))
+		}
+		assert.Nil(alice.Delete(org.ID, 0))
+		_, err := alice.Get(org.ID)
+		assert.Equal(api.ErrOrganizationNotFound, err)
+	})
+
+	afterTest()
+}
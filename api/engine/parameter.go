@@ -0,0 +1,352 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+)
+
+type parameterAPI struct {
+	ownerAPI
+	project string
+	env     string
+	// approved is set when applying an approved change request
+	approved bool
+}
+
+func (a *parameterAPI) s() storage.ParameterStorage {
+	return a.storage.ForOwner(a.owner).Projects().For(a.project).Environments().For(a.env).Parameters()
+}
+
+// checkEnvironment verifies parameters parent project and environment exist
+func (a *parameterAPI) checkEnvironment() error {
+	_, err := a.environment()
+	return err
+}
+
+func (a *parameterAPI) environment() (*domain.Environment, error) {
+	return (&environmentAPI{ownerAPI: a.ownerAPI, project: a.project}).Get(a.env)
+}
+
+// needsApproval checks if parameter changes must be submitted as change requests
+func (a *parameterAPI) needsApproval() (bool, error) {
+	env, err := a.environment()
+	if err != nil {
+		return false, err
+	}
+	return env.Protected && a.cfg.ApprovalsRequired > 0 && !a.approved, nil
+}
+
+// submit stores the change as a pending change request
+func (a *parameterAPI) submit(action, code string, param *domain.Parameter, baseVersion int) error {
+	return a.submitRequest(&domain.ChangeRequest{
+		Action:        action,
+		ParameterCode: code,
+		Parameter:     param,
+		BaseVersion:   baseVersion,
+	})
+}
+
+// submitRequest stores the change request of the parameters environment as pending
+func (a *parameterAPI) submitRequest(cr *domain.ChangeRequest) error {
+	return a.requestChange(a.project, a.env, cr)
+}
+
+func (a *parameterAPI) List() ([]*domain.Parameter, error) {
+	if err := a.checkEnvironment(); err != nil {
+		return nil, err
+	}
+	list, err := a.s().List()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range list {
+		normalizeParameter(p)
+	}
+	return list, nil
+}
+
+func (a *parameterAPI) Get(code string) (*domain.Parameter, error) {
+	if err := a.checkEnvironment(); err != nil {
+		return nil, err
+	}
+	p, err := a.s().Get(code)
+	if err != nil {
+		return nil, parameterError(err)
+	}
+	normalizeParameter(p)
+	return p, nil
+}
+
+func (a *parameterAPI) GetBatch(codes ...string) ([]*domain.Parameter, error) {
+	list, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		wanted[code] = true
+	}
+	res := make([]*domain.Parameter, 0, len(codes))
+	for _, p := range list {
+		if wanted[p.Code] {
+			res = append(res, p)
+		}
+	}
+	return res, nil
+}
+
+func (a *parameterAPI) Create(info *api.ParameterInfo) (*domain.Parameter, error) {
+	p, err := a.newParameter(info)
+	if err != nil {
+		return nil, err
+	}
+	approval, err := a.needsApproval()
+	if err != nil {
+		return nil, err
+	}
+	if approval {
+		return nil, a.submit(domain.ChangeActionCreate, p.Code, p, 0)
+	}
+	if err := a.s().Save(p); err != nil {
+		return nil, parameterError(err)
+	}
+	a.publish(domain.EventParameterCreated, a.project, a.env, p.Code, p)
+	return p, nil
+}
+
+func (a *parameterAPI) Update(info *api.ParameterInfo) (*domain.Parameter, error) {
+	p, err := a.newParameter(info)
+	if err != nil {
+		return nil, err
+	}
+	old, err := a.Get(info.Code)
+	if err != nil {
+		return nil, err
+	}
+	if info.Version != 0 && info.Version != old.Version {
+		return nil, api.ErrVersionConflict
+	}
+	p.Version = old.Version
+	if p.Type != old.Type {
+		deps, err := a.dependents(p.Code)
+		if err != nil {
+			return nil, err
+		}
+		if len(deps) > 0 {
+			var errs fieldErrors
+			errs.add("type", "Type of a prerequisite of `%s` can't be changed", strings.Join(deps, "`, `"))
+			return nil, errs.err("Wrong parameter type")
+		}
+	}
+	approval, err := a.needsApproval()
+	if err != nil {
+		return nil, err
+	}
+	if approval {
+		return nil, a.submit(domain.ChangeActionUpdate, p.Code, p, p.Version)
+	}
+	if err = a.s().Update(p); err != nil {
+		return nil, parameterError(err)
+	}
+	a.publish(domain.EventParameterUpdated, a.project, a.env, p.Code, p)
+	return p, nil
+}
+
+func (a *parameterAPI) Delete(code string, version int) error {
+	deps, err := a.dependents(code)
+	if err != nil {
+		return err
+	}
+	if len(deps) > 0 {
+		a.log.Info().Str("param", code).Strs("dependents", deps).Msg("Can't delete prerequisite parameter")
+		return api.ErrParameterInUse
+	}
+	approval, err := a.needsApproval()
+	if err != nil {
+		return err
+	}
+	if approval {
+		p, err := a.Get(code)
+		if err != nil {
+			return err
+		}
+		if version != 0 && version != p.Version {
+			return api.ErrVersionConflict
+		}
+		return a.submit(domain.ChangeActionDelete, code, nil, p.Version)
+	}
+	if err := a.s().Delete(code, version); err != nil {
+		return parameterError(err)
+	}
+	a.publish(domain.EventParameterDeleted, a.project, a.env, code, nil)
+	return nil
+}
+
+// newParameter validates parameter info and builds the parameter entity.
+// Values violating the parameter type or constraints are reported as field errors.
+func (a *parameterAPI) newParameter(info *api.ParameterInfo) (*domain.Parameter, error) {
+	p, err := a.buildParameter(info)
+	if err != nil || len(info.Prerequisites) == 0 {
+		return p, err
+	}
+	params, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+	if p.Prerequisites, err = checkPrerequisites(p.Code, info.Prerequisites, params); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// buildParameter validates parameter info except prerequisites and builds the parameter entity.
+// Prerequisites are copied as is.
+func (a *parameterAPI) buildParameter(info *api.ParameterInfo) (*domain.Parameter, error) {
+	if err := checkCode("Parameter", info.Code); err != nil {
+		return nil, err
+	}
+	var errs fieldErrors
+	if !knownType(info.Type) {
+		errs.addErr("type", unknownTypeError())
+		return nil, errs.err("Wrong parameter type")
+	}
+	value, err := normalizeValue(info.Type, info.Value)
+	if err != nil {
+		errs.addErr("value", err)
+	}
+	var off interface{}
+	if info.OffValue != nil {
+		if off, err = normalizeValue(info.Type, info.OffValue); err != nil {
+			errs.addErr("off_value", err)
+		}
+	}
+	var allowed []interface{}
+	for i, v := range info.AllowedValues {
+		n, err := normalizeValue(info.Type, v)
+		if err != nil {
+			errs.addErr(fmt.Sprintf("allowed_values[%d]", i), err)
+		}
+		allowed = append(allowed, n)
+	}
+	variants := normalizeVariants(info.Type, info.Variants, &errs)
+	if err = errs.err("Wrong parameter values"); err != nil {
+		return nil, err
+	}
+	vc, errs := newValueChecker(info.Type, allowed, info.Constraints)
+	if err = errs.err("Wrong parameter constraints"); err != nil {
+		return nil, err
+	}
+	vc.check("value", value, &errs)
+	if off != nil {
+		vc.check("off_value", off, &errs)
+	}
+	for i, v := range variants {
+		vc.check(fmt.Sprintf("variants[%d].value", i), v.Value, &errs)
+	}
+	// Allowed values are checked against the constraints only
+	vc.allowed = nil
+	for i, v := range allowed {
+		vc.check(fmt.Sprintf("allowed_values[%d]", i), v, &errs)
+	}
+	if err = errs.err("Parameter constraints violated"); err != nil {
+		return nil, err
+	}
+	constraints := info.Constraints
+	if constraints != nil && constraints.Schema != "" {
+		c := *constraints
+		c.Schema, _ = normalizeJSON(c.Schema)
+		constraints = &c
+	}
+	return &domain.Parameter{
+		Code:          info.Code,
+		Owner:         a.owner,
+		Project:       a.project,
+		Environment:   a.env,
+		Description:   info.Description,
+		Type:          info.Type,
+		Value:         value,
+		OffValue:      off,
+		AllowedValues: allowed,
+		Constraints:   constraints,
+		Variants:      variants,
+		Prerequisites: info.Prerequisites,
+		Expires:       normalizeTime(info.Expires),
+		Modified:      util.Now(),
+	}, nil
+}
+
+// normalizeTime converts time to UTC with the millisecond precision it is stored with
+func normalizeTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	n := t.UTC().Truncate(time.Millisecond)
+	return &n
+}
+
+// normalizeVariants validates variants and converts their values to the canonical representation
+func normalizeVariants(typ string, list []*domain.Variant, errs *fieldErrors) []*domain.Variant {
+	if len(list) == 0 {
+		return nil
+	}
+	variants := make([]*domain.Variant, 0, len(list))
+	names := make(map[string]bool, len(list))
+	total := 0
+	for i, v := range list {
+		field := fmt.Sprintf("variants[%d]", i)
+		if v.Name == "" {
+			errs.add(field+".name", "Variant name not specified")
+		} else if names[v.Name] {
+			errs.add(field+".name", "Variant `%s` specified twice", v.Name)
+		}
+		names[v.Name] = true
+		if v.Weight < 0 {
+			errs.add(field+".weight", "Variant weight can't be negative")
+		}
+		total += v.Weight
+		value, err := normalizeValue(typ, v.Value)
+		if err != nil {
+			errs.addErr(field+".value", err)
+		}
+		variants = append(variants, &domain.Variant{Name: v.Name, Value: value, Weight: v.Weight})
+	}
+	if total == 0 {
+		errs.add("variants", "Total variants weight must be positive")
+	}
+	return variants
+}
+
+// checkValue verifies value served by existing parameter and returns it normalized
+func checkValue(p *domain.Parameter, field string, value interface{}) (interface{}, error) {
+	var errs fieldErrors
+	v, err := normalizeValue(p.Type, value)
+	if err != nil {
+		errs.addErr(field, err)
+		return nil, errs.err("Wrong parameter value")
+	}
+	vc, _ := newValueChecker(p.Type, p.AllowedValues, p.Constraints)
+	vc.check(field, v, &errs)
+	if err = errs.err("Parameter constraints violated"); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func parameterError(err error) error {
+	if _, ok := err.(*storage.ErrUniqueIndex); ok {
+		return api.ErrParameterExists
+	}
+	switch err {
+	case storage.ErrNotFound:
+		return api.ErrParameterNotFound
+	case storage.ErrVersionConflict:
+		return api.ErrVersionConflict
+	}
+	return err
+}
@@ -0,0 +1,251 @@
+package engine_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIParameter(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), nil, logger)
+	pApi := e.ForOwner("ow1").Projects()
+	paramAPI := pApi.For("proj1").Environments().For("env1").Parameters()
+
+	beforeTest()
+
+	t.Run("environment not found", func(t *testing.T) {
+		_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+		assert.Nil(err)
+		_, err = paramAPI.List()
+		assert.Equal(api.ErrEnvironmentNotFound, err)
+		_, err = pApi.For("proj1").Environments().Create(&api.EnvironmentInfo{Code: "env1"})
+		assert.Nil(err)
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		tt := []*api.ParameterInfo{
+			&api.ParameterInfo{Type: domain.ParameterTypeBool, Value: true},
+			&api.ParameterInfo{Code: "p 1", Type: domain.ParameterTypeBool, Value: true},
+			&api.ParameterInfo{Code: "p1", Type: "wrong", Value: true},
+			&api.ParameterInfo{Code: "p1", Type: domain.ParameterTypeBool, Value: "true"},
+			&api.ParameterInfo{Code: "p1", Type: domain.ParameterTypeInt, Value: 1.5},
+			&api.ParameterInfo{Code: "p1", Type: domain.ParameterTypeString, Value: "a", AllowedValues: []interface{}{1}},
+		}
+		for _, tc := range tt {
+			_, err := paramAPI.Create(tc)
+			_, ok := err.(*api.ErrBadRequest)
+			assert.True(ok)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		p, err := paramAPI.Create(&api.ParameterInfo{Code: "p1", Type: domain.ParameterTypeInt, Value: float64(10)})
+		assert.Nil(err)
+		assert.Equal(int64(10), p.Value)
+		assert.Equal(1, p.Version)
+		p, err = paramAPI.Get("p1")
+		assert.Nil(err)
+		assert.Equal(int64(10), p.Value)
+		assert.Equal("env1", p.Environment)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		p, err := paramAPI.Update(&api.ParameterInfo{Code: "p1", Type: domain.ParameterTypeInt, Value: 11, Version: 1})
+		assert.Nil(err)
+		assert.Equal(2, p.Version)
+		_, err = paramAPI.Update(&api.ParameterInfo{Code: "p1", Type: domain.ParameterTypeInt, Value: 12, Version: 1})
+		assert.Equal(api.ErrVersionConflict, err)
+	})
+
+	t.Run("get batch", func(t *testing.T) {
+		_, err := paramAPI.Create(&api.ParameterInfo{Code: "p2", Type: domain.ParameterTypeBool, Value: true})
+		assert.Nil(err)
+		list, err := paramAPI.GetBatch("p2", "p3")
+		assert.Nil(err)
+		assert.Len(list, 1)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Nil(paramAPI.Delete("p1", 2))
+		_, err := paramAPI.Get("p1")
+		assert.Equal(api.ErrParameterNotFound, err)
+	})
+
+	afterTest()
+}
+
+func TestAPIParameterTypes(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "env1"}},
+	}, logger)
+	pApi := e.ForOwner("ow1").Projects()
+	paramAPI := pApi.For("proj1").Environments().For("env1").Parameters()
+
+	beforeTest()
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+
+	t.Run("bad request", func(t *testing.T) {
+		tt := []*api.ParameterInfo{
+			&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeFloat, Value: "1.5"},
+			&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeInt, Value: json.Number("1.5")},
+			&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeInt, Value: json.Number("12345678901234567890")},
+			&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeFloat, Value: json.Number("1e400")},
+			&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeJSON, Value: "not json"},
+			&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeJSON, Value: float64(1)},
+			&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeJSON, Value: `{"a":1} {}`},
+			&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeStringList, Value: []interface{}{"a", float64(1)}},
+			&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeSemver, Value: "v1.2.3"},
+			&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeSemver, Value: "1.2"},
+			&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeDuration, Value: "2 hours"},
+		}
+		for _, tc := range tt {
+			_, err := paramAPI.Create(tc)
+			_, ok := err.(*api.ErrBadRequest)
+			assert.True(ok, tc.Type)
+		}
+	})
+
+	t.Run("canonical values", func(t *testing.T) {
+		tt := []struct {
+			info     *api.ParameterInfo
+			expected interface{}
+		}{
+			{&api.ParameterInfo{Code: "float", Type: domain.ParameterTypeFloat, Value: float64(1.5)}, float64(1.5)},
+			{&api.ParameterInfo{Code: "float-int", Type: domain.ParameterTypeFloat, Value: 2}, float64(2)},
+			{&api.ParameterInfo{Code: "float-number", Type: domain.ParameterTypeFloat, Value: json.Number("0.25")}, float64(0.25)},
+			{&api.ParameterInfo{Code: "int-number", Type: domain.ParameterTypeInt, Value: json.Number("9007199254740993")}, int64(9007199254740993)},
+			{&api.ParameterInfo{Code: "int-exponent", Type: domain.ParameterTypeInt, Value: json.Number("1e3")}, int64(1000)},
+			{&api.ParameterInfo{Code: "json-number", Type: domain.ParameterTypeJSON, Value: map[string]interface{}{
+				"n": json.Number("12345678901234567890"),
+			}}, domain.JSONValue(`{"n":12345678901234567890}`)},
+			{&api.ParameterInfo{Code: "json", Type: domain.ParameterTypeJSON, Value: map[string]interface{}{
+				"b": []interface{}{true}, "a.b": "$x",
+			}}, domain.JSONValue(`{"a.b":"$x","b":[true]}`)},
+			{&api.ParameterInfo{Code: "json-string", Type: domain.ParameterTypeJSON, Value: `{ "n": 12345678901234567890 }`},
+				domain.JSONValue(`{"n":12345678901234567890}`)},
+			{&api.ParameterInfo{Code: "list", Type: domain.ParameterTypeStringList, Value: []interface{}{"a", "b"}}, []string{"a", "b"}},
+			{&api.ParameterInfo{Code: "semver", Type: domain.ParameterTypeSemver, Value: "1.2.3-rc.1+build.5"}, "1.2.3-rc.1+build.5"},
+			{&api.ParameterInfo{Code: "duration", Type: domain.ParameterTypeDuration, Value: "90m"}, "1h30m0s"},
+		}
+		for _, tc := range tt {
+			p, err := paramAPI.Create(tc.info)
+			assert.Nil(err)
+			assert.Equal(tc.expected, p.Value)
+			p, err = paramAPI.Get(tc.info.Code)
+			assert.Nil(err)
+			assert.Equal(tc.expected, p.Value)
+		}
+	})
+
+	t.Run("evaluation", func(t *testing.T) {
+		res, err := pApi.For("proj1").Environments().For("env1").Evaluate("", "json", "list")
+		assert.Nil(err)
+		assert.Equal(2, len(res))
+		for _, ev := range res {
+			switch ev.Code {
+			case "json":
+				assert.Equal(domain.JSONValue(`{"a.b":"$x","b":[true]}`), ev.Value)
+			case "list":
+				assert.Equal([]string{"a", "b"}, ev.Value)
+			}
+		}
+	})
+
+	afterTest()
+}
+
+func TestAPIParameterConstraints(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "env1"}},
+	}, logger)
+	pApi := e.ForOwner("ow1").Projects()
+	paramAPI := pApi.For("proj1").Environments().For("env1").Parameters()
+
+	beforeTest()
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+
+	one, ten, three := 1.0, 10.0, 3
+	schema := domain.JSONValue(`{"type": "object", "required": ["retries"], "properties": {"retries": {"type": "integer"}}}`)
+
+	t.Run("violations", func(t *testing.T) {
+		tt := []struct {
+			info  *api.ParameterInfo
+			field string
+		}{
+			{&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeInt, Value: 11,
+				Constraints: &domain.Constraints{Min: &one, Max: &ten}}, "value"},
+			{&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeInt, Value: 5, OffValue: 0,
+				Constraints: &domain.Constraints{Min: &one, Max: &ten}}, "off_value"},
+			{&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeString, Value: "c", AllowedValues: []interface{}{"a", "b"}}, "value"},
+			{&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeString, Value: "abcd",
+				Constraints: &domain.Constraints{MaxLength: &three}}, "value"},
+			{&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeStringList, Value: []interface{}{"ok", "NO"},
+				Constraints: &domain.Constraints{Pattern: "^[a-z]+$"}}, "value[1]"},
+			{&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeString, Value: "abc1",
+				Constraints: &domain.Constraints{Pattern: "[a-z]+"}}, "value"},
+			{&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeString, Value: "b",
+				Constraints: &domain.Constraints{Pattern: "a)|(b"}}, "constraints.pattern"},
+			{&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeJSON, Value: map[string]interface{}{"retries": "3"},
+				Constraints: &domain.Constraints{Schema: schema}}, "value"},
+			{&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeBool, Value: true,
+				Constraints: &domain.Constraints{Min: &one}}, "constraints.min"},
+			{&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeJSON, Value: map[string]interface{}{},
+				Constraints: &domain.Constraints{Schema: `{"$ref": "http://example.com/schema.json"}`}}, "constraints.schema"},
+			{&api.ParameterInfo{Code: "p", Type: domain.ParameterTypeBool, Value: "on"}, "value"},
+		}
+		for _, tc := range tt {
+			_, err := paramAPI.Create(tc.info)
+			e, ok := err.(*api.ErrBadRequest)
+			if assert.True(ok) && assert.NotEmpty(e.Fields) {
+				assert.Equal(tc.field, e.Fields[0].Field)
+			}
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		p, err := paramAPI.Create(&api.ParameterInfo{
+			Code: "config", Type: domain.ParameterTypeJSON, Value: map[string]interface{}{"retries": float64(3)},
+			Constraints: &domain.Constraints{Schema: schema},
+		})
+		assert.Nil(err)
+		assert.Equal(domain.JSONValue(`{"properties":{"retries":{"type":"integer"}},"required":["retries"],"type":"object"}`), p.Constraints.Schema)
+		_, err = paramAPI.Create(&api.ParameterInfo{
+			Code: "limit", Type: domain.ParameterTypeInt, Value: 5, Constraints: &domain.Constraints{Min: &one, Max: &ten},
+		})
+		assert.Nil(err)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		_, err := paramAPI.Update(&api.ParameterInfo{
+			Code: "limit", Type: domain.ParameterTypeInt, Value: 50, Constraints: &domain.Constraints{Min: &one, Max: &ten},
+		})
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+	})
+
+	t.Run("scheduled value", func(t *testing.T) {
+		_, err := pApi.For("proj1").ScheduledChanges().Create(&api.ScheduledChangeInfo{
+			Environment: "env1", Parameter: "limit", Value: 50, At: "2100-01-01T00:00:00Z",
+		})
+		e, ok := err.(*api.ErrBadRequest)
+		if assert.True(ok) && assert.NotEmpty(e.Fields) {
+			assert.Equal("value", e.Fields[0].Field)
+		}
+	})
+
+	afterTest()
+}
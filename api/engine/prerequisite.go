@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+)
+
+// canBePrerequisite checks if parameters of the type can be prerequisites.
+// Prerequisite values are compared as is, so structured values are not supported.
+func canBePrerequisite(typ string) bool {
+	switch typ {
+	case domain.ParameterTypeJSON, domain.ParameterTypeStringList:
+		return false
+	}
+	return true
+}
+
+// checkPrerequisites validates prerequisites of the parameter against the environment
+// parameters and returns them with values normalized to the prerequisite parameter types
+func checkPrerequisites(code string, list []*domain.Prerequisite, params []*domain.Parameter) ([]*domain.Prerequisite, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	byCode := make(map[string]*domain.Parameter, len(params))
+	graph := make(map[string][]string, len(params))
+	for _, p := range params {
+		byCode[p.Code] = p
+		for _, pr := range p.Prerequisites {
+			graph[p.Code] = append(graph[p.Code], pr.Parameter)
+		}
+	}
+	var errs fieldErrors
+	res := make([]*domain.Prerequisite, 0, len(list))
+	seen := make(map[string]bool, len(list))
+	graph[code] = nil
+	for i, pr := range list {
+		field := fmt.Sprintf("prerequisites[%d]", i)
+		target := byCode[pr.Parameter]
+		switch {
+		case pr.Parameter == "":
+			errs.add(field+".parameter", "Prerequisite parameter not specified")
+			continue
+		case pr.Parameter == code:
+			errs.add(field+".parameter", "Parameter can't be its own prerequisite")
+			continue
+		case seen[pr.Parameter]:
+			errs.add(field+".parameter", "Prerequisite `%s` specified twice", pr.Parameter)
+			continue
+		case target == nil:
+			errs.add(field+".parameter", "Parameter `%s` not found", pr.Parameter)
+			continue
+		case !canBePrerequisite(target.Type):
+			errs.add(field+".parameter", "Parameters of type `%s` can't be prerequisites", target.Type)
+			continue
+		}
+		seen[pr.Parameter] = true
+		graph[code] = append(graph[code], pr.Parameter)
+		value, err := normalizeValue(target.Type, pr.Value)
+		if err != nil {
+			errs.addErr(field+".value", err)
+			continue
+		}
+		res = append(res, &domain.Prerequisite{Parameter: pr.Parameter, Value: value})
+	}
+	if err := errs.err("Wrong parameter prerequisites"); err != nil {
+		return nil, err
+	}
+	if cycle := prerequisiteCycle(code, graph); cycle != nil {
+		errs.add("prerequisites", "Prerequisites form a cycle `%s`", strings.Join(cycle, " -> "))
+		return nil, errs.err("Wrong parameter prerequisites")
+	}
+	return res, nil
+}
+
+// prerequisiteCycle returns path leading from the parameter back to itself, if any.
+// Graph maps parameter codes to codes of their prerequisites.
+func prerequisiteCycle(code string, graph map[string][]string) []string {
+	visited := map[string]bool{code: true}
+	path := []string{}
+	var visit func(c string) bool
+	visit = func(c string) bool {
+		path = append(path, c)
+		for _, next := range graph[c] {
+			if next == code {
+				path = append(path, next)
+				return true
+			}
+			if !visited[next] {
+				visited[next] = true
+				if visit(next) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(code) {
+		return path
+	}
+	return nil
+}
+
+// dependents returns codes of environment parameters having the parameter as a prerequisite
+func (a *parameterAPI) dependents(code string) ([]string, error) {
+	params, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+	return dependentsOf(code, params), nil
+}
+
+// dependentsOf returns codes of parameters having the parameter as a prerequisite
+func dependentsOf(code string, params []*domain.Parameter) []string {
+	var res []string
+	for _, p := range params {
+		for _, pr := range p.Prerequisites {
+			if pr.Parameter == code {
+				res = append(res, p.Code)
+				break
+			}
+		}
+	}
+	return res
+}
+
+func (a *forProjectAPI) DependencyGraph() (*api.DependencyGraph, error) {
+	envs, err := a.Environments().List()
+	if err != nil {
+		return nil, err
+	}
+	graph := &api.DependencyGraph{
+		Nodes: make([]*api.DependencyNode, 0),
+		Edges: make([]*api.DependencyEdge, 0),
+	}
+	for _, env := range envs {
+		params, err := a.parameters(env.Code).List()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range params {
+			graph.Nodes = append(graph.Nodes, &api.DependencyNode{Environment: env.Code, Parameter: p.Code})
+			for _, pr := range p.Prerequisites {
+				graph.Edges = append(graph.Edges, &api.DependencyEdge{
+					Environment:  env.Code,
+					Parameter:    p.Code,
+					Prerequisite: pr.Parameter,
+					Value:        pr.Value,
+				})
+			}
+		}
+	}
+	return graph, nil
+}
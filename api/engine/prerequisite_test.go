@@ -0,0 +1,117 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIPrerequisites(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "prod"}},
+	}, logger)
+	pApi := e.ForOwner("ow1").Projects()
+	envAPI := pApi.For("proj1").Environments().For("prod")
+	params := envAPI.Parameters()
+
+	beforeTest()
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+	_, err = params.Create(&api.ParameterInfo{Code: "infra", Type: domain.ParameterTypeBool, Value: true})
+	assert.Nil(err)
+	_, err = params.Create(&api.ParameterInfo{Code: "doc", Type: domain.ParameterTypeJSON, Value: `{"a":1}`})
+	assert.Nil(err)
+
+	t.Run("create", func(t *testing.T) {
+		_, err := params.Create(&api.ParameterInfo{
+			Code: "feature", Type: domain.ParameterTypeInt, Value: 10, OffValue: 1,
+			Prerequisites: []*domain.Prerequisite{{Parameter: "infra", Value: true}},
+		})
+		assert.Nil(err)
+		_, err = params.Create(&api.ParameterInfo{
+			Code: "sub", Type: domain.ParameterTypeBool, Value: true,
+			Prerequisites: []*domain.Prerequisite{{Parameter: "feature", Value: float64(10)}},
+		})
+		assert.Nil(err)
+	})
+
+	t.Run("wrong prerequisites", func(t *testing.T) {
+		for _, list := range [][]*domain.Prerequisite{
+			{{Parameter: "missing", Value: true}},
+			{{Parameter: "infra", Value: "yes"}},
+			{{Parameter: "doc", Value: `{"a":1}`}},
+			{{Parameter: "infra", Value: true}, {Parameter: "infra", Value: false}},
+			{{Parameter: "other", Value: true}},
+		} {
+			_, err := params.Create(&api.ParameterInfo{Code: "other", Type: domain.ParameterTypeBool, Value: true, Prerequisites: list})
+			e, ok := err.(*api.ErrBadRequest)
+			if assert.True(ok) {
+				assert.NotEmpty(e.Fields)
+			}
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		_, err := params.Update(&api.ParameterInfo{
+			Code: "infra", Type: domain.ParameterTypeBool, Value: true,
+			Prerequisites: []*domain.Prerequisite{{Parameter: "sub", Value: true}},
+		})
+		e, ok := err.(*api.ErrBadRequest)
+		if assert.True(ok) && assert.Equal(1, len(e.Fields)) {
+			assert.Equal("prerequisites", e.Fields[0].Field)
+		}
+	})
+
+	t.Run("evaluate", func(t *testing.T) {
+		res, err := envAPI.Evaluate("", "feature", "sub")
+		assert.Nil(err)
+		assert.Equal(2, len(res))
+		for _, ev := range res {
+			assert.Equal(api.EvaluationReasonValue, ev.Reason)
+		}
+		_, err = params.Update(&api.ParameterInfo{Code: "infra", Type: domain.ParameterTypeBool, Value: false})
+		assert.Nil(err)
+		res, err = envAPI.Evaluate("", "feature", "sub")
+		assert.Nil(err)
+		values := map[string]interface{}{}
+		for _, ev := range res {
+			values[ev.Code] = ev.Value
+			assert.Equal(api.EvaluationReasonPrerequisiteFailed, ev.Reason)
+		}
+		assert.Equal(int64(1), values["feature"])
+		assert.Equal(false, values["sub"])
+	})
+
+	t.Run("type change", func(t *testing.T) {
+		_, err := params.Update(&api.ParameterInfo{Code: "infra", Type: domain.ParameterTypeString, Value: "on"})
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+	})
+
+	t.Run("graph", func(t *testing.T) {
+		graph, err := pApi.For("proj1").DependencyGraph()
+		assert.Nil(err)
+		assert.Equal(4, len(graph.Nodes))
+		edges := map[string]string{}
+		for _, edge := range graph.Edges {
+			assert.Equal("prod", edge.Environment)
+			edges[edge.Parameter] = edge.Prerequisite
+		}
+		assert.Equal(map[string]string{"feature": "infra", "sub": "feature"}, edges)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(api.ErrParameterInUse, params.Delete("infra", 0))
+		assert.Nil(params.Delete("sub", 0))
+		assert.Nil(params.Delete("feature", 0))
+		assert.Nil(params.Delete("infra", 0))
+	})
+
+	afterTest()
+}
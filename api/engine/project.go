@@ -23,17 +23,15 @@ func (a *projectAPI) List() ([]*domain.Project, error) {
 
 func (a *projectAPI) Get(code string) (*domain.Project, error) {
 	p, err := a.s().Get(code)
-	if err == storage.ErrNotFound {
-		return nil, api.ErrProjectNotFound
+	if err != nil {
+		return nil, projectError(err)
 	}
-	return p, err
+	return p, nil
 }
 
 func checkProjectParams(code, description, status string) error {
-	if code == "" {
-		return &api.ErrBadRequest{
-			Description: "Project code not specified",
-		}
+	if err := checkCode("Project", code); err != nil {
+		return err
 	}
 	if status != domain.ProjectStatusActive && status != domain.ProjectStatusDisabled {
 		return &api.ErrBadRequest{
@@ -54,45 +52,151 @@ func (a *projectAPI) Create(info *api.ProjectInfo) (*domain.Project, error) {
 		RegDate:     util.Now(),
 		Status:      info.Status,
 	}
-	if err := a.s().Save(newProj); err != nil {
+	envs := info.Environments
+	if envs == nil {
+		envs = a.cfg.DefaultEnvironments
+	}
+	if err := checkEnvironmentTemplates(envs); err != nil {
 		return nil, err
 	}
-	// TODO: create default env
+	if err := a.s().Save(newProj); err != nil {
+		return nil, projectError(err)
+	}
+	// Events are published once all environments are created, so a rolled back
+	// project is never announced
+	envAPI := &environmentAPI{ownerAPI: a.ownerAPI, project: newProj.Code}
+	created := make([]*domain.Environment, 0, len(envs))
+	for _, env := range envs {
+		e, err := envAPI.create(&api.EnvironmentInfo{
+			Code:        env.Code,
+			Description: env.Description,
+			Protected:   env.Protected,
+			Disabled:    env.Disabled,
+		})
+		if err != nil {
+			a.log.Error().Err(err).Str("env", env.Code).Msg("Can't create project environment, rolling back")
+			if rbErr := a.s().Delete(newProj.Code, 0, true); rbErr != nil {
+				a.log.Error().Err(rbErr).Msg("Can't roll back project creation")
+			}
+			return nil, err
+		}
+		created = append(created, e)
+	}
+	a.publish(domain.EventProjectCreated, newProj.Code, "", "", newProj)
+	for _, e := range created {
+		a.publish(domain.EventEnvironmentCreated, newProj.Code, e.Code, "", e)
+	}
 	return newProj, nil
 }
 
+func checkEnvironmentTemplates(envs []*api.EnvironmentInfo) error {
+	codes := make(map[string]bool, len(envs))
+	for _, env := range envs {
+		if err := checkEnvironmentParams(env.Code); err != nil {
+			return err
+		}
+		if codes[env.Code] {
+			return &api.ErrBadRequest{
+				Description: fmt.Sprintf("Environment `%s` specified twice", env.Code),
+			}
+		}
+		codes[env.Code] = true
+	}
+	return nil
+}
+
 func (a *projectAPI) Update(info *api.ProjectInfo) (*domain.Project, error) {
 	if err := checkProjectParams(info.Code, info.Description, info.Status); err != nil {
 		return nil, err
 	}
-	proj, err := a.s().Get(info.Code)
+	proj, err := a.Get(info.Code)
 	if err != nil {
 		return nil, err
 	}
+	if info.Version != 0 && info.Version != proj.Version {
+		return nil, api.ErrVersionConflict
+	}
 	newProj := &domain.Project{
 		Code:        info.Code,
 		Description: info.Description,
 		Owner:       a.owner,
 		RegDate:     proj.RegDate,
 		Status:      info.Status,
+		Version:     proj.Version,
 	}
 	err = a.s().Update(newProj)
 	if err != nil {
-		return nil, err
+		return nil, projectError(err)
 	}
+	a.publish(domain.EventProjectUpdated, newProj.Code, "", "", newProj)
 	return newProj, nil
 }
 
-func (a *projectAPI) Delete(code string) error {
-	return a.s().Delete(code)
+func (a *projectAPI) Delete(code string, version int, cascade bool) error {
+	if cascade && a.cfg.ApprovalsRequired > 0 {
+		if err := a.checkUnprotected(code); err != nil {
+			return err
+		}
+	}
+	if err := a.s().Delete(code, version, cascade); err != nil {
+		return projectError(err)
+	}
+	// Webhooks of the project are deleted with it and don't receive the event
+	a.publish(domain.EventProjectDeleted, code, "", "", nil)
+	return nil
+}
+
+// checkUnprotected refuses cascade deletion of protected environments, they have to be
+// deleted one by one through change requests first
+func (a *projectAPI) checkUnprotected(code string) error {
+	envs, err := a.s().For(code).Environments().List()
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		if env.Protected {
+			return api.ErrProjectProtected
+		}
+	}
+	return nil
+}
+
+func projectError(err error) error {
+	if _, ok := err.(*storage.ErrUniqueIndex); ok {
+		return api.ErrProjectExists
+	}
+	switch err {
+	case storage.ErrNotFound:
+		return api.ErrProjectNotFound
+	case storage.ErrVersionConflict:
+		return api.ErrVersionConflict
+	case storage.ErrNotEmpty:
+		return api.ErrProjectNotEmpty
+	}
+	return err
 }
 
 func (a *projectAPI) For(code string) api.ForProjectAPI {
-	return &forProjectAPI{}
+	return &forProjectAPI{a.ownerAPI, code}
 }
 
-type forProjectAPI struct{}
+type forProjectAPI struct {
+	ownerAPI
+	project string
+}
 
 func (a *forProjectAPI) Environments() api.EnvironmentAPI {
-	return &environmentAPI{}
+	return &environmentAPI{ownerAPI: a.ownerAPI, project: a.project}
+}
+
+func (a *forProjectAPI) ChangeRequests() api.ChangeRequestAPI {
+	return &changeRequestAPI{a.ownerAPI, a.project}
+}
+
+func (a *forProjectAPI) ScheduledChanges() api.ScheduledChangeAPI {
+	return &scheduledChangeAPI{a.ownerAPI, a.project}
+}
+
+func (a *forProjectAPI) Webhooks() api.WebhookAPI {
+	return &webhookAPI{a.ownerAPI, a.project}
 }
@@ -15,7 +15,7 @@ import (
 func TestAPIProject(t *testing.T) {
 
 	assert := asserts.New(t)
-	e := engine.NewTogglyAPI(getDB(), logger)
+	e := engine.NewTogglyAPI(getDB(), nil, logger)
 	pApi := e.ForOwner("ow1").Projects()
 
 	beforeTest()
@@ -38,6 +38,8 @@ func TestAPIProject(t *testing.T) {
 		tt := []*api.ProjectInfo{
 			&api.ProjectInfo{},
 			&api.ProjectInfo{Code: "p1", Status: "wrong"},
+			&api.ProjectInfo{Code: "p\x00x", Status: domain.ProjectStatusActive},
+			&api.ProjectInfo{Code: "p/x", Status: domain.ProjectStatusActive},
 		}
 		var err error
 		for _, tc := range tt {
@@ -65,6 +67,12 @@ func TestAPIProject(t *testing.T) {
 		assert.NotNil(proj.RegDate)
 		regDate = proj.RegDate
 		assert.Equal("ow1", proj.Owner)
+		assert.Equal(1, proj.Version)
+	})
+
+	t.Run("create duplicate", func(t *testing.T) {
+		_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+		assert.Equal(api.ErrProjectExists, err)
 	})
 
 	t.Run("list one item", func(t *testing.T) {
@@ -87,15 +95,116 @@ func TestAPIProject(t *testing.T) {
 		assert.Equal(p.Status, proj.Status)
 		assert.Equal("ow1", proj.Owner)
 		assert.Equal(regDate, proj.RegDate)
+		assert.Equal(2, proj.Version)
+	})
+
+	t.Run("update version conflict", func(t *testing.T) {
+		p := &api.ProjectInfo{
+			Code:    "proj1",
+			Status:  domain.ProjectStatusActive,
+			Version: 1,
+		}
+		proj, err := pApi.Update(p)
+		assert.Nil(proj)
+		assert.Equal(api.ErrVersionConflict, err)
+		p.Version = 2
+		proj, err = pApi.Update(p)
+		assert.Nil(err)
+		assert.Equal(3, proj.Version)
+	})
+
+	t.Run("delete version conflict", func(t *testing.T) {
+		err := pApi.Delete("proj1", 2, false)
+		assert.Equal(api.ErrVersionConflict, err)
+	})
+
+	t.Run("delete not empty", func(t *testing.T) {
+		envAPI := pApi.For("proj1").Environments()
+		_, err := envAPI.Create(&api.EnvironmentInfo{Code: "env1"})
+		assert.Nil(err)
+		err = pApi.Delete("proj1", 3, false)
+		assert.Equal(api.ErrProjectNotEmpty, err)
 	})
 
 	t.Run("delete", func(t *testing.T) {
-		err := pApi.Delete("proj1")
+		err := pApi.Delete("proj1", 3, true)
 		assert.Nil(err)
 		_, err = pApi.Get("proj1")
 		assert.Equal(api.ErrProjectNotFound, err)
+		_, err = pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+		assert.Nil(err)
+		list, err := pApi.For("proj1").Environments().List()
+		assert.Nil(err)
+		assert.Len(list, 0)
 	})
 
 	afterTest()
 
 }
+
+func TestAPIProjectDefaultEnvironments(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{
+			&api.EnvironmentInfo{Code: "dev"},
+			&api.EnvironmentInfo{Code: "prod", Protected: true},
+		},
+	}, logger)
+	pApi := e.ForOwner("ow1").Projects()
+
+	beforeTest()
+
+	t.Run("defaults", func(t *testing.T) {
+		_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+		assert.Nil(err)
+		list, err := pApi.For("proj1").Environments().List()
+		assert.Nil(err)
+		assert.Len(list, 2)
+		prod, err := pApi.For("proj1").Environments().Get("prod")
+		assert.Nil(err)
+		assert.True(prod.Protected)
+	})
+
+	t.Run("override", func(t *testing.T) {
+		_, err := pApi.Create(&api.ProjectInfo{
+			Code:         "proj2",
+			Status:       domain.ProjectStatusActive,
+			Environments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "qa"}},
+		})
+		assert.Nil(err)
+		list, err := pApi.For("proj2").Environments().List()
+		assert.Nil(err)
+		assert.Len(list, 1)
+		assert.Equal("qa", list[0].Code)
+	})
+
+	t.Run("no environments", func(t *testing.T) {
+		_, err := pApi.Create(&api.ProjectInfo{
+			Code:         "proj3",
+			Status:       domain.ProjectStatusActive,
+			Environments: []*api.EnvironmentInfo{},
+		})
+		assert.Nil(err)
+		list, err := pApi.For("proj3").Environments().List()
+		assert.Nil(err)
+		assert.Len(list, 0)
+	})
+
+	t.Run("duplicate environment", func(t *testing.T) {
+		_, err := pApi.Create(&api.ProjectInfo{
+			Code:   "proj4",
+			Status: domain.ProjectStatusActive,
+			Environments: []*api.EnvironmentInfo{
+				&api.EnvironmentInfo{Code: "qa"},
+				&api.EnvironmentInfo{Code: "qa"},
+			},
+		})
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+		_, err = pApi.Get("proj4")
+		assert.Equal(api.ErrProjectNotFound, err)
+	})
+
+	afterTest()
+}
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+)
+
+// parameterFields lists parameter fields which are compared and promoted between environments
+func parameterFields(p *domain.Parameter) []*api.FieldChange {
+	return []*api.FieldChange{
+		{Field: "description", Source: p.Description},
+		{Field: "type", Source: p.Type},
+		{Field: "value", Source: p.Value},
+		{Field: "off_value", Source: p.OffValue},
+		{Field: "allowed_values", Source: p.AllowedValues},
+		{Field: "constraints", Source: p.Constraints},
+		{Field: "variants", Source: p.Variants},
+		{Field: "prerequisites", Source: p.Prerequisites},
+		{Field: "expires", Source: p.Expires},
+	}
+}
+
+func diffParameter(source, target *domain.Parameter) []*api.FieldChange {
+	sf := parameterFields(source)
+	tf := parameterFields(target)
+	var changes []*api.FieldChange
+	for i, f := range sf {
+		if !reflect.DeepEqual(f.Source, tf[i].Source) {
+			changes = append(changes, &api.FieldChange{Field: f.Field, Source: f.Source, Target: tf[i].Source})
+		}
+	}
+	return changes
+}
+
+func diffParameters(source, target []*domain.Parameter) []*api.ParameterChange {
+	targets := make(map[string]*domain.Parameter, len(target))
+	for _, p := range target {
+		targets[p.Code] = p
+	}
+	changes := make([]*api.ParameterChange, 0)
+	for _, sp := range source {
+		tp, ok := targets[sp.Code]
+		if !ok {
+			changes = append(changes, &api.ParameterChange{Code: sp.Code, Action: api.ChangeAdded, Source: sp})
+			continue
+		}
+		delete(targets, sp.Code)
+		if fields := diffParameter(sp, tp); len(fields) > 0 {
+			changes = append(changes, &api.ParameterChange{Code: sp.Code, Action: api.ChangeChanged, Fields: fields, Source: sp, Target: tp})
+		}
+	}
+	for _, tp := range targets {
+		changes = append(changes, &api.ParameterChange{Code: tp.Code, Action: api.ChangeRemoved, Target: tp})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Code < changes[j].Code })
+	return changes
+}
+
+func (a *forProjectAPI) parameters(env string) api.ParameterAPI {
+	return (&forEnvironmentAPI{a.ownerAPI, a.project, env}).Parameters()
+}
+
+func (a *forProjectAPI) Diff(source, target string) (*api.EnvironmentDiff, error) {
+	if source == target {
+		return nil, &api.ErrBadRequest{
+			Description: "Source and target environments must differ",
+		}
+	}
+	sp, err := a.parameters(source).List()
+	if err != nil {
+		return nil, err
+	}
+	tp, err := a.parameters(target).List()
+	if err != nil {
+		return nil, err
+	}
+	return &api.EnvironmentDiff{
+		Project: a.project,
+		Source:  source,
+		Target:  target,
+		Changes: diffParameters(sp, tp),
+	}, nil
+}
+
+// Promote applies selected changes of source and target diff to the target environment.
+// Empty params list means all changes, repeated codes are promoted once. Changes are validated
+// together and applied as a single changeset, or submitted as a single change request
+// if the target environment is protected.
+func (a *forProjectAPI) Promote(source, target string, params []string) (*api.EnvironmentDiff, error) {
+	diff, err := a.Diff(source, target)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) > 0 {
+		changes := make(map[string]*api.ParameterChange, len(diff.Changes))
+		for _, c := range diff.Changes {
+			changes[c.Code] = c
+		}
+		selected := make([]*api.ParameterChange, 0, len(params))
+		for _, code := range params {
+			c, ok := changes[code]
+			if !ok {
+				return nil, &api.ErrBadRequest{
+					Description: fmt.Sprintf("Parameter `%s` has no changes to promote", code),
+				}
+			}
+			if c == nil {
+				continue
+			}
+			selected = append(selected, c)
+			changes[code] = nil
+		}
+		diff.Changes = selected
+	}
+	if len(diff.Changes) == 0 {
+		return diff, nil
+	}
+	info := &api.ChangesetInfo{Description: fmt.Sprintf("Promotion from %s", source)}
+	for _, c := range diff.Changes {
+		switch c.Action {
+		case api.ChangeAdded:
+			info.Changes = append(info.Changes, &api.ChangeInfo{Action: domain.ChangeActionCreate, Parameter: parameterInfo(c.Source, 0)})
+		case api.ChangeChanged:
+			info.Changes = append(info.Changes, &api.ChangeInfo{Action: domain.ChangeActionUpdate, Parameter: parameterInfo(c.Source, c.Target.Version)})
+		case api.ChangeRemoved:
+			info.Changes = append(info.Changes, &api.ChangeInfo{Action: domain.ChangeActionDelete, Parameter: &api.ParameterInfo{Code: c.Code, Version: c.Target.Version}})
+		}
+	}
+	cs, err := (&forEnvironmentAPI{a.ownerAPI, a.project, target}).Changesets().Apply(info)
+	if e, ok := err.(*api.ErrApprovalRequired); ok {
+		for _, c := range diff.Changes {
+			c.ChangeRequest = e.ChangeRequest.ID
+		}
+		return diff, nil
+	}
+	if err != nil {
+		a.log.Error().Err(err).Str("source", source).Str("target", target).Msg("Can't promote parameters")
+		return nil, err
+	}
+	diff.Changeset = cs.ID
+	return diff, nil
+}
+
+func parameterInfo(p *domain.Parameter, version int) *api.ParameterInfo {
+	info := api.NewParameterInfo(p)
+	info.Version = version
+	return info
+}
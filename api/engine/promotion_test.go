@@ -0,0 +1,154 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIPromotion(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{
+			&api.EnvironmentInfo{Code: "staging"},
+			&api.EnvironmentInfo{Code: "production"},
+		},
+		ApprovalsRequired: 1,
+	}, logger)
+	pApi := e.ForOwner("ow1").Projects()
+	fApi := pApi.For("proj1")
+	staging := fApi.Environments().For("staging").Parameters()
+	production := fApi.Environments().For("production").Parameters()
+
+	beforeTest()
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+
+	for _, info := range []*api.ParameterInfo{
+		&api.ParameterInfo{Code: "same", Type: domain.ParameterTypeBool, Value: true},
+		&api.ParameterInfo{Code: "changed", Type: domain.ParameterTypeInt, Value: 2},
+		&api.ParameterInfo{Code: "new", Type: domain.ParameterTypeString, Value: "a"},
+	} {
+		_, err = staging.Create(info)
+		assert.Nil(err)
+	}
+	for _, info := range []*api.ParameterInfo{
+		&api.ParameterInfo{Code: "same", Type: domain.ParameterTypeBool, Value: true},
+		&api.ParameterInfo{Code: "changed", Type: domain.ParameterTypeInt, Value: 1},
+		&api.ParameterInfo{Code: "old", Type: domain.ParameterTypeString, Value: "b"},
+	} {
+		_, err = production.Create(info)
+		assert.Nil(err)
+	}
+
+	t.Run("diff", func(t *testing.T) {
+		diff, err := fApi.Diff("staging", "production")
+		assert.Nil(err)
+		assert.Len(diff.Changes, 3)
+		assert.Equal("changed", diff.Changes[0].Code)
+		assert.Equal(api.ChangeChanged, diff.Changes[0].Action)
+		assert.Len(diff.Changes[0].Fields, 1)
+		assert.Equal("value", diff.Changes[0].Fields[0].Field)
+		assert.Equal(int64(2), diff.Changes[0].Fields[0].Source)
+		assert.Equal(int64(1), diff.Changes[0].Fields[0].Target)
+		assert.Equal(api.ChangeAdded, diff.Changes[1].Action)
+		assert.Equal(api.ChangeRemoved, diff.Changes[2].Action)
+	})
+
+	t.Run("diff bad request", func(t *testing.T) {
+		_, err := fApi.Diff("staging", "staging")
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+		_, err = fApi.Diff("staging", "qa")
+		assert.Equal(api.ErrEnvironmentNotFound, err)
+	})
+
+	t.Run("promote subset", func(t *testing.T) {
+		diff, err := fApi.Promote("staging", "production", []string{"changed", "new"})
+		assert.Nil(err)
+		assert.Len(diff.Changes, 2)
+		p, err := production.Get("changed")
+		assert.Nil(err)
+		assert.Equal(int64(2), p.Value)
+		_, err = production.Get("new")
+		assert.Nil(err)
+		_, err = production.Get("old")
+		assert.Nil(err)
+	})
+
+	t.Run("promote unknown", func(t *testing.T) {
+		_, err := fApi.Promote("staging", "production", []string{"same"})
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+	})
+
+	t.Run("promote duplicates", func(t *testing.T) {
+		diff, err := fApi.Promote("staging", "production", []string{"changed", "changed"})
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+		assert.Nil(diff)
+		_, err = staging.Update(&api.ParameterInfo{Code: "changed", Type: domain.ParameterTypeInt, Value: 3})
+		assert.Nil(err)
+		diff, err = fApi.Promote("staging", "production", []string{"changed", "changed"})
+		assert.Nil(err)
+		assert.Len(diff.Changes, 1)
+		assert.NotEmpty(diff.Changeset)
+		p, err := production.Get("changed")
+		assert.Nil(err)
+		assert.Equal(int64(3), p.Value)
+	})
+
+	t.Run("promote atomically", func(t *testing.T) {
+		_, err := staging.Create(&api.ParameterInfo{Code: "gate", Type: domain.ParameterTypeBool, Value: true})
+		assert.Nil(err)
+		_, err = staging.Create(&api.ParameterInfo{
+			Code:          "gated",
+			Type:          domain.ParameterTypeBool,
+			Value:         true,
+			Prerequisites: []*domain.Prerequisite{{Parameter: "gate", Value: true}},
+		})
+		assert.Nil(err)
+		_, err = fApi.Promote("staging", "production", []string{"old", "gated"})
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+		_, err = production.Get("old")
+		assert.Nil(err)
+		_, err = production.Get("gated")
+		assert.Equal(api.ErrParameterNotFound, err)
+	})
+
+	t.Run("promote to protected", func(t *testing.T) {
+		_, err := fApi.Environments().Create(&api.EnvironmentInfo{Code: "secure", Protected: true})
+		assert.Nil(err)
+		alice := e.ForOwner("ow1").As("alice").Projects().For("proj1")
+		diff, err := alice.Promote("staging", "secure", nil)
+		assert.Nil(err)
+		assert.Len(diff.Changes, 5)
+		assert.Empty(diff.Changeset)
+		for _, c := range diff.Changes {
+			assert.NotEmpty(c.ChangeRequest)
+			assert.Equal(diff.Changes[0].ChangeRequest, c.ChangeRequest)
+		}
+		list, err := alice.ChangeRequests().List(domain.ChangeRequestStatusPending)
+		assert.Nil(err)
+		assert.Len(list, 1)
+		params, err := alice.Environments().For("secure").Parameters().List()
+		assert.Nil(err)
+		assert.Len(params, 0)
+	})
+
+	t.Run("promote all", func(t *testing.T) {
+		_, err := fApi.Promote("staging", "production", nil)
+		assert.Nil(err)
+		diff, err := fApi.Diff("staging", "production")
+		assert.Nil(err)
+		assert.Len(diff.Changes, 0)
+	})
+
+	afterTest()
+}
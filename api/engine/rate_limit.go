@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	"github.com/rs/zerolog"
+)
+
+type rateLimitAPI struct {
+	storage storage.DataStorage
+	log     zerolog.Logger
+}
+
+func (a *rateLimitAPI) s() storage.RateLimitStorage {
+	return a.storage.RateLimits()
+}
+
+func (a *rateLimitAPI) List() ([]*domain.RateLimit, error) {
+	return a.s().List()
+}
+
+func (a *rateLimitAPI) Get(owner string) (*domain.RateLimit, error) {
+	limit, err := a.s().Get(owner)
+	if err != nil {
+		return nil, rateLimitError(err)
+	}
+	return limit, nil
+}
+
+func checkRateLimitParams(owner string, rates ...domain.Rate) error {
+	if owner == "" {
+		return &api.ErrBadRequest{
+			Description: "Rate limit owner not specified",
+		}
+	}
+	for _, r := range rates {
+		if r.Burst < 0 {
+			return &api.ErrBadRequest{
+				Description: "Rate limit burst can't be negative",
+			}
+		}
+	}
+	return nil
+}
+
+func (a *rateLimitAPI) Save(owner string, management, evaluation domain.Rate) (*domain.RateLimit, error) {
+	if err := checkRateLimitParams(owner, management, evaluation); err != nil {
+		return nil, err
+	}
+	limit, err := a.s().Get(owner)
+	switch err {
+	case nil:
+		limit.Management, limit.Evaluation, limit.Modified = management, evaluation, util.Now()
+		err = a.s().Update(limit)
+	case storage.ErrNotFound:
+		limit = &domain.RateLimit{Owner: owner, Management: management, Evaluation: evaluation, Modified: util.Now()}
+		err = a.s().Save(limit)
+	}
+	if err != nil {
+		return nil, rateLimitError(err)
+	}
+	a.log.Info().Str("owner", owner).Msg("Rate limit saved")
+	return limit, nil
+}
+
+func (a *rateLimitAPI) Delete(owner string) error {
+	if err := a.s().Delete(owner, 0); err != nil {
+		return rateLimitError(err)
+	}
+	a.log.Info().Str("owner", owner).Msg("Rate limit deleted")
+	return nil
+}
+
+func rateLimitError(err error) error {
+	switch err {
+	case storage.ErrNotFound:
+		return api.ErrRateLimitNotFound
+	case storage.ErrVersionConflict:
+		return api.ErrVersionConflict
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIRateLimit(t *testing.T) {
+
+	assert := asserts.New(t)
+	limits := engine.NewTogglyAPI(getDB(), nil, logger).RateLimits()
+
+	beforeTest()
+
+	t.Run("save", func(t *testing.T) {
+		_, err := limits.Save("", domain.Rate{}, domain.Rate{})
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+		_, err = limits.Save("ow1", domain.Rate{PerMinute: 10, Burst: -1}, domain.Rate{})
+		_, ok = err.(*api.ErrBadRequest)
+		assert.True(ok)
+
+		limit, err := limits.Save("ow1", domain.Rate{PerMinute: 10}, domain.Rate{})
+		assert.Nil(err)
+		assert.Equal(1, limit.Version)
+		limit, err = limits.Save("ow1", domain.Rate{PerMinute: 10}, domain.Rate{PerMinute: -1})
+		assert.Nil(err)
+		assert.Equal(2, limit.Version)
+		res, err := limits.Get("ow1")
+		assert.Nil(err)
+		assert.Equal(domain.Rate{PerMinute: -1}, res.Rate(domain.RouteClassEvaluation))
+		assert.Equal(domain.Rate{PerMinute: 10}, res.Rate(domain.RouteClassManagement))
+		_, err = limits.Get("ow2")
+		assert.Equal(api.ErrRateLimitNotFound, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		list, err := limits.List()
+		assert.Nil(err)
+		assert.Equal(1, len(list))
+		assert.Nil(limits.Delete("ow1"))
+		assert.Equal(api.ErrRateLimitNotFound, limits.Delete("ow1"))
+		list, err = limits.List()
+		assert.Nil(err)
+		assert.Equal(0, len(list))
+	})
+
+	afterTest()
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+)
+
+// localTimeLayouts are accepted for schedule time given together with a time zone
+var localTimeLayouts = []string{
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+}
+
+type scheduledChangeAPI struct {
+	ownerAPI
+	project string
+}
+
+func (a *scheduledChangeAPI) s() storage.ScheduledChangeStorage {
+	return a.storage.ForOwner(a.owner).Projects().For(a.project).ScheduledChanges()
+}
+
+func (a *scheduledChangeAPI) checkProject() error {
+	_, err := a.storage.ForOwner(a.owner).Projects().Get(a.project)
+	return projectError(err)
+}
+
+func (a *scheduledChangeAPI) List(status string) ([]*domain.ScheduledChange, error) {
+	if err := a.checkProject(); err != nil {
+		return nil, err
+	}
+	return a.s().List(status)
+}
+
+func (a *scheduledChangeAPI) Get(id string) (*domain.ScheduledChange, error) {
+	if err := a.checkProject(); err != nil {
+		return nil, err
+	}
+	sc, err := a.s().Get(id)
+	if err != nil {
+		return nil, scheduledChangeError(err)
+	}
+	return sc, nil
+}
+
+// parseScheduleTime returns schedule time in UTC
+func parseScheduleTime(at, zone string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, at); err == nil {
+		return t.UTC(), nil
+	}
+	if zone == "" {
+		return time.Time{}, &api.ErrBadRequest{
+			Description: fmt.Sprintf("Schedule time `%s` is not RFC3339 and time zone not specified", at),
+		}
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return time.Time{}, &api.ErrBadRequest{
+			Description: fmt.Sprintf("Unknown time zone `%s`", zone),
+		}
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, at, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, &api.ErrBadRequest{
+		Description: fmt.Sprintf("Can't parse schedule time `%s`", at),
+	}
+}
+
+func (a *scheduledChangeAPI) Create(info *api.ScheduledChangeInfo) (*domain.ScheduledChange, error) {
+	if info.Environment == "" || info.Parameter == "" {
+		return nil, &api.ErrBadRequest{
+			Description: "Environment and parameter must be specified",
+		}
+	}
+	at, err := parseScheduleTime(info.At, info.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	if at.Before(util.Now()) {
+		return nil, &api.ErrBadRequest{
+			Description: "Schedule time is in the past",
+		}
+	}
+	if info.RevertAfter != "" {
+		if d, err := time.ParseDuration(info.RevertAfter); err != nil || d <= 0 {
+			return nil, &api.ErrBadRequest{
+				Description: fmt.Sprintf("Wrong revert duration `%s`", info.RevertAfter),
+			}
+		}
+	}
+	p, err := (&forEnvironmentAPI{a.ownerAPI, a.project, info.Environment}).Parameters().Get(info.Parameter)
+	if err != nil {
+		return nil, err
+	}
+	value, err := checkValue(p, "value", info.Value)
+	if err != nil {
+		return nil, err
+	}
+	sc := &domain.ScheduledChange{
+		ID:          util.NewID(),
+		Owner:       a.owner,
+		Project:     a.project,
+		Environment: info.Environment,
+		Parameter:   info.Parameter,
+		Value:       value,
+		At:          at,
+		TimeZone:    info.TimeZone,
+		RevertAfter: info.RevertAfter,
+		Requester:   a.principal,
+		Status:      domain.ScheduledChangeStatusPending,
+		RegDate:     util.Now(),
+	}
+	if err = a.s().Save(sc); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+func (a *scheduledChangeAPI) Cancel(id string) (*domain.ScheduledChange, error) {
+	sc, err := a.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Status != domain.ScheduledChangeStatusPending {
+		return nil, api.ErrScheduledChangeClosed
+	}
+	sc.Status = domain.ScheduledChangeStatusCancelled
+	if err = a.s().Update(sc); err != nil {
+		// The scheduler claimed the change meanwhile
+		if err == storage.ErrVersionConflict {
+			return nil, api.ErrScheduledChangeClosed
+		}
+		return nil, scheduledChangeError(err)
+	}
+	return sc, nil
+}
+
+func scheduledChangeError(err error) error {
+	switch err {
+	case storage.ErrNotFound:
+		return api.ErrScheduledChangeNotFound
+	case storage.ErrVersionConflict:
+		return api.ErrVersionConflict
+	}
+	return err
+}
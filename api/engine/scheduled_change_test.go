@@ -0,0 +1,82 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIScheduledChange(t *testing.T) {
+
+	assert := asserts.New(t)
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "prod"}},
+	}, logger)
+	pApi := e.ForOwner("ow1").As("alice").Projects()
+	sApi := pApi.For("proj1").ScheduledChanges()
+
+	beforeTest()
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+	_, err = pApi.For("proj1").Environments().For("prod").Parameters().Create(&api.ParameterInfo{
+		Code: "flag", Type: domain.ParameterTypeBool, Value: false,
+	})
+	assert.Nil(err)
+
+	next := time.Now().Add(48 * time.Hour)
+
+	t.Run("bad request", func(t *testing.T) {
+		tt := []*api.ScheduledChangeInfo{
+			&api.ScheduledChangeInfo{Parameter: "flag", Value: true, At: next.Format(time.RFC3339)},
+			&api.ScheduledChangeInfo{Environment: "prod", Parameter: "flag", Value: true, At: "tomorrow"},
+			&api.ScheduledChangeInfo{Environment: "prod", Parameter: "flag", Value: true, At: "2018-01-01T09:00", TimeZone: "Europe/Berlin"},
+			&api.ScheduledChangeInfo{Environment: "prod", Parameter: "flag", Value: true, At: next.Format("2006-01-02T15:04"), TimeZone: "Mars/Base"},
+			&api.ScheduledChangeInfo{Environment: "prod", Parameter: "flag", Value: "on", At: next.Format(time.RFC3339)},
+			&api.ScheduledChangeInfo{Environment: "prod", Parameter: "flag", Value: true, At: next.Format(time.RFC3339), RevertAfter: "soon"},
+		}
+		for _, tc := range tt {
+			_, err := sApi.Create(tc)
+			_, ok := err.(*api.ErrBadRequest)
+			assert.True(ok)
+		}
+	})
+
+	var id string
+
+	t.Run("create with time zone", func(t *testing.T) {
+		sc, err := sApi.Create(&api.ScheduledChangeInfo{
+			Environment: "prod",
+			Parameter:   "flag",
+			Value:       true,
+			At:          next.Format("2006-01-02") + "T09:00",
+			TimeZone:    "Europe/Berlin",
+			RevertAfter: "2h",
+		})
+		assert.Nil(err)
+		loc, _ := time.LoadLocation("Europe/Berlin")
+		assert.Equal(9, sc.At.In(loc).Hour())
+		assert.Equal("alice", sc.Requester)
+		assert.Equal(domain.ScheduledChangeStatusPending, sc.Status)
+		id = sc.ID
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		sc, err := sApi.Cancel(id)
+		assert.Nil(err)
+		assert.Equal(domain.ScheduledChangeStatusCancelled, sc.Status)
+		_, err = sApi.Cancel(id)
+		assert.Equal(api.ErrScheduledChangeClosed, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := sApi.Get("none")
+		assert.Equal(api.ErrScheduledChangeNotFound, err)
+	})
+
+	afterTest()
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/util"
+)
+
+func (a *forProjectAPI) StaleParameters(days int) ([]*api.StaleParameter, error) {
+	if days <= 0 {
+		return nil, &api.ErrBadRequest{Description: "Number of days must be positive"}
+	}
+	envs, err := a.Environments().List()
+	if err != nil {
+		return nil, err
+	}
+	now := util.Now()
+	cutoff := now.Add(-time.Duration(days) * 24 * time.Hour)
+	res := make([]*api.StaleParameter, 0)
+	for _, env := range envs {
+		params, err := a.parameters(env.Code).List()
+		if err != nil {
+			return nil, err
+		}
+		last, err := a.storage.ForOwner(a.owner).Projects().For(a.project).Environments().For(env.Code).EvaluationCounts().LastEvaluated()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range params {
+			item := &api.StaleParameter{
+				Environment: env.Code,
+				Code:        p.Code,
+				Type:        p.Type,
+				Value:       p.Value,
+				Modified:    p.Modified,
+				Expires:     p.Expires,
+				Reasons:     []string{},
+			}
+			if t, ok := last[p.Code]; ok {
+				item.LastEvaluated = &t
+			}
+			unchanged := p.Modified.Before(cutoff)
+			if unchanged && (item.LastEvaluated == nil || item.LastEvaluated.Before(cutoff)) {
+				item.Reasons = append(item.Reasons, api.StaleReasonNotEvaluated)
+			}
+			if unchanged && (p.Type == domain.ParameterTypeBool || len(p.Variants) > 0) && servesSingleValue(p) {
+				item.Reasons = append(item.Reasons, api.StaleReasonUniform)
+			}
+			if p.Expires != nil && p.Expires.Before(now) {
+				item.Reasons = append(item.Reasons, api.StaleReasonExpired)
+			}
+			if len(item.Reasons) > 0 {
+				res = append(res, item)
+			}
+		}
+	}
+	return res, nil
+}
+
+// servesSingleValue checks if every evaluation context gets the same parameter value
+func servesSingleValue(p *domain.Parameter) bool {
+	var first *domain.Variant
+	for _, v := range p.Variants {
+		switch {
+		case v.Weight <= 0:
+		case first == nil:
+			first = v
+		case !reflect.DeepEqual(first.Value, v.Value):
+			return false
+		}
+	}
+	return true
+}
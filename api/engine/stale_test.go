@@ -0,0 +1,100 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/analytics"
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/util"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestAPIStaleParameters(t *testing.T) {
+
+	assert := asserts.New(t)
+	db := getDB()
+	counter := &analytics.Counter{Storage: db, Interval: time.Minute, Log: logger}
+	e := engine.NewTogglyAPI(db, &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "prod"}},
+		Evaluations:         counter,
+	}, logger)
+	pApi := e.ForOwner("ow1").Projects()
+	envAPI := pApi.For("proj1").Environments().For("prod")
+	params := db.ForOwner("ow1").Projects().For("proj1").Environments().For("prod").Parameters()
+	old := util.Now().Add(-10 * 24 * time.Hour)
+
+	beforeTest()
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+	for _, p := range []*domain.Parameter{
+		{Code: "unused", Type: domain.ParameterTypeBool, Value: true},
+		{Code: "used", Type: domain.ParameterTypeBool, Value: true},
+		{Code: "color", Type: domain.ParameterTypeString, Value: "white", Variants: []*domain.Variant{
+			{Name: "red", Value: "red", Weight: 1},
+			{Name: "blue", Value: "blue", Weight: 1},
+		}},
+		{Code: "url", Type: domain.ParameterTypeString, Value: "https://example.com"},
+	} {
+		p.Owner, p.Project, p.Environment, p.Modified = "ow1", "proj1", "prod", old
+		assert.Nil(params.Save(p))
+	}
+	_, err = envAPI.Parameters().Create(&api.ParameterInfo{Code: "fresh", Type: domain.ParameterTypeBool, Value: true})
+	assert.Nil(err)
+	expires := util.Now().Add(-time.Hour)
+	_, err = envAPI.Parameters().Create(&api.ParameterInfo{Code: "expired", Type: domain.ParameterTypeBool, Value: true, Expires: &expires})
+	assert.Nil(err)
+	_, err = envAPI.Evaluate("user-1", "used", "url")
+	assert.Nil(err)
+	counter.Flush()
+
+	t.Run("report", func(t *testing.T) {
+		list, err := pApi.For("proj1").StaleParameters(7)
+		assert.Nil(err)
+		reasons := map[string][]string{}
+		for _, p := range list {
+			assert.Equal("prod", p.Environment)
+			reasons[p.Code] = p.Reasons
+		}
+		assert.Equal(map[string][]string{
+			"unused":  {api.StaleReasonNotEvaluated, api.StaleReasonUniform},
+			"used":    {api.StaleReasonUniform},
+			"color":   {api.StaleReasonNotEvaluated},
+			"expired": {api.StaleReasonExpired},
+		}, reasons)
+	})
+
+	t.Run("last evaluated", func(t *testing.T) {
+		list, err := pApi.For("proj1").StaleParameters(7)
+		assert.Nil(err)
+		for _, p := range list {
+			switch p.Code {
+			case "used":
+				assert.NotNil(p.LastEvaluated)
+			case "unused":
+				assert.Nil(p.LastEvaluated)
+			}
+		}
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		_, err := envAPI.Parameters().Update(&api.ParameterInfo{Code: "unused", Type: domain.ParameterTypeBool, Value: false})
+		assert.Nil(err)
+		list, err := pApi.For("proj1").StaleParameters(7)
+		assert.Nil(err)
+		for _, p := range list {
+			assert.NotEqual("unused", p.Code)
+		}
+	})
+
+	t.Run("bad days", func(t *testing.T) {
+		_, err := pApi.For("proj1").StaleParameters(0)
+		_, ok := err.(*api.ErrBadRequest)
+		assert.True(ok)
+	})
+
+	afterTest()
+}
@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+)
+
+// semverRe is the semantic versioning 2.0.0 grammar
+var semverRe = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// normalizeValue checks value matches parameter type and converts it to the canonical
+// representation described in domain.Parameter. Numbers come as float64 or json.Number
+// from JSON and as int32 or int64 from BSON, lists come as []interface{} from JSON and as primitive.A
+// from BSON and JSON values are stored in BSON as strings.
+func normalizeValue(typ string, value interface{}) (interface{}, error) {
+	switch typ {
+	case domain.ParameterTypeBool:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case domain.ParameterTypeString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case domain.ParameterTypeInt:
+		switch v := value.(type) {
+		case int:
+			return int64(v), nil
+		case int32:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) <= 1<<53 {
+				return int64(v), nil
+			}
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				return n, nil
+			}
+			if f, err := v.Float64(); err == nil {
+				return normalizeValue(typ, f)
+			}
+		}
+	case domain.ParameterTypeFloat:
+		var f float64
+		switch v := value.(type) {
+		case int:
+			f = float64(v)
+		case int32:
+			f = float64(v)
+		case int64:
+			f = float64(v)
+		case float64:
+			f = v
+		case json.Number:
+			var err error
+			if f, err = v.Float64(); err != nil {
+				return nil, valueTypeError(typ, value)
+			}
+		default:
+			return nil, valueTypeError(typ, value)
+		}
+		if !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f, nil
+		}
+	case domain.ParameterTypeJSON:
+		if v, ok := normalizeJSON(value); ok {
+			return v, nil
+		}
+	case domain.ParameterTypeStringList:
+		if v, ok := normalizeStringList(value); ok {
+			return v, nil
+		}
+	case domain.ParameterTypeSemver:
+		if v, ok := value.(string); ok && semverRe.MatchString(v) {
+			return v, nil
+		}
+	case domain.ParameterTypeDuration:
+		if v, ok := value.(string); ok {
+			if d, err := time.ParseDuration(v); err == nil {
+				return d.String(), nil
+			}
+		}
+	default:
+		return nil, unknownTypeError()
+	}
+	return nil, valueTypeError(typ, value)
+}
+
+func knownType(typ string) bool {
+	for _, t := range domain.ParameterTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func unknownTypeError() error {
+	return &api.ErrBadRequest{
+		Description: fmt.Sprintf("Parameter type can be one of `%s`", strings.Join(domain.ParameterTypes, "`, `")),
+	}
+}
+
+func valueTypeError(typ string, value interface{}) error {
+	return &api.ErrBadRequest{
+		Description: fmt.Sprintf("Value `%v` is not of type `%s`", value, typ),
+	}
+}
+
+// normalizeJSON accepts a JSON object or array, either decoded or encoded as a string,
+// and returns its canonical encoding
+func normalizeJSON(value interface{}) (domain.JSONValue, bool) {
+	var data []byte
+	switch v := value.(type) {
+	case domain.JSONValue:
+		data = []byte(v)
+	case string:
+		data = []byte(v)
+	case map[string]interface{}, []interface{}:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil || dec.More() {
+		return "", false
+	}
+	switch decoded.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return "", false
+	}
+	// Marshal sorts object keys and keeps numbers as they were written
+	res, err := json.Marshal(decoded)
+	if err != nil {
+		return "", false
+	}
+	return domain.JSONValue(res), true
+}
+
+func normalizeStringList(value interface{}) ([]string, bool) {
+	if v, ok := value.([]string); ok {
+		return v, true
+	}
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() || rv.Kind() != reflect.Slice {
+		return nil, false
+	}
+	list := make([]string, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		s, ok := rv.Index(i).Interface().(string)
+		if !ok {
+			return nil, false
+		}
+		list = append(list, s)
+	}
+	return list, true
+}
+
+// normalizeParameter converts stored values to the canonical representation
+func normalizeParameter(p *domain.Parameter) {
+	if v, err := normalizeValue(p.Type, p.Value); err == nil {
+		p.Value = v
+	}
+	if v, err := normalizeValue(p.Type, p.OffValue); err == nil {
+		p.OffValue = v
+	}
+	for i, av := range p.AllowedValues {
+		if v, err := normalizeValue(p.Type, av); err == nil {
+			p.AllowedValues[i] = v
+		}
+	}
+	for _, variant := range p.Variants {
+		if v, err := normalizeValue(p.Type, variant.Value); err == nil {
+			variant.Value = v
+		}
+	}
+	p.Expires = normalizeTime(p.Expires)
+}
+
+// offValue returns value served when parameter is switched off
+func offValue(p *domain.Parameter) interface{} {
+	if p.OffValue != nil {
+		return p.OffValue
+	}
+	switch p.Type {
+	case domain.ParameterTypeBool:
+		return false
+	case domain.ParameterTypeString:
+		return ""
+	case domain.ParameterTypeInt:
+		return int64(0)
+	case domain.ParameterTypeFloat:
+		return float64(0)
+	case domain.ParameterTypeStringList:
+		return []string{}
+	case domain.ParameterTypeSemver:
+		return "0.0.0"
+	case domain.ParameterTypeDuration:
+		return time.Duration(0).String()
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+)
+
+// deliveryLogSize is the number of latest deliveries returned for a webhook
+const deliveryLogSize = 100
+
+type webhookAPI struct {
+	ownerAPI
+	project string
+}
+
+func (a *webhookAPI) s() storage.WebhookStorage {
+	return a.storage.ForOwner(a.owner).Projects().For(a.project).Webhooks()
+}
+
+func (a *webhookAPI) checkProject() error {
+	_, err := a.storage.ForOwner(a.owner).Projects().Get(a.project)
+	return projectError(err)
+}
+
+func (a *webhookAPI) List() ([]*domain.Webhook, error) {
+	if err := a.checkProject(); err != nil {
+		return nil, err
+	}
+	list, err := a.s().List()
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range list {
+		hook.Secret = ""
+	}
+	return list, nil
+}
+
+func (a *webhookAPI) get(id string) (*domain.Webhook, error) {
+	if err := a.checkProject(); err != nil {
+		return nil, err
+	}
+	hook, err := a.s().Get(id)
+	if err != nil {
+		return nil, webhookError(err)
+	}
+	return hook, nil
+}
+
+func (a *webhookAPI) Get(id string) (*domain.Webhook, error) {
+	hook, err := a.get(id)
+	if err != nil {
+		return nil, err
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+// checkWebhookParams checks the webhook URL and events. Unless private webhooks are allowed, hosts
+// having loopback, private or link-local addresses are refused. Hosts which can't be resolved now
+// are accepted, the dispatcher checks addresses again when it connects.
+func (a *webhookAPI) checkWebhookParams(info *api.WebhookInfo) error {
+	u, err := url.Parse(info.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return &api.ErrBadRequest{
+			Description: fmt.Sprintf("Wrong webhook URL `%s`", info.URL),
+		}
+	}
+	if !a.cfg.PrivateWebhooks && !publicHost(u.Hostname()) {
+		return &api.ErrBadRequest{
+			Description: fmt.Sprintf("Webhook URL `%s` points to a private address", info.URL),
+		}
+	}
+	known := make(map[string]bool, len(domain.EventTypes))
+	for _, typ := range domain.EventTypes {
+		known[typ] = true
+	}
+	for _, typ := range info.Events {
+		if !known[typ] {
+			return &api.ErrBadRequest{
+				Description: fmt.Sprintf("Unknown event type `%s`", typ),
+			}
+		}
+	}
+	return nil
+}
+
+// publicHost reports whether all addresses of the host are public
+func publicHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return util.PublicIP(ip)
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return true
+	}
+	for _, ip := range ips {
+		if !util.PublicIP(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *webhookAPI) Create(info *api.WebhookInfo) (*domain.Webhook, error) {
+	if err := a.checkWebhookParams(info); err != nil {
+		return nil, err
+	}
+	if err := a.checkProject(); err != nil {
+		return nil, err
+	}
+	secret := info.Secret
+	if secret == "" {
+		secret = util.NewID()
+	}
+	events := info.Events
+	if events == nil {
+		events = []string{}
+	}
+	hook := &domain.Webhook{
+		ID:      util.NewID(),
+		Owner:   a.owner,
+		Project: a.project,
+		URL:     info.URL,
+		Secret:  secret,
+		Events:  events,
+		Active:  info.Active,
+		RegDate: util.Now(),
+	}
+	if err := a.s().Save(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (a *webhookAPI) Update(info *api.WebhookInfo) (*domain.Webhook, error) {
+	if err := a.checkWebhookParams(info); err != nil {
+		return nil, err
+	}
+	hook, err := a.get(info.ID)
+	if err != nil {
+		return nil, err
+	}
+	if info.Version != 0 && info.Version != hook.Version {
+		return nil, api.ErrVersionConflict
+	}
+	if info.Secret != "" {
+		hook.Secret = info.Secret
+	}
+	hook.URL = info.URL
+	hook.Events = info.Events
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	hook.Active = info.Active
+	if err = a.s().Update(hook); err != nil {
+		return nil, webhookError(err)
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+func (a *webhookAPI) Delete(id string, version int) error {
+	if err := a.checkProject(); err != nil {
+		return err
+	}
+	return webhookError(a.s().Delete(id, version))
+}
+
+func (a *webhookAPI) Deliveries(id string) ([]*domain.WebhookDelivery, error) {
+	if _, err := a.get(id); err != nil {
+		return nil, err
+	}
+	return a.storage.ForOwner(a.owner).Projects().For(a.project).WebhookDeliveries().List(id, deliveryLogSize)
+}
+
+func webhookError(err error) error {
+	switch err {
+	case storage.ErrNotFound:
+		return api.ErrWebhookNotFound
+	case storage.ErrVersionConflict:
+		return api.ErrVersionConflict
+	}
+	return err
+}
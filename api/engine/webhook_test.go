@@ -0,0 +1,110 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+type eventRecorder struct {
+	events []*domain.Event
+}
+
+func (r *eventRecorder) Publish(e *domain.Event) {
+	r.events = append(r.events, e)
+}
+
+func TestAPIWebhook(t *testing.T) {
+
+	assert := asserts.New(t)
+	events := &eventRecorder{}
+	e := engine.NewTogglyAPI(getDB(), &engine.Config{Events: events}, logger)
+	pApi := e.ForOwner("ow1").As("alice").Projects()
+	wApi := pApi.For("proj1").Webhooks()
+
+	beforeTest()
+
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+
+	t.Run("bad request", func(t *testing.T) {
+		tt := []*api.WebhookInfo{
+			&api.WebhookInfo{URL: "ftp://example.com"},
+			&api.WebhookInfo{URL: "not a url"},
+			&api.WebhookInfo{URL: "https://example.com", Events: []string{"flag.flipped"}},
+			&api.WebhookInfo{URL: "http://127.0.0.1:8080/hook"},
+			&api.WebhookInfo{URL: "http://[::1]/hook"},
+			&api.WebhookInfo{URL: "http://10.1.2.3/hook"},
+			&api.WebhookInfo{URL: "http://169.254.169.254/latest/meta-data"},
+			&api.WebhookInfo{URL: "http://localhost/hook"},
+		}
+		for _, tc := range tt {
+			_, err := wApi.Create(tc)
+			_, ok := err.(*api.ErrBadRequest)
+			assert.True(ok)
+		}
+	})
+
+	var id string
+
+	t.Run("create", func(t *testing.T) {
+		hook, err := wApi.Create(&api.WebhookInfo{URL: "https://example.com/hook", Active: true})
+		assert.Nil(err)
+		assert.NotEmpty(hook.Secret)
+		id = hook.ID
+		hook, err = wApi.Get(id)
+		assert.Nil(err)
+		assert.Empty(hook.Secret)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		hook, err := wApi.Update(&api.WebhookInfo{ID: id, URL: "https://example.com/other", Version: 1})
+		assert.Nil(err)
+		assert.Equal(2, hook.Version)
+		assert.False(hook.Active)
+		_, err = wApi.Update(&api.WebhookInfo{ID: id, URL: "https://example.com/other", Version: 1})
+		assert.Equal(api.ErrVersionConflict, err)
+	})
+
+	t.Run("events", func(t *testing.T) {
+		env, err := pApi.For("proj1").Environments().Create(&api.EnvironmentInfo{Code: "dev"})
+		assert.Nil(err)
+		last := events.events[len(events.events)-1]
+		assert.Equal(domain.EventEnvironmentCreated, last.Type)
+		assert.Equal("dev", last.Environment)
+		assert.Equal("alice", last.Principal)
+		assert.Equal(env, last.Data)
+	})
+
+	t.Run("project create events", func(t *testing.T) {
+		n := len(events.events)
+		_, err := pApi.Create(&api.ProjectInfo{
+			Code:         "proj2",
+			Status:       domain.ProjectStatusActive,
+			Environments: []*api.EnvironmentInfo{{Code: "dev"}, {Code: "prod"}},
+		})
+		assert.Nil(err)
+		created := events.events[n:]
+		assert.Len(created, 3)
+		assert.Equal(domain.EventProjectCreated, created[0].Type)
+		assert.Equal(domain.EventEnvironmentCreated, created[1].Type)
+		assert.Equal("dev", created[1].Environment)
+		assert.Equal("prod", created[2].Environment)
+	})
+
+	t.Run("project delete removes webhooks", func(t *testing.T) {
+		assert.Nil(pApi.Delete("proj1", 0, true))
+		last := events.events[len(events.events)-1]
+		assert.Equal(domain.EventProjectDeleted, last.Type)
+		_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+		assert.Nil(err)
+		list, err := wApi.List()
+		assert.Nil(err)
+		assert.Empty(list)
+	})
+
+	afterTest()
+}
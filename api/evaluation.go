@@ -0,0 +1,53 @@
+package api
+
+import "time"
+
+// Evaluation reasons
+const (
+	// EvaluationReasonValue means parameter value is served
+	EvaluationReasonValue = "value"
+	// EvaluationReasonVariant means value of the variant assigned to the context is served
+	EvaluationReasonVariant = "variant"
+	// EvaluationReasonProjectDisabled means off value is served because the project is disabled
+	EvaluationReasonProjectDisabled = "project_disabled"
+	// EvaluationReasonEnvironmentDisabled means off value is served because the environment is disabled
+	EvaluationReasonEnvironmentDisabled = "environment_disabled"
+	// EvaluationReasonPrerequisiteFailed means off value is served because a prerequisite
+	// parameter serves another value
+	EvaluationReasonPrerequisiteFailed = "prerequisite_failed"
+)
+
+// Evaluation describes parameter value served to clients. Variant is the name
+// of the variant assigned to the evaluation context, if any.
+type Evaluation struct {
+	Code    string      `json:"code"`
+	Type    string      `json:"type"`
+	Value   interface{} `json:"value"`
+	Variant string      `json:"variant,omitempty"`
+	Reason  string      `json:"reason"`
+}
+
+// EvaluationRecorder receives evaluations served to clients of the owner environment
+type EvaluationRecorder interface {
+	Record(owner, project, env string, evals []*Evaluation)
+}
+
+// EvaluationSeries is hourly evaluation counts of a parameter for hours in [From, To).
+// Points lists hours having evaluations, oldest first, one point per served variant.
+// Variants sums counts per variant, empty variant counts evaluations served without one.
+type EvaluationSeries struct {
+	Parameter   string             `json:"parameter"`
+	Environment string             `json:"environment"`
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Total       int64              `json:"total"`
+	Variants    map[string]int64   `json:"variants"`
+	Points      []*EvaluationPoint `json:"points"`
+}
+
+// EvaluationPoint is the number of evaluations served the variant during the hour
+type EvaluationPoint struct {
+	Hour    time.Time `json:"hour"`
+	Variant string    `json:"variant"`
+	Count   int64     `json:"count"`
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/Toggly/core/domain"
+)
+
+var (
+	// ErrOrganizationNotFound error
+	ErrOrganizationNotFound = errors.New("Organization not found")
+	// ErrOrganizationNotEmpty error
+	ErrOrganizationNotEmpty = errors.New("Organization not empty")
+	// ErrTeamNotFound error
+	ErrTeamNotFound = errors.New("Team not found")
+	// ErrAccessDenied error
+	ErrAccessDenied = errors.New("Access denied")
+)
+
+// AllProjects requests access to every organization project
+const AllProjects = "*"
+
+// OrganizationInfo type. Non-zero Version is the expected version of the organization being updated.
+type OrganizationInfo struct {
+	ID          string
+	Name        string
+	Description string
+	Version     int
+}
+
+// TeamInfo type. Save replaces team members and projects.
+type TeamInfo struct {
+	Code        string
+	Description string
+	Members     []string
+	Projects    []string
+}
+
+// OrganizationAPI interface. As returns API acting on behalf of the principal.
+// Create makes the principal the organization owner. Organizations are visible to members only
+// and managed by owners and admins; only owners manage other owners and delete organizations.
+// Authorize checks the principal access to the owner entities: empty project means
+// any organization member, AllProjects means owners and admins, otherwise the project
+// must be granted to one of the member teams.
+type OrganizationAPI interface {
+	As(principal string) OrganizationAPI
+	List() ([]*domain.Organization, error)
+	Get(id string) (*domain.Organization, error)
+	Create(info *OrganizationInfo) (*domain.Organization, error)
+	Update(info *OrganizationInfo) (*domain.Organization, error)
+	Delete(id string, version int) error
+	SetMember(id, principal, role string) (*domain.Organization, error)
+	RemoveMember(id, principal string) (*domain.Organization, error)
+	SaveTeam(id string, info *TeamInfo) (*domain.Organization, error)
+	DeleteTeam(id, code string) (*domain.Organization, error)
+	Authorize(owner, project string) error
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/Toggly/core/domain"
+)
+
+// ErrRateLimitNotFound error
+var ErrRateLimitNotFound = errors.New("Rate limit not found")
+
+// RateLimitAPI interface. Rate limits override server default rates for owners.
+// They are managed by the server operator and are not exposed to owners.
+// Save creates or replaces the owner rate limit.
+type RateLimitAPI interface {
+	List() ([]*domain.RateLimit, error)
+	Get(owner string) (*domain.RateLimit, error)
+	Save(owner string, management, evaluation domain.Rate) (*domain.RateLimit, error)
+	Delete(owner string) error
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/Toggly/core/domain"
+)
+
+var (
+	// ErrScheduledChangeNotFound error
+	ErrScheduledChangeNotFound = errors.New("Scheduled change not found")
+	// ErrScheduledChangeClosed error
+	ErrScheduledChangeClosed = errors.New("Scheduled change is not pending")
+)
+
+// ScheduledChangeInfo type. At is either RFC3339 time or, when TimeZone is set,
+// a local time like 2006-01-02T15:04 in that zone. RevertAfter is a duration like 2h.
+type ScheduledChangeInfo struct {
+	Environment string
+	Parameter   string
+	Value       interface{}
+	At          string
+	TimeZone    string
+	RevertAfter string
+}
+
+// ScheduledChangeAPI interface
+type ScheduledChangeAPI interface {
+	List(status string) ([]*domain.ScheduledChange, error)
+	Get(id string) (*domain.ScheduledChange, error)
+	Create(info *ScheduledChangeInfo) (*domain.ScheduledChange, error)
+	Cancel(id string) (*domain.ScheduledChange, error)
+}
@@ -0,0 +1,27 @@
+package api
+
+import "time"
+
+// Stale parameter reasons
+const (
+	// StaleReasonNotEvaluated means the parameter was neither evaluated nor modified for the period
+	StaleReasonNotEvaluated = "not_evaluated"
+	// StaleReasonUniform means every evaluation context gets the same value of a bool parameter
+	// or of a parameter with variants, and the parameter was not modified for the period
+	StaleReasonUniform = "uniform"
+	// StaleReasonExpired means the parameter declared expiry date has passed
+	StaleReasonExpired = "expired"
+)
+
+// StaleParameter describes environment parameter which is likely not needed anymore.
+// LastEvaluated is nil if the parameter was not evaluated since evaluations are counted.
+type StaleParameter struct {
+	Environment   string      `json:"environment"`
+	Code          string      `json:"code"`
+	Type          string      `json:"type"`
+	Value         interface{} `json:"value"`
+	Modified      time.Time   `json:"modified"`
+	LastEvaluated *time.Time  `json:"last_evaluated"`
+	Expires       *time.Time  `json:"expires,omitempty"`
+	Reasons       []string    `json:"reasons"`
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/Toggly/core/domain"
+)
+
+var (
+	// ErrWebhookNotFound error
+	ErrWebhookNotFound = errors.New("Webhook not found")
+)
+
+// EventPublisher receives configuration change events
+type EventPublisher interface {
+	Publish(e *domain.Event)
+}
+
+// WebhookInfo type. Non-zero Version is the expected version of the webhook being updated.
+// Empty Secret generates a new one on create and keeps the current one on update.
+type WebhookInfo struct {
+	ID      string
+	URL     string
+	Secret  string
+	Events  []string
+	Active  bool
+	Version int
+}
+
+// WebhookAPI interface. Secret is returned by Create only.
+type WebhookAPI interface {
+	List() ([]*domain.Webhook, error)
+	Get(id string) (*domain.Webhook, error)
+	Create(info *WebhookInfo) (*domain.Webhook, error)
+	Update(info *WebhookInfo) (*domain.Webhook, error)
+	Delete(id string, version int) error
+	Deliveries(id string) ([]*domain.WebhookDelivery, error)
+}
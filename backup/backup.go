@@ -0,0 +1,516 @@
+package backup
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+)
+
+// Archive format
+const (
+	formatName = "toggly-backup"
+	// FormatVersion is the archive format version written by Backup. Restore reads archives
+	// of this and older versions.
+	FormatVersion = 1
+	// maxDocumentSize limits archive documents, it is the Mongo document size limit
+	maxDocumentSize = 16 * 1024 * 1024
+	// countBatch is the number of evaluation counters restored at once
+	countBatch = 500
+)
+
+// Archive entry types in the order they are written, parents before children
+const (
+	TypeOrganization    = "organization"
+	TypeRateLimit       = "rate_limit"
+	TypeProject         = "project"
+	TypeEnvironment     = "environment"
+	TypeParameter       = "parameter"
+	TypeChangeset       = "changeset"
+	TypeEvaluationCount = "evaluation_count"
+	TypeChangeRequest   = "change_request"
+	TypeScheduledChange = "scheduled_change"
+	TypeWebhook         = "webhook"
+	TypeWebhookDelivery = "webhook_delivery"
+	typeEnd             = "end"
+)
+
+var (
+	// ErrFormat is returned for input that is not a backup archive or has unknown format version
+	ErrFormat = errors.New("not a supported backup archive")
+	// ErrChecksum is returned for archives modified or truncated after they were written
+	ErrChecksum = errors.New("backup archive checksum mismatch")
+)
+
+// all entries are listed without limits
+var (
+	noLimit = math.MaxInt32
+	epoch   = time.Unix(0, 0)
+	endless = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// header is the first archive document
+type header struct {
+	Format  string    `bson:"format"`
+	Version int       `bson:"version"`
+	Created time.Time `bson:"created"`
+	Owners  []string  `bson:"owners"`
+}
+
+// entry is an archive document keeping an entity. The last archive document is the end entry
+// having counts of entries by type and SHA-256 checksum of all preceding documents.
+type entry struct {
+	Type     string         `bson:"type"`
+	Owner    string         `bson:"owner,omitempty"`
+	Data     interface{}    `bson:"data,omitempty"`
+	Counts   map[string]int `bson:"counts,omitempty"`
+	Checksum string         `bson:"checksum,omitempty"`
+}
+
+// rawEntry is entry with undecoded entity
+type rawEntry struct {
+	Type     string         `bson:"type"`
+	Owner    string         `bson:"owner"`
+	Data     bson.Raw       `bson:"data"`
+	Counts   map[string]int `bson:"counts"`
+	Checksum string         `bson:"checksum"`
+}
+
+// Summary describes an archive. Owners lists owners having entries in it.
+type Summary struct {
+	Version int
+	Created time.Time
+	Owners  []string
+	Counts  map[string]int
+}
+
+// Archiver copies storage entities of the owners, all owners if none given, to and from
+// backup archives. Archive is a gzip compressed sequence of BSON documents, so entities are
+// restored exactly as they were stored, including their versions. Webhooks of deleted projects
+// are not kept. Archives written from one storage type may be restored into another one.
+type Archiver struct {
+	Storage storage.DataStorage
+	Owners  []string
+	Log     zerolog.Logger
+}
+
+func (a *Archiver) included(owner string) bool {
+	if len(a.Owners) == 0 {
+		return true
+	}
+	for _, o := range a.Owners {
+		if o == owner {
+			return true
+		}
+	}
+	return false
+}
+
+// Backup writes the archive
+func (a *Archiver) Backup(w io.Writer) (*Summary, error) {
+	owners, err := a.Storage.Owners()
+	if err != nil {
+		return nil, err
+	}
+	zw := gzip.NewWriter(w)
+	aw := &archiveWriter{w: zw, hash: sha256.New(), counts: map[string]int{}}
+	created := util.Now()
+	err = aw.write(&header{Format: formatName, Version: FormatVersion, Created: created, Owners: a.Owners})
+	if err != nil {
+		return nil, err
+	}
+	written := make([]string, 0, len(owners))
+	for _, owner := range owners {
+		if !a.included(owner) {
+			continue
+		}
+		if err := a.backupOwner(aw, owner); err != nil {
+			return nil, err
+		}
+		written = append(written, owner)
+		a.Log.Info().Str("owner", owner).Msg("Owner backed up")
+	}
+	if err := aw.close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return &Summary{Version: FormatVersion, Created: created, Owners: written, Counts: aw.counts}, nil
+}
+
+func (a *Archiver) backupOwner(aw *archiveWriter, owner string) error {
+	org, err := a.Storage.Organizations().Get(owner)
+	switch err {
+	case nil:
+		if err := aw.entry(TypeOrganization, owner, org); err != nil {
+			return err
+		}
+	case storage.ErrNotFound:
+	default:
+		return err
+	}
+	limit, err := a.Storage.RateLimits().Get(owner)
+	switch err {
+	case nil:
+		if err := aw.entry(TypeRateLimit, owner, limit); err != nil {
+			return err
+		}
+	case storage.ErrNotFound:
+	default:
+		return err
+	}
+	pdb := a.Storage.ForOwner(owner).Projects()
+	projects, err := pdb.List()
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		if err := aw.entry(TypeProject, owner, p); err != nil {
+			return err
+		}
+		if err := a.backupProject(aw, owner, pdb.For(p.Code)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Archiver) backupProject(aw *archiveWriter, owner string, db storage.ForProject) error {
+	envs, err := db.Environments().List()
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		if err := aw.entry(TypeEnvironment, owner, env); err != nil {
+			return err
+		}
+		if err := a.backupEnvironment(aw, owner, db.Environments().For(env.Code)); err != nil {
+			return err
+		}
+	}
+	crs, err := db.ChangeRequests().List("")
+	if err != nil {
+		return err
+	}
+	for _, cr := range crs {
+		if err := aw.entry(TypeChangeRequest, owner, cr); err != nil {
+			return err
+		}
+	}
+	scs, err := db.ScheduledChanges().List("")
+	if err != nil {
+		return err
+	}
+	for _, sc := range scs {
+		if err := aw.entry(TypeScheduledChange, owner, sc); err != nil {
+			return err
+		}
+	}
+	hooks, err := db.Webhooks().List()
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if err := aw.entry(TypeWebhook, owner, hook); err != nil {
+			return err
+		}
+		deliveries, err := db.WebhookDeliveries().List(hook.ID, noLimit)
+		if err != nil {
+			return err
+		}
+		for _, d := range deliveries {
+			if err := aw.entry(TypeWebhookDelivery, owner, d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *Archiver) backupEnvironment(aw *archiveWriter, owner string, db storage.ForEnvironment) error {
+	params, err := db.Parameters().List()
+	if err != nil {
+		return err
+	}
+	for _, p := range params {
+		if err := aw.entry(TypeParameter, owner, p); err != nil {
+			return err
+		}
+	}
+	changesets, err := db.Changesets().List(noLimit)
+	if err != nil {
+		return err
+	}
+	for _, cs := range changesets {
+		if err := aw.entry(TypeChangeset, owner, cs); err != nil {
+			return err
+		}
+	}
+	last, err := db.EvaluationCounts().LastEvaluated()
+	if err != nil {
+		return err
+	}
+	counted := make([]string, 0, len(last))
+	for param := range last {
+		counted = append(counted, param)
+	}
+	sort.Strings(counted)
+	for _, param := range counted {
+		counts, err := db.EvaluationCounts().List(param, epoch, endless)
+		if err != nil {
+			return err
+		}
+		for _, c := range counts {
+			if err := aw.entry(TypeEvaluationCount, owner, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Restore reads the archive into the storage, which must implement storage.Importer.
+// Owners must have no projects or organization in the storage yet. The archive is checked
+// while it is read, so use Verify first to avoid restoring a part of a broken archive.
+func (a *Archiver) Restore(r io.Reader) (*Summary, error) {
+	importer, ok := a.Storage.(storage.Importer)
+	if !ok {
+		return nil, errors.New("Storage can't import entities")
+	}
+	existing, err := a.Storage.Owners()
+	if err != nil {
+		return nil, err
+	}
+	present := map[string]bool{}
+	for _, owner := range existing {
+		present[owner] = true
+	}
+	counts := make([]*domain.EvaluationCount, 0, countBatch)
+	flush := func() error {
+		if len(counts) == 0 {
+			return nil
+		}
+		err := a.Storage.EvaluationCounts().Add(counts)
+		counts = counts[:0]
+		return err
+	}
+	restored := map[string]int{}
+	current := ""
+	summary, err := read(r, func(e *rawEntry) error {
+		if !a.included(e.Owner) {
+			return nil
+		}
+		if e.Owner != current {
+			if present[e.Owner] {
+				return fmt.Errorf("Owner %s already has data in the storage", e.Owner)
+			}
+			if current != "" {
+				a.Log.Info().Str("owner", current).Msg("Owner restored")
+			}
+			present[e.Owner], current = true, e.Owner
+		}
+		entity, err := decode(e)
+		if err != nil {
+			return err
+		}
+		restored[e.Type]++
+		if c, ok := entity.(*domain.EvaluationCount); ok {
+			counts = append(counts, c)
+			if len(counts) < countBatch {
+				return nil
+			}
+			return flush()
+		}
+		return importer.Import(entity)
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if current != "" {
+		a.Log.Info().Str("owner", current).Msg("Owner restored")
+	}
+	summary.Counts = restored
+	if len(a.Owners) > 0 {
+		owners := make([]string, 0, len(summary.Owners))
+		for _, owner := range summary.Owners {
+			if a.included(owner) {
+				owners = append(owners, owner)
+			}
+		}
+		summary.Owners = owners
+	}
+	return summary, nil
+}
+
+// Verify reads the whole archive checking its format, checksum and entry counts
+func Verify(r io.Reader) (*Summary, error) {
+	return read(r, func(e *rawEntry) error {
+		_, err := decode(e)
+		return err
+	})
+}
+
+// decode returns the entity kept in the entry
+func decode(e *rawEntry) (interface{}, error) {
+	var entity interface{}
+	switch e.Type {
+	case TypeOrganization:
+		entity = &domain.Organization{}
+	case TypeRateLimit:
+		entity = &domain.RateLimit{}
+	case TypeProject:
+		entity = &domain.Project{}
+	case TypeEnvironment:
+		entity = &domain.Environment{}
+	case TypeParameter:
+		entity = &domain.Parameter{}
+	case TypeChangeset:
+		entity = &domain.Changeset{}
+	case TypeEvaluationCount:
+		entity = &domain.EvaluationCount{}
+	case TypeChangeRequest:
+		entity = &domain.ChangeRequest{}
+	case TypeScheduledChange:
+		entity = &domain.ScheduledChange{}
+	case TypeWebhook:
+		entity = &domain.Webhook{}
+	case TypeWebhookDelivery:
+		entity = &domain.WebhookDelivery{}
+	default:
+		return nil, fmt.Errorf("Unknown backup entry type %s", e.Type)
+	}
+	if err := bson.Unmarshal(e.Data, entity); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// read calls fn for every archive entry and checks the archive end entry
+func read(r io.Reader, fn func(e *rawEntry) error) (*Summary, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, ErrFormat
+	}
+	defer zr.Close()
+	h := sha256.New()
+	doc, err := readDocument(zr, h)
+	if err != nil {
+		return nil, ErrFormat
+	}
+	var head header
+	if err := bson.Unmarshal(doc, &head); err != nil || head.Format != formatName || head.Version < 1 || head.Version > FormatVersion {
+		return nil, ErrFormat
+	}
+	summary := &Summary{Version: head.Version, Created: head.Created, Owners: []string{}, Counts: map[string]int{}}
+	for {
+		sum := hex.EncodeToString(h.Sum(nil))
+		doc, err := readDocument(zr, h)
+		if err == io.EOF {
+			// the end entry is missing
+			return nil, ErrChecksum
+		}
+		if err != nil {
+			return nil, err
+		}
+		var e rawEntry
+		if err := bson.Unmarshal(doc, &e); err != nil {
+			return nil, err
+		}
+		if e.Type == typeEnd {
+			if e.Checksum != sum || !sameCounts(e.Counts, summary.Counts) {
+				return nil, ErrChecksum
+			}
+			return summary, nil
+		}
+		if n := len(summary.Owners); n == 0 || summary.Owners[n-1] != e.Owner {
+			summary.Owners = append(summary.Owners, e.Owner)
+		}
+		summary.Counts[e.Type]++
+		if err := fn(&e); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func sameCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// readDocument reads the next BSON document adding it to the hash.
+// It returns io.EOF if there are no more documents.
+func readDocument(r io.Reader, h hash.Hash) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, ErrChecksum
+		}
+		return nil, err
+	}
+	n := binary.LittleEndian.Uint32(size[:])
+	if n < 5 || n > maxDocumentSize {
+		return nil, ErrChecksum
+	}
+	doc := make([]byte, n)
+	copy(doc, size[:])
+	if _, err := io.ReadFull(r, doc[4:]); err != nil {
+		return nil, ErrChecksum
+	}
+	h.Write(doc)
+	return doc, nil
+}
+
+type archiveWriter struct {
+	w      io.Writer
+	hash   hash.Hash
+	counts map[string]int
+}
+
+func (aw *archiveWriter) write(v interface{}) error {
+	doc, err := bson.Marshal(v)
+	if err != nil {
+		return err
+	}
+	aw.hash.Write(doc)
+	_, err = aw.w.Write(doc)
+	return err
+}
+
+func (aw *archiveWriter) entry(typ, owner string, entity interface{}) error {
+	aw.counts[typ]++
+	return aw.write(&entry{Type: typ, Owner: owner, Data: entity})
+}
+
+// close writes the end entry
+func (aw *archiveWriter) close() error {
+	doc, err := bson.Marshal(&entry{Type: typeEnd, Counts: aw.counts, Checksum: hex.EncodeToString(aw.hash.Sum(nil))})
+	if err != nil {
+		return err
+	}
+	_, err = aw.w.Write(doc)
+	return err
+}
@@ -0,0 +1,228 @@
+package backup_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	asserts "github.com/stretchr/testify/assert"
+
+	"github.com/Toggly/core/backup"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/storage/bolt"
+	"github.com/Toggly/core/storage/sql"
+	"github.com/Toggly/core/util"
+)
+
+var logger = log.Output(zerolog.ConsoleWriter{
+	Out:     os.Stdout,
+	NoColor: true,
+}).Level(zerolog.DebugLevel)
+
+func openBolt(ctx context.Context, dir string) storage.DataStorage {
+	s, err := bolt.NewBoltDataStorage(ctx, filepath.Join(dir, "toggly.db"), logger)
+	if err == nil {
+		err = s.Connect()
+	}
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't open bolt storage")
+	}
+	return s
+}
+
+func openSQL(ctx context.Context, dir, name string) storage.DataStorage {
+	s, err := sql.NewSQLDataStorage(ctx, "sqlite3", filepath.Join(dir, name), logger)
+	if err == nil {
+		err = s.Connect()
+	}
+	if err == nil {
+		err = s.(storage.Migrator).Migrate("test")
+	}
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't open SQL storage")
+	}
+	return s
+}
+
+// fill saves an entity of every type for the owner
+func fill(assert *asserts.Assertions, s storage.DataStorage, owner string) {
+	now := util.Now()
+	assert.Nil(s.Organizations().Save(&domain.Organization{
+		ID: owner, Name: "Org " + owner, Members: []*domain.Member{{Principal: "alice", Role: domain.MemberRoleOwner}}, RegDate: now,
+	}))
+	assert.Nil(s.RateLimits().Save(&domain.RateLimit{Owner: owner, Evaluation: domain.Rate{PerMinute: 100}, Modified: now}))
+	pdb := s.ForOwner(owner).Projects()
+	p := &domain.Project{Code: "proj1", Owner: owner, Status: domain.ProjectStatusActive, RegDate: now}
+	assert.Nil(pdb.Save(p))
+	p.Description = "updated"
+	assert.Nil(pdb.Update(p))
+	prj := pdb.For("proj1")
+	assert.Nil(prj.Environments().Save(&domain.Environment{Code: "dev", Owner: owner, Project: "proj1", RegDate: now}))
+	env := prj.Environments().For("dev")
+	assert.Nil(env.Parameters().Save(&domain.Parameter{
+		Code: "limit", Owner: owner, Project: "proj1", Environment: "dev", Type: domain.ParameterTypeInt, Value: int64(1) << 60, Modified: now,
+	}))
+	assert.Nil(env.Changesets().Apply(&domain.Changeset{
+		ID: util.NewID(), Owner: owner, Project: "proj1", Environment: "dev", RegDate: now,
+		Changes: []*domain.ChangesetItem{{
+			Action:        domain.ChangeActionCreate,
+			ParameterCode: "flag",
+			Parameter:     &domain.Parameter{Code: "flag", Owner: owner, Project: "proj1", Environment: "dev", Type: domain.ParameterTypeBool, Value: true},
+		}},
+	}))
+	hour := now.UTC().Truncate(time.Hour)
+	assert.Nil(s.EvaluationCounts().Add([]*domain.EvaluationCount{
+		{Owner: owner, Project: "proj1", Environment: "dev", Parameter: "flag", Hour: hour, Count: 3, Last: now},
+	}))
+	assert.Nil(prj.ChangeRequests().Save(&domain.ChangeRequest{
+		ID: util.NewID(), Owner: owner, Project: "proj1", Environment: "dev", Status: domain.ChangeRequestStatusPending, RegDate: now,
+	}))
+	assert.Nil(prj.ScheduledChanges().Save(&domain.ScheduledChange{
+		ID: util.NewID(), Owner: owner, Project: "proj1", Environment: "dev", Parameter: "flag", Value: false,
+		At: now.Add(time.Hour), Status: domain.ScheduledChangeStatusPending, RegDate: now,
+	}))
+	hook := &domain.Webhook{ID: util.NewID(), Owner: owner, Project: "proj1", URL: "https://example.com", Active: true, RegDate: now}
+	assert.Nil(prj.Webhooks().Save(hook))
+	assert.Nil(prj.WebhookDeliveries().Save(&domain.WebhookDelivery{
+		ID: util.NewID(), Owner: owner, Project: "proj1", Webhook: hook.ID, Status: domain.WebhookDeliveryStatusPending,
+		NextAttempt: now, RegDate: now,
+	}))
+}
+
+// assertSame checks the target storage keeps the same owner entities as the source one
+func assertSame(assert *asserts.Assertions, src, dst storage.DataStorage, owner string) {
+	srcOrg, err := src.Organizations().Get(owner)
+	assert.Nil(err)
+	dstOrg, err := dst.Organizations().Get(owner)
+	assert.Nil(err)
+	assert.Equal(srcOrg, dstOrg)
+	srcLimit, err := src.RateLimits().Get(owner)
+	assert.Nil(err)
+	dstLimit, err := dst.RateLimits().Get(owner)
+	assert.Nil(err)
+	assert.Equal(srcLimit, dstLimit)
+
+	get := func(s storage.DataStorage) []interface{} {
+		prj := s.ForOwner(owner).Projects().For("proj1")
+		env := prj.Environments().For("dev")
+		p, err := s.ForOwner(owner).Projects().Get("proj1")
+		assert.Nil(err)
+		params, err := env.Parameters().List()
+		assert.Nil(err)
+		changesets, err := env.Changesets().List(10)
+		assert.Nil(err)
+		counts, err := env.EvaluationCounts().List("flag", time.Unix(0, 0), time.Now().Add(time.Hour))
+		assert.Nil(err)
+		crs, err := prj.ChangeRequests().List("")
+		assert.Nil(err)
+		scs, err := prj.ScheduledChanges().List("")
+		assert.Nil(err)
+		hooks, err := prj.Webhooks().List()
+		assert.Nil(err)
+		var deliveries []*domain.WebhookDelivery
+		if assert.Len(hooks, 1) {
+			deliveries, err = prj.WebhookDeliveries().List(hooks[0].ID, 10)
+			assert.Nil(err)
+		}
+		return []interface{}{p, params, changesets, counts, crs, scs, hooks, deliveries}
+	}
+	assert.Equal(get(src), get(dst))
+}
+
+func TestBackup(t *testing.T) {
+	assert := asserts.New(t)
+
+	dir, err := ioutil.TempDir("", "toggly_backup_test")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := openBolt(ctx, dir)
+	fill(assert, src, "ow1")
+	fill(assert, src, "ow2")
+
+	var archive bytes.Buffer
+	summary, err := (&backup.Archiver{Storage: src, Log: logger}).Backup(&archive)
+	assert.Nil(err)
+	assert.Equal([]string{"ow1", "ow2"}, summary.Owners)
+	assert.Equal(4, summary.Counts[backup.TypeParameter])
+
+	t.Run("verify", func(t *testing.T) {
+		res, err := backup.Verify(bytes.NewReader(archive.Bytes()))
+		assert.Nil(err)
+		assert.Equal(backup.FormatVersion, res.Version)
+		assert.Equal(summary.Owners, res.Owners)
+		assert.Equal(summary.Counts, res.Counts)
+	})
+
+	t.Run("restore into another storage type", func(t *testing.T) {
+		dst := openSQL(ctx, dir, "all.sqlite")
+		res, err := (&backup.Archiver{Storage: dst, Log: logger}).Restore(bytes.NewReader(archive.Bytes()))
+		assert.Nil(err)
+		assert.Equal(summary.Counts, res.Counts)
+		assertSame(assert, src, dst, "ow1")
+		assertSame(assert, src, dst, "ow2")
+
+		t.Run("not empty", func(t *testing.T) {
+			_, err := (&backup.Archiver{Storage: dst, Log: logger}).Restore(bytes.NewReader(archive.Bytes()))
+			assert.EqualError(err, "Owner ow1 already has data in the storage")
+		})
+	})
+
+	t.Run("owner filter", func(t *testing.T) {
+		dst := openSQL(ctx, dir, "ow2.sqlite")
+		res, err := (&backup.Archiver{Storage: dst, Owners: []string{"ow2"}, Log: logger}).Restore(bytes.NewReader(archive.Bytes()))
+		assert.Nil(err)
+		assert.Equal([]string{"ow2"}, res.Owners)
+		owners, err := dst.Owners()
+		assert.Nil(err)
+		assert.Equal([]string{"ow2"}, owners)
+
+		var part bytes.Buffer
+		res, err = (&backup.Archiver{Storage: src, Owners: []string{"ow1"}, Log: logger}).Backup(&part)
+		assert.Nil(err)
+		assert.Equal([]string{"ow1"}, res.Owners)
+		assert.Equal(1, res.Counts[backup.TypeProject])
+	})
+
+	// rewrite returns the archive with uncompressed content changed by fn
+	rewrite := func(fn func(data []byte) []byte) *bytes.Buffer {
+		zr, err := gzip.NewReader(bytes.NewReader(archive.Bytes()))
+		assert.Nil(err)
+		data, err := ioutil.ReadAll(zr)
+		assert.Nil(err)
+		var res bytes.Buffer
+		zw := gzip.NewWriter(&res)
+		zw.Write(fn(data))
+		zw.Close()
+		return &res
+	}
+
+	t.Run("modified", func(t *testing.T) {
+		broken := rewrite(func(data []byte) []byte {
+			return bytes.Replace(data, []byte("https://example.com"), []byte("https://example.org"), 1)
+		})
+		_, err := backup.Verify(broken)
+		assert.Equal(backup.ErrChecksum, err)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		broken := rewrite(func(data []byte) []byte { return data[:len(data)-10] })
+		_, err := backup.Verify(broken)
+		assert.Equal(backup.ErrChecksum, err)
+	})
+
+	t.Run("not an archive", func(t *testing.T) {
+		_, err := backup.Verify(bytes.NewReader([]byte("plain text")))
+		assert.Equal(backup.ErrFormat, err)
+	})
+}
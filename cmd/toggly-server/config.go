@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/rest"
+	"github.com/jessevdk/go-flags"
+	"github.com/rs/zerolog"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config file keys are options config tags, nested sections are separated by dots:
+//
+//	storage:
+//	  type: bolt
+//	  bolt:
+//	    path: /var/lib/toggly/toggly.db
+//
+// File values are used for options set neither by flags nor by environment.
+
+func newParser(opts *options, parserOptions flags.Options) *flags.Parser {
+	parser := flags.NewParser(opts, parserOptions)
+	parser.SubcommandsOptional = true
+	return parser
+}
+
+// parseOptions parses command line arguments, environment and config file
+func parseOptions(args []string) (*options, *flags.Parser, error) {
+	var opts options
+	parser := newParser(&opts, flags.Default)
+	if _, err := parser.ParseArgs(args); err != nil {
+		return nil, nil, err
+	}
+	if opts.Config == "" {
+		return &opts, parser, opts.validate()
+	}
+	values, err := readConfig(opts.Config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Can't read config %s: %s", opts.Config, err)
+	}
+	fileArgs, err := configArgs(parser, values)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Config %s: %s", opts.Config, err)
+	}
+	var fileOpts options
+	parser = newParser(&fileOpts, flags.PassDoubleDash)
+	if _, err := parser.ParseArgs(append(fileArgs, args...)); err != nil {
+		return nil, nil, fmt.Errorf("Config %s: %s", opts.Config, err)
+	}
+	return &fileOpts, parser, fileOpts.validate()
+}
+
+// readConfig returns config file values by their dotted keys
+func readConfig(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("Unknown config format %s, use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	for k, v := range doc {
+		if err := flatten(k, v, values); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func flatten(key string, value interface{}, values map[string]interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if err := flatten(key+"."+k, item, values); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		for k, item := range v {
+			name, ok := k.(string)
+			if !ok {
+				return fmt.Errorf("Key %v in %s is not a string", k, key)
+			}
+			if err := flatten(key+"."+name, item, values); err != nil {
+				return err
+			}
+		}
+	default:
+		values[key] = value
+	}
+	return nil
+}
+
+// configArgs turns config values into arguments for options not set by flags or environment
+func configArgs(parser *flags.Parser, values map[string]interface{}) ([]string, error) {
+	known := map[string]*flags.Option{}
+	var collect func(groups []*flags.Group)
+	collect = func(groups []*flags.Group) {
+		for _, group := range groups {
+			for _, opt := range group.Options() {
+				if key := opt.Field().Tag.Get("config"); key != "" {
+					known[key] = opt
+				}
+			}
+			collect(group.Groups())
+		}
+	}
+	collect(parser.Groups())
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	args := make([]string, 0, len(keys))
+	for _, key := range keys {
+		opt, ok := known[key]
+		if !ok {
+			return nil, fmt.Errorf("Unknown key %s", key)
+		}
+		items, err := configItems(opt, values[key])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s value: %s", key, err)
+		}
+		if opt.IsSet() && !opt.IsSetDefault() {
+			continue
+		}
+		if opt.EnvDefaultKey != "" {
+			if _, ok := os.LookupEnv(opt.EnvDefaultKey); ok {
+				continue
+			}
+		}
+		args = append(args, items...)
+	}
+	return args, nil
+}
+
+// configItems returns command line arguments for the option value
+func configItems(opt *flags.Option, value interface{}) ([]string, error) {
+	name := "--" + opt.LongName
+	kind := opt.Field().Type.Kind()
+	if kind == reflect.Bool {
+		set, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a boolean", value)
+		}
+		if set {
+			return []string{name}, nil
+		}
+		return nil, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		list = []interface{}{value}
+	} else if kind != reflect.Slice {
+		return nil, fmt.Errorf("list is not allowed")
+	}
+	args := make([]string, 0, len(list))
+	for _, item := range list {
+		switch v := item.(type) {
+		case string, bool, int, int64:
+			args = append(args, fmt.Sprintf("%s=%v", name, item))
+		case float64:
+			// Without exponent integral values are parsed by integer options
+			args = append(args, name+"="+strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			return nil, fmt.Errorf("%v is not a value", item)
+		}
+	}
+	return args, nil
+}
+
+// validate checks option values beyond their types and choices
+func (o *options) validate() error {
+	switch {
+	case o.Port <= 0 || o.Port > 65535:
+		return fmt.Errorf("Invalid port %d", o.Port)
+	case !strings.HasPrefix(o.BasePath, "/"):
+		return fmt.Errorf("Base path %s must start with /", o.BasePath)
+	case o.StoreType == "mongo" && (o.StoreMongoURL == "" || o.StoreMongoDB == ""):
+		return fmt.Errorf("Mongo connection url and database name are required")
+	case o.StoreType == "bolt" && o.StoreBoltPath == "":
+		return fmt.Errorf("Bolt database file path is required")
+	case o.StoreType == "sql" && o.StoreSQLDSN == "":
+		return fmt.Errorf("SQL data source name is required")
+	case o.CacheType == "redis" && o.CacheRedisURL == "":
+		return fmt.Errorf("Redis connection url is required for redis cache")
+	case o.Approvals < 0:
+		return fmt.Errorf("Approvals can't be negative")
+	case o.Approvals > 0 && (o.TLSClientCA == "" || o.TLSPrincipalField == "none"):
+		return fmt.Errorf("Approvals require principals mapped from verified client certificates")
+	case o.SchedulerInterval < 0 || o.WebhookInterval < 0 || o.AnalyticsInterval < 0:
+		return fmt.Errorf("Intervals can't be negative")
+	case o.WebhookInterval > 0 && (o.WebhookAttempts < 1 || o.WebhookTimeout <= 0 || o.WebhookBackoff < 0):
+		return fmt.Errorf("Webhook attempts and timeout must be positive")
+	case o.MaxRequests < 0:
+		return fmt.Errorf("Max requests can't be negative")
+	case o.ManagementRate < 0 || o.ManagementBurst < 0 || o.EvaluationRate < 0 || o.EvaluationBurst < 0:
+		return fmt.Errorf("Rates can't be negative")
+	case o.PrincipalShare < 1 || o.PrincipalShare > 100:
+		return fmt.Errorf("Principal share must be from 1 to 100 percent")
+	case o.CORSMaxAge < 0:
+		return fmt.Errorf("CORS max age can't be negative")
+	case (o.TLSCert == "") != (o.TLSKey == ""):
+		return fmt.Errorf("TLS certificate and key files are required together")
+	case o.TLSClientCA != "" && o.TLSCert == "":
+		return fmt.Errorf("TLS client CA requires TLS certificate and key files")
+	case o.TLSClientRequired && o.TLSClientCA == "":
+		return fmt.Errorf("TLS client CA is required to verify client certificates")
+	}
+	for _, origin := range o.CORSOrigins {
+		if origin == "*" && o.CORSCredentials {
+			return fmt.Errorf("CORS credentials can't be allowed for any origin")
+		}
+	}
+	return nil
+}
+
+// logLevel returns global log level for options
+func logLevel(opts *options) zerolog.Level {
+	if opts.Debug {
+		return zerolog.DebugLevel
+	}
+	level, err := zerolog.ParseLevel(opts.LogLevel)
+	if err != nil {
+		return zerolog.InfoLevel
+	}
+	return level
+}
+
+// serverSettings returns rest server settings which can be reloaded
+func serverSettings(opts *options) *rest.Settings {
+	return &rest.Settings{
+		MaxRequests:    opts.MaxRequests,
+		ManagementRate: domain.Rate{PerMinute: opts.ManagementRate, Burst: opts.ManagementBurst},
+		EvaluationRate: domain.Rate{PerMinute: opts.EvaluationRate, Burst: opts.EvaluationBurst},
+		PrincipalShare: opts.PrincipalShare,
+		CORS: rest.CORS{
+			Origins:     opts.CORSOrigins,
+			Methods:     opts.CORSMethods,
+			Headers:     opts.CORSHeaders,
+			Credentials: opts.CORSCredentials,
+			MaxAge:      opts.CORSMaxAge,
+		},
+	}
+}
+
+// reload parses options again and applies log level and server settings.
+// It returns options in use, other changes wait for restart.
+func reload(args []string, current *options, server *rest.Server, logger zerolog.Logger) *options {
+	opts, _, err := parseOptions(args)
+	if err != nil {
+		logger.Error().Err(err).Msg("Can't reload options")
+		return current
+	}
+	zerolog.SetGlobalLevel(logLevel(opts))
+	server.Reload(serverSettings(opts))
+
+	applied := *current
+	applied.LogLevel, applied.Debug, applied.MaxRequests = opts.LogLevel, opts.Debug, opts.MaxRequests
+	applied.ManagementRate, applied.ManagementBurst = opts.ManagementRate, opts.ManagementBurst
+	applied.EvaluationRate, applied.EvaluationBurst = opts.EvaluationRate, opts.EvaluationBurst
+	applied.PrincipalShare = opts.PrincipalShare
+	applied.CORSOrigins, applied.CORSMethods, applied.CORSHeaders = opts.CORSOrigins, opts.CORSMethods, opts.CORSHeaders
+	applied.CORSCredentials, applied.CORSMaxAge = opts.CORSCredentials, opts.CORSMaxAge
+	if !reflect.DeepEqual(&applied, opts) {
+		logger.Warn().Msg("Options other than log level, limits and CORS are applied on restart")
+	}
+	logger.Info().Str("log_level", logLevel(opts).String()).Msg("Options reloaded")
+	return &applied
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/rest"
+	"github.com/jessevdk/go-flags"
+	"github.com/rs/zerolog"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+// writeConfig writes the config file with the name into the directory and returns its path
+func writeConfig(t *testing.T, dir, name, data string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigParse(t *testing.T) {
+	assert := asserts.New(t)
+
+	dir, err := ioutil.TempDir("", "toggly_config_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("precedence", func(t *testing.T) {
+		path := writeConfig(t, dir, "precedence.yaml", `
+port: 9000
+log-level: warn
+storage:
+  type: bolt
+  bolt:
+    path: /tmp/toggly.db
+`)
+		os.Setenv("TOGGLY_SRV_LOG_LEVEL", "error")
+		defer os.Unsetenv("TOGGLY_SRV_LOG_LEVEL")
+		opts, _, err := parseOptions([]string{"--config", path, "--port", "9100"})
+		assert.Nil(err)
+		if assert.NotNil(opts) {
+			assert.Equal(9100, opts.Port)
+			assert.Equal("error", opts.LogLevel)
+			assert.Equal("bolt", opts.StoreType)
+			assert.Equal("/tmp/toggly.db", opts.StoreBoltPath)
+			assert.Equal("/api", opts.BasePath)
+		}
+	})
+
+	t.Run("toml", func(t *testing.T) {
+		path := writeConfig(t, dir, "config.toml", `
+debug = true
+
+[cors]
+origins = ["https://a.example.com", "https://b.example.com"]
+credentials = false
+
+[limits]
+max-requests = 1e6
+`)
+		opts, _, err := parseOptions([]string{"--config", path})
+		assert.Nil(err)
+		if assert.NotNil(opts) {
+			assert.True(opts.Debug)
+			assert.Equal([]string{"https://a.example.com", "https://b.example.com"}, opts.CORSOrigins)
+			assert.False(opts.CORSCredentials)
+			assert.Equal(1000000, opts.MaxRequests)
+		}
+	})
+
+	tt := []struct {
+		name   string
+		file   string
+		data   string
+		failed bool
+	}{
+		{name: "float", file: "float.yaml", data: "limits:\n  max-requests: 1e6\n"},
+		{name: "duration", file: "duration.yaml", data: "cors:\n  max-age: 5m\n"},
+		{name: "unknown key", file: "unknown.yaml", data: "storage:\n  kind: bolt\n", failed: true},
+		{name: "unknown format", file: "config.json", data: "{}", failed: true},
+		{name: "list not allowed", file: "list.yaml", data: "port: [8080, 8081]\n", failed: true},
+		{name: "not a boolean", file: "bool.yaml", data: "debug: yes please\n", failed: true},
+		{name: "not a value", file: "map.yaml", data: "cors:\n  origins:\n    - a: b\n", failed: true},
+		{name: "wrong choice", file: "choice.yaml", data: "storage:\n  type: redis\n", failed: true},
+		{name: "invalid", file: "invalid.yaml", data: "port: 0\n", failed: true},
+	}
+	for _, tc := range tt {
+		path := writeConfig(t, dir, tc.file, tc.data)
+		opts, _, err := parseOptions([]string{"--config", path})
+		if tc.failed {
+			assert.NotNil(err, tc.name)
+		} else {
+			assert.Nil(err, tc.name)
+			assert.NotNil(opts, tc.name)
+		}
+	}
+}
+
+func TestConfigItems(t *testing.T) {
+	assert := asserts.New(t)
+
+	var opts options
+	parser := newParser(&opts, flags.None)
+
+	tt := []struct {
+		option string
+		value  interface{}
+		items  []string
+		failed bool
+	}{
+		{option: "port", value: 9000, items: []string{"--port=9000"}},
+		{option: "port", value: int64(9000), items: []string{"--port=9000"}},
+		{option: "port", value: float64(1000000), items: []string{"--port=1000000"}},
+		{option: "port", value: "9000", items: []string{"--port=9000"}},
+		{option: "port", value: []interface{}{9000}, failed: true},
+		{option: "debug", value: true, items: []string{"--debug"}},
+		{option: "debug", value: false, items: nil},
+		{option: "debug", value: "true", failed: true},
+		{option: "cors-origin", value: "*", items: []string{"--cors-origin=*"}},
+		{option: "cors-origin", value: []interface{}{"a", "b"}, items: []string{"--cors-origin=a", "--cors-origin=b"}},
+		{option: "cors-origin", value: []interface{}{[]interface{}{"a"}}, failed: true},
+		{option: "cors-max-age", value: 0.5, items: []string{"--cors-max-age=0.5"}},
+	}
+	for _, tc := range tt {
+		items, err := configItems(parser.FindOptionByLongName(tc.option), tc.value)
+		if tc.failed {
+			assert.NotNil(err, "%s %v", tc.option, tc.value)
+			continue
+		}
+		assert.Nil(err, "%s %v", tc.option, tc.value)
+		assert.Equal(tc.items, items, "%s %v", tc.option, tc.value)
+	}
+}
+
+func TestConfigArgs(t *testing.T) {
+	assert := asserts.New(t)
+
+	var opts options
+	parser := newParser(&opts, flags.None)
+	_, err := parser.ParseArgs([]string{"--port", "9100"})
+	assert.Nil(err)
+
+	os.Setenv("TOGGLY_SRV_BASE_PATH", "/env")
+	defer os.Unsetenv("TOGGLY_SRV_BASE_PATH")
+	args, err := configArgs(parser, map[string]interface{}{
+		"port":         9000,
+		"base-path":    "/file",
+		"log-level":    "warn",
+		"storage.type": "bolt",
+	})
+	assert.Nil(err)
+	assert.Equal([]string{"--log-level=warn", "--store-type=bolt"}, args)
+
+	args, err = configArgs(parser, map[string]interface{}{"cache.type": "redis", "cache.redis-url": "redis://localhost:6379"})
+	assert.Nil(err)
+	assert.Equal([]string{"--cache-redis-url=redis://localhost:6379", "--cache-type=redis"}, args)
+	_, err = configArgs(parser, map[string]interface{}{"cache.kind": "memory"})
+	assert.NotNil(err)
+	_, err = configArgs(parser, map[string]interface{}{"port": []interface{}{9000, 9001}})
+	assert.NotNil(err)
+}
+
+func TestOptionsValidate(t *testing.T) {
+	assert := asserts.New(t)
+
+	valid := func() *options {
+		var opts options
+		if _, err := newParser(&opts, flags.None).ParseArgs(nil); err != nil {
+			t.Fatal(err)
+		}
+		return &opts
+	}
+	assert.Nil(valid().validate())
+
+	tt := []func(o *options){
+		func(o *options) { o.Port = 70000 },
+		func(o *options) { o.BasePath = "api" },
+		func(o *options) { o.StoreMongoDB = "" },
+		func(o *options) { o.StoreType, o.StoreBoltPath = "bolt", "" },
+		func(o *options) { o.StoreType, o.StoreSQLDSN = "sql", "" },
+		func(o *options) { o.CacheType = "redis" },
+		func(o *options) { o.Approvals = -1 },
+		func(o *options) { o.Approvals = 1 },
+		func(o *options) { o.SchedulerInterval = -time.Second },
+		func(o *options) { o.WebhookAttempts = 0 },
+		func(o *options) { o.MaxRequests = -1 },
+		func(o *options) { o.EvaluationBurst = -1 },
+		func(o *options) { o.PrincipalShare = 0 },
+		func(o *options) { o.CORSMaxAge = -time.Second },
+		func(o *options) { o.TLSCert = "cert.pem" },
+		func(o *options) { o.TLSClientCA = "ca.pem" },
+		func(o *options) { o.TLSClientRequired = true },
+		func(o *options) { o.CORSOrigins, o.CORSCredentials = []string{"*"}, true },
+	}
+	for i, change := range tt {
+		opts := valid()
+		change(opts)
+		assert.NotNil(opts.validate(), "case %d", i)
+	}
+
+	opts := valid()
+	opts.WebhookInterval, opts.WebhookAttempts = 0, 0
+	assert.Nil(opts.validate())
+
+	opts = valid()
+	opts.Approvals, opts.TLSCert, opts.TLSKey, opts.TLSClientCA = 2, "cert.pem", "key.pem", "ca.pem"
+	assert.Nil(opts.validate())
+}
+
+func TestConfigReload(t *testing.T) {
+	assert := asserts.New(t)
+
+	level := zerolog.GlobalLevel()
+	defer zerolog.SetGlobalLevel(level)
+
+	dir, err := ioutil.TempDir("", "toggly_config_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeConfig(t, dir, "config.yaml", "port: 9000\nlog-level: info\n")
+	args := []string{"--config", path}
+	current, _, err := parseOptions(args)
+	if !assert.Nil(err) {
+		return
+	}
+	server := &rest.Server{}
+
+	writeConfig(t, dir, "config.yaml", `
+port: 9100
+log-level: warn
+limits:
+  max-requests: 10
+  management:
+    per-minute: 60
+cors:
+  origins: ["*"]
+`)
+	applied := reload(args, current, server, zerolog.Nop())
+	assert.Equal(9000, applied.Port)
+	assert.Equal("warn", applied.LogLevel)
+	assert.Equal(zerolog.WarnLevel, zerolog.GlobalLevel())
+	assert.Equal(10, applied.MaxRequests)
+	assert.Equal(60, applied.ManagementRate)
+	assert.Equal([]string{"*"}, applied.CORSOrigins)
+
+	writeConfig(t, dir, "config.yaml", "port: 0\n")
+	assert.Equal(applied, reload(args, applied, server, zerolog.Nop()))
+}
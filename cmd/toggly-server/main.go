@@ -3,14 +3,27 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
+	"github.com/Toggly/core/analytics"
+	"github.com/Toggly/core/api"
 	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/backup"
+	"github.com/Toggly/core/domain"
 	"github.com/Toggly/core/rest"
+	"github.com/Toggly/core/scheduler"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/storage/bolt"
 	"github.com/Toggly/core/storage/mongo"
+	"github.com/Toggly/core/storage/sql"
+	"github.com/Toggly/core/webhook"
 	"github.com/jessevdk/go-flags"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -25,42 +38,292 @@ _______  _____   ______  ______       __   __      _______ _______  ______ _
 var version = "development"
 
 type options struct {
-	Version       bool   `short:"v" long:"version" description:"Show version"`
-	Port          int    `short:"p" long:"port" default:"8080" env:"TOGGLY_SRV_PORT" description:"Port"`
-	NoLogo        bool   `long:"no-logo" description:"Do not display logo"`
-	BasePath      string `long:"base-path" default:"/api" env:"TOGGLY_SRV_BASE_PATH" description:"Rest API base path"`
-	StoreMongoURL string `long:"store-mongo-url" default:"mongodb://localhost:27017" env:"TOGGLY_SRV_STORE_MONGO_URL" description:"Mongo connection url"`
-	StoreMongoDB  string `long:"store-mongo-db" default:"toggly" env:"TOGGLY_SRV_STORE_MONGO_DB" description:"Mongo database name"`
-	CacheType     string `long:"cache-type" env:"TOGGLY_SRV_CACHE_TYPE" choice:"memory" choice:"redis" default:"memory" description:"Cache type"`
-	CacheRedisURL string `long:"cache-redis-url" env:"TOGGLY_SRV_CACHE_REDIS_URL" description:"Redis connection url"`
-	Debug         bool   `long:"debug" env:"TOGGLY_SRV_DEBUG" description:"Debug mode"`
+	Version           bool           `short:"v" long:"version" description:"Show version"`
+	Config            string         `short:"c" long:"config" env:"TOGGLY_SRV_CONFIG" description:"Config file path, YAML or TOML by extension"`
+	Port              int            `short:"p" long:"port" default:"8080" env:"TOGGLY_SRV_PORT" description:"Port" config:"port"`
+	NoLogo            bool           `long:"no-logo" description:"Do not display logo" config:"no-logo"`
+	BasePath          string         `long:"base-path" default:"/api" env:"TOGGLY_SRV_BASE_PATH" description:"Rest API base path" config:"base-path"`
+	StoreType         string         `long:"store-type" env:"TOGGLY_SRV_STORE_TYPE" choice:"mongo" choice:"bolt" choice:"sql" default:"mongo" description:"Storage type" config:"storage.type"`
+	StoreMongoURL     string         `long:"store-mongo-url" default:"mongodb://localhost:27017" env:"TOGGLY_SRV_STORE_MONGO_URL" description:"Mongo connection url" config:"storage.mongo.url"`
+	StoreMongoDB      string         `long:"store-mongo-db" default:"toggly" env:"TOGGLY_SRV_STORE_MONGO_DB" description:"Mongo database name" config:"storage.mongo.db"`
+	StoreBoltPath     string         `long:"store-bolt-path" default:"toggly.db" env:"TOGGLY_SRV_STORE_BOLT_PATH" description:"Bolt database file path" config:"storage.bolt.path"`
+	StoreSQLDriver    string         `long:"store-sql-driver" env:"TOGGLY_SRV_STORE_SQL_DRIVER" choice:"sqlite3" choice:"postgres" default:"sqlite3" description:"SQL database driver" config:"storage.sql.driver"`
+	StoreSQLDSN       string         `long:"store-sql-dsn" default:"toggly.sqlite" env:"TOGGLY_SRV_STORE_SQL_DSN" description:"SQL data source name, file path for sqlite3" config:"storage.sql.dsn"`
+	CacheType         string         `long:"cache-type" env:"TOGGLY_SRV_CACHE_TYPE" choice:"memory" choice:"redis" default:"memory" description:"Cache type" config:"cache.type"`
+	CacheRedisURL     string         `long:"cache-redis-url" env:"TOGGLY_SRV_CACHE_REDIS_URL" description:"Redis connection url" config:"cache.redis-url"`
+	LogLevel          string         `long:"log-level" env:"TOGGLY_SRV_LOG_LEVEL" choice:"debug" choice:"info" choice:"warn" choice:"error" default:"info" description:"Log level, reloaded on SIGHUP" config:"log-level"`
+	Debug             bool           `long:"debug" env:"TOGGLY_SRV_DEBUG" description:"Debug mode" config:"debug"`
+	DefaultEnvs       []string       `long:"default-env" env:"TOGGLY_SRV_DEFAULT_ENVS" env-delim:"," default:"development" default:"staging" default:"production:protected" description:"Environment created with every new project, code[:protected]" config:"projects.default-envs"`
+	Approvals         int            `long:"approvals" env:"TOGGLY_SRV_APPROVALS" default:"0" description:"Approvals required to change protected environment, 0 disables approvals, approvers must be mapped from client certificates with --tls-principal-field" config:"projects.approvals"`
+	SchedulerInterval time.Duration  `long:"scheduler-interval" env:"TOGGLY_SRV_SCHEDULER_INTERVAL" default:"10s" description:"Scheduled changes check interval, 0 disables scheduler" config:"scheduler.interval"`
+	WebhookInterval   time.Duration  `long:"webhook-interval" env:"TOGGLY_SRV_WEBHOOK_INTERVAL" default:"5s" description:"Webhook deliveries check interval, 0 disables webhooks" config:"webhook.interval"`
+	WebhookAttempts   int            `long:"webhook-attempts" env:"TOGGLY_SRV_WEBHOOK_ATTEMPTS" default:"8" description:"Webhook delivery attempts before giving up" config:"webhook.attempts"`
+	WebhookBackoff    time.Duration  `long:"webhook-backoff" env:"TOGGLY_SRV_WEBHOOK_BACKOFF" default:"30s" description:"Delay before the first webhook delivery retry, doubled after every next one" config:"webhook.backoff"`
+	WebhookTimeout    time.Duration  `long:"webhook-timeout" env:"TOGGLY_SRV_WEBHOOK_TIMEOUT" default:"10s" description:"Webhook delivery request timeout" config:"webhook.timeout"`
+	WebhookPrivate    bool           `long:"webhook-private" env:"TOGGLY_SRV_WEBHOOK_PRIVATE" description:"Allow webhooks to loopback, private and link-local addresses" config:"webhook.private"`
+	AnalyticsInterval time.Duration  `long:"analytics-interval" env:"TOGGLY_SRV_ANALYTICS_INTERVAL" default:"1m" description:"Evaluation counters flush interval, 0 disables counting" config:"analytics.interval"`
+	Organizations     bool           `long:"organizations" env:"TOGGLY_SRV_ORGANIZATIONS" description:"Require owners to be registered organizations and principals to be their members" config:"auth.organizations"`
+	MaxRequests       int            `long:"max-requests" env:"TOGGLY_SRV_MAX_REQUESTS" default:"1000" description:"Requests served at the same time, 0 means no limit, reloaded on SIGHUP" config:"limits.max-requests"`
+	ManagementRate    int            `long:"rate-management" env:"TOGGLY_SRV_RATE_MANAGEMENT" default:"600" description:"Management requests per minute of every owner, 0 means no limit, reloaded on SIGHUP" config:"limits.management.per-minute"`
+	ManagementBurst   int            `long:"rate-management-burst" env:"TOGGLY_SRV_RATE_MANAGEMENT_BURST" default:"60" description:"Management requests burst, 0 means requests per minute, reloaded on SIGHUP" config:"limits.management.burst"`
+	EvaluationRate    int            `long:"rate-evaluation" env:"TOGGLY_SRV_RATE_EVALUATION" default:"6000" description:"Evaluation requests per minute of every owner, 0 means no limit, reloaded on SIGHUP" config:"limits.evaluation.per-minute"`
+	EvaluationBurst   int            `long:"rate-evaluation-burst" env:"TOGGLY_SRV_RATE_EVALUATION_BURST" default:"600" description:"Evaluation requests burst, 0 means requests per minute, reloaded on SIGHUP" config:"limits.evaluation.burst"`
+	PrincipalShare    int            `long:"rate-principal-share" env:"TOGGLY_SRV_RATE_PRINCIPAL_SHARE" default:"100" description:"Percent of the owner rates a single principal mapped from client certificate, or all header principals together, can use, reloaded on SIGHUP" config:"limits.principal-share"`
+	CORSOrigins       []string       `long:"cors-origin" env:"TOGGLY_SRV_CORS_ORIGINS" env-delim:"," description:"Origin allowed to make cross-origin requests, * allows any, reloaded on SIGHUP" config:"cors.origins"`
+	CORSMethods       []string       `long:"cors-method" env:"TOGGLY_SRV_CORS_METHODS" env-delim:"," default:"GET" default:"POST" default:"PUT" default:"DELETE" description:"Method allowed in cross-origin requests, reloaded on SIGHUP" config:"cors.methods"`
+	CORSHeaders       []string       `long:"cors-header" env:"TOGGLY_SRV_CORS_HEADERS" env-delim:"," default:"Content-Type" default:"If-Match" default:"X-Toggly-Owner-Id" default:"X-Toggly-Principal" default:"X-Toggly-Request-Id" description:"Header allowed in cross-origin requests, reloaded on SIGHUP" config:"cors.headers"`
+	CORSCredentials   bool           `long:"cors-credentials" env:"TOGGLY_SRV_CORS_CREDENTIALS" description:"Allow credentials in cross-origin requests, reloaded on SIGHUP" config:"cors.credentials"`
+	CORSMaxAge        time.Duration  `long:"cors-max-age" env:"TOGGLY_SRV_CORS_MAX_AGE" default:"10m" description:"Cross-origin preflight response cache time, reloaded on SIGHUP" config:"cors.max-age"`
+	TLSCert           string         `long:"tls-cert" env:"TOGGLY_SRV_TLS_CERT" description:"TLS certificate file, enables HTTPS" config:"tls.cert"`
+	TLSKey            string         `long:"tls-key" env:"TOGGLY_SRV_TLS_KEY" description:"TLS private key file" config:"tls.key"`
+	TLSClientCA       string         `long:"tls-client-ca" env:"TOGGLY_SRV_TLS_CLIENT_CA" description:"CA bundle file to verify client certificates with" config:"tls.client-ca"`
+	TLSClientRequired bool           `long:"tls-client-required" env:"TOGGLY_SRV_TLS_CLIENT_REQUIRED" description:"Reject clients without verified certificate" config:"tls.client-required"`
+	TLSOwnerField     string         `long:"tls-owner-field" env:"TOGGLY_SRV_TLS_OWNER_FIELD" choice:"none" choice:"CN" choice:"O" choice:"OU" default:"O" description:"Client certificate subject field used as owner instead of X-Toggly-Owner-Id header, the header is ignored without certificate" config:"tls.owner-field"`
+	TLSPrincipalField string         `long:"tls-principal-field" env:"TOGGLY_SRV_TLS_PRINCIPAL_FIELD" choice:"none" choice:"CN" choice:"O" choice:"OU" default:"CN" description:"Client certificate subject field used as principal instead of X-Toggly-Principal header, the header is ignored without certificate" config:"tls.principal-field"`
+	TLSCheckInterval  time.Duration  `long:"tls-check-interval" env:"TOGGLY_SRV_TLS_CHECK_INTERVAL" default:"30s" description:"TLS files change check interval, 0 disables reload" config:"tls.check-interval"`
+	ChangesName       string         `long:"changes-name" env:"TOGGLY_SRV_CHANGES_NAME" description:"Storage changes subscription name, unique for every server instance, host name if not set" config:"storage.changes-name"`
+	NoAutoMigrate     bool           `long:"no-auto-migrate" env:"TOGGLY_SRV_NO_AUTO_MIGRATE" description:"Do not apply pending storage migrations on server startup" config:"storage.no-auto-migrate"`
+	Migrate           migrateCommand `command:"migrate" description:"Apply pending storage migrations and exit"`
+	Backup            backupCommand  `command:"backup" description:"Write storage entities to a backup archive and exit"`
+	Restore           restoreCommand `command:"restore" description:"Restore storage entities from a backup archive and exit"`
+	RateLimit         limitCommand   `command:"rate-limit" description:"Set or delete owner rate limit, list rate limits and exit"`
 }
 
-func main() {
+type migrateCommand struct {
+	Status bool `long:"status" description:"List storage migrations without applying them"`
+}
+
+type backupCommand struct {
+	File   string   `short:"f" long:"file" required:"true" description:"Backup archive file path"`
+	Owners []string `long:"owner" description:"Owner to back up, all owners if not set"`
+}
+
+type restoreCommand struct {
+	File   string   `short:"f" long:"file" required:"true" description:"Backup archive file path"`
+	Owners []string `long:"owner" description:"Owner to restore, all archive owners if not set"`
+	Verify bool     `long:"verify" description:"Check the archive without restoring it"`
+}
+
+type limitCommand struct {
+	Owner           string `long:"owner" description:"Owner to set rate limit for, rate limits are only listed if not set"`
+	Management      int    `long:"management" description:"Management requests per minute, 0 keeps server default, negative removes the limit"`
+	ManagementBurst int    `long:"management-burst" description:"Management requests burst, 0 means requests per minute"`
+	Evaluation      int    `long:"evaluation" description:"Evaluation requests per minute, 0 keeps server default, negative removes the limit"`
+	EvaluationBurst int    `long:"evaluation-burst" description:"Evaluation requests burst, 0 means requests per minute"`
+	Delete          bool   `long:"delete" description:"Delete owner rate limit"`
+}
+
+// runRateLimit changes the owner rate limit if one is set and lists all rate limits
+func runRateLimit(limits api.RateLimitAPI, cmd *limitCommand) error {
+	var err error
+	switch {
+	case cmd.Owner != "" && cmd.Delete:
+		err = limits.Delete(cmd.Owner)
+	case cmd.Owner != "":
+		_, err = limits.Save(cmd.Owner,
+			domain.Rate{PerMinute: cmd.Management, Burst: cmd.ManagementBurst},
+			domain.Rate{PerMinute: cmd.Evaluation, Burst: cmd.EvaluationBurst})
+	}
+	if err != nil {
+		return err
+	}
+	list, err := limits.List()
+	if err != nil {
+		return err
+	}
+	format := func(r domain.Rate) string {
+		switch {
+		case r.PerMinute == 0:
+			return "default"
+		case r.PerMinute < 0:
+			return "unlimited"
+		}
+		return fmt.Sprintf("%d/min burst %d", r.PerMinute, r.Burst)
+	}
+	for _, l := range list {
+		fmt.Printf("%-30s  management %-24s  evaluation %s\n", l.Owner, format(l.Management), format(l.Evaluation))
+	}
+	return nil
+}
+
+// runBackup writes the archive file, which is removed if the backup fails
+func runBackup(dataStorage storage.DataStorage, cmd *backupCommand, logger zerolog.Logger) error {
+	f, err := os.Create(cmd.File)
+	if err != nil {
+		return err
+	}
+	archiver := &backup.Archiver{Storage: dataStorage, Owners: cmd.Owners, Log: logger}
+	summary, err := archiver.Backup(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(cmd.File)
+		return err
+	}
+	printSummary(summary)
+	return nil
+}
+
+// runRestore verifies the whole archive before restoring it
+func runRestore(dataStorage storage.DataStorage, cmd *restoreCommand, logger zerolog.Logger) error {
+	f, err := os.Open(cmd.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	summary, err := backup.Verify(f)
+	if err != nil {
+		return err
+	}
+	if !cmd.Verify {
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		archiver := &backup.Archiver{Storage: dataStorage, Owners: cmd.Owners, Log: logger}
+		if summary, err = archiver.Restore(f); err != nil {
+			return err
+		}
+	}
+	printSummary(summary)
+	return nil
+}
+
+func printSummary(summary *backup.Summary) {
+	fmt.Printf("Archive version %d created %s\n", summary.Version, summary.Created.Format(time.RFC3339))
+	fmt.Printf("Owners: %s\n", strings.Join(summary.Owners, ", "))
+	types := make([]string, 0, len(summary.Counts))
+	for typ := range summary.Counts {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		fmt.Printf("%8d  %s\n", summary.Counts[typ], typ)
+	}
+}
 
-	var opts options
+// migrate applies pending storage migrations or lists them
+func migrate(migrator storage.Migrator, worker string, status bool, logger zerolog.Logger) error {
+	if !status {
+		return migrator.Migrate(worker)
+	}
+	list, err := migrator.Migrations()
+	if err != nil {
+		return err
+	}
+	for _, m := range list {
+		applied := "pending"
+		if m.Applied != nil {
+			applied = m.Applied.Format(time.RFC3339)
+		}
+		fmt.Printf("%4d  %-25s  %s\n", m.Version, applied, m.Description)
+	}
+	return nil
+}
 
-	if _, err := flags.Parse(&opts); err != nil {
+// checkMigrated returns error if storage has pending migrations
+func checkMigrated(migrator storage.Migrator) error {
+	list, err := migrator.Migrations()
+	if err != nil {
+		return err
+	}
+	for _, m := range list {
+		if m.Applied == nil {
+			return fmt.Errorf("Storage has pending migration %d, apply it with migrate command", m.Version)
+		}
+	}
+	return nil
+}
+
+// tlsSettings returns rest server TLS settings, nil for plain HTTP
+func tlsSettings(opts *options) *rest.TLS {
+	if opts.TLSCert == "" {
+		return nil
+	}
+	field := func(name string) string {
+		if name == "none" {
+			return ""
+		}
+		return name
+	}
+	return &rest.TLS{
+		CertFile:           opts.TLSCert,
+		KeyFile:            opts.TLSKey,
+		ClientCAFile:       opts.TLSClientCA,
+		ClientCertRequired: opts.TLSClientRequired,
+		OwnerField:         field(opts.TLSOwnerField),
+		PrincipalField:     field(opts.TLSPrincipalField),
+		CheckInterval:      opts.TLSCheckInterval,
+	}
+}
+
+// newDataStorage returns storage of the configured type
+func newDataStorage(ctx context.Context, opts *options, logger zerolog.Logger) (storage.DataStorage, error) {
+	switch opts.StoreType {
+	case "bolt":
+		return bolt.NewBoltDataStorage(ctx, opts.StoreBoltPath, logger)
+	case "sql":
+		return sql.NewSQLDataStorage(ctx, opts.StoreSQLDriver, opts.StoreSQLDSN, logger)
+	}
+	return mongo.NewMongoDataStorage(ctx, opts.StoreMongoURL, opts.StoreMongoDB, logger)
+}
+
+// subscribe drops data cached by the server when other server instances change it
+func subscribe(dataStorage storage.DataStorage, name string, server *rest.Server, logger zerolog.Logger) {
+	err := dataStorage.Subscribe(name, func(c *storage.Change) {
+		if c.Entity == "rate_limit" {
+			server.Forget(c.Owner)
+		}
+	})
+	switch err {
+	case nil:
+	case storage.ErrChangesNotSupported:
+		logger.Info().Msg("Storage doesn't report changes made by other server instances")
+	default:
+		logger.Error().Err(err).Msg("Can't subscribe to storage changes")
+	}
+}
+
+func environmentTemplates(list []string) []*api.EnvironmentInfo {
+	envs := make([]*api.EnvironmentInfo, 0, len(list))
+	for _, item := range list {
+		code := strings.TrimSuffix(item, ":protected")
+		envs = append(envs, &api.EnvironmentInfo{
+			Code:      code,
+			Protected: code != item,
+		})
+	}
+	return envs
+}
+
+func main() {
+
+	opts, parser, err := parseOptions(os.Args[1:])
+	if err != nil {
+		if _, ok := err.(*flags.Error); !ok {
+			fmt.Fprintln(os.Stderr, err)
+		}
 		os.Exit(1)
 	}
+	command := ""
+	if parser.Active != nil {
+		command = parser.Active.Name
+	}
 
 	if opts.Version {
 		fmt.Printf("Version: %s\n", version)
 		os.Exit(0)
 	}
 
-	if !opts.NoLogo {
+	if !opts.NoLogo && command == "" {
 		fmt.Print(logo)
 		fmt.Printf("\n  ver. %s\n\n", version)
 	}
 
-	logLevel := zerolog.InfoLevel
-
 	if opts.Debug {
-		logLevel = zerolog.DebugLevel
 		log.Logger = log.With().Caller().Logger()
 	}
-	zerolog.SetGlobalLevel(logLevel)
+	zerolog.SetGlobalLevel(logLevel(opts))
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -77,9 +340,11 @@ func main() {
 		cancel()
 	}()
 
-	dataStorage, err := mongo.NewMongoDataStorage(ctx, opts.StoreMongoURL, opts.StoreMongoDB, logger)
+	// Storage is closed after the server stops, so evaluation counts are flushed on shutdown
+	storageCtx, closeStorage := context.WithCancel(context.Background())
+	dataStorage, err := newDataStorage(storageCtx, opts, logger)
 	if err != nil {
-		logger.Fatal().Err(err).Msg("Can't create mongo client")
+		logger.Fatal().Err(err).Msg("Can't create storage")
 	}
 
 	err = dataStorage.Connect()
@@ -87,14 +352,128 @@ func main() {
 		logger.Fatal().Err(err).Msg("Can't open storage connection")
 	}
 
+	hostname, _ := os.Hostname()
+	worker := fmt.Sprintf("%s-%d", hostname, os.Getpid())
+
+	// Other commands work with the storage as it is, only serving migrates it on startup
+	if migrator, ok := dataStorage.(storage.Migrator); ok {
+		switch {
+		case command == "migrate" || command == "" && !opts.NoAutoMigrate:
+			err = migrate(migrator, worker, opts.Migrate.Status, logger)
+		case command != "":
+			err = checkMigrated(migrator)
+		}
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Can't migrate storage")
+		}
+	}
+	switch command {
+	case "backup":
+		err = runBackup(dataStorage, &opts.Backup, logger)
+	case "restore":
+		err = runRestore(dataStorage, &opts.Restore, logger)
+	case "rate-limit":
+		if err = runRateLimit(engine.NewTogglyAPI(dataStorage, nil, logger).RateLimits(), &opts.RateLimit); err != nil {
+			logger.Fatal().Err(err).Msg("Can't set rate limit")
+		}
+	}
+	if err != nil {
+		logger.Fatal().Err(err).Msgf("Can't %s storage", command)
+	}
+	if command != "" {
+		cancel()
+		closeStorage()
+		return
+	}
+
+	engineConfig := &engine.Config{
+		DefaultEnvironments: environmentTemplates(opts.DefaultEnvs),
+		ApprovalsRequired:   opts.Approvals,
+		Organizations:       opts.Organizations,
+		PrivateWebhooks:     opts.WebhookPrivate,
+	}
+
+	if opts.WebhookInterval > 0 {
+		dispatcher := &webhook.Dispatcher{
+			Storage:     dataStorage,
+			Client:      webhook.NewClient(opts.WebhookTimeout, opts.WebhookPrivate),
+			Worker:      worker,
+			Interval:    opts.WebhookInterval,
+			Lease:       opts.WebhookTimeout + time.Minute,
+			MaxAttempts: opts.WebhookAttempts,
+			Backoff:     opts.WebhookBackoff,
+			Log:         logger,
+		}
+		engineConfig.Events = dispatcher
+		go dispatcher.Run(ctx)
+	}
+
+	var counted chan struct{}
+	if opts.AnalyticsInterval > 0 {
+		counter := &analytics.Counter{
+			Storage:  dataStorage,
+			Interval: opts.AnalyticsInterval,
+			Log:      logger,
+		}
+		engineConfig.Evaluations = counter
+		counted = make(chan struct{})
+		go func() {
+			counter.Run(ctx)
+			close(counted)
+		}()
+	}
+
+	togglyAPI := engine.NewTogglyAPI(dataStorage, engineConfig, logger)
+
+	if opts.SchedulerInterval > 0 {
+		sched := &scheduler.Scheduler{
+			Storage:  dataStorage,
+			API:      togglyAPI,
+			Worker:   worker,
+			Interval: opts.SchedulerInterval,
+			Lease:    time.Minute,
+			Log:      logger,
+		}
+		go sched.Run(ctx)
+	}
+
 	server := &rest.Server{
 		Version:  version,
-		API:      engine.NewTogglyAPI(dataStorage, logger),
+		API:      togglyAPI,
 		Log:      logger,
-		LogLevel: logLevel,
+		LogLevel: logLevel(opts),
+		Settings: serverSettings(opts),
+		TLS:      tlsSettings(opts),
 	}
 
+	changesName := opts.ChangesName
+	if changesName == "" {
+		changesName = hostname
+	}
+	go subscribe(dataStorage, changesName, server, logger)
+
+	go func(current *options) {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			logger.Info().Msg("Reload signal")
+			current = reload(os.Args[1:], current, server, logger)
+		}
+	}(opts)
+
 	logger.Info().Msg("API server started")
-	server.Run(ctx, opts.Port, opts.BasePath)
+	if err = server.Run(ctx, opts.Port, opts.BasePath); err != nil {
+		logger.Fatal().Err(err).Msg("Can't run API server")
+	}
+	if counted != nil {
+		shutdown, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+		select {
+		case <-counted:
+		case <-shutdown.Done():
+			logger.Warn().Msg("Evaluation counters aren't stored in time")
+		}
+		cancelShutdown()
+	}
+	closeStorage()
 	logger.Warn().Msg("Application terminated")
 }
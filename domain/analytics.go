@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// EvaluationCount is the number of parameter evaluations in the environment during the hour
+// starting at Hour that served the variant. Empty Variant counts evaluations served without one.
+// Last is the time of the latest of these evaluations.
+type EvaluationCount struct {
+	Owner       string    `json:"owner"`
+	Project     string    `json:"project"`
+	Environment string    `json:"environment"`
+	Parameter   string    `json:"parameter"`
+	Variant     string    `json:"variant"`
+	Hour        time.Time `json:"hour"`
+	Count       int64     `json:"count"`
+	Last        time.Time `json:"last"`
+}
@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// ChangeRequestStatus enum
+const (
+	ChangeRequestStatusPending  = "pending"
+	ChangeRequestStatusApplied  = "applied"
+	ChangeRequestStatusRejected = "rejected"
+	ChangeRequestStatusFailed   = "failed"
+)
+
+// ChangeAction enum
+const (
+	ChangeActionCreate = "create"
+	ChangeActionUpdate = "update"
+	ChangeActionDelete = "delete"
+	// ChangeActionChangeset applies the changeset
+	ChangeActionChangeset = "changeset"
+	// ChangeActionEnvironmentUpdate updates the protected environment itself
+	ChangeActionEnvironmentUpdate = "environment_update"
+	// ChangeActionEnvironmentDelete deletes the protected environment with its parameters
+	ChangeActionEnvironmentDelete = "environment_delete"
+)
+
+// ChangeRequest type. Parameter holds the new parameter state for create and update actions,
+// BaseVersion is the parameter or environment version the change was requested against.
+// Changeset holds the changeset to apply for the changeset action.
+// EnvironmentState holds the new environment state for the environment update action.
+type ChangeRequest struct {
+	ID               string           `json:"id"`
+	Owner            string           `json:"owner"`
+	Project          string           `json:"project"`
+	Environment      string           `json:"environment"`
+	Action           string           `json:"action"`
+	ParameterCode    string           `json:"parameter_code" bson:"parameter_code"`
+	Parameter        *Parameter       `json:"parameter,omitempty" bson:"parameter,omitempty"`
+	BaseVersion      int              `json:"base_version" bson:"base_version"`
+	Changeset        *Changeset       `json:"changeset,omitempty" bson:"changeset,omitempty"`
+	EnvironmentState *Environment     `json:"environment_state,omitempty" bson:"environment_state,omitempty"`
+	Requester        string           `json:"requester"`
+	Status           string           `json:"status"`
+	Error            string           `json:"error,omitempty" bson:"error,omitempty"`
+	Approvals        []*ChangeComment `json:"approvals"`
+	Comments         []*ChangeComment `json:"comments"`
+	RegDate          time.Time        `json:"reg_date" bson:"reg_date"`
+	Version          int              `json:"version"`
+}
+
+// ChangeComment type
+type ChangeComment struct {
+	Principal string    `json:"principal"`
+	Text      string    `json:"text,omitempty" bson:"text,omitempty"`
+	Date      time.Time `json:"date"`
+}
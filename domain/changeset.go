@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// Changeset type. Changes of the environment parameters are applied all together or not at all.
+type Changeset struct {
+	ID          string           `json:"id"`
+	Owner       string           `json:"owner"`
+	Project     string           `json:"project"`
+	Environment string           `json:"environment"`
+	Description string           `json:"description"`
+	Principal   string           `json:"principal"`
+	Changes     []*ChangesetItem `json:"changes"`
+	RegDate     time.Time        `json:"reg_date" bson:"reg_date"`
+}
+
+// ChangesetItem is a change of a single parameter. Parameter holds the new parameter state
+// for create and update actions, BaseVersion is the parameter version the change was made against.
+type ChangesetItem struct {
+	Action        string     `json:"action"`
+	ParameterCode string     `json:"parameter_code" bson:"parameter_code"`
+	Parameter     *Parameter `json:"parameter,omitempty" bson:"parameter,omitempty"`
+	BaseVersion   int        `json:"base_version" bson:"base_version"`
+}
@@ -2,10 +2,14 @@ package domain
 
 import "time"
 
-// Environment type
+// Environment type. Disabled environment serves parameters off values.
 type Environment struct {
-	Code      string    `json:"code"`
-	Project   string    `json:"project"`
-	Protected bool      `json:"protected"`
-	RegDate   time.Time `json:"reg_date" bson:"reg_date"`
+	Code        string    `json:"code"`
+	Owner       string    `json:"owner"`
+	Project     string    `json:"project"`
+	Description string    `json:"description"`
+	Protected   bool      `json:"protected"`
+	Disabled    bool      `json:"disabled"`
+	RegDate     time.Time `json:"reg_date" bson:"reg_date"`
+	Version     int       `json:"version"`
 }
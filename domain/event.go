@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// Event types
+const (
+	EventProjectCreated     = "project.created"
+	EventProjectUpdated     = "project.updated"
+	EventProjectDeleted     = "project.deleted"
+	EventEnvironmentCreated = "environment.created"
+	EventEnvironmentUpdated = "environment.updated"
+	EventEnvironmentDeleted = "environment.deleted"
+	EventParameterCreated   = "parameter.created"
+	EventParameterUpdated   = "parameter.updated"
+	EventParameterDeleted   = "parameter.deleted"
+	EventChangesetApplied   = "changeset.applied"
+)
+
+// EventTypes lists all event types
+var EventTypes = []string{
+	EventProjectCreated, EventProjectUpdated, EventProjectDeleted,
+	EventEnvironmentCreated, EventEnvironmentUpdated, EventEnvironmentDeleted,
+	EventParameterCreated, EventParameterUpdated, EventParameterDeleted,
+	EventChangesetApplied,
+}
+
+// Event describes a configuration change. Data holds the entity state after the change.
+type Event struct {
+	ID          string      `json:"id"`
+	Type        string      `json:"type"`
+	Owner       string      `json:"owner"`
+	Project     string      `json:"project"`
+	Environment string      `json:"environment,omitempty"`
+	Parameter   string      `json:"parameter,omitempty"`
+	Principal   string      `json:"principal,omitempty"`
+	Date        time.Time   `json:"date"`
+	Data        interface{} `json:"data,omitempty"`
+}
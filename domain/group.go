@@ -7,4 +7,5 @@ type Group struct {
 	Description string `json:"description"`
 	Project     string `json:"project"`
 	Environment string `json:"environment"`
+	Version     int    `json:"version"`
 }
@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// MemberRole enum. Owners and admins access all organization projects,
+// members access projects granted to their teams.
+const (
+	MemberRoleOwner  = "owner"
+	MemberRoleAdmin  = "admin"
+	MemberRoleMember = "member"
+)
+
+// Organization type. ID is the owner of organization projects and other entities.
+type Organization struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Members     []*Member `json:"members"`
+	Teams       []*Team   `json:"teams"`
+	RegDate     time.Time `json:"reg_date" bson:"reg_date"`
+	Version     int       `json:"version"`
+}
+
+// Member type. Principal is the user identity passed with requests.
+type Member struct {
+	Principal string `json:"principal"`
+	Role      string `json:"role"`
+}
+
+// Team type. Team members access the listed projects.
+type Team struct {
+	Code        string   `json:"code"`
+	Description string   `json:"description"`
+	Members     []string `json:"members"`
+	Projects    []string `json:"projects"`
+}
+
+// Member returns organization member by principal or nil
+func (o *Organization) Member(principal string) *Member {
+	for _, m := range o.Members {
+		if m.Principal == principal {
+			return m
+		}
+	}
+	return nil
+}
+
+// Team returns organization team by code or nil
+func (o *Organization) Team(code string) *Team {
+	for _, t := range o.Teams {
+		if t.Code == code {
+			return t
+		}
+	}
+	return nil
+}
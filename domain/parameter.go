@@ -1,20 +1,93 @@
 package domain
 
+import "time"
+
 // Parameter types enum
 const (
-	ParameterTypeBool   = "bool"
-	ParameterTypeString = "string"
-	ParameterTypeInt    = "int"
+	ParameterTypeBool       = "bool"
+	ParameterTypeString     = "string"
+	ParameterTypeInt        = "int"
+	ParameterTypeFloat      = "float"
+	ParameterTypeJSON       = "json"
+	ParameterTypeStringList = "stringlist"
+	ParameterTypeSemver     = "semver"
+	ParameterTypeDuration   = "duration"
 )
 
-// Parameter type
+// ParameterTypes lists all parameter types
+var ParameterTypes = []string{
+	ParameterTypeBool, ParameterTypeString, ParameterTypeInt, ParameterTypeFloat,
+	ParameterTypeJSON, ParameterTypeStringList, ParameterTypeSemver, ParameterTypeDuration,
+}
+
+// Parameter type. OffValue is served instead of Value when the project or environment
+// is disabled; nil means the zero value of the parameter type. When Variants are defined
+// every evaluation context gets one of them instead of Value. Parameter with Prerequisites
+// serves OffValue unless every prerequisite parameter of the same environment serves
+// the prerequisite value. Modified is the time of the
+// last parameter change and Expires is the declared date the parameter is no longer needed after.
+//
+// Values are kept in canonical form: bool, string, int64, float64, JSONValue, []string,
+// semantic version string like 1.2.3-rc.1 and duration string like 1h30m0s.
 type Parameter struct {
-	Code string `json:"code"`
-	// Project       string        `json:"project"`
-	// Environment   string        `json:"environment"`
+	Code        string `json:"code"`
+	Owner       string `json:"owner"`
+	Project     string `json:"project"`
+	Environment string `json:"environment"`
 	// Group         string        `json:"group"`
-	Description   string        `json:"description"`
-	Type          string        `json:"type"`
-	Value         interface{}   `json:"value"`
-	AllowedValues []interface{} `json:"allowed_values,omitempty" bson:"allowed_values,omitempty"`
+	Description   string          `json:"description"`
+	Type          string          `json:"type"`
+	Value         interface{}     `json:"value"`
+	OffValue      interface{}     `json:"off_value,omitempty" bson:"off_value,omitempty"`
+	AllowedValues []interface{}   `json:"allowed_values,omitempty" bson:"allowed_values,omitempty"`
+	Constraints   *Constraints    `json:"constraints,omitempty" bson:"constraints,omitempty"`
+	Variants      []*Variant      `json:"variants,omitempty" bson:"variants,omitempty"`
+	Prerequisites []*Prerequisite `json:"prerequisites,omitempty" bson:"prerequisites,omitempty"`
+	Expires       *time.Time      `json:"expires,omitempty" bson:"expires"`
+	Modified      time.Time       `json:"modified"`
+	Version       int             `json:"version"`
+}
+
+// Variant is a named parameter value served to the share of evaluation contexts
+// proportional to its weight among all parameter variants
+type Variant struct {
+	Name   string      `json:"name"`
+	Value  interface{} `json:"value"`
+	Weight int         `json:"weight"`
+}
+
+// Prerequisite requires Parameter to serve Value to the evaluation context
+type Prerequisite struct {
+	Parameter string      `json:"parameter"`
+	Value     interface{} `json:"value"`
+}
+
+// Constraints restrict parameter values. Min and Max apply to int and float values,
+// MinLength, MaxLength and Pattern apply to string values and to every item of string lists,
+// Schema is a JSON Schema json values must conform to. Pattern must match the whole value.
+type Constraints struct {
+	Min       *float64  `json:"min,omitempty" bson:"min,omitempty"`
+	Max       *float64  `json:"max,omitempty" bson:"max,omitempty"`
+	MinLength *int      `json:"min_length,omitempty" bson:"min_length,omitempty"`
+	MaxLength *int      `json:"max_length,omitempty" bson:"max_length,omitempty"`
+	Pattern   string    `json:"pattern,omitempty" bson:"pattern,omitempty"`
+	Schema    JSONValue `json:"schema,omitempty" bson:"schema,omitempty"`
+}
+
+// JSONValue is a compact JSON encoding with sorted object keys. It is written to JSON as is
+// and stored in BSON as a string, since object keys may be invalid Mongo field names.
+type JSONValue string
+
+// MarshalJSON implements json.Marshaler
+func (v JSONValue) MarshalJSON() ([]byte, error) {
+	if v == "" {
+		return []byte("null"), nil
+	}
+	return []byte(v), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The value is kept as is, without canonicalization.
+func (v *JSONValue) UnmarshalJSON(data []byte) error {
+	*v = JSONValue(data)
+	return nil
 }
@@ -8,11 +8,12 @@ const (
 	ProjectStatusDisabled = "disabled"
 )
 
-// Project type
+// Project type. Disabled project serves parameters off values in all environments.
 type Project struct {
 	Code        string    `json:"code"`
 	Owner       string    `json:"owner"`
 	Status      string    `json:"status"`
 	Description string    `json:"description"`
 	RegDate     time.Time `json:"reg_date" bson:"reg_date"`
+	Version     int       `json:"version"`
 }
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// RouteClass enum. Rates are limited separately for management and evaluation requests.
+const (
+	RouteClassManagement = "management"
+	RouteClassEvaluation = "evaluation"
+)
+
+// Rate type. Requests are allowed at PerMinute pace in bursts of up to Burst requests,
+// zero Burst allows PerMinute requests at once. Zero PerMinute keeps the server default rate,
+// negative one removes the limit.
+type Rate struct {
+	PerMinute int `json:"per_minute" bson:"per_minute"`
+	Burst     int `json:"burst"`
+}
+
+// RateLimit type. It overrides server default rates for the owner.
+type RateLimit struct {
+	Owner      string    `json:"owner"`
+	Management Rate      `json:"management"`
+	Evaluation Rate      `json:"evaluation"`
+	Modified   time.Time `json:"modified"`
+	Version    int       `json:"version"`
+}
+
+// Rate returns the owner rate of the route class
+func (l *RateLimit) Rate(class string) Rate {
+	if class == RouteClassEvaluation {
+		return l.Evaluation
+	}
+	return l.Management
+}
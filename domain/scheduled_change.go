@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// ScheduledChangeStatus enum
+const (
+	ScheduledChangeStatusPending   = "pending"
+	ScheduledChangeStatusRunning   = "running"
+	ScheduledChangeStatusDone      = "done"
+	ScheduledChangeStatusFailed    = "failed"
+	ScheduledChangeStatusCancelled = "cancelled"
+)
+
+// ScheduledChange type. At is the execution time in UTC, TimeZone is the zone the change was scheduled in.
+// RevertAfter schedules a change restoring the previous value once this one is executed.
+// LockedBy and LockedUntil hold the lease of the scheduler instance executing the change.
+// ExecutedAt, Previous and BaseVersion are stored before the parameter is written: Previous is the
+// value the revert restores, BaseVersion is the parameter version the write is conditional on.
+type ScheduledChange struct {
+	ID            string      `json:"id"`
+	Owner         string      `json:"owner"`
+	Project       string      `json:"project"`
+	Environment   string      `json:"environment"`
+	Parameter     string      `json:"parameter"`
+	Value         interface{} `json:"value"`
+	At            time.Time   `json:"at"`
+	TimeZone      string      `json:"time_zone" bson:"time_zone"`
+	RevertAfter   string      `json:"revert_after,omitempty" bson:"revert_after,omitempty"`
+	RevertOf      string      `json:"revert_of,omitempty" bson:"revert_of,omitempty"`
+	Requester     string      `json:"requester"`
+	Status        string      `json:"status"`
+	LockedBy      string      `json:"locked_by,omitempty" bson:"locked_by,omitempty"`
+	LockedUntil   time.Time   `json:"-" bson:"locked_until"`
+	ExecutedAt    time.Time   `json:"executed_at,omitempty" bson:"executed_at"`
+	Previous      interface{} `json:"previous,omitempty" bson:"previous"`
+	BaseVersion   int         `json:"base_version,omitempty" bson:"base_version,omitempty"`
+	ChangeRequest string      `json:"change_request,omitempty" bson:"change_request,omitempty"`
+	Error         string      `json:"error,omitempty" bson:"error,omitempty"`
+	RegDate       time.Time   `json:"reg_date" bson:"reg_date"`
+	Version       int         `json:"version"`
+}
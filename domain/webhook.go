@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+// WebhookDeliveryStatus enum
+const (
+	WebhookDeliveryStatusPending = "pending"
+	WebhookDeliveryStatusRunning = "running"
+	WebhookDeliveryStatusSuccess = "success"
+	WebhookDeliveryStatusFailed  = "failed"
+)
+
+// Webhook type. Empty Events list subscribes to all events.
+type Webhook struct {
+	ID      string    `json:"id"`
+	Owner   string    `json:"owner"`
+	Project string    `json:"project"`
+	URL     string    `json:"url"`
+	Secret  string    `json:"secret,omitempty"`
+	Events  []string  `json:"events"`
+	Active  bool      `json:"active"`
+	RegDate time.Time `json:"reg_date" bson:"reg_date"`
+	Version int       `json:"version"`
+}
+
+// WebhookDelivery type. URL and Secret are copied from the webhook when the event happens,
+// so deliveries survive webhook changes. Deliveries are removed together with the webhook.
+type WebhookDelivery struct {
+	ID          string             `json:"id"`
+	Owner       string             `json:"owner"`
+	Project     string             `json:"project"`
+	Webhook     string             `json:"webhook"`
+	Event       string             `json:"event"`
+	URL         string             `json:"url"`
+	Secret      string             `json:"-"`
+	Payload     string             `json:"payload"`
+	Status      string             `json:"status"`
+	Attempts    []*DeliveryAttempt `json:"attempts"`
+	NextAttempt time.Time          `json:"next_attempt" bson:"next_attempt"`
+	LockedBy    string             `json:"-" bson:"locked_by,omitempty"`
+	LockedUntil time.Time          `json:"-" bson:"locked_until"`
+	RegDate     time.Time          `json:"reg_date" bson:"reg_date"`
+	Version     int                `json:"version"`
+}
+
+// DeliveryAttempt type
+type DeliveryAttempt struct {
+	Date     time.Time `json:"date"`
+	Code     int       `json:"code"`
+	Error    string    `json:"error,omitempty" bson:"error,omitempty"`
+	Duration string    `json:"duration"`
+}
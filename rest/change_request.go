@@ -0,0 +1,108 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog"
+)
+
+type changeRequestCommentRequest struct {
+	Text string
+}
+
+type changeRequestRestAPI struct {
+	API      api.TogglyAPI
+	Log      zerolog.Logger
+	LogLevel zerolog.Level
+}
+
+func (a *changeRequestRestAPI) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(ProjectAccess(a.API, a.Log))
+	router.Group(func(group chi.Router) {
+		group.Get("/", a.list)
+		group.Get("/{change_id}", a.getChangeRequest)
+		group.Post("/{change_id}/approve", a.approve)
+		group.Post("/{change_id}/reject", a.reject)
+		group.Post("/{change_id}/comment", a.comment)
+	})
+	return router
+}
+
+func (a *changeRequestRestAPI) engine(r *http.Request) api.ChangeRequestAPI {
+	return ownerAPI(a.API, r).Projects().For(projectCode(r)).ChangeRequests()
+}
+
+func (a *changeRequestRestAPI) list(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	list, err := a.engine(r).List(r.URL.Query().Get("status"))
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get change requests list")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, list)
+}
+
+func (a *changeRequestRestAPI) getChangeRequest(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	cr, err := a.engine(r).Get(changeRequestID(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get change request")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, cr.Version)
+	JSONResponse(w, r, cr)
+}
+
+func (a *changeRequestRestAPI) approve(w http.ResponseWriter, r *http.Request) {
+	a.review(w, r, a.engine(r).Approve)
+}
+
+func (a *changeRequestRestAPI) reject(w http.ResponseWriter, r *http.Request) {
+	a.review(w, r, a.engine(r).Reject)
+}
+
+func (a *changeRequestRestAPI) comment(w http.ResponseWriter, r *http.Request) {
+	a.review(w, r, a.engine(r).Comment)
+}
+
+// review runs the action on behalf of the request principal. Reviews need the principal verified by
+// the client certificate, as a header principal could approve its own change under another name.
+func (a *changeRequestRestAPI) review(w http.ResponseWriter, r *http.Request, action func(id, text string) (*domain.ChangeRequest, error)) {
+	log := WithRequest(a.Log, r)
+	if !PrincipalVerified(r) {
+		log.Warn().Str("principal", principal(r)).Msg("Change request review by unverified principal")
+		APIErrorResponse(w, r, api.ErrPrincipalNotVerified)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't read request body")
+		ErrorResponse(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	req := &changeRequestCommentRequest{}
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, req); err != nil {
+			log.Error().Err(err).Msg("Can't parse request body")
+			ErrorResponse(w, r, errors.New("Bad request"), http.StatusBadRequest)
+			return
+		}
+	}
+	cr, err := action(changeRequestID(r), req.Text)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't review change request")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, cr.Version)
+	JSONResponse(w, r, cr)
+}
@@ -0,0 +1,98 @@
+package rest
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Toggly/core/api"
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog"
+)
+
+type changesetCreateRequest struct {
+	Description string
+	Changes     []*changeRequest
+}
+
+// changeRequest is a single parameter change. Version is the expected parameter version.
+type changeRequest struct {
+	Action    string
+	Parameter *parameterCreateRequest
+	Version   int
+}
+
+type changesetRestAPI struct {
+	API      api.TogglyAPI
+	Log      zerolog.Logger
+	LogLevel zerolog.Level
+}
+
+func (a *changesetRestAPI) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(ProjectAccess(a.API, a.Log))
+	router.Group(func(group chi.Router) {
+		group.Get("/", a.list)
+		group.Post("/", a.apply)
+		group.Get("/{changeset_id}", a.getChangeset)
+	})
+	return router
+}
+
+func (a *changesetRestAPI) engine(r *http.Request) api.ChangesetAPI {
+	return ownerAPI(a.API, r).Projects().For(projectCode(r)).Environments().For(environmentCode(r)).Changesets()
+}
+
+func (a *changesetRestAPI) list(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	list, err := a.engine(r).List()
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get changesets list")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, list)
+}
+
+func (a *changesetRestAPI) getChangeset(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	cs, err := a.engine(r).Get(changesetID(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get changeset")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, cs)
+}
+
+func (a *changesetRestAPI) apply(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't read request body")
+		ErrorResponse(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	req := &changesetCreateRequest{}
+	if err = decodeValues(body, req); err != nil {
+		log.Error().Err(err).Msg("Can't parse request body")
+		ErrorResponse(w, r, errors.New("Bad request"), http.StatusBadRequest)
+		return
+	}
+	info := &api.ChangesetInfo{Description: req.Description}
+	for _, c := range req.Changes {
+		change := &api.ChangeInfo{Action: c.Action}
+		if c.Parameter != nil {
+			change.Parameter = c.Parameter.info()
+			change.Parameter.Version = c.Version
+		}
+		info.Changes = append(info.Changes, change)
+	}
+	cs, err := a.engine(r).Apply(info)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't apply changeset")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, cs)
+}
@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/rs/zerolog"
+)
+
+type environmentCreateRequest struct {
+	Code        string
+	Description string
+	Protected   bool
+	Disabled    bool
+}
+
+type environmentRestAPI struct {
+	API      api.TogglyAPI
+	Log      zerolog.Logger
+	LogLevel zerolog.Level
+}
+
+func (a *environmentRestAPI) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(ProjectAccess(a.API, a.Log))
+	router.Group(func(group chi.Router) {
+		group.Get("/", a.list)
+		group.Post("/", a.createEnvironment)
+		group.Put("/", a.updateEnvironment)
+		group.Get("/{env_code}", a.getEnvironment)
+		group.Delete("/{env_code}", a.deleteEnvironment)
+	})
+	return router
+}
+
+func (a *environmentRestAPI) engine(r *http.Request) api.EnvironmentAPI {
+	return ownerAPI(a.API, r).Projects().For(projectCode(r)).Environments()
+}
+
+func (a *environmentRestAPI) list(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	list, err := a.engine(r).List()
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get environments list")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, list)
+}
+
+func (a *environmentRestAPI) getEnvironment(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	env, err := a.engine(r).Get(environmentCode(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get environment")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, env.Version)
+	JSONResponse(w, r, env)
+}
+
+func (a *environmentRestAPI) deleteEnvironment(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		APIErrorResponse(w, r, err)
+		return
+	}
+	if err = a.engine(r).Delete(environmentCode(r), version); err != nil {
+		log.Error().Err(err).Msg("Can't delete environment")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func (a *environmentRestAPI) createEnvironment(w http.ResponseWriter, r *http.Request) {
+	a.createUpdate(w, r, true)
+}
+
+func (a *environmentRestAPI) updateEnvironment(w http.ResponseWriter, r *http.Request) {
+	a.createUpdate(w, r, false)
+}
+
+func (a *environmentRestAPI) createUpdate(w http.ResponseWriter, r *http.Request, create bool) {
+	log := WithRequest(a.Log, r)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't read request body")
+		ErrorResponse(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	req := &environmentCreateRequest{}
+	err = json.Unmarshal(body, req)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't parse request body")
+		ErrorResponse(w, r, errors.New("Bad request"), http.StatusBadRequest)
+		return
+	}
+	info := &api.EnvironmentInfo{
+		Code:        req.Code,
+		Description: req.Description,
+		Protected:   req.Protected,
+		Disabled:    req.Disabled,
+	}
+	var env *domain.Environment
+	if create {
+		env, err = a.engine(r).Create(info)
+	} else {
+		if info.Version, err = ifMatchVersion(r); err == nil {
+			env, err = a.engine(r).Update(info)
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Can't save/update environment")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, env.Version)
+	JSONResponse(w, r, env)
+}
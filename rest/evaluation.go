@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog"
+)
+
+type evaluationRestAPI struct {
+	API      api.TogglyAPI
+	Log      zerolog.Logger
+	LogLevel zerolog.Level
+}
+
+func (a *evaluationRestAPI) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(ProjectAccess(a.API, a.Log))
+	router.Group(func(group chi.Router) {
+		group.Get("/", a.evaluate)
+		group.Get("/{param_code}/series", a.series)
+	})
+	return router
+}
+
+func (a *evaluationRestAPI) engine(r *http.Request) api.ForEnvironmentAPI {
+	return ownerAPI(a.API, r).Projects().For(projectCode(r)).Environments().For(environmentCode(r))
+}
+
+func (a *evaluationRestAPI) evaluate(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	query := r.URL.Query()
+	list, err := a.engine(r).Evaluate(query.Get("key"), query["param"]...)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't evaluate parameters")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, list)
+}
+
+func (a *evaluationRestAPI) series(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	query := r.URL.Query()
+	from, err := queryTime(query.Get("from"))
+	if err != nil {
+		APIErrorResponse(w, r, err)
+		return
+	}
+	to, err := queryTime(query.Get("to"))
+	if err != nil {
+		APIErrorResponse(w, r, err)
+		return
+	}
+	series, err := a.engine(r).EvaluationSeries(parameterCode(r), from, to)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get evaluation series")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, series)
+}
+
+// queryTime parses RFC 3339 time query parameter. Empty value means zero time.
+func queryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, &api.ErrBadRequest{Description: fmt.Sprintf("Wrong time `%s`, RFC 3339 expected", value)}
+	}
+	return t, nil
+}
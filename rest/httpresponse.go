@@ -2,8 +2,10 @@ package rest
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/Toggly/core/api"
 	"github.com/go-chi/render"
 )
 
@@ -28,3 +30,44 @@ func UnauthorizedResponse(w http.ResponseWriter, r *http.Request) {
 	render.Status(r, http.StatusUnauthorized)
 	render.PlainText(w, r, "")
 }
+
+// ETagHeader sets entity version as a response ETag
+func ETagHeader(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", fmt.Sprintf("\"%d\"", version))
+}
+
+// APIErrorResponse responds with the http code matching api error
+func APIErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch e := err.(type) {
+	case *api.ErrBadRequest:
+		if len(e.Fields) > 0 {
+			render.Status(r, http.StatusBadRequest)
+			JSONResponse(w, r, map[string]interface{}{"error": err.Error(), "fields": e.Fields})
+			return
+		}
+		ErrorResponse(w, r, err, http.StatusBadRequest)
+		return
+	case *api.ErrApprovalRequired:
+		render.Status(r, http.StatusAccepted)
+		JSONResponse(w, r, e.ChangeRequest)
+		return
+	case *api.ErrChangeRequestFailed:
+		ErrorResponse(w, r, err, http.StatusConflict)
+		return
+	}
+	switch err {
+	case api.ErrProjectNotFound, api.ErrEnvironmentNotFound, api.ErrParameterNotFound, api.ErrChangeRequestNotFound,
+		api.ErrScheduledChangeNotFound, api.ErrWebhookNotFound, api.ErrChangesetNotFound, api.ErrOrganizationNotFound,
+		api.ErrTeamNotFound:
+		NotFoundResponse(w, r, err.Error())
+	case api.ErrVersionConflict:
+		ErrorResponse(w, r, err, http.StatusPreconditionFailed)
+	case api.ErrProjectNotEmpty, api.ErrProjectProtected, api.ErrParameterInUse, api.ErrChangeRequestClosed, api.ErrScheduledChangeClosed,
+		api.ErrOrganizationNotEmpty, api.ErrProjectExists, api.ErrEnvironmentExists, api.ErrParameterExists:
+		ErrorResponse(w, r, err, http.StatusConflict)
+	case api.ErrApprovalNotAllowed, api.ErrPrincipalRequired, api.ErrPrincipalNotVerified, api.ErrAccessDenied:
+		ErrorResponse(w, r, err, http.StatusForbidden)
+	default:
+		ErrorResponse(w, r, err, http.StatusInternalServerError)
+	}
+}
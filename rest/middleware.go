@@ -20,12 +20,15 @@ const (
 	CtxValueOwner
 	CtxValueRequestID
 	CtxValueAuth
+	CtxValuePrincipal
+	CtxValuePrincipalVerified
 )
 
 // Headers
 const (
 	XTogglyRequestID string = "X-Toggly-Request-Id"
 	XTogglyOwnerID   string = "X-Toggly-Owner-Id"
+	XTogglyPrincipal string = "X-Toggly-Principal"
 	XServiceName     string = "X-Service-Name"
 	XServiceVersion  string = "X-Service-Version"
 )
@@ -36,6 +39,19 @@ func OwnerFromContext(r *http.Request) string {
 	return owner.(string)
 }
 
+// PrincipalFromContext returns context value for acting principal
+func PrincipalFromContext(r *http.Request) string {
+	principal, _ := r.Context().Value(CtxValuePrincipal).(string)
+	return principal
+}
+
+// PrincipalVerified reports whether the principal was mapped from a verified client certificate.
+// Otherwise the principal comes from the header as the client put it.
+func PrincipalVerified(r *http.Request) bool {
+	verified, _ := r.Context().Value(CtxValuePrincipalVerified).(bool)
+	return verified
+}
+
 // VersionCtx adds api version to context
 func VersionCtx(version string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -162,6 +178,18 @@ func OwnerCtx(log zerolog.Logger) func(http.Handler) http.Handler {
 	}
 }
 
+// PrincipalCtx adds acting principal to context
+func PrincipalCtx(log zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			principal := r.Header.Get(http.CanonicalHeaderKey(XTogglyPrincipal))
+			ctx := context.WithValue(r.Context(), CtxValuePrincipal, principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
 // ServiceInfo adds service information to the response header
 func ServiceInfo(name string, version string) func(http.Handler) http.Handler {
 	f := func(h http.Handler) http.Handler {
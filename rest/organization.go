@@ -0,0 +1,214 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/rs/zerolog"
+)
+
+type organizationCreateRequest struct {
+	Name        string
+	Description string
+}
+
+type memberRequest struct {
+	Role string
+}
+
+type teamRequest struct {
+	Description string
+	Members     []string
+	Projects    []string
+}
+
+type organizationRestAPI struct {
+	API      api.TogglyAPI
+	Log      zerolog.Logger
+	LogLevel zerolog.Level
+}
+
+func (a *organizationRestAPI) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Group(func(group chi.Router) {
+		group.Get("/", a.list)
+		group.Post("/", a.createOrganization)
+		group.Get("/{org_id}", a.getOrganization)
+		group.Put("/{org_id}", a.updateOrganization)
+		group.Delete("/{org_id}", a.deleteOrganization)
+		group.Put("/{org_id}/member/{principal}", a.setMember)
+		group.Delete("/{org_id}/member/{principal}", a.removeMember)
+		group.Put("/{org_id}/team/{team_code}", a.saveTeam)
+		group.Delete("/{org_id}/team/{team_code}", a.deleteTeam)
+	})
+	return router
+}
+
+func (a *organizationRestAPI) engine(r *http.Request) api.OrganizationAPI {
+	return a.API.Organizations().As(principal(r))
+}
+
+// readJSON parses request body into v and responds with error if it fails
+func (a *organizationRestAPI) readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	log := WithRequest(a.Log, r)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't read request body")
+		ErrorResponse(w, r, err, http.StatusInternalServerError)
+		return false
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		log.Error().Err(err).Msg("Can't parse request body")
+		ErrorResponse(w, r, errors.New("Bad request"), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// respond writes the organization or the api error
+func (a *organizationRestAPI) respond(w http.ResponseWriter, r *http.Request, org *domain.Organization, err error, msg string) {
+	if err != nil {
+		log := WithRequest(a.Log, r)
+		log.Error().Err(err).Msg(msg)
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, org.Version)
+	JSONResponse(w, r, org)
+}
+
+func (a *organizationRestAPI) list(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	list, err := a.engine(r).List()
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get organizations list")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, list)
+}
+
+func (a *organizationRestAPI) getOrganization(w http.ResponseWriter, r *http.Request) {
+	org, err := a.engine(r).Get(organizationID(r))
+	a.respond(w, r, org, err, "Can't get organization")
+}
+
+func (a *organizationRestAPI) createOrganization(w http.ResponseWriter, r *http.Request) {
+	req := &organizationCreateRequest{}
+	if !a.readJSON(w, r, req) {
+		return
+	}
+	org, err := a.engine(r).Create(&api.OrganizationInfo{
+		Name:        req.Name,
+		Description: req.Description,
+	})
+	a.respond(w, r, org, err, "Can't create organization")
+}
+
+func (a *organizationRestAPI) updateOrganization(w http.ResponseWriter, r *http.Request) {
+	req := &organizationCreateRequest{}
+	if !a.readJSON(w, r, req) {
+		return
+	}
+	info := &api.OrganizationInfo{
+		ID:          organizationID(r),
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	var org *domain.Organization
+	var err error
+	if info.Version, err = ifMatchVersion(r); err == nil {
+		org, err = a.engine(r).Update(info)
+	}
+	a.respond(w, r, org, err, "Can't update organization")
+}
+
+func (a *organizationRestAPI) deleteOrganization(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		APIErrorResponse(w, r, err)
+		return
+	}
+	if err = a.engine(r).Delete(organizationID(r), version); err != nil {
+		log.Error().Err(err).Msg("Can't delete organization")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func (a *organizationRestAPI) setMember(w http.ResponseWriter, r *http.Request) {
+	req := &memberRequest{}
+	if !a.readJSON(w, r, req) {
+		return
+	}
+	org, err := a.engine(r).SetMember(organizationID(r), memberPrincipal(r), req.Role)
+	a.respond(w, r, org, err, "Can't set organization member")
+}
+
+func (a *organizationRestAPI) removeMember(w http.ResponseWriter, r *http.Request) {
+	org, err := a.engine(r).RemoveMember(organizationID(r), memberPrincipal(r))
+	a.respond(w, r, org, err, "Can't remove organization member")
+}
+
+func (a *organizationRestAPI) saveTeam(w http.ResponseWriter, r *http.Request) {
+	req := &teamRequest{}
+	if !a.readJSON(w, r, req) {
+		return
+	}
+	org, err := a.engine(r).SaveTeam(organizationID(r), &api.TeamInfo{
+		Code:        teamCode(r),
+		Description: req.Description,
+		Members:     req.Members,
+		Projects:    req.Projects,
+	})
+	a.respond(w, r, org, err, "Can't save organization team")
+}
+
+func (a *organizationRestAPI) deleteTeam(w http.ResponseWriter, r *http.Request) {
+	org, err := a.engine(r).DeleteTeam(organizationID(r), teamCode(r))
+	a.respond(w, r, org, err, "Can't delete organization team")
+}
+
+// authorize checks the request principal access to the project of the request owner.
+// See api.OrganizationAPI for project values meaning.
+func authorize(a api.TogglyAPI, r *http.Request, project string) error {
+	return a.Organizations().As(principal(r)).Authorize(owner(r), project)
+}
+
+// OwnerAccess rejects requests of principals which are not members of the owner organization
+func OwnerAccess(a api.TogglyAPI, log zerolog.Logger) func(http.Handler) http.Handler {
+	return access(a, log, func(r *http.Request) string { return "" })
+}
+
+// ManagerAccess rejects requests of principals having no access to all owner projects
+func ManagerAccess(a api.TogglyAPI, log zerolog.Logger) func(http.Handler) http.Handler {
+	return access(a, log, func(r *http.Request) string { return api.AllProjects })
+}
+
+// ProjectAccess rejects requests of principals having no access to the request project
+func ProjectAccess(a api.TogglyAPI, log zerolog.Logger) func(http.Handler) http.Handler {
+	return access(a, log, projectCode)
+}
+
+func access(a api.TogglyAPI, log zerolog.Logger, project func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if err := authorize(a, r, project(r)); err != nil {
+				log := WithRequest(log, r)
+				log.Warn().Err(err).Str("principal", principal(r)).Msg("Access denied")
+				APIErrorResponse(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
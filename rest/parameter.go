@@ -0,0 +1,144 @@
+package rest
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/rs/zerolog"
+)
+
+type parameterCreateRequest struct {
+	Code          string
+	Description   string
+	Type          string
+	Value         interface{}
+	OffValue      interface{}         `json:"off_value"`
+	AllowedValues []interface{}       `json:"allowed_values"`
+	Constraints   *domain.Constraints `json:"constraints"`
+	Variants      []*domain.Variant
+	Prerequisites []*domain.Prerequisite
+	Expires       *time.Time `json:"expires"`
+}
+
+func (req *parameterCreateRequest) info() *api.ParameterInfo {
+	return &api.ParameterInfo{
+		Code:          req.Code,
+		Description:   req.Description,
+		Type:          req.Type,
+		Value:         req.Value,
+		OffValue:      req.OffValue,
+		AllowedValues: req.AllowedValues,
+		Constraints:   req.Constraints,
+		Variants:      req.Variants,
+		Prerequisites: req.Prerequisites,
+		Expires:       req.Expires,
+	}
+}
+
+type parameterRestAPI struct {
+	API      api.TogglyAPI
+	Log      zerolog.Logger
+	LogLevel zerolog.Level
+}
+
+func (a *parameterRestAPI) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(ProjectAccess(a.API, a.Log))
+	router.Group(func(group chi.Router) {
+		group.Get("/", a.list)
+		group.Post("/", a.createParameter)
+		group.Put("/", a.updateParameter)
+		group.Get("/{param_code}", a.getParameter)
+		group.Delete("/{param_code}", a.deleteParameter)
+	})
+	return router
+}
+
+func (a *parameterRestAPI) engine(r *http.Request) api.ParameterAPI {
+	return ownerAPI(a.API, r).Projects().For(projectCode(r)).Environments().For(environmentCode(r)).Parameters()
+}
+
+func (a *parameterRestAPI) list(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	list, err := a.engine(r).List()
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get parameters list")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, list)
+}
+
+func (a *parameterRestAPI) getParameter(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	param, err := a.engine(r).Get(parameterCode(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get parameter")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, param.Version)
+	JSONResponse(w, r, param)
+}
+
+func (a *parameterRestAPI) deleteParameter(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		APIErrorResponse(w, r, err)
+		return
+	}
+	if err = a.engine(r).Delete(parameterCode(r), version); err != nil {
+		log.Error().Err(err).Msg("Can't delete parameter")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func (a *parameterRestAPI) createParameter(w http.ResponseWriter, r *http.Request) {
+	a.createUpdate(w, r, true)
+}
+
+func (a *parameterRestAPI) updateParameter(w http.ResponseWriter, r *http.Request) {
+	a.createUpdate(w, r, false)
+}
+
+func (a *parameterRestAPI) createUpdate(w http.ResponseWriter, r *http.Request, create bool) {
+	log := WithRequest(a.Log, r)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't read request body")
+		ErrorResponse(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	req := &parameterCreateRequest{}
+	err = decodeValues(body, req)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't parse request body")
+		ErrorResponse(w, r, errors.New("Bad request"), http.StatusBadRequest)
+		return
+	}
+	info := req.info()
+	var param *domain.Parameter
+	if create {
+		param, err = a.engine(r).Create(info)
+	} else {
+		if info.Version, err = ifMatchVersion(r); err == nil {
+			param, err = a.engine(r).Update(info)
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Can't save/update parameter")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, param.Version)
+	JSONResponse(w, r, param)
+}
@@ -5,19 +5,31 @@ import (
 	"errors"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 
 	"github.com/Toggly/core/api"
 	"github.com/Toggly/core/domain"
 	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
 	"github.com/rs/zerolog"
 )
 
 type projectCreateRequest struct {
-	Code        string
-	Description string
-	Status      string
+	Code         string
+	Description  string
+	Status       string
+	Environments []*environmentCreateRequest
 }
 
+type promoteRequest struct {
+	Source     string
+	Target     string
+	Parameters []string
+}
+
+// defaultStaleDays is the stale parameters report period used when none is requested
+const defaultStaleDays = 30
+
 type projectRestAPI struct {
 	API      api.TogglyAPI
 	Log      zerolog.Logger
@@ -28,16 +40,26 @@ func (a *projectRestAPI) Routes() chi.Router {
 	router := chi.NewRouter()
 	router.Group(func(group chi.Router) {
 		group.Get("/", a.list)
-		group.Post("/", a.createProject)
 		group.Put("/", a.updateProject)
+	})
+	router.Group(func(group chi.Router) {
+		group.Use(ManagerAccess(a.API, a.Log))
+		group.Post("/", a.createProject)
+		group.Delete("/{project_code}", a.deleteProject)
+	})
+	router.Group(func(group chi.Router) {
+		group.Use(ProjectAccess(a.API, a.Log))
 		group.Get("/{project_code}", a.getProject)
-		// group.Delete("/{project_code}", a.deleteProject)
+		group.Get("/{project_code}/diff", a.diff)
+		group.Post("/{project_code}/promote", a.promote)
+		group.Get("/{project_code}/stale", a.stale)
+		group.Get("/{project_code}/graph", a.graph)
 	})
 	return router
 }
 
 func (a *projectRestAPI) engine(r *http.Request) api.ProjectAPI {
-	return a.API.ForOwner(owner(r)).Projects()
+	return ownerAPI(a.API, r).Projects()
 }
 
 func (a *projectRestAPI) list(w http.ResponseWriter, r *http.Request) {
@@ -48,6 +70,15 @@ func (a *projectRestAPI) list(w http.ResponseWriter, r *http.Request) {
 		ErrorResponse(w, r, err, http.StatusInternalServerError)
 		return
 	}
+	if authorize(a.API, r, api.AllProjects) != nil {
+		granted := make([]*domain.Project, 0, len(list))
+		for _, p := range list {
+			if authorize(a.API, r, p.Code) == nil {
+				granted = append(granted, p)
+			}
+		}
+		list = granted
+	}
 	JSONResponse(w, r, list)
 }
 
@@ -56,8 +87,27 @@ func (a *projectRestAPI) getProject(w http.ResponseWriter, r *http.Request) {
 	proj, err := a.engine(r).Get(projectCode(r))
 	if err != nil {
 		log.Error().Err(err).Msg("Can't get project")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, proj.Version)
+	JSONResponse(w, r, proj)
+}
+
+func (a *projectRestAPI) deleteProject(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		APIErrorResponse(w, r, err)
+		return
 	}
-	_ = proj
+	cascade := r.URL.Query().Get("cascade") == "true"
+	if err = a.engine(r).Delete(projectCode(r), version, cascade); err != nil {
+		log.Error().Err(err).Msg("Can't delete project")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	render.NoContent(w, r)
 }
 
 func (a *projectRestAPI) createProject(w http.ResponseWriter, r *http.Request) {
@@ -88,15 +138,99 @@ func (a *projectRestAPI) createUpdate(w http.ResponseWriter, r *http.Request, cr
 		Description: proj.Description,
 		Status:      proj.Status,
 	}
+	if proj.Environments != nil {
+		info.Environments = make([]*api.EnvironmentInfo, 0, len(proj.Environments))
+		for _, env := range proj.Environments {
+			if env == nil {
+				ErrorResponse(w, r, errors.New("Bad request"), http.StatusBadRequest)
+				return
+			}
+			info.Environments = append(info.Environments, &api.EnvironmentInfo{
+				Code:        env.Code,
+				Description: env.Description,
+				Protected:   env.Protected,
+				Disabled:    env.Disabled,
+			})
+		}
+	}
 	var p *domain.Project
 	if create {
 		p, err = a.engine(r).Create(info)
-	} else {
-		p, err = a.engine(r).Update(info)
+	} else if err = authorize(a.API, r, info.Code); err == nil {
+		if info.Version, err = ifMatchVersion(r); err == nil {
+			p, err = a.engine(r).Update(info)
+		}
 	}
 	if err != nil {
 		log.Error().Err(err).Msg("Can't save/update project")
+		APIErrorResponse(w, r, err)
 		return
 	}
+	ETagHeader(w, p.Version)
 	JSONResponse(w, r, p)
 }
+
+func (a *projectRestAPI) diff(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	q := r.URL.Query()
+	diff, err := a.engine(r).For(projectCode(r)).Diff(q.Get("source"), q.Get("target"))
+	if err != nil {
+		log.Error().Err(err).Msg("Can't compare environments")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, diff)
+}
+
+func (a *projectRestAPI) promote(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't read request body")
+		ErrorResponse(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	req := &promoteRequest{}
+	if err = json.Unmarshal(body, req); err != nil {
+		log.Error().Err(err).Msg("Can't parse request body")
+		ErrorResponse(w, r, errors.New("Bad request"), http.StatusBadRequest)
+		return
+	}
+	diff, err := a.engine(r).For(projectCode(r)).Promote(req.Source, req.Target, req.Parameters)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't promote environment")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, diff)
+}
+
+func (a *projectRestAPI) stale(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	days := defaultStaleDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		if days, err = strconv.Atoi(value); err != nil {
+			APIErrorResponse(w, r, &api.ErrBadRequest{Description: "Number of days must be an integer"})
+			return
+		}
+	}
+	list, err := a.engine(r).For(projectCode(r)).StaleParameters(days)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get stale parameters")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, list)
+}
+
+func (a *projectRestAPI) graph(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	graph, err := a.engine(r).For(projectCode(r)).DependencyGraph()
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get dependency graph")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, graph)
+}
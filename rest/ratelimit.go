@@ -0,0 +1,258 @@
+package rest
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/rs/zerolog"
+)
+
+// ErrRateLimited error
+var ErrRateLimited = errors.New("Rate limit exceeded")
+
+// Rate limit response headers
+const (
+	RateLimitLimit     string = "RateLimit-Limit"
+	RateLimitRemaining string = "RateLimit-Remaining"
+	RateLimitReset     string = "RateLimit-Reset"
+)
+
+const (
+	// overridesTTL is the time owner rate limits are cached for
+	overridesTTL = time.Minute
+	// sweepInterval is the interval between removals of idle full buckets
+	sweepInterval = time.Minute
+)
+
+// bucketKey identifies the owner bucket or the principal sub-limit bucket of the owner.
+// Unverified principals are keyed by empty principal and share their bucket.
+type bucketKey struct {
+	owner     string
+	principal string
+	sub       bool
+	class     string
+}
+
+// bucket of tokens refilled at rate per second up to burst
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+// refill adds tokens accumulated since the last refill
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// take refills the bucket and takes a token if there is one
+func (b *bucket) take(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// full reports whether the bucket would be full by now
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
+
+// RateLimiter keeps token buckets of owners by route class. The owner, taken from the verified
+// client certificate when it's mapped and from the header otherwise, is the key limits are kept
+// by, so the owner rate is shared by all owner principals. Settings PrincipalShare limits the part
+// of it a single principal can use. As header principals can be made up per request, only
+// principals verified by client certificates get buckets of their own, the other ones share
+// a single bucket of the owner. Requests without owner are limited the same way by principal.
+// Owner rate limits stored with the API override default rates, they are read all at once,
+// so unknown owners cost no reads. The limiter runs before owner access checks, so rejected
+// requests count too and can't make the checks read the storage beyond the limit.
+type RateLimiter struct {
+	API      api.TogglyAPI
+	Settings func() *Settings
+	Log      zerolog.Logger
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	overrides map[string]*domain.RateLimit
+	expires   time.Time
+	loading   bool
+	swept     time.Time
+}
+
+// ownerLimit returns cached owner rate limit or nil if there is none.
+// Requests coming while rate limits are read use the previous ones.
+func (l *RateLimiter) ownerLimit(owner string, now time.Time) *domain.RateLimit {
+	l.mu.Lock()
+	if l.loading || now.Before(l.expires) {
+		limit := l.overrides[owner]
+		l.mu.Unlock()
+		return limit
+	}
+	l.loading = true
+	l.mu.Unlock()
+
+	overrides := map[string]*domain.RateLimit{}
+	list, err := l.API.RateLimits().List()
+	if err == nil {
+		for _, limit := range list {
+			overrides[limit.Owner] = limit
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loading = false
+	l.expires = now.Add(overridesTTL)
+	if err != nil {
+		l.Log.Error().Err(err).Msg("Can't read rate limits")
+	} else {
+		l.overrides = overrides
+	}
+	return l.overrides[owner]
+}
+
+// Forget makes the limiter read rate limits again, as the owner rate limit was changed
+// by another server instance. All rate limits are read at once, so owner isn't used.
+func (l *RateLimiter) Forget(owner string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expires = time.Time{}
+}
+
+// rate returns the rate applied to the owner requests of the route class
+func (l *RateLimiter) rate(owner, class string, now time.Time) domain.Rate {
+	rate := l.Settings().Rate(class)
+	if owner == "" {
+		return rate
+	}
+	if limit := l.ownerLimit(owner, now); limit != nil && limit.Rate(class).PerMinute != 0 {
+		rate = limit.Rate(class)
+	}
+	return rate
+}
+
+// share returns the part of the rate in percents, at least one request
+func share(rate domain.Rate, percent int) domain.Rate {
+	scale := func(n int) int {
+		if n = n * percent / 100; n < 1 {
+			return 1
+		}
+		return n
+	}
+	burst := rate.Burst
+	if burst == 0 {
+		burst = rate.PerMinute
+	}
+	return domain.Rate{PerMinute: scale(rate.PerMinute), Burst: scale(burst)}
+}
+
+// sweep removes buckets which are full again, they are the same as new ones
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < sweepInterval {
+		return
+	}
+	l.swept = now
+	for k, b := range l.buckets {
+		if b.full(now) {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// bucket returns refilled bucket of the key set to the rate
+func (l *RateLimiter) bucket(k bucketKey, rate domain.Rate, now time.Time) *bucket {
+	burst := rate.Burst
+	if burst == 0 {
+		burst = rate.PerMinute
+	}
+	if l.buckets == nil {
+		l.buckets = map[bucketKey]*bucket{}
+	}
+	b, ok := l.buckets[k]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[k] = b
+	}
+	b.rate, b.burst = float64(rate.PerMinute)/60, float64(burst)
+	b.refill(now)
+	return b
+}
+
+// allow takes a token from the owner bucket and the principal one if the principal rate
+// is a part of the owner rate. Rate limit headers are set by the bucket with less tokens.
+// Empty principal stands for all unverified principals.
+func (l *RateLimiter) allow(w http.ResponseWriter, owner, principal, class string, rate domain.Rate, now time.Time) bool {
+	percent := l.Settings().PrincipalShare
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	var buckets []*bucket
+	if owner == "" {
+		buckets = append(buckets, l.bucket(bucketKey{principal: principal, class: class}, rate, now))
+	} else {
+		buckets = append(buckets, l.bucket(bucketKey{owner: owner, class: class}, rate, now))
+		if percent > 0 && percent < 100 {
+			buckets = append(buckets, l.bucket(bucketKey{owner: owner, principal: principal, sub: true, class: class}, share(rate, percent), now))
+		}
+	}
+	b := buckets[0]
+	for _, item := range buckets[1:] {
+		if item.tokens < b.tokens {
+			b = item
+		}
+	}
+	allowed := b.tokens >= 1
+	if allowed {
+		for _, item := range buckets {
+			item.take(now)
+		}
+	}
+
+	h := w.Header()
+	h.Set(RateLimitLimit, strconv.Itoa(int(b.burst)))
+	h.Set(RateLimitRemaining, strconv.Itoa(int(b.tokens)))
+	h.Set(RateLimitReset, strconv.Itoa(int(math.Ceil((b.burst-b.tokens)/b.rate))))
+	if !allowed {
+		h.Set("Retry-After", strconv.Itoa(int(math.Ceil((1-b.tokens)/b.rate))))
+	}
+	return allowed
+}
+
+// Limit middleware responds with 429 code to clients exceeding the route class rate
+func (l *RateLimiter) Limit(class string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+			owner := r.Header.Get(http.CanonicalHeaderKey(XTogglyOwnerID))
+			rate := l.rate(owner, class, now)
+			if rate.PerMinute <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			principal := ""
+			if PrincipalVerified(r) {
+				principal = PrincipalFromContext(r)
+			}
+			if !l.allow(w, owner, principal, class, rate, now) {
+				log := WithRequest(l.Log, r)
+				log.Warn().Str("owner", owner).Str("principal", PrincipalFromContext(r)).Str("class", class).
+					Msg("Rate limit exceeded")
+				ErrorResponse(w, r, ErrRateLimited, http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,231 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/rs/zerolog"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+var logger = zerolog.New(os.Stdout).Level(zerolog.Disabled)
+
+// rateLimitAPI serves stored rate limits and counts their reads
+type rateLimitAPI struct {
+	api.TogglyAPI
+	api.RateLimitAPI
+	limits []*domain.RateLimit
+	err    error
+	reads  int
+}
+
+func (a *rateLimitAPI) RateLimits() api.RateLimitAPI {
+	return a
+}
+
+func (a *rateLimitAPI) List() ([]*domain.RateLimit, error) {
+	a.reads++
+	return a.limits, a.err
+}
+
+func newLimiter(limits *rateLimitAPI, settings *Settings) *RateLimiter {
+	return &RateLimiter{API: limits, Settings: func() *Settings { return settings }, Log: logger}
+}
+
+// request passes the request of the owner principal verified by client certificate through the limiter
+func request(l *RateLimiter, class, owner, principal string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), CtxValuePrincipalVerified, true))
+	return serve(l, class, owner, principal, r)
+}
+
+// unverifiedRequest passes the request of the owner principal taken from the header through the limiter
+func unverifiedRequest(l *RateLimiter, class, owner, principal string) *httptest.ResponseRecorder {
+	return serve(l, class, owner, principal, httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func serve(l *RateLimiter, class, owner, principal string, r *http.Request) *httptest.ResponseRecorder {
+	r.Header.Set(XTogglyOwnerID, owner)
+	r.Header.Set(XTogglyPrincipal, principal)
+	w := httptest.NewRecorder()
+	handler := PrincipalCtx(logger)(l.Limit(class)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestBucket(t *testing.T) {
+	assert := asserts.New(t)
+
+	now := time.Now()
+	b := &bucket{tokens: 2, last: now, rate: 1, burst: 2}
+	assert.True(b.take(now))
+	assert.True(b.take(now))
+	assert.False(b.take(now))
+	assert.False(b.take(now.Add(500 * time.Millisecond)))
+	assert.True(b.take(now.Add(time.Second)))
+	assert.False(b.full(now.Add(time.Second)))
+	assert.True(b.full(now.Add(3 * time.Second)))
+	assert.True(b.take(now.Add(time.Hour)))
+	assert.Equal(float64(1), b.tokens)
+}
+
+func TestRateLimit(t *testing.T) {
+	assert := asserts.New(t)
+
+	settings := &Settings{
+		ManagementRate: domain.Rate{PerMinute: 60, Burst: 2},
+		EvaluationRate: domain.Rate{PerMinute: 600},
+	}
+
+	t.Run("headers", func(t *testing.T) {
+		l := newLimiter(&rateLimitAPI{}, settings)
+		w := request(l, domain.RouteClassManagement, "ow1", "alice")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("2", w.Header().Get(RateLimitLimit))
+		assert.Equal("1", w.Header().Get(RateLimitRemaining))
+		assert.Equal("1", w.Header().Get(RateLimitReset))
+		assert.Empty(w.Header().Get("Retry-After"))
+
+		w = request(l, domain.RouteClassEvaluation, "ow1", "alice")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("600", w.Header().Get(RateLimitLimit))
+		assert.Equal("599", w.Header().Get(RateLimitRemaining))
+	})
+
+	t.Run("exceeded", func(t *testing.T) {
+		l := newLimiter(&rateLimitAPI{}, settings)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "ow1", "alice").Code)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "ow1", "alice").Code)
+		w := request(l, domain.RouteClassManagement, "ow1", "alice")
+		assert.Equal(http.StatusTooManyRequests, w.Code)
+		assert.Equal("0", w.Header().Get(RateLimitRemaining))
+		assert.Equal("1", w.Header().Get("Retry-After"))
+		assert.Contains(w.Body.String(), ErrRateLimited.Error())
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassEvaluation, "ow1", "alice").Code)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "ow2", "alice").Code)
+	})
+
+	t.Run("owner bucket", func(t *testing.T) {
+		l := newLimiter(&rateLimitAPI{}, settings)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "ow1", "alice").Code)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "ow1", "bob").Code)
+		assert.Equal(http.StatusTooManyRequests, request(l, domain.RouteClassManagement, "ow1", "carol").Code)
+	})
+
+	t.Run("principal share", func(t *testing.T) {
+		shared := *settings
+		shared.ManagementRate = domain.Rate{PerMinute: 60, Burst: 4}
+		shared.PrincipalShare = 50
+		l := newLimiter(&rateLimitAPI{}, &shared)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "ow1", "alice").Code)
+		w := request(l, domain.RouteClassManagement, "ow1", "alice")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("2", w.Header().Get(RateLimitLimit))
+		assert.Equal("0", w.Header().Get(RateLimitRemaining))
+		assert.Equal(http.StatusTooManyRequests, request(l, domain.RouteClassManagement, "ow1", "alice").Code)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "ow1", "bob").Code)
+		w = request(l, domain.RouteClassManagement, "ow1", "bob")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("0", w.Header().Get(RateLimitRemaining))
+		assert.Equal(http.StatusTooManyRequests, request(l, domain.RouteClassManagement, "ow1", "carol").Code)
+	})
+
+	t.Run("unverified principals", func(t *testing.T) {
+		shared := *settings
+		shared.ManagementRate = domain.Rate{PerMinute: 60, Burst: 4}
+		shared.PrincipalShare = 50
+		l := newLimiter(&rateLimitAPI{}, &shared)
+		assert.Equal(http.StatusOK, unverifiedRequest(l, domain.RouteClassManagement, "ow1", "alice").Code)
+		assert.Equal(http.StatusOK, unverifiedRequest(l, domain.RouteClassManagement, "ow1", "bob").Code)
+		assert.Equal(http.StatusTooManyRequests, unverifiedRequest(l, domain.RouteClassManagement, "ow1", "carol").Code)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "ow1", "alice").Code)
+
+		l = newLimiter(&rateLimitAPI{}, settings)
+		assert.Equal(http.StatusOK, unverifiedRequest(l, domain.RouteClassManagement, "", "alice").Code)
+		assert.Equal(http.StatusOK, unverifiedRequest(l, domain.RouteClassManagement, "", "bob").Code)
+		assert.Equal(http.StatusTooManyRequests, unverifiedRequest(l, domain.RouteClassManagement, "", "carol").Code)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "", "alice").Code)
+	})
+
+	t.Run("no owner", func(t *testing.T) {
+		l := newLimiter(&rateLimitAPI{}, settings)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "", "alice").Code)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "", "alice").Code)
+		assert.Equal(http.StatusTooManyRequests, request(l, domain.RouteClassManagement, "", "alice").Code)
+		assert.Equal(http.StatusOK, request(l, domain.RouteClassManagement, "", "bob").Code)
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		l := newLimiter(&rateLimitAPI{}, &Settings{})
+		for i := 0; i < 10; i++ {
+			w := request(l, domain.RouteClassManagement, "ow1", "alice")
+			assert.Equal(http.StatusOK, w.Code)
+			assert.Empty(w.Header().Get(RateLimitLimit))
+		}
+	})
+}
+
+func TestRateLimitOverride(t *testing.T) {
+	assert := asserts.New(t)
+
+	settings := &Settings{
+		ManagementRate: domain.Rate{PerMinute: 60, Burst: 2},
+		EvaluationRate: domain.Rate{PerMinute: 600},
+	}
+	limits := &rateLimitAPI{limits: []*domain.RateLimit{
+		{Owner: "ow1", Management: domain.Rate{PerMinute: 120, Burst: 5}},
+		{Owner: "ow2", Management: domain.Rate{PerMinute: -1}},
+	}}
+	l := newLimiter(limits, settings)
+
+	t.Run("precedence", func(t *testing.T) {
+		w := request(l, domain.RouteClassManagement, "ow1", "alice")
+		assert.Equal("5", w.Header().Get(RateLimitLimit))
+		w = request(l, domain.RouteClassEvaluation, "ow1", "alice")
+		assert.Equal("600", w.Header().Get(RateLimitLimit))
+		w = request(l, domain.RouteClassManagement, "ow2", "alice")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Empty(w.Header().Get(RateLimitLimit))
+		w = request(l, domain.RouteClassManagement, "ow3", "alice")
+		assert.Equal("2", w.Header().Get(RateLimitLimit))
+	})
+
+	t.Run("unknown owners", func(t *testing.T) {
+		reads := limits.reads
+		for _, owner := range []string{"ow4", "ow5", "ow6"} {
+			request(l, domain.RouteClassManagement, owner, "alice")
+		}
+		assert.Equal(reads, limits.reads)
+		assert.Len(l.overrides, 2)
+	})
+
+	t.Run("forget", func(t *testing.T) {
+		limits.limits = limits.limits[1:]
+		w := request(l, domain.RouteClassEvaluation, "ow1", "bob")
+		assert.Equal("600", w.Header().Get(RateLimitLimit))
+		l.Forget("ow1")
+		reads := limits.reads
+		w = request(l, domain.RouteClassManagement, "ow1", "bob")
+		assert.Equal(reads+1, limits.reads)
+		assert.Equal("2", w.Header().Get(RateLimitLimit))
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		limits.err = errors.New("read error")
+		l.Forget("")
+		w := request(l, domain.RouteClassManagement, "ow2", "alice")
+		assert.Empty(w.Header().Get(RateLimitLimit))
+		reads := limits.reads
+		request(l, domain.RouteClassManagement, "ow2", "alice")
+		assert.Equal(reads, limits.reads)
+	})
+}
@@ -1,13 +1,20 @@
 package rest
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/rs/zerolog"
@@ -27,16 +34,30 @@ type Server struct {
 	BasePath string
 	Log      zerolog.Logger
 	LogLevel zerolog.Level
+	Settings *Settings
+	TLS      *TLS
+	settings atomic.Value
+	limiter  *RateLimiter
+	once     sync.Once
 }
 
-// Run rest api
-func (s *Server) Run(ctx context.Context, port int, basePath string) {
+// Run rest api, over HTTPS if TLS is set
+func (s *Server) Run(ctx context.Context, port int, basePath string) error {
 	log := s.Log
 	routes := s.Router(basePath)
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
 		Handler: chi.ServerBaseContext(ctx, routes),
 	}
+	var files *tlsFiles
+	if s.TLS != nil {
+		var err error
+		if files, err = newTLSFiles(s.TLS, log); err != nil {
+			return err
+		}
+		srv.TLSConfig = files.config()
+		go files.watch(ctx)
+	}
 	go func() {
 		<-ctx.Done()
 		if err := srv.Shutdown(ctx); err != nil {
@@ -44,9 +65,32 @@ func (s *Server) Run(ctx context.Context, port int, basePath string) {
 		}
 		log.Info().Msg("REST server stopped")
 	}()
-	log.Info().Str("addr", srv.Addr).Msg("HTTP server listening")
-	err := srv.ListenAndServe()
+	var err error
+	if files != nil {
+		log.Info().Str("addr", srv.Addr).Bool("client_ca", s.TLS.ClientCAFile != "").Msg("HTTPS server listening")
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		log.Info().Str("addr", srv.Addr).Msg("HTTP server listening")
+		err = srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
+		return err
+	}
 	log.Info().Msgf("HTTP server terminated, %s", err)
+	return nil
+}
+
+func (s *Server) rateLimiter() *RateLimiter {
+	s.once.Do(func() {
+		s.limiter = &RateLimiter{API: s.API, Settings: s.currentSettings, Log: s.Log}
+	})
+	return s.limiter
+}
+
+// Forget drops data of the owner cached by the server, as the owner was changed
+// by another server instance. Empty owner drops cached data of all owners.
+func (s *Server) Forget(owner string) {
+	s.rateLimiter().Forget(owner)
 }
 
 // Router returns router
@@ -54,7 +98,11 @@ func (s *Server) Router(basePath string) chi.Router {
 	router := chi.NewRouter()
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.Throttle(1000))
+	if s.TLS != nil && s.TLS.ClientCAFile != "" {
+		router.Use(ClientCertCtx(s.TLS.OwnerField, s.TLS.PrincipalField, s.Log))
+	}
+	router.Use(CORSHandler(s.currentSettings))
+	router.Use(Throttle(s.currentSettings))
 	router.Use(middleware.Timeout(60 * time.Second))
 	router.Use(middleware.Heartbeat("/ping"))
 	router.Use(ServiceInfo("Toggly", s.Version))
@@ -69,25 +117,53 @@ func (s *Server) versions(router chi.Router) {
 func (s *Server) v1(router chi.Router) {
 	router.Use(RequestIDCtx(s.Log))
 	router.Use(Logger(s.Log, s.LogLevel))
-	router.Use(OwnerCtx(s.Log))
+	router.Use(PrincipalCtx(s.Log))
 	router.Use(VersionCtx("v1"))
-	// router.Get("/", func(w http.ResponseWriter, r *http.Request) {
-	// 	log := WithRequest(s.Log, r)
-	// 	log.Info().Msg("Some log text")
-	// 	render.PlainText(w, r, "hello")
-	// })
-	// router.Get("/nf", func(w http.ResponseWriter, r *http.Request) {
-	// 	NotFoundResponse(w, r, "Did not found that")
-	// })
-	router.Mount("/project", (&projectRestAPI{API: s.API, Log: s.Log, LogLevel: s.LogLevel}).Routes())
-	// router.Mount("/project/{project_code}/env", (&EnvironmentRestAPI{API: s.API}).Routes())
-	// router.Mount("/project/{project_code}/env/{env_code}/object", (&ObjectRestAPI{API: s.API}).Routes())
+	management := s.rateLimiter().Limit(domain.RouteClassManagement)
+	evaluation := s.rateLimiter().Limit(domain.RouteClassEvaluation)
+	router.Mount("/org", management((&organizationRestAPI{API: s.API, Log: s.Log, LogLevel: s.LogLevel}).Routes()))
+	// Rate limits go before owner access, which reads organizations of the owner
+	router.Group(func(router chi.Router) {
+		router.Use(OwnerCtx(s.Log))
+		router.Use(management)
+		router.Use(OwnerAccess(s.API, s.Log))
+		// router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		// 	log := WithRequest(s.Log, r)
+		// 	log.Info().Msg("Some log text")
+		// 	render.PlainText(w, r, "hello")
+		// })
+		// router.Get("/nf", func(w http.ResponseWriter, r *http.Request) {
+		// 	NotFoundResponse(w, r, "Did not found that")
+		// })
+		router.Mount("/project", (&projectRestAPI{API: s.API, Log: s.Log, LogLevel: s.LogLevel}).Routes())
+		router.Mount("/project/{project_code}/env", (&environmentRestAPI{API: s.API, Log: s.Log, LogLevel: s.LogLevel}).Routes())
+		router.Mount("/project/{project_code}/change", (&changeRequestRestAPI{API: s.API, Log: s.Log, LogLevel: s.LogLevel}).Routes())
+		router.Mount("/project/{project_code}/schedule", (&scheduledChangeRestAPI{API: s.API, Log: s.Log, LogLevel: s.LogLevel}).Routes())
+		router.Mount("/project/{project_code}/webhook", (&webhookRestAPI{API: s.API, Log: s.Log, LogLevel: s.LogLevel}).Routes())
+		router.Mount("/project/{project_code}/env/{env_code}/param", (&parameterRestAPI{API: s.API, Log: s.Log, LogLevel: s.LogLevel}).Routes())
+		router.Mount("/project/{project_code}/env/{env_code}/changeset", (&changesetRestAPI{API: s.API, Log: s.Log, LogLevel: s.LogLevel}).Routes())
+	})
+	router.Group(func(router chi.Router) {
+		router.Use(OwnerCtx(s.Log))
+		router.Use(evaluation)
+		router.Use(OwnerAccess(s.API, s.Log))
+		router.Mount("/project/{project_code}/env/{env_code}/eval", (&evaluationRestAPI{API: s.API, Log: s.Log, LogLevel: s.LogLevel}).Routes())
+	})
 }
 
 func owner(s *http.Request) string {
 	return OwnerFromContext(s)
 }
 
+func principal(s *http.Request) string {
+	return PrincipalFromContext(s)
+}
+
+// ownerAPI returns engine API for request owner acting as request principal
+func ownerAPI(a api.TogglyAPI, r *http.Request) api.OwnerAPI {
+	return a.ForOwner(owner(r)).As(principal(r))
+}
+
 func projectCode(s *http.Request) string {
 	return chi.URLParam(s, "project_code")
 }
@@ -96,6 +172,63 @@ func environmentCode(s *http.Request) string {
 	return chi.URLParam(s, "env_code")
 }
 
-func objectCode(s *http.Request) string {
-	return chi.URLParam(s, "object_code")
+func parameterCode(s *http.Request) string {
+	return chi.URLParam(s, "param_code")
+}
+
+func organizationID(s *http.Request) string {
+	return chi.URLParam(s, "org_id")
+}
+
+func memberPrincipal(s *http.Request) string {
+	return chi.URLParam(s, "principal")
+}
+
+func teamCode(s *http.Request) string {
+	return chi.URLParam(s, "team_code")
+}
+
+func changesetID(s *http.Request) string {
+	return chi.URLParam(s, "changeset_id")
+}
+
+func changeRequestID(s *http.Request) string {
+	return chi.URLParam(s, "change_id")
+}
+
+func scheduledChangeID(s *http.Request) string {
+	return chi.URLParam(s, "schedule_id")
+}
+
+func webhookID(s *http.Request) string {
+	return chi.URLParam(s, "webhook_id")
+}
+
+// decodeValues parses request body carrying parameter values. Numbers are kept as
+// json.Number, so integers beyond float64 precision are not rounded.
+func decodeValues(body []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("Unexpected data after request body")
+	}
+	return nil
+}
+
+// ifMatchVersion returns entity version expected by If-Match header.
+// Zero means any version. Tags not produced by ETagHeader never match.
+func ifMatchVersion(r *http.Request) (int, error) {
+	tag := strings.TrimSpace(r.Header.Get("If-Match"))
+	if tag == "" || tag == "*" {
+		return 0, nil
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	version, err := strconv.Atoi(strings.Trim(tag, "\""))
+	if err != nil || version <= 0 {
+		return 0, api.ErrVersionConflict
+	}
+	return version, nil
 }
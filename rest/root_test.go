@@ -0,0 +1,22 @@
+package rest
+
+import (
+	"encoding/json"
+	"testing"
+
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestDecodeValues(t *testing.T) {
+	assert := asserts.New(t)
+
+	req := &parameterCreateRequest{}
+	assert.Nil(decodeValues([]byte(`{"value": 9007199254740993, "allowed_values": [1.5],
+		"variants": [{"name": "big", "value": 12345678901234567890}]}`), req))
+	assert.Equal(json.Number("9007199254740993"), req.Value)
+	assert.Equal([]interface{}{json.Number("1.5")}, req.AllowedValues)
+	assert.Equal(json.Number("12345678901234567890"), req.Variants[0].Value)
+
+	assert.NotNil(decodeValues([]byte(`{"value": 1} {}`), &parameterCreateRequest{}))
+	assert.NotNil(decodeValues([]byte(`{"value": }`), &parameterCreateRequest{}))
+}
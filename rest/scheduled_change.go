@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Toggly/core/api"
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog"
+)
+
+type scheduledChangeCreateRequest struct {
+	Environment string
+	Parameter   string
+	Value       interface{}
+	At          string
+	TimeZone    string `json:"time_zone"`
+	RevertAfter string `json:"revert_after"`
+}
+
+type scheduledChangeRestAPI struct {
+	API      api.TogglyAPI
+	Log      zerolog.Logger
+	LogLevel zerolog.Level
+}
+
+func (a *scheduledChangeRestAPI) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(ProjectAccess(a.API, a.Log))
+	router.Group(func(group chi.Router) {
+		group.Get("/", a.list)
+		group.Post("/", a.createScheduledChange)
+		group.Get("/{schedule_id}", a.getScheduledChange)
+		group.Delete("/{schedule_id}", a.cancelScheduledChange)
+	})
+	return router
+}
+
+func (a *scheduledChangeRestAPI) engine(r *http.Request) api.ScheduledChangeAPI {
+	return ownerAPI(a.API, r).Projects().For(projectCode(r)).ScheduledChanges()
+}
+
+func (a *scheduledChangeRestAPI) list(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	list, err := a.engine(r).List(r.URL.Query().Get("status"))
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get scheduled changes list")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, list)
+}
+
+func (a *scheduledChangeRestAPI) getScheduledChange(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	sc, err := a.engine(r).Get(scheduledChangeID(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get scheduled change")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, sc.Version)
+	JSONResponse(w, r, sc)
+}
+
+func (a *scheduledChangeRestAPI) cancelScheduledChange(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	sc, err := a.engine(r).Cancel(scheduledChangeID(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Can't cancel scheduled change")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, sc)
+}
+
+func (a *scheduledChangeRestAPI) createScheduledChange(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't read request body")
+		ErrorResponse(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	req := &scheduledChangeCreateRequest{}
+	if err = decodeValues(body, req); err != nil {
+		log.Error().Err(err).Msg("Can't parse request body")
+		ErrorResponse(w, r, errors.New("Bad request"), http.StatusBadRequest)
+		return
+	}
+	sc, err := a.engine(r).Create(&api.ScheduledChangeInfo{
+		Environment: req.Environment,
+		Parameter:   req.Parameter,
+		Value:       req.Value,
+		At:          req.At,
+		TimeZone:    req.TimeZone,
+		RevertAfter: req.RevertAfter,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Can't schedule change")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, sc.Version)
+	JSONResponse(w, r, sc)
+}
@@ -0,0 +1,134 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Toggly/core/domain"
+)
+
+// ErrServerBusy error
+var ErrServerBusy = errors.New("Server is busy")
+
+// Settings holds server settings which can be changed while it runs
+type Settings struct {
+	// MaxRequests limits requests served at the same time, 0 means no limit
+	MaxRequests int
+	// ManagementRate and EvaluationRate are default rates of every owner,
+	// zero PerMinute means no limit
+	ManagementRate domain.Rate
+	EvaluationRate domain.Rate
+	// PrincipalShare is the percent of the owner rate a single principal verified by client
+	// certificate, or all unverified principals together, can use, 0 or 100 means no principal limit
+	PrincipalShare int
+	CORS           CORS
+}
+
+// Rate returns the default rate of the route class
+func (s *Settings) Rate(class string) domain.Rate {
+	if class == domain.RouteClassEvaluation {
+		return s.EvaluationRate
+	}
+	return s.ManagementRate
+}
+
+// CORS settings. Cross-origin requests are not allowed if Origins is empty.
+type CORS struct {
+	Origins     []string
+	Methods     []string
+	Headers     []string
+	Credentials bool
+	MaxAge      time.Duration
+}
+
+// allowedOrigin returns Access-Control-Allow-Origin value for the request origin or empty string
+func (c *CORS) allowedOrigin(origin string) string {
+	for _, o := range c.Origins {
+		if o == "*" {
+			return "*"
+		}
+		if strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// Reload applies settings to running server
+func (s *Server) Reload(settings *Settings) {
+	s.settings.Store(settings)
+}
+
+// currentSettings returns the last reloaded settings, initial ones if there were no reloads
+func (s *Server) currentSettings() *Settings {
+	if settings, ok := s.settings.Load().(*Settings); ok {
+		return settings
+	}
+	if s.Settings != nil {
+		return s.Settings
+	}
+	return &Settings{}
+}
+
+// Throttle middleware responds with 503 code when there are too many requests in progress
+func Throttle(settings func() *Settings) func(http.Handler) http.Handler {
+	var inProgress int64
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt64(&inProgress, 1)
+			defer atomic.AddInt64(&inProgress, -1)
+			if limit := settings().MaxRequests; limit > 0 && n > int64(limit) {
+				w.Header().Set("Retry-After", "1")
+				ErrorResponse(w, r, ErrServerBusy, http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// CORSHandler middleware adds cross-origin headers and responds to preflight requests
+func CORSHandler(settings func() *Settings) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cors := &settings().CORS
+			w.Header().Add("Vary", "Origin")
+			allowed := cors.allowedOrigin(origin)
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if allowed == "" {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			if cors.Credentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join([]string{XTogglyRequestID, "ETag"}, ", "))
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(cors.Methods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(cors.Headers, ", "))
+			if cors.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cors.MaxAge/time.Second)))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
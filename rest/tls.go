@@ -0,0 +1,228 @@
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrNoClientCA error
+var ErrNoClientCA = errors.New("No certificates found in client CA file")
+
+// Client certificate subject fields
+const (
+	SubjectCommonName         string = "CN"
+	SubjectOrganization       string = "O"
+	SubjectOrganizationalUnit string = "OU"
+)
+
+// TLS settings. Files are checked every CheckInterval and reloaded when changed.
+type TLS struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables verification of client certificates against the CA bundle
+	ClientCAFile string
+	// ClientCertRequired rejects clients without certificate
+	ClientCertRequired bool
+	// OwnerField and PrincipalField are client certificate subject fields which replace
+	// owner and principal headers, the headers are ignored without certificate.
+	// Empty field keeps the header.
+	OwnerField     string
+	PrincipalField string
+	CheckInterval  time.Duration
+}
+
+// tlsFiles keeps certificate and client CAs loaded from TLS files
+type tlsFiles struct {
+	settings  *TLS
+	log       zerolog.Logger
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	stamps    map[string]string
+}
+
+func newTLSFiles(settings *TLS, log zerolog.Logger) (*tlsFiles, error) {
+	f := &tlsFiles{settings: settings, log: log}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *tlsFiles) paths() []string {
+	paths := []string{f.settings.CertFile, f.settings.KeyFile}
+	if f.settings.ClientCAFile != "" {
+		paths = append(paths, f.settings.ClientCAFile)
+	}
+	return paths
+}
+
+// fileStamps returns files modification time and size, which change on file update
+func (f *tlsFiles) fileStamps() map[string]string {
+	stamps := map[string]string{}
+	for _, path := range f.paths() {
+		if info, err := os.Stat(path); err == nil {
+			stamps[path] = fmt.Sprintf("%s/%d", info.ModTime(), info.Size())
+		}
+	}
+	return stamps
+}
+
+func (f *tlsFiles) changed() bool {
+	stamps := f.fileStamps()
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(stamps) != len(f.stamps) {
+		return true
+	}
+	for path, stamp := range stamps {
+		if f.stamps[path] != stamp {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *tlsFiles) load() error {
+	stamps := f.fileStamps()
+	cert, err := tls.LoadX509KeyPair(f.settings.CertFile, f.settings.KeyFile)
+	if err != nil {
+		return err
+	}
+	var pool *x509.CertPool
+	if f.settings.ClientCAFile != "" {
+		data, err := ioutil.ReadFile(f.settings.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return ErrNoClientCA
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cert, f.clientCAs, f.stamps = &cert, pool, stamps
+	return nil
+}
+
+// watch reloads changed files until context is done. Current files are kept if new ones are broken.
+func (f *tlsFiles) watch(ctx context.Context) {
+	if f.settings.CheckInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(f.settings.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !f.changed() {
+				continue
+			}
+			if err := f.load(); err != nil {
+				f.log.Error().Err(err).Msg("Can't reload TLS files")
+				continue
+			}
+			f.log.Info().Msg("TLS files reloaded")
+		}
+	}
+}
+
+func (f *tlsFiles) certificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.cert, nil
+}
+
+// config returns server TLS config using the last loaded files
+func (f *tlsFiles) config() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: f.certificate,
+	}
+	if f.settings.ClientCAFile == "" {
+		return cfg
+	}
+	clientAuth := tls.VerifyClientCertIfGiven
+	if f.settings.ClientCertRequired {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+		return &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			NextProtos:     cfg.NextProtos,
+			GetCertificate: f.certificate,
+			ClientAuth:     clientAuth,
+			ClientCAs:      f.clientCAs,
+		}, nil
+	}
+	return cfg
+}
+
+func subjectField(subject pkix.Name, field string) string {
+	var values []string
+	switch field {
+	case SubjectCommonName:
+		return subject.CommonName
+	case SubjectOrganization:
+		values = subject.Organization
+	case SubjectOrganizationalUnit:
+		values = subject.OrganizationalUnit
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// ClientCertCtx replaces owner and principal headers with verified client certificate subject fields.
+// Headers of mapped fields are removed from requests without verified certificate, so clients
+// can't claim the identity by headers. The principal mapped from the certificate is marked
+// as verified, see PrincipalVerified.
+func ClientCertCtx(ownerField, principalField string, log zerolog.Logger) func(http.Handler) http.Handler {
+	fields := map[string]string{XTogglyOwnerID: ownerField, XTogglyPrincipal: principalField}
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			var subject *pkix.Name
+			if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+				subject = &r.TLS.VerifiedChains[0][0].Subject
+			}
+			for header, field := range fields {
+				if field == "" {
+					continue
+				}
+				if subject == nil {
+					r.Header.Del(header)
+					continue
+				}
+				if value := subjectField(*subject, field); value != "" {
+					r.Header.Set(header, value)
+					if header == XTogglyPrincipal {
+						r = r.WithContext(context.WithValue(r.Context(), CtxValuePrincipalVerified, true))
+					}
+				} else {
+					log.Warn().Str("subject", subject.String()).Msgf("Client certificate has no %s", field)
+					r.Header.Del(header)
+				}
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,179 @@
+package rest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	asserts "github.com/stretchr/testify/assert"
+)
+
+// writeCert writes a new self-signed certificate of the common name and its key
+func writeCert(t *testing.T, certFile, keyFile, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+// writeFile writes the file and moves its modification time forward, so the change is seen
+// regardless of file system time resolution
+func writeFile(t *testing.T, path string, data []byte) {
+	var stamp time.Time
+	if info, err := os.Stat(path); err == nil {
+		stamp = info.ModTime().Add(time.Second)
+	} else {
+		stamp = time.Now()
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, stamp, stamp); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Subject.CommonName
+}
+
+func TestTLSFiles(t *testing.T) {
+	assert := asserts.New(t)
+
+	dir, err := ioutil.TempDir("", "toggly_tls_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	settings := &TLS{
+		CertFile:     filepath.Join(dir, "cert.pem"),
+		KeyFile:      filepath.Join(dir, "key.pem"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+	}
+	writeCert(t, settings.CertFile, settings.KeyFile, "server1")
+	writeCert(t, settings.ClientCAFile, filepath.Join(dir, "ca-key.pem"), "ca")
+
+	f, err := newTLSFiles(settings, logger)
+	if !assert.Nil(err) {
+		return
+	}
+	cert, _ := f.certificate(nil)
+	assert.Equal("server1", commonName(t, cert))
+	assert.False(f.changed())
+
+	t.Run("reload", func(t *testing.T) {
+		writeCert(t, settings.CertFile, settings.KeyFile, "server2")
+		assert.True(f.changed())
+		assert.Nil(f.load())
+		assert.False(f.changed())
+		cert, _ := f.certificate(nil)
+		assert.Equal("server2", commonName(t, cert))
+	})
+
+	t.Run("broken certificate", func(t *testing.T) {
+		writeFile(t, settings.CertFile, []byte("broken"))
+		assert.True(f.changed())
+		assert.NotNil(f.load())
+		assert.True(f.changed())
+		cert, _ := f.certificate(nil)
+		assert.Equal("server2", commonName(t, cert))
+	})
+
+	t.Run("broken client CA", func(t *testing.T) {
+		writeCert(t, settings.CertFile, settings.KeyFile, "server3")
+		writeFile(t, settings.ClientCAFile, []byte("broken"))
+		assert.Equal(ErrNoClientCA, f.load())
+		cert, _ := f.certificate(nil)
+		assert.Equal("server2", commonName(t, cert))
+		assert.NotNil(f.clientCAs)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		assert.Nil(os.Remove(settings.KeyFile))
+		assert.True(f.changed())
+		assert.NotNil(f.load())
+		cert, _ := f.certificate(nil)
+		assert.Equal("server2", commonName(t, cert))
+	})
+
+	t.Run("no files", func(t *testing.T) {
+		_, err := newTLSFiles(&TLS{CertFile: filepath.Join(dir, "none.pem"), KeyFile: settings.KeyFile}, logger)
+		assert.NotNil(err)
+	})
+}
+
+func TestClientCertCtx(t *testing.T) {
+	assert := asserts.New(t)
+
+	verified := func(subject pkix.Name) *tls.ConnectionState {
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: subject}}}}
+	}
+	subject := pkix.Name{CommonName: "alice", Organization: []string{"ow1", "ow2"}, OrganizationalUnit: []string{"dev"}}
+
+	tt := []struct {
+		name      string
+		owner     string
+		principal string
+		state     *tls.ConnectionState
+		expected  [2]string
+		verified  bool
+	}{
+		{name: "mapped", owner: SubjectOrganization, principal: SubjectCommonName, state: verified(subject), expected: [2]string{"ow1", "alice"}, verified: true},
+		{name: "unit", owner: SubjectOrganizationalUnit, principal: SubjectCommonName, state: verified(subject), expected: [2]string{"dev", "alice"}, verified: true},
+		{name: "header kept", owner: "", principal: SubjectCommonName, state: verified(subject), expected: [2]string{"header-owner", "alice"}, verified: true},
+		{name: "no field value", owner: SubjectOrganization, principal: SubjectCommonName, state: verified(pkix.Name{CommonName: "bob"}), expected: [2]string{"", "bob"}, verified: true},
+		{name: "no certificate", owner: SubjectOrganization, principal: SubjectCommonName, state: &tls.ConnectionState{}, expected: [2]string{"", ""}},
+		{name: "no TLS", owner: SubjectOrganization, principal: "", expected: [2]string{"", "header-principal"}},
+		{name: "no mapping", owner: "", principal: "", expected: [2]string{"header-owner", "header-principal"}},
+	}
+	for _, tc := range tt {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(XTogglyOwnerID, "header-owner")
+		r.Header.Set(XTogglyPrincipal, "header-principal")
+		r.TLS = tc.state
+		var headers [2]string
+		var principalVerified bool
+		handler := ClientCertCtx(tc.owner, tc.principal, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers = [2]string{r.Header.Get(XTogglyOwnerID), r.Header.Get(XTogglyPrincipal)}
+			principalVerified = PrincipalVerified(r)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		assert.Equal(tc.expected, headers, tc.name)
+		assert.Equal(tc.verified, principalVerified, tc.name)
+	}
+}
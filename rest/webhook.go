@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/rs/zerolog"
+)
+
+type webhookCreateRequest struct {
+	URL    string
+	Secret string
+	Events []string
+	Active *bool
+}
+
+type webhookRestAPI struct {
+	API      api.TogglyAPI
+	Log      zerolog.Logger
+	LogLevel zerolog.Level
+}
+
+func (a *webhookRestAPI) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(ProjectAccess(a.API, a.Log))
+	router.Group(func(group chi.Router) {
+		group.Get("/", a.list)
+		group.Post("/", a.createWebhook)
+		group.Get("/{webhook_id}", a.getWebhook)
+		group.Put("/{webhook_id}", a.updateWebhook)
+		group.Delete("/{webhook_id}", a.deleteWebhook)
+		group.Get("/{webhook_id}/delivery", a.deliveries)
+	})
+	return router
+}
+
+func (a *webhookRestAPI) engine(r *http.Request) api.WebhookAPI {
+	return ownerAPI(a.API, r).Projects().For(projectCode(r)).Webhooks()
+}
+
+func (a *webhookRestAPI) list(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	list, err := a.engine(r).List()
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get webhooks list")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, list)
+}
+
+func (a *webhookRestAPI) getWebhook(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	hook, err := a.engine(r).Get(webhookID(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get webhook")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, hook.Version)
+	JSONResponse(w, r, hook)
+}
+
+func (a *webhookRestAPI) deliveries(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	list, err := a.engine(r).Deliveries(webhookID(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Can't get webhook deliveries")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	JSONResponse(w, r, list)
+}
+
+func (a *webhookRestAPI) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	log := WithRequest(a.Log, r)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		APIErrorResponse(w, r, err)
+		return
+	}
+	if err = a.engine(r).Delete(webhookID(r), version); err != nil {
+		log.Error().Err(err).Msg("Can't delete webhook")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func (a *webhookRestAPI) createWebhook(w http.ResponseWriter, r *http.Request) {
+	a.createUpdate(w, r, true)
+}
+
+func (a *webhookRestAPI) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	a.createUpdate(w, r, false)
+}
+
+func (a *webhookRestAPI) createUpdate(w http.ResponseWriter, r *http.Request, create bool) {
+	log := WithRequest(a.Log, r)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't read request body")
+		ErrorResponse(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	req := &webhookCreateRequest{}
+	err = json.Unmarshal(body, req)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't parse request body")
+		ErrorResponse(w, r, errors.New("Bad request"), http.StatusBadRequest)
+		return
+	}
+	info := &api.WebhookInfo{
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: req.Events,
+		Active: req.Active == nil || *req.Active,
+	}
+	var hook *domain.Webhook
+	if create {
+		hook, err = a.engine(r).Create(info)
+	} else {
+		info.ID = webhookID(r)
+		if info.Version, err = ifMatchVersion(r); err == nil {
+			hook, err = a.engine(r).Update(info)
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Can't save/update webhook")
+		APIErrorResponse(w, r, err)
+		return
+	}
+	ETagHeader(w, hook.Version)
+	JSONResponse(w, r, hook)
+}
@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	"github.com/rs/zerolog"
+)
+
+// ErrChangedConcurrently is the error of a resumed change whose parameter was changed
+// by someone else after the change state was saved
+var ErrChangedConcurrently = errors.New("Parameter changed concurrently")
+
+// Scheduler executes due scheduled changes. Several instances may run against
+// the same storage: every change is leased to a single worker before execution.
+type Scheduler struct {
+	Storage  storage.DataStorage
+	API      api.TogglyAPI
+	Worker   string
+	Interval time.Duration
+	Lease    time.Duration
+	Log      zerolog.Logger
+}
+
+// Run executes due changes every interval until context is done
+func (s *Scheduler) Run(ctx context.Context) {
+	log := s.Log.With().Str("worker", s.Worker).Logger()
+	log.Info().Str("interval", s.Interval.String()).Msg("Scheduler started")
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		s.RunDue()
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue executes all changes due by now
+func (s *Scheduler) RunDue() {
+	for {
+		sc, err := s.Storage.ScheduledChanges().Claim(util.Now(), s.Worker, s.Lease)
+		if err == storage.ErrNotFound {
+			return
+		}
+		if err != nil {
+			s.Log.Error().Err(err).Msg("Can't claim scheduled change")
+			return
+		}
+		s.execute(sc)
+	}
+}
+
+// execute applies the claimed change. The previous value and the parameter version are saved
+// before the parameter is written, and the write is conditional on that version. A change claimed
+// again after a crash or a failed save is finished with the saved state: the write is repeated
+// only if the parameter still has the saved version. Otherwise the change is done if the parameter
+// has the change value, and fails with ErrChangedConcurrently if it has another one.
+func (s *Scheduler) execute(sc *domain.ScheduledChange) {
+	log := s.Log.With().Str("id", sc.ID).Str("owner", sc.Owner).Str("project", sc.Project).
+		Str("env", sc.Environment).Str("param", sc.Parameter).Logger()
+	params := s.API.ForOwner(sc.Owner).As(sc.Requester).Projects().For(sc.Project).Environments().For(sc.Environment).Parameters()
+	db := s.Storage.ForOwner(sc.Owner).Projects().For(sc.Project).ScheduledChanges()
+	p, err := params.Get(sc.Parameter)
+	if err == nil {
+		if sc.ExecutedAt.IsZero() {
+			sc.ExecutedAt = util.Now()
+			sc.Previous = p.Value
+			sc.BaseVersion = p.Version
+			if err := db.Update(sc); err != nil {
+				log.Error().Err(err).Msg("Can't save scheduled change state")
+				return
+			}
+		} else {
+			log.Info().Msg("Resuming interrupted scheduled change")
+		}
+		switch {
+		case p.Version == sc.BaseVersion:
+			info := api.NewParameterInfo(p)
+			info.Value = sc.Value
+			_, err = params.Update(info)
+		case !sameValue(p, sc.Value):
+			err = ErrChangedConcurrently
+		}
+	}
+	if e, ok := err.(*api.ErrApprovalRequired); ok {
+		sc.ChangeRequest = e.ChangeRequest.ID
+		err = nil
+	}
+	if sc.ExecutedAt.IsZero() {
+		sc.ExecutedAt = util.Now()
+	}
+	sc.LockedBy = ""
+	if err != nil {
+		log.Error().Err(err).Msg("Scheduled change failed")
+		sc.Status = domain.ScheduledChangeStatusFailed
+		sc.Error = err.Error()
+	} else {
+		log.Info().Interface("value", sc.Value).Msg("Scheduled change executed")
+		sc.Status = domain.ScheduledChangeStatusDone
+	}
+	if err := db.Update(sc); err != nil {
+		log.Error().Err(err).Msg("Can't save scheduled change result")
+		return
+	}
+	if sc.Status == domain.ScheduledChangeStatusDone && sc.RevertAfter != "" {
+		s.scheduleRevert(sc, db, log)
+	}
+}
+
+// sameValue reports whether the parameter has the change value. Values are compared in JSON,
+// as the change value is read in the form the storage keeps it, json values as strings in BSON.
+func sameValue(p *domain.Parameter, value interface{}) bool {
+	if v, ok := value.(string); ok && p.Type == domain.ParameterTypeJSON {
+		value = domain.JSONValue(v)
+	}
+	a, err := json.Marshal(p.Value)
+	if err != nil {
+		return false
+	}
+	b, err := json.Marshal(value)
+	return err == nil && bytes.Equal(a, b)
+}
+
+func (s *Scheduler) scheduleRevert(sc *domain.ScheduledChange, db storage.ScheduledChangeStorage, log zerolog.Logger) {
+	d, err := time.ParseDuration(sc.RevertAfter)
+	if err != nil {
+		log.Error().Err(err).Msg("Can't schedule revert")
+		return
+	}
+	revert := &domain.ScheduledChange{
+		ID:          util.NewID(),
+		Owner:       sc.Owner,
+		Project:     sc.Project,
+		Environment: sc.Environment,
+		Parameter:   sc.Parameter,
+		Value:       sc.Previous,
+		At:          sc.ExecutedAt.Add(d),
+		TimeZone:    sc.TimeZone,
+		RevertOf:    sc.ID,
+		Requester:   sc.Requester,
+		Status:      domain.ScheduledChangeStatusPending,
+		RegDate:     util.Now(),
+	}
+	if err = db.Save(revert); err != nil {
+		log.Error().Err(err).Msg("Can't schedule revert")
+		return
+	}
+	log.Info().Str("revert", revert.ID).Time("at", revert.At).Msg("Revert scheduled")
+}
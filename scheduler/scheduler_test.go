@@ -0,0 +1,205 @@
+package scheduler_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/scheduler"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/storage/mongo"
+	"github.com/Toggly/core/util"
+	driver "github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+var logger = log.Output(zerolog.ConsoleWriter{
+	Out:     os.Stdout,
+	NoColor: true,
+}).Level(zerolog.DebugLevel)
+
+func getDB() storage.DataStorage {
+	ctx := context.Background()
+	dataStorage, err := mongo.NewMongoDataStorage(ctx, "mongodb://localhost:27017", "toggly_scheduler_test", logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't create storage")
+	}
+	err = dataStorage.Connect()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't connect")
+	}
+	return dataStorage
+}
+
+func dropDB() {
+	client, err := driver.NewClient("mongodb://localhost:27017")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't connect to mongo")
+	}
+	ctx := context.Background()
+	client.Connect(ctx)
+	err = client.Database("toggly_scheduler_test").Drop(ctx)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't drop db")
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	assert := asserts.New(t)
+
+	dropDB()
+
+	dataStorage := getDB()
+	togglyAPI := engine.NewTogglyAPI(dataStorage, &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "prod"}},
+	}, logger)
+	pApi := togglyAPI.ForOwner("ow1").Projects()
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+	params := pApi.For("proj1").Environments().For("prod").Parameters()
+	_, err = params.Create(&api.ParameterInfo{Code: "flag", Type: domain.ParameterTypeBool, Value: false})
+	assert.Nil(err)
+
+	db := dataStorage.ForOwner("ow1").Projects().For("proj1").ScheduledChanges()
+	sc := &domain.ScheduledChange{
+		ID:          util.NewID(),
+		Owner:       "ow1",
+		Project:     "proj1",
+		Environment: "prod",
+		Parameter:   "flag",
+		Value:       true,
+		At:          util.Now().Add(-time.Second),
+		RevertAfter: "2h",
+		Status:      domain.ScheduledChangeStatusPending,
+	}
+	assert.Nil(db.Save(sc))
+
+	s := &scheduler.Scheduler{
+		Storage: dataStorage,
+		API:     togglyAPI,
+		Worker:  "w1",
+		Lease:   time.Minute,
+		Log:     logger,
+	}
+	s.RunDue()
+
+	p, err := params.Get("flag")
+	assert.Nil(err)
+	assert.Equal(true, p.Value)
+
+	res, err := db.Get(sc.ID)
+	assert.Nil(err)
+	assert.Equal(domain.ScheduledChangeStatusDone, res.Status)
+	assert.False(res.ExecutedAt.IsZero())
+
+	pending, err := db.List(domain.ScheduledChangeStatusPending)
+	assert.Nil(err)
+	assert.Len(pending, 1)
+	assert.Equal(sc.ID, pending[0].RevertOf)
+	assert.Equal(false, pending[0].Value)
+
+	dropDB()
+}
+
+func TestSchedulerResume(t *testing.T) {
+	assert := asserts.New(t)
+
+	dropDB()
+
+	dataStorage := getDB()
+	togglyAPI := engine.NewTogglyAPI(dataStorage, &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "prod"}},
+	}, logger)
+	pApi := togglyAPI.ForOwner("ow1").Projects()
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+	params := pApi.For("proj1").Environments().For("prod").Parameters()
+	p, err := params.Create(&api.ParameterInfo{Code: "flag", Type: domain.ParameterTypeBool, Value: false})
+	assert.Nil(err)
+
+	// The previous worker saved the state and wrote the parameter, but died before saving the result
+	db := dataStorage.ForOwner("ow1").Projects().For("proj1").ScheduledChanges()
+	sc := &domain.ScheduledChange{
+		ID:          util.NewID(),
+		Owner:       "ow1",
+		Project:     "proj1",
+		Environment: "prod",
+		Parameter:   "flag",
+		Value:       true,
+		At:          util.Now().Add(-time.Minute),
+		RevertAfter: "2h",
+		Status:      domain.ScheduledChangeStatusPending,
+	}
+	assert.Nil(db.Save(sc))
+	sc, err = dataStorage.ScheduledChanges().Claim(util.Now(), "w0", time.Millisecond)
+	assert.Nil(err)
+	sc.ExecutedAt = util.Now()
+	sc.Previous = false
+	sc.BaseVersion = p.Version
+	assert.Nil(db.Update(sc))
+	info := api.NewParameterInfo(p)
+	info.Value = true
+	p, err = params.Update(info)
+	assert.Nil(err)
+	time.Sleep(5 * time.Millisecond)
+
+	s := &scheduler.Scheduler{
+		Storage: dataStorage,
+		API:     togglyAPI,
+		Worker:  "w1",
+		Lease:   time.Minute,
+		Log:     logger,
+	}
+	s.RunDue()
+
+	res, err := params.Get("flag")
+	assert.Nil(err)
+	assert.Equal(true, res.Value)
+	assert.Equal(p.Version, res.Version)
+
+	done, err := db.Get(sc.ID)
+	assert.Nil(err)
+	assert.Equal(domain.ScheduledChangeStatusDone, done.Status)
+
+	pending, err := db.List(domain.ScheduledChangeStatusPending)
+	assert.Nil(err)
+	assert.Len(pending, 1)
+	assert.Equal(false, pending[0].Value)
+
+	// The parameter got another value after the state was saved
+	sc = &domain.ScheduledChange{
+		ID:          util.NewID(),
+		Owner:       "ow1",
+		Project:     "proj1",
+		Environment: "prod",
+		Parameter:   "flag",
+		Value:       false,
+		At:          util.Now().Add(-time.Minute),
+		Status:      domain.ScheduledChangeStatusPending,
+	}
+	assert.Nil(db.Save(sc))
+	sc, err = dataStorage.ScheduledChanges().Claim(util.Now(), "w0", time.Millisecond)
+	assert.Nil(err)
+	sc.ExecutedAt = util.Now()
+	sc.Previous = true
+	sc.BaseVersion = p.Version - 1
+	assert.Nil(db.Update(sc))
+	time.Sleep(5 * time.Millisecond)
+	s.RunDue()
+
+	res, err = params.Get("flag")
+	assert.Nil(err)
+	assert.Equal(true, res.Value)
+	failed, err := db.Get(sc.ID)
+	assert.Nil(err)
+	assert.Equal(domain.ScheduledChangeStatusFailed, failed.Status)
+	assert.Equal(scheduler.ErrChangedConcurrently.Error(), failed.Error)
+
+	dropDB()
+}
@@ -0,0 +1,281 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Bucket names
+const (
+	projectBucket         = "project"
+	environmentBucket     = "environment"
+	parameterBucket       = "parameter"
+	changeRequestBucket   = "change_request"
+	scheduledChangeBucket = "scheduled_change"
+	webhookBucket         = "webhook"
+	webhookDeliveryBucket = "webhook_delivery"
+	evaluationCountBucket = "evaluation_count"
+	changesetBucket       = "changeset"
+	organizationBucket    = "organization"
+	rateLimitBucket       = "rate_limit"
+)
+
+var buckets = []string{
+	projectBucket,
+	environmentBucket,
+	parameterBucket,
+	changeRequestBucket,
+	scheduledChangeBucket,
+	webhookBucket,
+	webhookDeliveryBucket,
+	evaluationCountBucket,
+	changesetBucket,
+	organizationBucket,
+	rateLimitBucket,
+}
+
+// projectChildBuckets lists buckets cleaned together with a project
+var projectChildBuckets = []string{
+	environmentBucket,
+	parameterBucket,
+	changeRequestBucket,
+	scheduledChangeBucket,
+	webhookBucket,
+	webhookDeliveryBucket,
+	evaluationCountBucket,
+	changesetBucket,
+}
+
+var errNoPath = errors.New("bolt storage file path not specified")
+
+// openTimeout limits waiting for the database file lock held by another process
+const openTimeout = time.Second
+
+// NewBoltDataStorage returns embedded storage implementation keeping all data in a single bbolt file.
+// Entities are kept in buckets under keys made of owner, parent codes and the entity code or ID,
+// encoded in BSON the same way Mongo storage keeps them. The file is locked by the process,
+// so there are no other instances to report changes and Subscribe returns ErrChangesNotSupported.
+func NewBoltDataStorage(ctx context.Context, path string, log zerolog.Logger) (storage.DataStorage, error) {
+	if path == "" {
+		return nil, errNoPath
+	}
+	return &boltStorage{
+		ctx:  ctx,
+		path: path,
+		log:  log,
+	}, nil
+}
+
+type boltStorage struct {
+	ctx  context.Context
+	path string
+	log  zerolog.Logger
+	db   *bbolt.DB
+}
+
+func (s *boltStorage) Connect() error {
+	db, err := bbolt.Open(s.path, 0600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return err
+	}
+	s.db = db
+	go func() {
+		<-s.ctx.Done()
+		if err := db.Close(); err != nil {
+			s.log.Error().Err(err).Msg("Can't close Bolt storage")
+		}
+		s.log.Info().Msg("Bolt storage closed")
+	}()
+	s.log.Debug().Str("path", s.path).Msg("Bolt storage opened")
+	return nil
+}
+
+func (s *boltStorage) ForOwner(owner string) storage.OwnerStorage {
+	return &boltOwnerStorage{
+		log:   s.log,
+		owner: owner,
+		db:    s.db,
+	}
+}
+
+func (s *boltStorage) ScheduledChanges() storage.ScheduledChangeQueue {
+	return &boltScheduledChangeQueue{
+		log: s.log,
+		db:  s.db,
+	}
+}
+
+func (s *boltStorage) WebhookDeliveries() storage.WebhookDeliveryQueue {
+	return &boltWebhookDeliveryQueue{
+		log: s.log,
+		db:  s.db,
+	}
+}
+
+func (s *boltStorage) EvaluationCounts() storage.EvaluationCountSink {
+	return &boltEvaluationCountSink{
+		log: s.log,
+		db:  s.db,
+	}
+}
+
+func (s *boltStorage) Organizations() storage.OrganizationStorage {
+	return &boltOrganizationStorage{
+		log: s.log,
+		db:  s.db,
+	}
+}
+
+func (s *boltStorage) RateLimits() storage.RateLimitStorage {
+	return &boltRateLimitStorage{
+		log: s.log,
+		db:  s.db,
+	}
+}
+
+func (s *boltStorage) Subscribe(name string, fn func(c *storage.Change)) error {
+	return storage.ErrChangesNotSupported
+}
+
+type boltOwnerStorage struct {
+	log   zerolog.Logger
+	owner string
+	db    *bbolt.DB
+}
+
+func (s *boltOwnerStorage) Projects() storage.ProjectStorage {
+	return &boltProjectStorage{
+		log:   s.log,
+		owner: s.owner,
+		db:    s.db,
+	}
+}
+
+// keySep separates key parts. Zero byte never appears in codes and IDs coming from the API,
+// the api engine accepts only letters, digits, `_`, `.` and `-` in codes and generates IDs,
+// so keys of an entity children always start with the entity key followed by keySep.
+const keySep = "\x00"
+
+// key joins key parts
+func key(parts ...string) []byte {
+	return []byte(strings.Join(parts, keySep))
+}
+
+// prefix returns the common prefix of keys starting with the parts
+func prefix(parts ...string) []byte {
+	return []byte(strings.Join(parts, keySep) + keySep)
+}
+
+// get decodes the entity stored under the key
+func get(tx *bbolt.Tx, bucket string, k []byte, v interface{}) error {
+	data := tx.Bucket([]byte(bucket)).Get(k)
+	if data == nil {
+		return storage.ErrNotFound
+	}
+	return bson.Unmarshal(data, v)
+}
+
+// put stores the entity under the key
+func put(tx *bbolt.Tx, bucket string, k []byte, v interface{}) error {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(bucket)).Put(k, data)
+}
+
+// insert stores a new entity and returns *storage.ErrUniqueIndex if the key is taken
+func insert(tx *bbolt.Tx, bucket string, k []byte, v interface{}, typ, code string) error {
+	if tx.Bucket([]byte(bucket)).Get(k) != nil {
+		return &storage.ErrUniqueIndex{Type: typ, Key: code}
+	}
+	return put(tx, bucket, k, v)
+}
+
+// storedVersion returns version of the entity stored under the key
+func storedVersion(tx *bbolt.Tx, bucket string, k []byte) (int, error) {
+	var v struct {
+		Version int `bson:"version"`
+	}
+	if err := get(tx, bucket, k, &v); err != nil {
+		return 0, err
+	}
+	return v.Version, nil
+}
+
+// replace stores the entity only if the stored one has the expected version
+func replace(tx *bbolt.Tx, bucket string, k []byte, version int, v interface{}) error {
+	stored, err := storedVersion(tx, bucket, k)
+	if err != nil {
+		return err
+	}
+	if stored != version {
+		return storage.ErrVersionConflict
+	}
+	return put(tx, bucket, k, v)
+}
+
+// remove deletes the entity if it has the expected version. Zero version is unconditional.
+func remove(tx *bbolt.Tx, bucket string, k []byte, version int) error {
+	stored, err := storedVersion(tx, bucket, k)
+	if err != nil {
+		return err
+	}
+	if version > 0 && stored != version {
+		return storage.ErrVersionConflict
+	}
+	return tx.Bucket([]byte(bucket)).Delete(k)
+}
+
+// scan calls fn for every entity having the key prefix in key order
+func scan(tx *bbolt.Tx, bucket string, p []byte, fn func(data []byte) error) error {
+	c := tx.Bucket([]byte(bucket)).Cursor()
+	for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// empty reports whether there are no entities having the key prefix
+func empty(tx *bbolt.Tx, bucket string, p []byte) bool {
+	k, _ := tx.Bucket([]byte(bucket)).Cursor().Seek(p)
+	return k == nil || !bytes.HasPrefix(k, p)
+}
+
+// removePrefix deletes all entities having the key prefix and returns their number
+func removePrefix(tx *bbolt.Tx, bucket string, p []byte) (int, error) {
+	b := tx.Bucket([]byte(bucket))
+	var keys [][]byte
+	c := b.Cursor()
+	for k, _ := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, _ = c.Next() {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
@@ -0,0 +1,106 @@
+package bolt
+
+import (
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// countKey orders counters by hour and variant within the parameter
+func countKey(c *domain.EvaluationCount) []byte {
+	return key(c.Owner, c.Project, c.Environment, c.Parameter, c.Hour.UTC().Format(time.RFC3339), c.Variant)
+}
+
+type boltEvaluationCountStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	db      *bbolt.DB
+}
+
+func (s *boltEvaluationCountStorage) List(param string, from, to time.Time) ([]*domain.EvaluationCount, error) {
+	list := make([]*domain.EvaluationCount, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, evaluationCountBucket, prefix(s.owner, s.project, s.env, param), func(data []byte) error {
+			var item domain.EvaluationCount
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			if !item.Hour.Before(from) && item.Hour.Before(to) {
+				list = append(list, &item)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *boltEvaluationCountStorage) LastEvaluated() (map[string]time.Time, error) {
+	res := map[string]time.Time{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, evaluationCountBucket, prefix(s.owner, s.project, s.env), func(data []byte) error {
+			var item domain.EvaluationCount
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			if last, ok := res[item.Parameter]; !ok || item.Last.After(last) {
+				res[item.Parameter] = item.Last
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return res, nil
+}
+
+type boltEvaluationCountSink struct {
+	log zerolog.Logger
+	db  *bbolt.DB
+}
+
+func (s *boltEvaluationCountSink) Add(counts []*domain.EvaluationCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	var added int
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		for _, c := range counts {
+			k := countKey(c)
+			var stored domain.EvaluationCount
+			err := get(tx, evaluationCountBucket, k, &stored)
+			switch err {
+			case nil:
+				stored.Count += c.Count
+				if c.Last.After(stored.Last) {
+					stored.Last = c.Last
+				}
+			case storage.ErrNotFound:
+				stored = *c
+				added++
+			default:
+				return err
+			}
+			if err := put(tx, evaluationCountBucket, k, &stored); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Int("matched", len(counts)-added).Int("upserted", added).Msg("Evaluation counters added")
+	return nil
+}
@@ -0,0 +1,86 @@
+package bolt
+
+import (
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+	bbolt "go.etcd.io/bbolt"
+)
+
+type boltChangeRequestStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	db      *bbolt.DB
+}
+
+func (s *boltChangeRequestStorage) List(status string) ([]*domain.ChangeRequest, error) {
+	list := make([]*domain.ChangeRequest, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, changeRequestBucket, prefix(s.owner, s.project), func(data []byte) error {
+			var item domain.ChangeRequest
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			if status == "" || item.Status == status {
+				list = append(list, &item)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *boltChangeRequestStorage) Get(id string) (cr *domain.ChangeRequest, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return get(tx, changeRequestBucket, key(s.owner, s.project, id), &cr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+func (s *boltChangeRequestStorage) checkRelations(cr *domain.ChangeRequest) error {
+	if s.owner != cr.Owner || s.project != cr.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, cr.Owner, cr.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *boltChangeRequestStorage) Save(cr *domain.ChangeRequest) error {
+	if err := s.checkRelations(cr); err != nil {
+		return err
+	}
+	cr.Version = 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return insert(tx, changeRequestBucket, key(s.owner, s.project, cr.ID), cr, "change request", cr.ID)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", cr.ID).Msg("Change request inserted")
+	return nil
+}
+
+func (s *boltChangeRequestStorage) Update(cr *domain.ChangeRequest) error {
+	if err := s.checkRelations(cr); err != nil {
+		return err
+	}
+	version := cr.Version
+	cr.Version = version + 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return replace(tx, changeRequestBucket, key(s.owner, s.project, cr.ID), version, cr)
+	})
+	if err != nil {
+		cr.Version = version
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package bolt
+
+import (
+	"sort"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+	bbolt "go.etcd.io/bbolt"
+)
+
+type boltChangesetStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	db      *bbolt.DB
+}
+
+func (s *boltChangesetStorage) List(limit int) ([]*domain.Changeset, error) {
+	list := make([]*domain.Changeset, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, changesetBucket, prefix(s.owner, s.project, s.env), func(data []byte) error {
+			var item domain.Changeset
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			list = append(list, &item)
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].RegDate.After(list[j].RegDate) })
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (s *boltChangesetStorage) Get(id string) (cs *domain.Changeset, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return get(tx, changesetBucket, key(s.owner, s.project, s.env, id), &cs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+func (s *boltChangesetStorage) checkRelations(cs *domain.Changeset) error {
+	if s.owner != cs.Owner || s.project != cs.Project || s.env != cs.Environment {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s/%s, got: %s/%s/%s", s.owner, s.project, s.env, cs.Owner, cs.Project, cs.Environment)
+		return storage.ErrEntityRelationsBroken
+	}
+	for _, item := range cs.Changes {
+		p := item.Parameter
+		if p != nil && (s.owner != p.Owner || s.project != p.Project || s.env != p.Environment || item.ParameterCode != p.Code) {
+			s.log.Error().Str("param", item.ParameterCode).Msg("Wrong changeset parameter relations")
+			return storage.ErrEntityRelationsBroken
+		}
+	}
+	return nil
+}
+
+// Apply writes all changes in a single transaction, so nothing is written if any change fails
+func (s *boltChangesetStorage) Apply(cs *domain.Changeset) error {
+	if err := s.checkRelations(cs); err != nil {
+		return err
+	}
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		for _, item := range cs.Changes {
+			if err := s.write(tx, item); err != nil {
+				return err
+			}
+		}
+		err := insert(tx, changesetBucket, key(s.owner, s.project, s.env, cs.ID), cs, "changeset", cs.ID)
+		if _, ok := err.(*storage.ErrUniqueIndex); ok {
+			return storage.ErrVersionConflict
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", cs.ID).Int("changes", len(cs.Changes)).Msg("Changeset applied")
+	return nil
+}
+
+// write applies a single parameter change
+func (s *boltChangesetStorage) write(tx *bbolt.Tx, item *domain.ChangesetItem) error {
+	k := key(s.owner, s.project, s.env, item.ParameterCode)
+	version, err := storedVersion(tx, parameterBucket, k)
+	if err != nil && err != storage.ErrNotFound {
+		return err
+	}
+	switch {
+	case item.Action == domain.ChangeActionCreate && err == nil:
+		return storage.ErrVersionConflict
+	case item.Action == domain.ChangeActionCreate:
+		item.Parameter.Version = 1
+		return put(tx, parameterBucket, k, item.Parameter)
+	case err != nil:
+		return err
+	case item.BaseVersion > 0 && item.BaseVersion != version:
+		return storage.ErrVersionConflict
+	case item.Action == domain.ChangeActionDelete:
+		return tx.Bucket([]byte(parameterBucket)).Delete(k)
+	}
+	item.Parameter.Version = version + 1
+	return put(tx, parameterBucket, k, item.Parameter)
+}
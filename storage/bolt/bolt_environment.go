@@ -0,0 +1,153 @@
+package bolt
+
+import (
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+	bbolt "go.etcd.io/bbolt"
+)
+
+type boltEnvironmentStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	db      *bbolt.DB
+}
+
+func (s *boltEnvironmentStorage) key(code string) []byte {
+	return key(s.owner, s.project, code)
+}
+
+func (s *boltEnvironmentStorage) List() ([]*domain.Environment, error) {
+	list := make([]*domain.Environment, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, environmentBucket, prefix(s.owner, s.project), func(data []byte) error {
+			var item domain.Environment
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			list = append(list, &item)
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *boltEnvironmentStorage) Get(code string) (env *domain.Environment, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return get(tx, environmentBucket, s.key(code), &env)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+func (s *boltEnvironmentStorage) Delete(code string, version int) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		if err := remove(tx, environmentBucket, s.key(code), version); err != nil {
+			return err
+		}
+		s.log.Debug().Str("code", code).Msg("Environment deleted")
+		for _, name := range []string{parameterBucket, evaluationCountBucket, changesetBucket} {
+			n, err := removePrefix(tx, name, prefix(s.owner, s.project, code))
+			if err != nil {
+				return err
+			}
+			s.log.Debug().Int("count", n).Str("bucket", name).Msg("Environment children deleted")
+		}
+		return nil
+	})
+}
+
+func (s *boltEnvironmentStorage) checkRelations(env *domain.Environment) error {
+	if s.owner != env.Owner || s.project != env.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, env.Owner, env.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *boltEnvironmentStorage) Save(env *domain.Environment) error {
+	if err := s.checkRelations(env); err != nil {
+		return err
+	}
+	env.Version = 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return insert(tx, environmentBucket, s.key(env.Code), env, "environment", env.Code)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("code", env.Code).Msg("Environment inserted")
+	return nil
+}
+
+func (s *boltEnvironmentStorage) Update(env *domain.Environment) error {
+	if err := s.checkRelations(env); err != nil {
+		return err
+	}
+	version := env.Version
+	env.Version = version + 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return replace(tx, environmentBucket, s.key(env.Code), version, env)
+	})
+	if err != nil {
+		env.Version = version
+		return err
+	}
+	return nil
+}
+
+func (s *boltEnvironmentStorage) For(env string) storage.ForEnvironment {
+	return &boltForEnvironmentStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     env,
+		db:      s.db,
+	}
+}
+
+type boltForEnvironmentStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	db      *bbolt.DB
+}
+
+func (s *boltForEnvironmentStorage) Parameters() storage.ParameterStorage {
+	return &boltParameterStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     s.env,
+		db:      s.db,
+	}
+}
+
+func (s *boltForEnvironmentStorage) EvaluationCounts() storage.EvaluationCountStorage {
+	return &boltEvaluationCountStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     s.env,
+		db:      s.db,
+	}
+}
+
+func (s *boltForEnvironmentStorage) Changesets() storage.ChangesetStorage {
+	return &boltChangesetStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     s.env,
+		db:      s.db,
+	}
+}
@@ -0,0 +1,66 @@
+package bolt
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/Toggly/core/domain"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Owners reads project, organization and rate limit keys only, which start with the owner
+func (s *boltStorage) Owners() ([]string, error) {
+	set := map[string]bool{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		for _, name := range []string{projectBucket, organizationBucket, rateLimitBucket} {
+			c := tx.Bucket([]byte(name)).Cursor()
+			for k, _ := c.First(); k != nil; k, _ = c.Next() {
+				set[string(bytes.SplitN(k, []byte(keySep), 2)[0])] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	owners := make([]string, 0, len(set))
+	for owner := range set {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	return owners, nil
+}
+
+func (s *boltStorage) Import(entity interface{}) error {
+	var bucket, typ, code string
+	var k []byte
+	switch e := entity.(type) {
+	case *domain.Organization:
+		bucket, typ, code, k = organizationBucket, "organization", e.ID, key(e.ID)
+	case *domain.RateLimit:
+		bucket, typ, code, k = rateLimitBucket, "rate limit", e.Owner, key(e.Owner)
+	case *domain.Project:
+		bucket, typ, code, k = projectBucket, "project", e.Code, key(e.Owner, e.Code)
+	case *domain.Environment:
+		bucket, typ, code, k = environmentBucket, "environment", e.Code, key(e.Owner, e.Project, e.Code)
+	case *domain.Parameter:
+		bucket, typ, code, k = parameterBucket, "parameter", e.Code, key(e.Owner, e.Project, e.Environment, e.Code)
+	case *domain.Changeset:
+		bucket, typ, code, k = changesetBucket, "changeset", e.ID, key(e.Owner, e.Project, e.Environment, e.ID)
+	case *domain.ChangeRequest:
+		bucket, typ, code, k = changeRequestBucket, "change request", e.ID, key(e.Owner, e.Project, e.ID)
+	case *domain.ScheduledChange:
+		bucket, typ, code, k = scheduledChangeBucket, "scheduled change", e.ID, key(e.Owner, e.Project, e.ID)
+	case *domain.Webhook:
+		bucket, typ, code, k = webhookBucket, "webhook", e.ID, key(e.Owner, e.Project, e.ID)
+	case *domain.WebhookDelivery:
+		bucket, typ, code, k = webhookDeliveryBucket, "webhook delivery", e.ID, key(e.Owner, e.Project, e.ID)
+	default:
+		return fmt.Errorf("Can't import %T", entity)
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return insert(tx, bucket, k, entity, typ, code)
+	})
+}
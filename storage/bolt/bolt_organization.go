@@ -0,0 +1,83 @@
+package bolt
+
+import (
+	"sort"
+
+	"github.com/Toggly/core/domain"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+	bbolt "go.etcd.io/bbolt"
+)
+
+type boltOrganizationStorage struct {
+	log zerolog.Logger
+	db  *bbolt.DB
+}
+
+func (s *boltOrganizationStorage) List(principal string) ([]*domain.Organization, error) {
+	list := make([]*domain.Organization, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, organizationBucket, nil, func(data []byte) error {
+			var item domain.Organization
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			if item.Member(principal) != nil {
+				list = append(list, &item)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+func (s *boltOrganizationStorage) Get(id string) (org *domain.Organization, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return get(tx, organizationBucket, key(id), &org)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (s *boltOrganizationStorage) Delete(id string, version int) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return remove(tx, organizationBucket, key(id), version)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", id).Msg("Organization deleted")
+	return nil
+}
+
+func (s *boltOrganizationStorage) Save(org *domain.Organization) error {
+	org.Version = 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return insert(tx, organizationBucket, key(org.ID), org, "organization", org.ID)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", org.ID).Msg("Organization inserted")
+	return nil
+}
+
+func (s *boltOrganizationStorage) Update(org *domain.Organization) error {
+	version := org.Version
+	org.Version = version + 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return replace(tx, organizationBucket, key(org.ID), version, org)
+	})
+	if err != nil {
+		org.Version = version
+		return err
+	}
+	return nil
+}
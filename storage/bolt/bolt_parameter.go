@@ -0,0 +1,101 @@
+package bolt
+
+import (
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+	bbolt "go.etcd.io/bbolt"
+)
+
+type boltParameterStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	db      *bbolt.DB
+}
+
+func (s *boltParameterStorage) key(code string) []byte {
+	return key(s.owner, s.project, s.env, code)
+}
+
+func (s *boltParameterStorage) List() ([]*domain.Parameter, error) {
+	list := make([]*domain.Parameter, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, parameterBucket, prefix(s.owner, s.project, s.env), func(data []byte) error {
+			var item domain.Parameter
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			list = append(list, &item)
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *boltParameterStorage) Get(code string) (param *domain.Parameter, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return get(tx, parameterBucket, s.key(code), &param)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return param, nil
+}
+
+func (s *boltParameterStorage) Delete(code string, version int) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return remove(tx, parameterBucket, s.key(code), version)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("code", code).Msg("Parameter deleted")
+	return nil
+}
+
+func (s *boltParameterStorage) checkRelations(param *domain.Parameter) error {
+	if s.owner != param.Owner || s.project != param.Project || s.env != param.Environment {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s/%s, got: %s/%s/%s",
+			s.owner, s.project, s.env, param.Owner, param.Project, param.Environment)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *boltParameterStorage) Save(param *domain.Parameter) error {
+	if err := s.checkRelations(param); err != nil {
+		return err
+	}
+	param.Version = 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return insert(tx, parameterBucket, s.key(param.Code), param, "parameter", param.Code)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("code", param.Code).Msg("Parameter inserted")
+	return nil
+}
+
+func (s *boltParameterStorage) Update(param *domain.Parameter) error {
+	if err := s.checkRelations(param); err != nil {
+		return err
+	}
+	version := param.Version
+	param.Version = version + 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return replace(tx, parameterBucket, s.key(param.Code), version, param)
+	})
+	if err != nil {
+		param.Version = version
+		return err
+	}
+	return nil
+}
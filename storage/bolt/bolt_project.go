@@ -0,0 +1,161 @@
+package bolt
+
+import (
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+	bbolt "go.etcd.io/bbolt"
+)
+
+type boltProjectStorage struct {
+	log   zerolog.Logger
+	owner string
+	db    *bbolt.DB
+}
+
+func (s *boltProjectStorage) List() ([]*domain.Project, error) {
+	list := make([]*domain.Project, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, projectBucket, prefix(s.owner), func(data []byte) error {
+			var item domain.Project
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			list = append(list, &item)
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *boltProjectStorage) Get(code string) (project *domain.Project, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return get(tx, projectBucket, key(s.owner, code), &project)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (s *boltProjectStorage) Delete(code string, version int, cascade bool) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		if !cascade && !empty(tx, environmentBucket, prefix(s.owner, code)) {
+			if _, err := storedVersion(tx, projectBucket, key(s.owner, code)); err != nil {
+				return err
+			}
+			return storage.ErrNotEmpty
+		}
+		if err := remove(tx, projectBucket, key(s.owner, code), version); err != nil {
+			return err
+		}
+		s.log.Debug().Str("code", code).Msg("Project deleted")
+		for _, name := range projectChildBuckets {
+			n, err := removePrefix(tx, name, prefix(s.owner, code))
+			if err != nil {
+				return err
+			}
+			s.log.Debug().Int("count", n).Str("bucket", name).Msg("Project children deleted")
+		}
+		return nil
+	})
+}
+
+func (s *boltProjectStorage) Save(project *domain.Project) error {
+	if s.owner != project.Owner {
+		s.log.Error().Msgf("Wrong owner. Expected: %s, got: %s", s.owner, project.Owner)
+		return storage.ErrEntityRelationsBroken
+	}
+	project.Version = 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return insert(tx, projectBucket, key(s.owner, project.Code), project, "project", project.Code)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("code", project.Code).Msg("Project inserted")
+	return nil
+}
+
+func (s *boltProjectStorage) Update(project *domain.Project) error {
+	if s.owner != project.Owner {
+		s.log.Error().Msgf("Wrong owner. Expected: %s, got: %s", s.owner, project.Owner)
+		return storage.ErrEntityRelationsBroken
+	}
+	version := project.Version
+	project.Version = version + 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return replace(tx, projectBucket, key(s.owner, project.Code), version, project)
+	})
+	if err != nil {
+		project.Version = version
+		return err
+	}
+	return nil
+}
+
+func (s *boltProjectStorage) For(project string) storage.ForProject {
+	return &boltForProjectStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: project,
+		db:      s.db,
+	}
+}
+
+type boltForProjectStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	db      *bbolt.DB
+}
+
+func (s *boltForProjectStorage) Environments() storage.EnvironmentStorage {
+	return &boltEnvironmentStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		db:      s.db,
+	}
+}
+
+func (s *boltForProjectStorage) ChangeRequests() storage.ChangeRequestStorage {
+	return &boltChangeRequestStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		db:      s.db,
+	}
+}
+
+func (s *boltForProjectStorage) ScheduledChanges() storage.ScheduledChangeStorage {
+	return &boltScheduledChangeStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		db:      s.db,
+	}
+}
+
+func (s *boltForProjectStorage) Webhooks() storage.WebhookStorage {
+	return &boltWebhookStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		db:      s.db,
+	}
+}
+
+func (s *boltForProjectStorage) WebhookDeliveries() storage.WebhookDeliveryStorage {
+	return &boltWebhookDeliveryStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		db:      s.db,
+	}
+}
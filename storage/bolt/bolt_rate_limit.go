@@ -0,0 +1,78 @@
+package bolt
+
+import (
+	"github.com/Toggly/core/domain"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+	bbolt "go.etcd.io/bbolt"
+)
+
+type boltRateLimitStorage struct {
+	log zerolog.Logger
+	db  *bbolt.DB
+}
+
+func (s *boltRateLimitStorage) List() ([]*domain.RateLimit, error) {
+	list := make([]*domain.RateLimit, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, rateLimitBucket, nil, func(data []byte) error {
+			var item domain.RateLimit
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			list = append(list, &item)
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *boltRateLimitStorage) Get(owner string) (limit *domain.RateLimit, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return get(tx, rateLimitBucket, key(owner), &limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return limit, nil
+}
+
+func (s *boltRateLimitStorage) Delete(owner string, version int) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return remove(tx, rateLimitBucket, key(owner), version)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("owner", owner).Msg("Rate limit deleted")
+	return nil
+}
+
+func (s *boltRateLimitStorage) Save(limit *domain.RateLimit) error {
+	limit.Version = 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return insert(tx, rateLimitBucket, key(limit.Owner), limit, "rate limit", limit.Owner)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("owner", limit.Owner).Msg("Rate limit inserted")
+	return nil
+}
+
+func (s *boltRateLimitStorage) Update(limit *domain.RateLimit) error {
+	version := limit.Version
+	limit.Version = version + 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return replace(tx, rateLimitBucket, key(limit.Owner), version, limit)
+	})
+	if err != nil {
+		limit.Version = version
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package bolt
+
+import (
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+	bbolt "go.etcd.io/bbolt"
+)
+
+type boltScheduledChangeStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	db      *bbolt.DB
+}
+
+func (s *boltScheduledChangeStorage) List(status string) ([]*domain.ScheduledChange, error) {
+	list := make([]*domain.ScheduledChange, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, scheduledChangeBucket, prefix(s.owner, s.project), func(data []byte) error {
+			var item domain.ScheduledChange
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			if status == "" || item.Status == status {
+				list = append(list, &item)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *boltScheduledChangeStorage) Get(id string) (sc *domain.ScheduledChange, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return get(tx, scheduledChangeBucket, key(s.owner, s.project, id), &sc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+func (s *boltScheduledChangeStorage) checkRelations(sc *domain.ScheduledChange) error {
+	if s.owner != sc.Owner || s.project != sc.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, sc.Owner, sc.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *boltScheduledChangeStorage) Save(sc *domain.ScheduledChange) error {
+	if err := s.checkRelations(sc); err != nil {
+		return err
+	}
+	sc.Version = 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return insert(tx, scheduledChangeBucket, key(s.owner, s.project, sc.ID), sc, "scheduled change", sc.ID)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", sc.ID).Msg("Scheduled change inserted")
+	return nil
+}
+
+func (s *boltScheduledChangeStorage) Update(sc *domain.ScheduledChange) error {
+	if err := s.checkRelations(sc); err != nil {
+		return err
+	}
+	version := sc.Version
+	sc.Version = version + 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return replace(tx, scheduledChangeBucket, key(s.owner, s.project, sc.ID), version, sc)
+	})
+	if err != nil {
+		sc.Version = version
+		return err
+	}
+	return nil
+}
+
+type boltScheduledChangeQueue struct {
+	log zerolog.Logger
+	db  *bbolt.DB
+}
+
+// Claim scans all scheduled changes, which is fine for the number of them a single file keeps
+func (s *boltScheduledChangeQueue) Claim(now time.Time, worker string, lease time.Duration) (*domain.ScheduledChange, error) {
+	var sc *domain.ScheduledChange
+	var k []byte
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		c := tx.Bucket([]byte(scheduledChangeBucket)).Cursor()
+		for ik, data := c.First(); ik != nil; ik, data = c.Next() {
+			var item domain.ScheduledChange
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			due := item.Status == domain.ScheduledChangeStatusPending && !item.At.After(now) ||
+				item.Status == domain.ScheduledChangeStatusRunning && item.LockedUntil.Before(now)
+			if due && (sc == nil || item.At.Before(sc.At)) {
+				sc, k = &item, ik
+			}
+		}
+		if sc == nil {
+			return storage.ErrNotFound
+		}
+		sc.Status = domain.ScheduledChangeStatusRunning
+		sc.LockedBy = worker
+		sc.LockedUntil = now.Add(lease)
+		sc.Version++
+		return put(tx, scheduledChangeBucket, k, sc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log.Debug().Str("id", sc.ID).Str("worker", worker).Msg("Scheduled change claimed")
+	return sc, nil
+}
@@ -0,0 +1,139 @@
+package bolt_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	asserts "github.com/stretchr/testify/assert"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/storage/bolt"
+	"github.com/Toggly/core/storage/storagetest"
+)
+
+var logger = log.Output(zerolog.ConsoleWriter{
+	Out:     os.Stdout,
+	NoColor: true,
+}).Level(zerolog.DebugLevel)
+
+var (
+	dir         string
+	dataStorage storage.DataStorage
+	closeDB     context.CancelFunc
+)
+
+func openDB(path string) (storage.DataStorage, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := bolt.NewBoltDataStorage(ctx, path, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't create storage")
+	}
+	err = s.Connect()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't open")
+	}
+	return s, cancel
+}
+
+// getDB returns storage opened in the test directory, the same one until afterTest
+func getDB() storage.DataStorage {
+	if dataStorage == nil {
+		dataStorage, closeDB = openDB(filepath.Join(dir, "toggly.db"))
+	}
+	return dataStorage
+}
+
+func beforeTest() {
+	var err error
+	dir, err = ioutil.TempDir("", "toggly_storage_test")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't create test directory")
+	}
+}
+
+func afterTest() {
+	if closeDB != nil {
+		closeDB()
+	}
+	dataStorage, closeDB = nil, nil
+	os.RemoveAll(dir)
+}
+
+func TestBoltStorage(t *testing.T) {
+	assert := asserts.New(t)
+
+	beforeTest()
+
+	path := filepath.Join(dir, "toggly.db")
+	db, cancel := openDB(path)
+
+	p := &domain.Project{Code: "proj1", Owner: "ow1", Status: domain.ProjectStatusActive}
+	assert.Nil(db.ForOwner("ow1").Projects().Save(p))
+
+	t.Run("owner isolation", func(t *testing.T) {
+		_, err := db.ForOwner("ow2").Projects().Get("proj1")
+		assert.Equal(storage.ErrNotFound, err)
+		list, err := db.ForOwner("ow2").Projects().List()
+		assert.Nil(err)
+		assert.Len(list, 0)
+		p2 := &domain.Project{Code: "proj1", Owner: "ow2", Status: domain.ProjectStatusActive}
+		assert.Nil(db.ForOwner("ow2").Projects().Save(p2))
+		assert.Nil(db.ForOwner("ow2").Projects().Delete("proj1", 0, true))
+		_, err = db.ForOwner("ow1").Projects().Get("proj1")
+		assert.Nil(err)
+	})
+
+	t.Run("owner prefix", func(t *testing.T) {
+		list, err := db.ForOwner("ow").Projects().List()
+		assert.Nil(err)
+		assert.Len(list, 0)
+	})
+
+	t.Run("owners", func(t *testing.T) {
+		assert.Nil(db.Organizations().Save(&domain.Organization{ID: "org1", Name: "Org 1"}))
+		assert.Nil(db.RateLimits().Save(&domain.RateLimit{Owner: "ow3"}))
+		owners, err := db.Owners()
+		assert.Nil(err)
+		assert.Equal([]string{"org1", "ow1", "ow3"}, owners)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		env := &domain.Environment{Code: "env1", Owner: "ow1", Project: "proj1", Version: 5}
+		assert.Nil(db.(storage.Importer).Import(env))
+		res, err := db.ForOwner("ow1").Projects().For("proj1").Environments().Get("env1")
+		assert.Nil(err)
+		assert.Equal(5, res.Version)
+		assert.Equal(&storage.ErrUniqueIndex{Type: "environment", Key: "env1"}, db.(storage.Importer).Import(env))
+	})
+
+	t.Run("subscribe", func(t *testing.T) {
+		assert.Equal(storage.ErrChangesNotSupported, db.Subscribe("test", func(c *storage.Change) {}))
+	})
+
+	t.Run("reopen", func(t *testing.T) {
+		cancel()
+		db, cancel = openDB(path)
+		res, err := db.ForOwner("ow1").Projects().Get("proj1")
+		assert.Nil(err)
+		assert.Equal(1, res.Version)
+	})
+
+	cancel()
+	afterTest()
+}
+
+func TestBoltConformance(t *testing.T) {
+	storagetest.Run(t, &storagetest.Backend{
+		Open: func() storage.DataStorage {
+			beforeTest()
+			return getDB()
+		},
+		Close: afterTest,
+	})
+}
@@ -0,0 +1,252 @@
+package bolt
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/rs/zerolog"
+	bbolt "go.etcd.io/bbolt"
+)
+
+type boltWebhookStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	db      *bbolt.DB
+}
+
+func (s *boltWebhookStorage) key(id string) []byte {
+	return key(s.owner, s.project, id)
+}
+
+func (s *boltWebhookStorage) List() ([]*domain.Webhook, error) {
+	list := make([]*domain.Webhook, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, webhookBucket, prefix(s.owner, s.project), func(data []byte) error {
+			var item domain.Webhook
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			list = append(list, &item)
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *boltWebhookStorage) Get(id string) (hook *domain.Webhook, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return get(tx, webhookBucket, s.key(id), &hook)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (s *boltWebhookStorage) Delete(id string, version int) error {
+	var n int
+	err := s.db.Update(func(tx *bbolt.Tx) (err error) {
+		if err = remove(tx, webhookBucket, s.key(id), version); err != nil {
+			return err
+		}
+		n, err = s.removeDeliveries(tx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", id).Int("deliveries", n).Msg("Webhook deleted")
+	return nil
+}
+
+// removeDeliveries deletes deliveries of the webhook and returns their number
+func (s *boltWebhookStorage) removeDeliveries(tx *bbolt.Tx, id string) (int, error) {
+	var keys [][]byte
+	err := scan(tx, webhookDeliveryBucket, prefix(s.owner, s.project), func(data []byte) error {
+		var d domain.WebhookDelivery
+		if err := bson.Unmarshal(data, &d); err != nil {
+			return err
+		}
+		if d.Webhook == id {
+			keys = append(keys, key(s.owner, s.project, d.ID))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	b := tx.Bucket([]byte(webhookDeliveryBucket))
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+func (s *boltWebhookStorage) checkRelations(hook *domain.Webhook) error {
+	if s.owner != hook.Owner || s.project != hook.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, hook.Owner, hook.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *boltWebhookStorage) Save(hook *domain.Webhook) error {
+	if err := s.checkRelations(hook); err != nil {
+		return err
+	}
+	hook.Version = 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return insert(tx, webhookBucket, s.key(hook.ID), hook, "webhook", hook.ID)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", hook.ID).Msg("Webhook inserted")
+	return nil
+}
+
+func (s *boltWebhookStorage) Update(hook *domain.Webhook) error {
+	if err := s.checkRelations(hook); err != nil {
+		return err
+	}
+	version := hook.Version
+	hook.Version = version + 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return replace(tx, webhookBucket, s.key(hook.ID), version, hook)
+	})
+	if err != nil {
+		hook.Version = version
+		return err
+	}
+	return nil
+}
+
+type boltWebhookDeliveryStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	db      *bbolt.DB
+}
+
+func (s *boltWebhookDeliveryStorage) List(webhook string, limit int) ([]*domain.WebhookDelivery, error) {
+	list := make([]*domain.WebhookDelivery, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scan(tx, webhookDeliveryBucket, prefix(s.owner, s.project), func(data []byte) error {
+			var item domain.WebhookDelivery
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			if item.Webhook == webhook {
+				list = append(list, &item)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].RegDate.After(list[j].RegDate) })
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (s *boltWebhookDeliveryStorage) checkRelations(d *domain.WebhookDelivery) error {
+	if s.owner != d.Owner || s.project != d.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, d.Owner, d.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *boltWebhookDeliveryStorage) Save(d *domain.WebhookDelivery) error {
+	if err := s.checkRelations(d); err != nil {
+		return err
+	}
+	d.Version = 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return insert(tx, webhookDeliveryBucket, key(s.owner, s.project, d.ID), d, "webhook delivery", d.ID)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", d.ID).Msg("Webhook delivery inserted")
+	return nil
+}
+
+func (s *boltWebhookDeliveryStorage) Update(d *domain.WebhookDelivery) error {
+	if err := s.checkRelations(d); err != nil {
+		return err
+	}
+	version := d.Version
+	d.Version = version + 1
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return replace(tx, webhookDeliveryBucket, key(s.owner, s.project, d.ID), version, d)
+	})
+	if err != nil {
+		d.Version = version
+		return err
+	}
+	return nil
+}
+
+func (s *boltWebhookDeliveryStorage) Delete(id string, version int) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return remove(tx, webhookDeliveryBucket, key(s.owner, s.project, id), version)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", id).Msg("Webhook delivery deleted")
+	return nil
+}
+
+type boltWebhookDeliveryQueue struct {
+	log zerolog.Logger
+	db  *bbolt.DB
+}
+
+// Claim scans all webhook deliveries, which is fine for the number of them a single file keeps
+func (s *boltWebhookDeliveryQueue) Claim(now time.Time, worker string, lease time.Duration) (*domain.WebhookDelivery, error) {
+	var d *domain.WebhookDelivery
+	var k []byte
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		c := tx.Bucket([]byte(webhookDeliveryBucket)).Cursor()
+		for ik, data := c.First(); ik != nil; ik, data = c.Next() {
+			var item domain.WebhookDelivery
+			if err := bson.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			due := item.Status == domain.WebhookDeliveryStatusPending && !item.NextAttempt.After(now) ||
+				item.Status == domain.WebhookDeliveryStatusRunning && item.LockedUntil.Before(now)
+			if due && (d == nil || item.NextAttempt.Before(d.NextAttempt)) {
+				d, k = &item, ik
+			}
+		}
+		if d == nil {
+			return storage.ErrNotFound
+		}
+		d.Status = domain.WebhookDeliveryStatusRunning
+		d.LockedBy = worker
+		d.LockedUntil = now.Add(lease)
+		d.Version++
+		return put(tx, webhookDeliveryBucket, k, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log.Debug().Str("id", d.ID).Str("worker", worker).Msg("Webhook delivery claimed")
+	return d, nil
+}
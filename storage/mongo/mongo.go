@@ -6,9 +6,47 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
 	"github.com/mongodb/mongo-go-driver/mongo"
 )
 
+// Collection names
+const (
+	projectCollection         = "project"
+	environmentCollection     = "environment"
+	parameterCollection       = "parameter"
+	changeRequestCollection   = "change_request"
+	scheduledChangeCollection = "scheduled_change"
+	webhookCollection         = "webhook"
+	webhookDeliveryCollection = "webhook_delivery"
+	evaluationCountCollection = "evaluation_count"
+	changesetCollection       = "changeset"
+	organizationCollection    = "organization"
+	rateLimitCollection       = "rate_limit"
+)
+
+// projectChildCollections lists collections removed together with a project
+var projectChildCollections = []string{
+	environmentCollection,
+	parameterCollection,
+	changeRequestCollection,
+	scheduledChangeCollection,
+	webhookCollection,
+	webhookDeliveryCollection,
+	evaluationCountCollection,
+	changesetCollection,
+}
+
+// projectOwnCollections lists project children not belonging to environments. They are the only
+// children removed with a project deleted without cascade, so an environment created
+// after the emptiness check is left in place rather than removed silently.
+var projectOwnCollections = []string{
+	changeRequestCollection,
+	scheduledChangeCollection,
+	webhookCollection,
+	webhookDeliveryCollection,
+}
+
 // NewMongoDataStorage returns mongo storage implementation
 func NewMongoDataStorage(ctx context.Context, url, dbName string, log zerolog.Logger) (storage.DataStorage, error) {
 	client, err := mongo.NewClient(url)
@@ -37,10 +75,19 @@ type mongoStorage struct {
 	client *mongo.Client
 	log    zerolog.Logger
 	db     *mongo.Database
+	txn    bool
 }
 
 func (s *mongoStorage) Connect() error {
-	return s.client.Connect(s.ctx)
+	if err := s.client.Connect(s.ctx); err != nil {
+		return err
+	}
+	if err := ensureIndexes(s.ctx, s.db); err != nil {
+		return err
+	}
+	s.txn = transactionsSupported(s.ctx, s.db)
+	s.log.Debug().Bool("transactions", s.txn).Msg("Mongo storage connected")
+	return nil
 }
 
 func (s *mongoStorage) ForOwner(owner string) storage.OwnerStorage {
@@ -49,6 +96,47 @@ func (s *mongoStorage) ForOwner(owner string) storage.OwnerStorage {
 		owner: owner,
 		ctx:   s.ctx,
 		db:    s.db,
+		txn:   s.txn,
+	}
+}
+
+func (s *mongoStorage) ScheduledChanges() storage.ScheduledChangeQueue {
+	return &mongoScheduledChangeQueue{
+		log: s.log,
+		ctx: s.ctx,
+		db:  s.db,
+	}
+}
+
+func (s *mongoStorage) WebhookDeliveries() storage.WebhookDeliveryQueue {
+	return &mongoWebhookDeliveryQueue{
+		log: s.log,
+		ctx: s.ctx,
+		db:  s.db,
+	}
+}
+
+func (s *mongoStorage) EvaluationCounts() storage.EvaluationCountSink {
+	return &mongoEvaluationCountSink{
+		log: s.log,
+		ctx: s.ctx,
+		db:  s.db,
+	}
+}
+
+func (s *mongoStorage) Organizations() storage.OrganizationStorage {
+	return &mongoOrganizationStorage{
+		log: s.log,
+		ctx: s.ctx,
+		db:  s.db,
+	}
+}
+
+func (s *mongoStorage) RateLimits() storage.RateLimitStorage {
+	return &mongoRateLimitStorage{
+		log: s.log,
+		ctx: s.ctx,
+		db:  s.db,
 	}
 }
 
@@ -57,6 +145,7 @@ type mongoOwnerStorage struct {
 	owner string
 	ctx   context.Context
 	db    *mongo.Database
+	txn   bool
 }
 
 func (s *mongoOwnerStorage) Projects() storage.ProjectStorage {
@@ -65,5 +154,36 @@ func (s *mongoOwnerStorage) Projects() storage.ProjectStorage {
 		owner: s.owner,
 		ctx:   s.ctx,
 		db:    s.db,
+		txn:   s.txn,
+	}
+}
+
+// transactionsSupported checks if deployment is a replica set or a sharded cluster
+func transactionsSupported(ctx context.Context, db *mongo.Database) bool {
+	var res struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := db.RunCommand(ctx, bson.M{"isMaster": 1}).Decode(&res); err != nil {
+		return false
+	}
+	return res.SetName != "" || res.Msg == "isdbgrid"
+}
+
+// inTransaction runs fn in a multi-document transaction if txn is set.
+// Otherwise fn is executed as is, one operation after another.
+func inTransaction(ctx context.Context, db *mongo.Database, txn bool, fn func(ctx context.Context) error) error {
+	if !txn {
+		return fn(ctx)
 	}
+	return db.Client().UseSession(ctx, func(sc mongo.SessionContext) error {
+		if err := sc.StartTransaction(); err != nil {
+			return err
+		}
+		if err := fn(sc); err != nil {
+			sc.AbortTransaction(sc)
+			return err
+		}
+		return sc.CommitTransaction(sc)
+	})
 }
@@ -0,0 +1,117 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"github.com/rs/zerolog"
+)
+
+type mongoEvaluationCountStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	ctx     context.Context
+	db      *mongo.Database
+}
+
+func (s *mongoEvaluationCountStorage) List(param string, from, to time.Time) ([]*domain.EvaluationCount, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := bson.M{
+		"owner":       s.owner,
+		"project":     s.project,
+		"environment": s.env,
+		"parameter":   param,
+		"hour":        bson.M{"$gte": from, "$lt": to},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "hour", Value: 1}, {Key: "variant", Value: 1}})
+	cur, err := s.db.Collection(evaluationCountCollection).Find(ctxT, filter, opts)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer cur.Close(ctxT)
+	list := make([]*domain.EvaluationCount, 0)
+	for cur.Next(ctxT) {
+		var item domain.EvaluationCount
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		list = append(list, &item)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *mongoEvaluationCountStorage) LastEvaluated() (map[string]time.Time, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+	pipeline := []interface{}{
+		bson.M{"$match": bson.M{"owner": s.owner, "project": s.project, "environment": s.env}},
+		bson.M{"$group": bson.M{"_id": "$parameter", "last": bson.M{"$max": "$last"}}},
+	}
+	cur, err := s.db.Collection(evaluationCountCollection).Aggregate(ctxT, pipeline)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer cur.Close(ctxT)
+	res := map[string]time.Time{}
+	for cur.Next(ctxT) {
+		var item struct {
+			Parameter string    `bson:"_id"`
+			Last      time.Time `bson:"last"`
+		}
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		res[item.Parameter] = item.Last
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+type mongoEvaluationCountSink struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *mongo.Database
+}
+
+func (s *mongoEvaluationCountSink) Add(counts []*domain.EvaluationCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+
+	collection := s.db.Collection(evaluationCountCollection)
+	models := make([]mongo.WriteModel, 0, len(counts))
+	for _, c := range counts {
+		filter := bson.M{
+			"owner":       c.Owner,
+			"project":     c.Project,
+			"environment": c.Environment,
+			"parameter":   c.Parameter,
+			"variant":     c.Variant,
+			"hour":        c.Hour,
+		}
+		update := bson.M{"$inc": bson.M{"count": c.Count}, "$max": bson.M{"last": c.Last}}
+		models = append(models, mongo.NewUpdateOneModel().Filter(filter).Update(update).Upsert(true))
+	}
+	res, err := collection.BulkWrite(ctxT, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Int64("matched", res.MatchedCount).Int64("upserted", res.UpsertedCount).Msg("Evaluation counters added")
+	return nil
+}
@@ -0,0 +1,254 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/bsontype"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"github.com/mongodb/mongo-go-driver/x/network/command"
+)
+
+const (
+	// changeStreamCollection keeps resume tokens of subscriptions
+	changeStreamCollection = "change_stream"
+	// changeRetryInterval is the delay before a failed change stream is reopened
+	changeRetryInterval = 5 * time.Second
+	// codes of errors returned when the resume token is no longer in the oplog
+	errorChangeStreamFatal       = 280
+	errorChangeStreamHistoryLost = 286
+)
+
+// changeEntities maps watched collections to entity types
+var changeEntities = map[string]string{
+	projectCollection:      "project",
+	environmentCollection:  "environment",
+	parameterCollection:    "parameter",
+	webhookCollection:      "webhook",
+	organizationCollection: "organization",
+	rateLimitCollection:    "rate_limit",
+}
+
+// changeOperations maps change stream operation types to change operations
+var changeOperations = map[string]string{
+	"insert":  storage.ChangeInsert,
+	"update":  storage.ChangeUpdate,
+	"replace": storage.ChangeUpdate,
+	"delete":  storage.ChangeDelete,
+}
+
+// entityKey identifies a watched entity. Documents of watched collections are stored
+// with their key as _id, because delete events carry only the document _id.
+type entityKey struct {
+	ID          string `bson:"id,omitempty"`
+	Code        string `bson:"code,omitempty"`
+	Owner       string `bson:"owner,omitempty"`
+	Project     string `bson:"project,omitempty"`
+	Environment string `bson:"environment,omitempty"`
+}
+
+// keyOf returns the key of a watched entity or nil for other entities
+func keyOf(entity interface{}) *entityKey {
+	switch e := entity.(type) {
+	case *domain.Project:
+		return &entityKey{Owner: e.Owner, Code: e.Code}
+	case *domain.Environment:
+		return &entityKey{Owner: e.Owner, Project: e.Project, Code: e.Code}
+	case *domain.Parameter:
+		return &entityKey{Owner: e.Owner, Project: e.Project, Environment: e.Environment, Code: e.Code}
+	case *domain.Webhook:
+		return &entityKey{Owner: e.Owner, Project: e.Project, ID: e.ID}
+	case *domain.Organization:
+		return &entityKey{ID: e.ID}
+	case *domain.RateLimit:
+		return &entityKey{Owner: e.Owner}
+	}
+	return nil
+}
+
+// keyed returns the document to insert the entity as, with the key as _id for watched entities
+func keyed(entity interface{}) (interface{}, error) {
+	key := keyOf(entity)
+	if key == nil {
+		return entity, nil
+	}
+	data, err := bson.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	// Raw values keep the encoded types of the entity fields
+	elems, err := bson.Raw(data).Elements()
+	if err != nil {
+		return nil, err
+	}
+	doc := make(bson.D, 0, len(elems)+1)
+	doc = append(doc, primitive.E{Key: "_id", Value: key})
+	for _, el := range elems {
+		doc = append(doc, primitive.E{Key: el.Key(), Value: el.Value()})
+	}
+	return doc, nil
+}
+
+// insert stores a new entity, with its key as _id for watched entities
+func insert(ctx context.Context, coll *mongo.Collection, entity interface{}) error {
+	doc, err := keyed(entity)
+	if err != nil {
+		return err
+	}
+	_, err = coll.InsertOne(ctx, doc)
+	return err
+}
+
+// changeEvent is a change stream document. ID is the resume token.
+// FullDocument is missing in delete events, the key is taken from DocumentKey then.
+type changeEvent struct {
+	ID            bson.Raw `bson:"_id"`
+	OperationType string   `bson:"operationType"`
+	NS            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		ID bson.RawValue `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument *entityKey `bson:"fullDocument"`
+}
+
+func (e *changeEvent) change() *storage.Change {
+	doc := e.FullDocument
+	if doc == nil {
+		doc = &entityKey{}
+		// Documents written before keys were stored as _id have no key
+		if e.DocumentKey.ID.Type == bsontype.EmbeddedDocument {
+			e.DocumentKey.ID.Unmarshal(doc)
+		}
+	}
+	c := &storage.Change{
+		Entity:      changeEntities[e.NS.Coll],
+		Operation:   changeOperations[e.OperationType],
+		Owner:       doc.Owner,
+		Project:     doc.Project,
+		Environment: doc.Environment,
+		Key:         doc.Code,
+	}
+	if c.Key == "" {
+		c.Key = doc.ID
+	}
+	switch c.Entity {
+	case "organization":
+		c.Owner = doc.ID
+	case "rate_limit":
+		c.Key = doc.Owner
+	}
+	return c
+}
+
+// resumeToken is a stored subscription position
+type resumeToken struct {
+	Name    string    `bson:"_id"`
+	Token   bson.Raw  `bson:"token"`
+	Updated time.Time `bson:"updated"`
+}
+
+func (s *mongoStorage) Subscribe(name string, fn func(c *storage.Change)) error {
+	if !s.txn {
+		return storage.ErrChangesNotSupported
+	}
+	for {
+		err := s.watch(name, fn)
+		if s.ctx.Err() != nil {
+			return nil
+		}
+		if e, ok := err.(command.Error); ok && (e.Code == errorChangeStreamHistoryLost || e.Code == errorChangeStreamFatal) {
+			s.log.Warn().Err(err).Str("subscription", name).Msg("Change stream can't be resumed, changes may be lost")
+			if err := s.saveToken(name, nil); err != nil {
+				s.log.Error().Err(err).Str("subscription", name).Msg("Can't reset change stream position")
+			}
+			continue
+		}
+		s.log.Error().Err(err).Str("subscription", name).Msg("Change stream failed")
+		select {
+		case <-s.ctx.Done():
+			return nil
+		case <-time.After(changeRetryInterval):
+		}
+	}
+}
+
+// watch passes changes to fn until the stream fails or the storage context is done
+func (s *mongoStorage) watch(name string, fn func(c *storage.Change)) error {
+	colls := make([]string, 0, len(changeEntities))
+	for coll := range changeEntities {
+		colls = append(colls, coll)
+	}
+	ops := make([]string, 0, len(changeOperations))
+	for op := range changeOperations {
+		ops = append(ops, op)
+	}
+	pipeline := []interface{}{
+		bson.M{"$match": bson.M{"ns.coll": bson.M{"$in": colls}, "operationType": bson.M{"$in": ops}}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	token, err := s.loadToken(name)
+	if err != nil {
+		return err
+	}
+	if token != nil {
+		opts.SetResumeAfter(token)
+	}
+	cur, err := s.db.Watch(s.ctx, pipeline, opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(context.Background())
+	s.log.Debug().Str("subscription", name).Bool("resumed", token != nil).Msg("Change stream opened")
+	for cur.Next(s.ctx) {
+		// database streams can't be decoded by the cursor itself
+		raw, err := cur.DecodeBytes()
+		if err != nil {
+			return err
+		}
+		var e changeEvent
+		if err := bson.Unmarshal(raw, &e); err != nil {
+			return fmt.Errorf("Can't decode change: %v", err)
+		}
+		fn(e.change())
+		if err := s.saveToken(name, e.ID); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+func (s *mongoStorage) loadToken(name string) (bson.Raw, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	var t resumeToken
+	err := s.db.Collection(changeStreamCollection).FindOne(ctxT, bson.M{"_id": name}).Decode(&t)
+	switch err {
+	case nil:
+		return t.Token, nil
+	case mongo.ErrNoDocuments:
+		return nil, nil
+	}
+	return nil, err
+}
+
+// saveToken stores the subscription position. Nil token starts the subscription from now.
+func (s *mongoStorage) saveToken(name string, token bson.Raw) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	coll := s.db.Collection(changeStreamCollection)
+	if token == nil {
+		_, err := coll.DeleteOne(ctxT, bson.M{"_id": name})
+		return err
+	}
+	t := &resumeToken{Name: name, Token: token, Updated: time.Now().UTC()}
+	_, err := coll.ReplaceOne(ctxT, bson.M{"_id": name}, t, options.Replace().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,119 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/rs/zerolog"
+)
+
+type mongoChangeRequestStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *mongo.Database
+}
+
+func (s *mongoChangeRequestStorage) collection() *mongo.Collection {
+	return s.db.Collection(changeRequestCollection)
+}
+
+func (s *mongoChangeRequestStorage) filter(id string) bson.M {
+	return bson.M{"owner": s.owner, "project": s.project, "id": id}
+}
+
+func (s *mongoChangeRequestStorage) List(status string) ([]*domain.ChangeRequest, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := bson.M{"owner": s.owner, "project": s.project}
+	if status != "" {
+		filter["status"] = status
+	}
+	cur, err := s.collection().Find(ctxT, filter)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer cur.Close(ctxT)
+	list := make([]*domain.ChangeRequest, 0)
+	for cur.Next(ctxT) {
+		var item domain.ChangeRequest
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		list = append(list, &item)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *mongoChangeRequestStorage) Get(id string) (cr *domain.ChangeRequest, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.collection().FindOne(ctxT, s.filter(id)).Decode(&cr)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, storage.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return cr, nil
+}
+
+func (s *mongoChangeRequestStorage) checkRelations(cr *domain.ChangeRequest) error {
+	if s.owner != cr.Owner || s.project != cr.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, cr.Owner, cr.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *mongoChangeRequestStorage) Save(cr *domain.ChangeRequest) error {
+	if err := s.checkRelations(cr); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	cr.Version = 1
+	res, err := s.collection().InsertOne(ctxT, cr)
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", fmt.Sprintf("%v", res.InsertedID)).Msg("Change request inserted")
+	return nil
+}
+
+func (s *mongoChangeRequestStorage) Update(cr *domain.ChangeRequest) error {
+	if err := s.checkRelations(cr); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := cr.Version
+	cr.Version = version + 1
+	filter := s.filter(cr.ID)
+	filter["version"] = version
+	res, err := s.collection().ReplaceOne(ctxT, filter, cr)
+	if err != nil {
+		cr.Version = version
+		return err
+	}
+	if res.MatchedCount == 0 {
+		cr.Version = version
+		if _, err := s.Get(cr.ID); err != nil {
+			return err
+		}
+		return storage.ErrVersionConflict
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package mongo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/storage/mongo"
+	"github.com/Toggly/core/util"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+// subscribe starts the named subscription on a new storage instance and returns
+// the channel receiving changes together with the function stopping the subscription
+func subscribe(t *testing.T, name string) (<-chan *storage.Change, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	dataStorage, err := mongo.NewMongoDataStorage(ctx, "mongodb://localhost:27017", "toggly_storage_test", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dataStorage.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan *storage.Change, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- dataStorage.Subscribe(name, func(c *storage.Change) { changes <- c })
+	}()
+	select {
+	case err := <-done:
+		cancel()
+		if err == storage.ErrChangesNotSupported {
+			t.Skip("Change streams require a replica set")
+		}
+		t.Fatal(err)
+	case <-time.After(time.Second):
+	}
+	return changes, func() {
+		cancel()
+		<-done
+	}
+}
+
+func receive(changes <-chan *storage.Change) *storage.Change {
+	select {
+	case c := <-changes:
+		return c
+	case <-time.After(5 * time.Second):
+		return nil
+	}
+}
+
+func TestMongoChanges(t *testing.T) {
+	assert := asserts.New(t)
+
+	beforeTest()
+
+	db := getDB().ForOwner("ow1").Projects()
+	project := func(code string) *domain.Project {
+		return &domain.Project{Code: code, Owner: "ow1", Status: domain.ProjectStatusActive, RegDate: util.Now()}
+	}
+
+	changes, stop := subscribe(t, "test")
+
+	t.Run("insert and update", func(t *testing.T) {
+		p := project("proj1")
+		assert.Nil(db.Save(p))
+		c := receive(changes)
+		if assert.NotNil(c) {
+			assert.Equal(&storage.Change{Entity: "project", Operation: storage.ChangeInsert, Owner: "ow1", Key: "proj1"}, c)
+		}
+		p.Description = "updated"
+		assert.Nil(db.Update(p))
+		c = receive(changes)
+		if assert.NotNil(c) {
+			assert.Equal(storage.ChangeUpdate, c.Operation)
+			assert.Equal("proj1", c.Key)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Nil(db.Delete("proj1", 0, true))
+		c := receive(changes)
+		if assert.NotNil(c) {
+			assert.Equal(&storage.Change{Entity: "project", Operation: storage.ChangeDelete, Owner: "ow1", Key: "proj1"}, c)
+		}
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		limits := getDB().RateLimits()
+		assert.Nil(limits.Save(&domain.RateLimit{Owner: "ow2", Modified: util.Now()}))
+		c := receive(changes)
+		if assert.NotNil(c) {
+			assert.Equal(&storage.Change{Entity: "rate_limit", Operation: storage.ChangeInsert, Owner: "ow2", Key: "ow2"}, c)
+		}
+		assert.Nil(limits.Delete("ow2", 0))
+		c = receive(changes)
+		if assert.NotNil(c) {
+			assert.Equal(&storage.Change{Entity: "rate_limit", Operation: storage.ChangeDelete, Owner: "ow2", Key: "ow2"}, c)
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		stop()
+		assert.Nil(db.Save(project("proj2")))
+		changes, stop = subscribe(t, "test")
+		c := receive(changes)
+		if assert.NotNil(c) {
+			assert.Equal("proj2", c.Key)
+		}
+	})
+
+	stop()
+	afterTest()
+}
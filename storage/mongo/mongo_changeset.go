@@ -0,0 +1,206 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"github.com/rs/zerolog"
+)
+
+type mongoChangesetStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	ctx     context.Context
+	db      *mongo.Database
+	txn     bool
+}
+
+func (s *mongoChangesetStorage) collection() *mongo.Collection {
+	return s.db.Collection(changesetCollection)
+}
+
+func (s *mongoChangesetStorage) params() *mongo.Collection {
+	return s.db.Collection(parameterCollection)
+}
+
+func (s *mongoChangesetStorage) paramFilter(code string) bson.M {
+	return bson.M{"owner": s.owner, "project": s.project, "environment": s.env, "code": code}
+}
+
+func (s *mongoChangesetStorage) List(limit int) ([]*domain.Changeset, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := bson.M{"owner": s.owner, "project": s.project, "environment": s.env}
+	opts := options.Find().SetSort(bson.M{"reg_date": -1}).SetLimit(int64(limit))
+	cur, err := s.collection().Find(ctxT, filter, opts)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer cur.Close(ctxT)
+	list := make([]*domain.Changeset, 0)
+	for cur.Next(ctxT) {
+		var item domain.Changeset
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		list = append(list, &item)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *mongoChangesetStorage) Get(id string) (cs *domain.Changeset, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := bson.M{"owner": s.owner, "project": s.project, "environment": s.env, "id": id}
+	err = s.collection().FindOne(ctxT, filter).Decode(&cs)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, storage.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return cs, nil
+}
+
+func (s *mongoChangesetStorage) checkRelations(cs *domain.Changeset) error {
+	if s.owner != cs.Owner || s.project != cs.Project || s.env != cs.Environment {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s/%s, got: %s/%s/%s", s.owner, s.project, s.env, cs.Owner, cs.Project, cs.Environment)
+		return storage.ErrEntityRelationsBroken
+	}
+	for _, item := range cs.Changes {
+		p := item.Parameter
+		if p != nil && (s.owner != p.Owner || s.project != p.Project || s.env != p.Environment || item.ParameterCode != p.Code) {
+			s.log.Error().Str("param", item.ParameterCode).Msg("Wrong changeset parameter relations")
+			return storage.ErrEntityRelationsBroken
+		}
+	}
+	return nil
+}
+
+// undo restores parameter state before a change. Nil Parameter means it did not exist,
+// deleted means the change removed it.
+type undo struct {
+	code      string
+	parameter *domain.Parameter
+	deleted   bool
+}
+
+func (s *mongoChangesetStorage) Apply(cs *domain.Changeset) error {
+	if err := s.checkRelations(cs); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+	var undos []*undo
+	err := inTransaction(ctxT, s.db, s.txn, func(ctx context.Context) error {
+		for _, item := range cs.Changes {
+			u, err := s.write(ctx, item)
+			if err != nil {
+				return err
+			}
+			undos = append(undos, u)
+		}
+		_, err := s.collection().InsertOne(ctx, cs)
+		return err
+	})
+	if err != nil {
+		if !s.txn {
+			// The apply context may be the cause, so revert gets its own
+			ctxR, cancelR := context.WithTimeout(s.ctx, 10*time.Second)
+			defer cancelR()
+			return s.revert(ctxR, undos, err)
+		}
+		return err
+	}
+	s.log.Debug().Str("id", cs.ID).Int("changes", len(cs.Changes)).Msg("Changeset applied")
+	return nil
+}
+
+// write applies a single parameter change and returns the way to revert it
+func (s *mongoChangesetStorage) write(ctx context.Context, item *domain.ChangesetItem) (*undo, error) {
+	var old *domain.Parameter
+	err := s.params().FindOne(ctx, s.paramFilter(item.ParameterCode)).Decode(&old)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	switch {
+	case item.Action == domain.ChangeActionCreate && old != nil:
+		return nil, storage.ErrVersionConflict
+	case item.Action == domain.ChangeActionCreate:
+		item.Parameter.Version = 1
+		if err = insert(ctx, s.params(), item.Parameter); err != nil {
+			if isDuplicateKey(err) {
+				return nil, storage.ErrVersionConflict
+			}
+			return nil, err
+		}
+		return &undo{code: item.ParameterCode}, nil
+	case old == nil:
+		return nil, storage.ErrNotFound
+	case item.BaseVersion > 0 && item.BaseVersion != old.Version:
+		return nil, storage.ErrVersionConflict
+	}
+	filter := s.paramFilter(item.ParameterCode)
+	filter["version"] = old.Version
+	var matched int64
+	if item.Action == domain.ChangeActionDelete {
+		res, err := s.params().DeleteOne(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		matched = res.DeletedCount
+	} else {
+		item.Parameter.Version = old.Version + 1
+		res, err := s.params().ReplaceOne(ctx, filter, item.Parameter)
+		if err != nil {
+			return nil, err
+		}
+		matched = res.MatchedCount
+	}
+	if matched == 0 {
+		return nil, storage.ErrVersionConflict
+	}
+	return &undo{code: item.ParameterCode, parameter: old, deleted: item.Action == domain.ChangeActionDelete}, nil
+}
+
+// revert restores parameters changed before the cause failure, latest change first.
+// It returns the cause, or *storage.ErrPartialChangeset if some changes can't be reverted.
+func (s *mongoChangesetStorage) revert(ctx context.Context, undos []*undo, cause error) error {
+	var partial *storage.ErrPartialChangeset
+	for i := len(undos) - 1; i >= 0; i-- {
+		u := undos[i]
+		var err error
+		switch {
+		case u.parameter == nil:
+			_, err = s.params().DeleteOne(ctx, s.paramFilter(u.code))
+		case u.deleted:
+			err = insert(ctx, s.params(), u.parameter)
+		default:
+			_, err = s.params().ReplaceOne(ctx, s.paramFilter(u.code), u.parameter)
+		}
+		if err != nil {
+			s.log.Error().Err(err).Str("param", u.code).Msg("Can't revert changeset parameter change")
+			if partial == nil {
+				partial = &storage.ErrPartialChangeset{Err: cause, Revert: err}
+			}
+			partial.Parameters = append(partial.Parameters, u.code)
+		}
+	}
+	if partial != nil {
+		return partial
+	}
+	return cause
+}
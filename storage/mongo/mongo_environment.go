@@ -0,0 +1,207 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/rs/zerolog"
+)
+
+type mongoEnvironmentStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *mongo.Database
+	txn     bool
+}
+
+func (s *mongoEnvironmentStorage) collection() *mongo.Collection {
+	return s.db.Collection(environmentCollection)
+}
+
+func (s *mongoEnvironmentStorage) filter(code string) bson.M {
+	return bson.M{"owner": s.owner, "project": s.project, "code": code}
+}
+
+func (s *mongoEnvironmentStorage) List() ([]*domain.Environment, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	cur, err := s.collection().Find(ctxT, bson.M{"owner": s.owner, "project": s.project})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer cur.Close(ctxT)
+	list := make([]*domain.Environment, 0)
+	for cur.Next(ctxT) {
+		var item domain.Environment
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		list = append(list, &item)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *mongoEnvironmentStorage) Get(code string) (env *domain.Environment, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.collection().FindOne(ctxT, s.filter(code)).Decode(&env)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, storage.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+func (s *mongoEnvironmentStorage) Delete(code string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := s.filter(code)
+	if version > 0 {
+		filter["version"] = version
+	}
+	return inTransaction(ctxT, s.db, s.txn, func(ctx context.Context) error {
+		res, err := s.collection().DeleteOne(ctx, filter)
+		if err != nil {
+			return err
+		}
+		s.log.Debug().Int64("count", res.DeletedCount).Msg("Environment deleted")
+		if res.DeletedCount == 0 {
+			return s.versionError(code)
+		}
+		params, err := s.db.Collection(parameterCollection).DeleteMany(ctx, bson.M{"owner": s.owner, "project": s.project, "environment": code})
+		if err != nil {
+			return err
+		}
+		s.log.Debug().Int64("count", params.DeletedCount).Msg("Environment parameters deleted")
+		for _, name := range []string{evaluationCountCollection, changesetCollection} {
+			res, err := s.db.Collection(name).DeleteMany(ctx, bson.M{"owner": s.owner, "project": s.project, "environment": code})
+			if err != nil {
+				return err
+			}
+			s.log.Debug().Int64("count", res.DeletedCount).Str("collection", name).Msg("Environment children deleted")
+		}
+		return nil
+	})
+}
+
+func (s *mongoEnvironmentStorage) checkRelations(env *domain.Environment) error {
+	if s.owner != env.Owner || s.project != env.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, env.Owner, env.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *mongoEnvironmentStorage) Save(env *domain.Environment) error {
+	if err := s.checkRelations(env); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+
+	env.Version = 1
+	if err := insert(ctxT, s.collection(), env); err != nil {
+		return uniqueIndexError(err, "environment", env.Code)
+	}
+	s.log.Debug().Str("code", env.Code).Msg("Environment inserted")
+	return nil
+}
+
+func (s *mongoEnvironmentStorage) Update(env *domain.Environment) error {
+	if err := s.checkRelations(env); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := env.Version
+	env.Version = version + 1
+	filter := s.filter(env.Code)
+	filter["version"] = version
+	res, err := s.collection().ReplaceOne(ctxT, filter, env)
+	if err != nil {
+		env.Version = version
+		return err
+	}
+	if res.MatchedCount == 0 {
+		env.Version = version
+		return s.versionError(env.Code)
+	}
+	return nil
+}
+
+func (s *mongoEnvironmentStorage) versionError(code string) error {
+	if _, err := s.Get(code); err != nil {
+		return err
+	}
+	return storage.ErrVersionConflict
+}
+
+func (s *mongoEnvironmentStorage) For(env string) storage.ForEnvironment {
+	return &mongoForEnvironmentStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     env,
+		ctx:     s.ctx,
+		db:      s.db,
+		txn:     s.txn,
+	}
+}
+
+type mongoForEnvironmentStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	ctx     context.Context
+	db      *mongo.Database
+	txn     bool
+}
+
+func (s *mongoForEnvironmentStorage) Parameters() storage.ParameterStorage {
+	return &mongoParameterStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     s.env,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+func (s *mongoForEnvironmentStorage) EvaluationCounts() storage.EvaluationCountStorage {
+	return &mongoEvaluationCountStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     s.env,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+func (s *mongoForEnvironmentStorage) Changesets() storage.ChangesetStorage {
+	return &mongoChangesetStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     s.env,
+		ctx:     s.ctx,
+		db:      s.db,
+		txn:     s.txn,
+	}
+}
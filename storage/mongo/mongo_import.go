@@ -0,0 +1,69 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/mongodb/mongo-go-driver/bson"
+)
+
+func (s *mongoStorage) Owners() ([]string, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+	set := map[string]bool{}
+	for collection, field := range map[string]string{projectCollection: "owner", organizationCollection: "id", rateLimitCollection: "owner"} {
+		values, err := s.db.Collection(collection).Distinct(ctxT, field, bson.M{})
+		if err != nil {
+			s.log.Error().Err(err).Msg("DB error")
+			return nil, err
+		}
+		for _, v := range values {
+			if owner, ok := v.(string); ok {
+				set[owner] = true
+			}
+		}
+	}
+	owners := make([]string, 0, len(set))
+	for owner := range set {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	return owners, nil
+}
+
+func (s *mongoStorage) Import(entity interface{}) error {
+	var collection, typ, key string
+	switch e := entity.(type) {
+	case *domain.Organization:
+		collection, typ, key = organizationCollection, "organization", e.ID
+	case *domain.RateLimit:
+		collection, typ, key = rateLimitCollection, "rate limit", e.Owner
+	case *domain.Project:
+		collection, typ, key = projectCollection, "project", e.Code
+	case *domain.Environment:
+		collection, typ, key = environmentCollection, "environment", e.Code
+	case *domain.Parameter:
+		collection, typ, key = parameterCollection, "parameter", e.Code
+	case *domain.Changeset:
+		collection, typ, key = changesetCollection, "changeset", e.ID
+	case *domain.ChangeRequest:
+		collection, typ, key = changeRequestCollection, "change request", e.ID
+	case *domain.ScheduledChange:
+		collection, typ, key = scheduledChangeCollection, "scheduled change", e.ID
+	case *domain.Webhook:
+		collection, typ, key = webhookCollection, "webhook", e.ID
+	case *domain.WebhookDelivery:
+		collection, typ, key = webhookDeliveryCollection, "webhook delivery", e.ID
+	default:
+		return fmt.Errorf("Can't import %T", entity)
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	if err := insert(ctxT, s.db.Collection(collection), entity); err != nil {
+		return uniqueIndexError(err, typ, key)
+	}
+	return nil
+}
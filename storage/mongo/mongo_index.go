@@ -0,0 +1,94 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/x/bsonx"
+)
+
+// index declares a collection index. Keys are ascending.
+type index struct {
+	collection string
+	keys       []string
+	unique     bool
+}
+
+// indexes lists indexes required by the storage. They are ensured on Connect.
+var indexes = []*index{
+	{projectCollection, []string{"owner", "code"}, true},
+	{environmentCollection, []string{"owner", "project", "code"}, true},
+	{parameterCollection, []string{"owner", "project", "environment", "code"}, true},
+	{changeRequestCollection, []string{"owner", "project", "id"}, true},
+	{changeRequestCollection, []string{"owner", "project", "status"}, false},
+	{scheduledChangeCollection, []string{"owner", "project", "id"}, true},
+	{scheduledChangeCollection, []string{"status", "at"}, false},
+	{webhookCollection, []string{"owner", "project", "id"}, true},
+	{webhookDeliveryCollection, []string{"owner", "project", "id"}, true},
+	{webhookDeliveryCollection, []string{"owner", "project", "webhook", "reg_date"}, false},
+	{webhookDeliveryCollection, []string{"status", "next_attempt"}, false},
+	// keeps concurrent upserts from different instances from creating duplicate counters
+	{evaluationCountCollection, []string{"owner", "project", "environment", "parameter", "hour", "variant"}, true},
+	{changesetCollection, []string{"owner", "project", "environment", "id"}, true},
+	{changesetCollection, []string{"owner", "project", "environment", "reg_date"}, false},
+	{organizationCollection, []string{"id"}, true},
+	{organizationCollection, []string{"members.principal"}, false},
+	{rateLimitCollection, []string{"owner"}, true},
+	{migrationCollection, []string{"version"}, true},
+}
+
+func (idx *index) model() mongo.IndexModel {
+	keys := make([]bsonx.Elem, 0, len(idx.keys))
+	for _, key := range idx.keys {
+		keys = append(keys, bsonx.Elem{Key: key, Value: bsonx.Int32(1)})
+	}
+	model := mongo.IndexModel{Keys: keys}
+	if idx.unique {
+		model.Options = []bsonx.Elem{bsonx.Elem{Key: "unique", Value: bsonx.Boolean(true)}}
+	}
+	return model
+}
+
+// ensureIndexes creates missing indexes. Existing indexes are left as is.
+func ensureIndexes(ctx context.Context, db *mongo.Database) error {
+	for _, idx := range indexes {
+		if _, err := db.Collection(idx.collection).Indexes().CreateOne(ctx, idx.model()); err != nil {
+			return fmt.Errorf("Can't create index %s(%s): %v", idx.collection, strings.Join(idx.keys, ", "), err)
+		}
+	}
+	return nil
+}
+
+// duplicateKeyCode is the error code of unique index violations
+const duplicateKeyCode = 11000
+
+// isDuplicateKey checks if the write failed because of a unique index violation
+func isDuplicateKey(err error) bool {
+	switch e := err.(type) {
+	case mongo.WriteErrors:
+		for _, we := range e {
+			if we.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	case mongo.BulkWriteException:
+		for _, we := range e.WriteErrors {
+			if we.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// uniqueIndexError converts unique index violation of the entity type to *storage.ErrUniqueIndex.
+// Other errors are returned as is.
+func uniqueIndexError(err error, typ, key string) error {
+	if isDuplicateKey(err) {
+		return &storage.ErrUniqueIndex{Type: typ, Key: key}
+	}
+	return err
+}
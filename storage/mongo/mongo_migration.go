@@ -0,0 +1,169 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"github.com/mongodb/mongo-go-driver/x/bsonx"
+)
+
+const (
+	migrationCollection     = "migration"
+	migrationLockCollection = "migration_lock"
+	// migrationLockID identifies the single migration lock document
+	migrationLockID = "migration"
+	// migrationLease is the time the lock is held for before another worker may take it over
+	migrationLease = 10 * time.Minute
+	// migrationWait is the interval between attempts to take the lock held by another worker
+	migrationWait = time.Second
+)
+
+// migration changes the database schema. Migrations are applied in version order,
+// each one once, and must not be changed after they are released.
+type migration struct {
+	version     int
+	description string
+	up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// migrations lists schema migrations in version order
+var migrations = []*migration{
+	{1, "Set versions of entities created before versioning", setMissingVersions},
+	{2, "Index organizations by member principal", indexOrganizationMembers},
+}
+
+// appliedMigration records migration applied to the database
+type appliedMigration struct {
+	Version     int       `bson:"version"`
+	Description string    `bson:"description"`
+	Worker      string    `bson:"worker"`
+	Applied     time.Time `bson:"applied"`
+}
+
+func (s *mongoStorage) applied(ctx context.Context) (map[int]*appliedMigration, error) {
+	cur, err := s.db.Collection(migrationCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	res := make(map[int]*appliedMigration)
+	for cur.Next(ctx) {
+		var item appliedMigration
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		res[item.Version] = &item
+	}
+	return res, cur.Err()
+}
+
+func (s *mongoStorage) Migrations() ([]*storage.Migration, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	applied, err := s.applied(ctxT)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*storage.Migration, 0, len(migrations))
+	for _, m := range migrations {
+		item := &storage.Migration{Version: m.version, Description: m.description}
+		if a, ok := applied[m.version]; ok {
+			date := a.Applied
+			item.Applied = &date
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+func (s *mongoStorage) Migrate(worker string) error {
+	if err := s.lock(worker, migrationLease); err != nil {
+		return err
+	}
+	defer s.unlock(worker)
+	applied, err := s.applied(s.ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		// every migration gets a full lease
+		if err := s.lock(worker, migrationLease); err != nil {
+			return err
+		}
+		s.log.Info().Int("version", m.version).Str("description", m.description).Msg("Applying migration")
+		if err := m.up(s.ctx, s.db); err != nil {
+			s.log.Error().Err(err).Int("version", m.version).Msg("Migration failed")
+			return err
+		}
+		_, err := s.db.Collection(migrationCollection).InsertOne(s.ctx, &appliedMigration{
+			Version:     m.version,
+			Description: m.description,
+			Worker:      worker,
+			Applied:     time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	s.log.Debug().Int("version", len(migrations)).Msg("Storage schema is up to date")
+	return nil
+}
+
+// lock takes the migration lock for the lease or extends the lease of the lock held by the worker.
+// It waits while the lock is held by another worker, but not longer than the lease.
+func (s *mongoStorage) lock(worker string, lease time.Duration) error {
+	deadline := time.Now().Add(lease)
+	for {
+		now := time.Now().UTC()
+		filter := bson.M{
+			"_id": migrationLockID,
+			"$or": []bson.M{{"worker": worker}, {"expires": bson.M{"$lt": now}}},
+		}
+		update := bson.M{"$set": bson.M{"worker": worker, "expires": now.Add(lease)}}
+		_, err := s.db.Collection(migrationLockCollection).UpdateOne(s.ctx, filter, update, options.Update().SetUpsert(true))
+		if !isDuplicateKey(err) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return storage.ErrMigrationLocked
+		}
+		s.log.Info().Msg("Waiting for migration lock")
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-time.After(migrationWait):
+		}
+	}
+}
+
+func (s *mongoStorage) unlock(worker string) {
+	_, err := s.db.Collection(migrationLockCollection).DeleteOne(s.ctx, bson.M{"_id": migrationLockID, "worker": worker})
+	if err != nil {
+		s.log.Error().Err(err).Msg("Can't release migration lock")
+	}
+}
+
+func setMissingVersions(ctx context.Context, db *mongo.Database) error {
+	for _, name := range []string{projectCollection, environmentCollection, parameterCollection} {
+		_, err := db.Collection(name).UpdateMany(ctx, bson.M{"version": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"version": 1}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indexOrganizationMembers(ctx context.Context, db *mongo.Database) error {
+	idx := mongo.IndexModel{
+		Keys: []bsonx.Elem{bsonx.Elem{Key: "members.principal", Value: bsonx.Int32(1)}},
+	}
+	_, err := db.Collection(organizationCollection).Indexes().CreateOne(ctx, idx)
+	return err
+}
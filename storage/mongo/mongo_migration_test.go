@@ -0,0 +1,72 @@
+package mongo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	driver "github.com/mongodb/mongo-go-driver/mongo"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func TestMongoMigration(t *testing.T) {
+	assert := asserts.New(t)
+
+	beforeTest()
+
+	dataStorage := getDB()
+	migrator, ok := dataStorage.(storage.Migrator)
+	if !assert.True(ok) {
+		return
+	}
+
+	client, err := driver.NewClient("mongodb://localhost:27017")
+	assert.Nil(err)
+	ctx := context.Background()
+	assert.Nil(client.Connect(ctx))
+	db := client.Database("toggly_storage_test")
+
+	t.Run("pending", func(t *testing.T) {
+		list, err := migrator.Migrations()
+		assert.Nil(err)
+		assert.NotEmpty(list)
+		for i, m := range list {
+			assert.Equal(i+1, m.Version)
+			assert.Nil(m.Applied)
+		}
+	})
+
+	t.Run("migrate", func(t *testing.T) {
+		_, err := db.Collection("project").InsertOne(ctx, bson.M{"owner": "ow1", "code": "legacy", "status": "active"})
+		assert.Nil(err)
+		// expired lock of a crashed worker is taken over
+		_, err = db.Collection("migration_lock").InsertOne(ctx, bson.M{"_id": "migration", "worker": "w0", "expires": time.Now().Add(-time.Minute)})
+		assert.Nil(err)
+
+		assert.Nil(migrator.Migrate("w1"))
+		list, err := migrator.Migrations()
+		assert.Nil(err)
+		for _, m := range list {
+			assert.NotNil(m.Applied)
+		}
+		p, err := dataStorage.ForOwner("ow1").Projects().Get("legacy")
+		assert.Nil(err)
+		assert.Equal(1, p.Version)
+		n, err := db.Collection("migration_lock").Count(ctx, bson.M{})
+		assert.Nil(err)
+		assert.Equal(int64(0), n, "lock is released")
+	})
+
+	t.Run("migrate twice", func(t *testing.T) {
+		assert.Nil(migrator.Migrate("w2"))
+		list, err := migrator.Migrations()
+		assert.Nil(err)
+		n, err := db.Collection("migration").Count(ctx, bson.M{})
+		assert.Nil(err)
+		assert.Equal(int64(len(list)), n)
+	})
+
+	afterTest()
+}
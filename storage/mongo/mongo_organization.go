@@ -0,0 +1,117 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"github.com/rs/zerolog"
+)
+
+type mongoOrganizationStorage struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *mongo.Database
+}
+
+func (s *mongoOrganizationStorage) collection() *mongo.Collection {
+	return s.db.Collection(organizationCollection)
+}
+
+func (s *mongoOrganizationStorage) List(principal string) ([]*domain.Organization, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	opts := options.Find().SetSort(bson.M{"name": 1})
+	cur, err := s.collection().Find(ctxT, bson.M{"members.principal": principal}, opts)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer cur.Close(ctxT)
+	list := make([]*domain.Organization, 0)
+	for cur.Next(ctxT) {
+		var item domain.Organization
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		list = append(list, &item)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *mongoOrganizationStorage) Get(id string) (org *domain.Organization, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.collection().FindOne(ctxT, bson.M{"id": id}).Decode(&org)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, storage.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return org, nil
+}
+
+func (s *mongoOrganizationStorage) Delete(id string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := bson.M{"id": id}
+	if version > 0 {
+		filter["version"] = version
+	}
+	res, err := s.collection().DeleteOne(ctxT, filter)
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Int64("count", res.DeletedCount).Msg("Organization deleted")
+	if res.DeletedCount == 0 {
+		return s.versionError(id)
+	}
+	return nil
+}
+
+// versionError explains why a conditional write matched nothing
+func (s *mongoOrganizationStorage) versionError(id string) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return storage.ErrVersionConflict
+}
+
+func (s *mongoOrganizationStorage) Save(org *domain.Organization) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+
+	org.Version = 1
+	if err := insert(ctxT, s.collection(), org); err != nil {
+		return uniqueIndexError(err, "organization", org.ID)
+	}
+	s.log.Debug().Str("id", org.ID).Msg("Organization inserted")
+	return nil
+}
+
+func (s *mongoOrganizationStorage) Update(org *domain.Organization) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := org.Version
+	org.Version = version + 1
+	res, err := s.collection().ReplaceOne(ctxT, bson.M{"id": org.ID, "version": version}, org)
+	if err != nil {
+		org.Version = version
+		return err
+	}
+	if res.MatchedCount == 0 {
+		org.Version = version
+		return s.versionError(org.ID)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/rs/zerolog"
+)
+
+type mongoParameterStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	ctx     context.Context
+	db      *mongo.Database
+}
+
+func (s *mongoParameterStorage) collection() *mongo.Collection {
+	return s.db.Collection(parameterCollection)
+}
+
+func (s *mongoParameterStorage) filter(code string) bson.M {
+	return bson.M{"owner": s.owner, "project": s.project, "environment": s.env, "code": code}
+}
+
+func (s *mongoParameterStorage) List() ([]*domain.Parameter, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	cur, err := s.collection().Find(ctxT, bson.M{"owner": s.owner, "project": s.project, "environment": s.env})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer cur.Close(ctxT)
+	list := make([]*domain.Parameter, 0)
+	for cur.Next(ctxT) {
+		var item domain.Parameter
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		list = append(list, &item)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *mongoParameterStorage) Get(code string) (param *domain.Parameter, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.collection().FindOne(ctxT, s.filter(code)).Decode(&param)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, storage.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return param, nil
+}
+
+func (s *mongoParameterStorage) Delete(code string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := s.filter(code)
+	if version > 0 {
+		filter["version"] = version
+	}
+	res, err := s.collection().DeleteOne(ctxT, filter)
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Int64("count", res.DeletedCount).Msg("Parameter deleted")
+	if res.DeletedCount == 0 {
+		return s.versionError(code)
+	}
+	return nil
+}
+
+func (s *mongoParameterStorage) checkRelations(param *domain.Parameter) error {
+	if s.owner != param.Owner || s.project != param.Project || s.env != param.Environment {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s/%s, got: %s/%s/%s",
+			s.owner, s.project, s.env, param.Owner, param.Project, param.Environment)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *mongoParameterStorage) Save(param *domain.Parameter) error {
+	if err := s.checkRelations(param); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+
+	param.Version = 1
+	if err := insert(ctxT, s.collection(), param); err != nil {
+		return uniqueIndexError(err, "parameter", param.Code)
+	}
+	s.log.Debug().Str("code", param.Code).Msg("Parameter inserted")
+	return nil
+}
+
+func (s *mongoParameterStorage) Update(param *domain.Parameter) error {
+	if err := s.checkRelations(param); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := param.Version
+	param.Version = version + 1
+	filter := s.filter(param.Code)
+	filter["version"] = version
+	res, err := s.collection().ReplaceOne(ctxT, filter, param)
+	if err != nil {
+		param.Version = version
+		return err
+	}
+	if res.MatchedCount == 0 {
+		param.Version = version
+		return s.versionError(param.Code)
+	}
+	return nil
+}
+
+func (s *mongoParameterStorage) versionError(code string) error {
+	if _, err := s.Get(code); err != nil {
+		return err
+	}
+	return storage.ErrVersionConflict
+}
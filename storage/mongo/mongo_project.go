@@ -2,7 +2,6 @@ package mongo
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"time"
 
@@ -10,7 +9,6 @@ import (
 	"github.com/Toggly/core/storage"
 	"github.com/mongodb/mongo-go-driver/bson"
 	"github.com/mongodb/mongo-go-driver/mongo"
-	"github.com/mongodb/mongo-go-driver/x/bsonx"
 	"github.com/rs/zerolog"
 )
 
@@ -19,16 +17,17 @@ type mongoProjectStorage struct {
 	owner string
 	ctx   context.Context
 	db    *mongo.Database
+	txn   bool
 }
 
 func (s *mongoProjectStorage) collection() *mongo.Collection {
-	return s.db.Collection("project")
+	return s.db.Collection(projectCollection)
 }
 
 func (s *mongoProjectStorage) List() ([]*domain.Project, error) {
 	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
 	defer cancel()
-	cur, err := s.collection().Find(ctxT, nil)
+	cur, err := s.collection().Find(ctxT, bson.M{"owner": s.owner})
 	if err != nil {
 		s.log.Error().Err(err).Msg("DB error")
 		return nil, err
@@ -52,7 +51,12 @@ func (s *mongoProjectStorage) List() ([]*domain.Project, error) {
 func (s *mongoProjectStorage) Get(code string) (project *domain.Project, err error) {
 	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
 	defer cancel()
-	err = s.collection().FindOne(ctxT, bson.M{"owner": s.owner, "code": code}).Decode(&project)
+	return s.get(ctxT, code)
+}
+
+// get reads the project within the context, which may be a session one
+func (s *mongoProjectStorage) get(ctx context.Context, code string) (project *domain.Project, err error) {
+	err = s.collection().FindOne(ctx, bson.M{"owner": s.owner, "code": code}).Decode(&project)
 	if err != nil {
 		switch err {
 		case mongo.ErrNoDocuments:
@@ -64,12 +68,80 @@ func (s *mongoProjectStorage) Get(code string) (project *domain.Project, err err
 	return project, nil
 }
 
-func (s *mongoProjectStorage) Delete(code string) error {
+func (s *mongoProjectStorage) Delete(code string, version int, cascade bool) error {
 	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
 	defer cancel()
-	res, err := s.collection().DeleteOne(ctxT, bson.M{"owner": s.owner, "code": code})
-	s.log.Debug().Int64("count", res.DeletedCount).Msg("Project deleted")
-	return err
+	filter := bson.M{"owner": s.owner, "code": code}
+	if version > 0 {
+		filter["version"] = version
+	}
+	children := projectChildCollections
+	if !cascade {
+		children = projectOwnCollections
+	}
+	return inTransaction(ctxT, s.db, s.txn, func(ctx context.Context) error {
+		if !cascade {
+			if err := s.checkEmpty(ctx, code); err != nil {
+				return err
+			}
+		}
+		var deleted domain.Project
+		err := s.collection().FindOneAndDelete(ctx, filter).Decode(&deleted)
+		if err == mongo.ErrNoDocuments {
+			return s.versionError(ctx, code)
+		}
+		if err != nil {
+			return err
+		}
+		s.log.Debug().Str("code", code).Msg("Project deleted")
+		// Without a transaction an environment may be created between the check and the removal
+		if !cascade && !s.txn {
+			if err := s.restoreIfNotEmpty(ctx, &deleted); err != nil {
+				return err
+			}
+		}
+		for _, name := range children {
+			res, err := s.db.Collection(name).DeleteMany(ctx, bson.M{"owner": s.owner, "project": code})
+			if err != nil {
+				return err
+			}
+			s.log.Debug().Int64("count", res.DeletedCount).Str("collection", name).Msg("Project children deleted")
+		}
+		return nil
+	})
+}
+
+// checkEmpty returns storage.ErrNotEmpty if the project has environments
+func (s *mongoProjectStorage) checkEmpty(ctx context.Context, code string) error {
+	n, err := s.db.Collection(environmentCollection).CountDocuments(ctx, bson.M{"owner": s.owner, "project": code})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	if _, err := s.get(ctx, code); err != nil {
+		return err
+	}
+	return storage.ErrNotEmpty
+}
+
+// restoreIfNotEmpty repeats the environments check after the project removal
+// and puts the project back if environments were created meanwhile
+func (s *mongoProjectStorage) restoreIfNotEmpty(ctx context.Context, project *domain.Project) error {
+	n, err := s.db.Collection(environmentCollection).CountDocuments(ctx, bson.M{"owner": s.owner, "project": project.Code})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	if err := insert(ctx, s.collection(), project); err != nil {
+		s.log.Error().Err(err).Str("code", project.Code).Msg("Can't restore project having environments")
+		return err
+	}
+	s.log.Debug().Str("code", project.Code).Msg("Project restored, environments were created meanwhile")
+	return storage.ErrNotEmpty
 }
 
 func (s *mongoProjectStorage) Save(project *domain.Project) error {
@@ -80,25 +152,12 @@ func (s *mongoProjectStorage) Save(project *domain.Project) error {
 	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
 	defer cancel()
 
-	idx := mongo.IndexModel{
-		Keys: []bsonx.Elem{
-			bsonx.Elem{Key: "owner", Value: bsonx.Int32(1)},
-			bsonx.Elem{Key: "code", Value: bsonx.Int32(1)},
-		},
-		Options: []bsonx.Elem{bsonx.Elem{Key: "unique", Value: bsonx.Boolean(true)}},
-	}
-
-	name, err := s.collection().Indexes().CreateOne(ctxT, idx)
-	if err != nil {
-		s.log.Error().Err(err).Msg("Can't create index")
-		return err
+	project.Version = 1
+	if err := insert(ctxT, s.collection(), project); err != nil {
+		return uniqueIndexError(err, "project", project.Code)
 	}
-	s.log.Debug().Str("name", name).Msg("Index created")
-
-	res, err := s.collection().InsertOne(ctxT, project)
-	// TODO: check unique index error
-	s.log.Debug().Str("id", fmt.Sprintf("%v", res.InsertedID)).Msg("Project inserted")
-	return err
+	s.log.Debug().Str("code", project.Code).Msg("Project inserted")
+	return nil
 }
 
 func (s *mongoProjectStorage) Update(project *domain.Project) error {
@@ -108,7 +167,95 @@ func (s *mongoProjectStorage) Update(project *domain.Project) error {
 	}
 	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
 	defer cancel()
-	res := s.collection().FindOneAndReplace(ctxT, bson.M{"owner": s.owner, "code": project.Code}, project)
-	// TODO: check not found error
-	return res.Err()
+	version := project.Version
+	project.Version = version + 1
+	res, err := s.collection().ReplaceOne(ctxT, bson.M{"owner": s.owner, "code": project.Code, "version": version}, project)
+	if err != nil {
+		project.Version = version
+		return err
+	}
+	if res.MatchedCount == 0 {
+		project.Version = version
+		return s.versionError(ctxT, project.Code)
+	}
+	return nil
+}
+
+// versionError explains why a conditional write matched nothing
+func (s *mongoProjectStorage) versionError(ctx context.Context, code string) error {
+	if _, err := s.get(ctx, code); err != nil {
+		return err
+	}
+	return storage.ErrVersionConflict
+}
+
+func (s *mongoProjectStorage) For(project string) storage.ForProject {
+	return &mongoForProjectStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: project,
+		ctx:     s.ctx,
+		db:      s.db,
+		txn:     s.txn,
+	}
+}
+
+type mongoForProjectStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *mongo.Database
+	txn     bool
+}
+
+func (s *mongoForProjectStorage) Environments() storage.EnvironmentStorage {
+	return &mongoEnvironmentStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		ctx:     s.ctx,
+		db:      s.db,
+		txn:     s.txn,
+	}
+}
+
+func (s *mongoForProjectStorage) ChangeRequests() storage.ChangeRequestStorage {
+	return &mongoChangeRequestStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+func (s *mongoForProjectStorage) ScheduledChanges() storage.ScheduledChangeStorage {
+	return &mongoScheduledChangeStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+func (s *mongoForProjectStorage) Webhooks() storage.WebhookStorage {
+	return &mongoWebhookStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+func (s *mongoForProjectStorage) WebhookDeliveries() storage.WebhookDeliveryStorage {
+	return &mongoWebhookDeliveryStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
 }
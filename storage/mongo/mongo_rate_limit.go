@@ -0,0 +1,116 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"github.com/rs/zerolog"
+)
+
+type mongoRateLimitStorage struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *mongo.Database
+}
+
+func (s *mongoRateLimitStorage) collection() *mongo.Collection {
+	return s.db.Collection(rateLimitCollection)
+}
+
+func (s *mongoRateLimitStorage) List() ([]*domain.RateLimit, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	opts := options.Find().SetSort(bson.M{"owner": 1})
+	cur, err := s.collection().Find(ctxT, bson.M{}, opts)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer cur.Close(ctxT)
+	list := make([]*domain.RateLimit, 0)
+	for cur.Next(ctxT) {
+		var item domain.RateLimit
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		list = append(list, &item)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *mongoRateLimitStorage) Get(owner string) (limit *domain.RateLimit, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.collection().FindOne(ctxT, bson.M{"owner": owner}).Decode(&limit)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, storage.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return limit, nil
+}
+
+func (s *mongoRateLimitStorage) Delete(owner string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := bson.M{"owner": owner}
+	if version > 0 {
+		filter["version"] = version
+	}
+	res, err := s.collection().DeleteOne(ctxT, filter)
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Int64("count", res.DeletedCount).Msg("Rate limit deleted")
+	if res.DeletedCount == 0 {
+		return s.versionError(owner)
+	}
+	return nil
+}
+
+// versionError explains why a conditional write matched nothing
+func (s *mongoRateLimitStorage) versionError(owner string) error {
+	if _, err := s.Get(owner); err != nil {
+		return err
+	}
+	return storage.ErrVersionConflict
+}
+
+func (s *mongoRateLimitStorage) Save(limit *domain.RateLimit) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	limit.Version = 1
+	if err := insert(ctxT, s.collection(), limit); err != nil {
+		return uniqueIndexError(err, "rate limit", limit.Owner)
+	}
+	s.log.Debug().Str("owner", limit.Owner).Msg("Rate limit inserted")
+	return nil
+}
+
+func (s *mongoRateLimitStorage) Update(limit *domain.RateLimit) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := limit.Version
+	limit.Version = version + 1
+	res, err := s.collection().ReplaceOne(ctxT, bson.M{"owner": limit.Owner, "version": version}, limit)
+	if err != nil {
+		limit.Version = version
+		return err
+	}
+	if res.MatchedCount == 0 {
+		limit.Version = version
+		return s.versionError(limit.Owner)
+	}
+	return nil
+}
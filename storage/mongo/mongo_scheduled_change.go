@@ -0,0 +1,156 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"github.com/rs/zerolog"
+)
+
+type mongoScheduledChangeStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *mongo.Database
+}
+
+func (s *mongoScheduledChangeStorage) collection() *mongo.Collection {
+	return s.db.Collection(scheduledChangeCollection)
+}
+
+func (s *mongoScheduledChangeStorage) filter(id string) bson.M {
+	return bson.M{"owner": s.owner, "project": s.project, "id": id}
+}
+
+func (s *mongoScheduledChangeStorage) List(status string) ([]*domain.ScheduledChange, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := bson.M{"owner": s.owner, "project": s.project}
+	if status != "" {
+		filter["status"] = status
+	}
+	cur, err := s.collection().Find(ctxT, filter)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer cur.Close(ctxT)
+	list := make([]*domain.ScheduledChange, 0)
+	for cur.Next(ctxT) {
+		var item domain.ScheduledChange
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		list = append(list, &item)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *mongoScheduledChangeStorage) Get(id string) (sc *domain.ScheduledChange, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.collection().FindOne(ctxT, s.filter(id)).Decode(&sc)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, storage.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return sc, nil
+}
+
+func (s *mongoScheduledChangeStorage) checkRelations(sc *domain.ScheduledChange) error {
+	if s.owner != sc.Owner || s.project != sc.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, sc.Owner, sc.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *mongoScheduledChangeStorage) Save(sc *domain.ScheduledChange) error {
+	if err := s.checkRelations(sc); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	sc.Version = 1
+	res, err := s.collection().InsertOne(ctxT, sc)
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", fmt.Sprintf("%v", res.InsertedID)).Msg("Scheduled change inserted")
+	return nil
+}
+
+func (s *mongoScheduledChangeStorage) Update(sc *domain.ScheduledChange) error {
+	if err := s.checkRelations(sc); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := sc.Version
+	sc.Version = version + 1
+	filter := s.filter(sc.ID)
+	filter["version"] = version
+	res, err := s.collection().ReplaceOne(ctxT, filter, sc)
+	if err != nil {
+		sc.Version = version
+		return err
+	}
+	if res.MatchedCount == 0 {
+		sc.Version = version
+		if _, err := s.Get(sc.ID); err != nil {
+			return err
+		}
+		return storage.ErrVersionConflict
+	}
+	return nil
+}
+
+type mongoScheduledChangeQueue struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *mongo.Database
+}
+
+func (s *mongoScheduledChangeQueue) Claim(now time.Time, worker string, lease time.Duration) (*domain.ScheduledChange, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := bson.M{"$or": []bson.M{
+		{"status": domain.ScheduledChangeStatusPending, "at": bson.M{"$lte": now}},
+		{"status": domain.ScheduledChangeStatusRunning, "locked_until": bson.M{"$lt": now}},
+	}}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       domain.ScheduledChangeStatusRunning,
+			"locked_by":    worker,
+			"locked_until": now.Add(lease),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"at": 1}).SetReturnDocument(options.After)
+	var sc *domain.ScheduledChange
+	err := s.db.Collection(scheduledChangeCollection).FindOneAndUpdate(ctxT, filter, update, opts).Decode(&sc)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, storage.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	s.log.Debug().Str("id", sc.ID).Str("worker", worker).Msg("Scheduled change claimed")
+	return sc, nil
+}
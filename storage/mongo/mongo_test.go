@@ -3,6 +3,7 @@ package mongo_test
 import (
 	"context"
 	"os"
+	"testing"
 
 	driver "github.com/mongodb/mongo-go-driver/mongo"
 	"github.com/rs/zerolog"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/Toggly/core/storage"
 	"github.com/Toggly/core/storage/mongo"
+	"github.com/Toggly/core/storage/storagetest"
 )
 
 var logger = log.Output(zerolog.ConsoleWriter{
@@ -50,3 +52,13 @@ func beforeTest() {
 func afterTest() {
 	dropDB()
 }
+
+func TestMongoConformance(t *testing.T) {
+	storagetest.Run(t, &storagetest.Backend{
+		Open: func() storage.DataStorage {
+			beforeTest()
+			return getDB()
+		},
+		Close: afterTest,
+	})
+}
@@ -0,0 +1,293 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"github.com/rs/zerolog"
+)
+
+type mongoWebhookStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *mongo.Database
+}
+
+func (s *mongoWebhookStorage) collection() *mongo.Collection {
+	return s.db.Collection(webhookCollection)
+}
+
+func (s *mongoWebhookStorage) filter(id string) bson.M {
+	return bson.M{"owner": s.owner, "project": s.project, "id": id}
+}
+
+func (s *mongoWebhookStorage) List() ([]*domain.Webhook, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	cur, err := s.collection().Find(ctxT, bson.M{"owner": s.owner, "project": s.project})
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer cur.Close(ctxT)
+	list := make([]*domain.Webhook, 0)
+	for cur.Next(ctxT) {
+		var item domain.Webhook
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		list = append(list, &item)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *mongoWebhookStorage) Get(id string) (hook *domain.Webhook, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.collection().FindOne(ctxT, s.filter(id)).Decode(&hook)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, storage.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return hook, nil
+}
+
+func (s *mongoWebhookStorage) Delete(id string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := s.filter(id)
+	if version > 0 {
+		filter["version"] = version
+	}
+	res, err := s.collection().DeleteOne(ctxT, filter)
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Int64("count", res.DeletedCount).Msg("Webhook deleted")
+	if res.DeletedCount == 0 {
+		return s.versionError(id)
+	}
+	// Deliveries saved concurrently after this point are dropped by the dispatcher
+	res, err = s.db.Collection(webhookDeliveryCollection).DeleteMany(ctxT, bson.M{"owner": s.owner, "project": s.project, "webhook": id})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Int64("count", res.DeletedCount).Msg("Webhook deliveries deleted")
+	return nil
+}
+
+// versionError explains why a conditional write matched nothing
+func (s *mongoWebhookStorage) versionError(id string) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return storage.ErrVersionConflict
+}
+
+func (s *mongoWebhookStorage) checkRelations(hook *domain.Webhook) error {
+	if s.owner != hook.Owner || s.project != hook.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, hook.Owner, hook.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *mongoWebhookStorage) Save(hook *domain.Webhook) error {
+	if err := s.checkRelations(hook); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	hook.Version = 1
+	if err := insert(ctxT, s.collection(), hook); err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", hook.ID).Msg("Webhook inserted")
+	return nil
+}
+
+func (s *mongoWebhookStorage) Update(hook *domain.Webhook) error {
+	if err := s.checkRelations(hook); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := hook.Version
+	hook.Version = version + 1
+	filter := s.filter(hook.ID)
+	filter["version"] = version
+	res, err := s.collection().ReplaceOne(ctxT, filter, hook)
+	if err != nil {
+		hook.Version = version
+		return err
+	}
+	if res.MatchedCount == 0 {
+		hook.Version = version
+		return s.versionError(hook.ID)
+	}
+	return nil
+}
+
+type mongoWebhookDeliveryStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *mongo.Database
+}
+
+func (s *mongoWebhookDeliveryStorage) collection() *mongo.Collection {
+	return s.db.Collection(webhookDeliveryCollection)
+}
+
+func (s *mongoWebhookDeliveryStorage) List(webhook string, limit int) ([]*domain.WebhookDelivery, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := bson.M{"owner": s.owner, "project": s.project, "webhook": webhook}
+	opts := options.Find().SetSort(bson.M{"reg_date": -1}).SetLimit(int64(limit))
+	cur, err := s.collection().Find(ctxT, filter, opts)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer cur.Close(ctxT)
+	list := make([]*domain.WebhookDelivery, 0)
+	for cur.Next(ctxT) {
+		var item domain.WebhookDelivery
+		if err := cur.Decode(&item); err != nil {
+			return nil, err
+		}
+		list = append(list, &item)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *mongoWebhookDeliveryStorage) checkRelations(d *domain.WebhookDelivery) error {
+	if s.owner != d.Owner || s.project != d.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, d.Owner, d.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *mongoWebhookDeliveryStorage) Save(d *domain.WebhookDelivery) error {
+	if err := s.checkRelations(d); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	d.Version = 1
+	res, err := s.collection().InsertOne(ctxT, d)
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", fmt.Sprintf("%v", res.InsertedID)).Msg("Webhook delivery inserted")
+	return nil
+}
+
+func (s *mongoWebhookDeliveryStorage) Update(d *domain.WebhookDelivery) error {
+	if err := s.checkRelations(d); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := d.Version
+	d.Version = version + 1
+	filter := bson.M{"owner": s.owner, "project": s.project, "id": d.ID, "version": version}
+	res, err := s.collection().ReplaceOne(ctxT, filter, d)
+	if err != nil {
+		d.Version = version
+		return err
+	}
+	if res.MatchedCount == 0 {
+		d.Version = version
+		return s.versionError(d.ID)
+	}
+	return nil
+}
+
+func (s *mongoWebhookDeliveryStorage) Delete(id string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := bson.M{"owner": s.owner, "project": s.project, "id": id}
+	if version > 0 {
+		filter["version"] = version
+	}
+	res, err := s.collection().DeleteOne(ctxT, filter)
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Int64("count", res.DeletedCount).Msg("Webhook delivery deleted")
+	if res.DeletedCount == 0 {
+		return s.versionError(id)
+	}
+	return nil
+}
+
+// versionError explains why a conditional write matched nothing
+func (s *mongoWebhookDeliveryStorage) versionError(id string) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err := s.collection().FindOne(ctxT, bson.M{"owner": s.owner, "project": s.project, "id": id}).Err()
+	if err == mongo.ErrNoDocuments {
+		return storage.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return storage.ErrVersionConflict
+}
+
+type mongoWebhookDeliveryQueue struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *mongo.Database
+}
+
+func (s *mongoWebhookDeliveryQueue) Claim(now time.Time, worker string, lease time.Duration) (*domain.WebhookDelivery, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	filter := bson.M{"$or": []bson.M{
+		{"status": domain.WebhookDeliveryStatusPending, "next_attempt": bson.M{"$lte": now}},
+		{"status": domain.WebhookDeliveryStatusRunning, "locked_until": bson.M{"$lt": now}},
+	}}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       domain.WebhookDeliveryStatusRunning,
+			"locked_by":    worker,
+			"locked_until": now.Add(lease),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"next_attempt": 1}).SetReturnDocument(options.After)
+	var d *domain.WebhookDelivery
+	err := s.db.Collection(webhookDeliveryCollection).FindOneAndUpdate(ctxT, filter, update, opts).Decode(&d)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, storage.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	s.log.Debug().Str("id", d.ID).Str("worker", worker).Msg("Webhook delivery claimed")
+	return d, nil
+}
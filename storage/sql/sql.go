@@ -0,0 +1,377 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Toggly/core/storage"
+	"github.com/rs/zerolog"
+)
+
+// Table names
+const (
+	projectTable         = "project"
+	environmentTable     = "environment"
+	parameterTable       = "parameter"
+	changeRequestTable   = "change_request"
+	scheduledChangeTable = "scheduled_change"
+	webhookTable         = "webhook"
+	webhookDeliveryTable = "webhook_delivery"
+	evaluationCountTable = "evaluation_count"
+	changesetTable       = "changeset"
+	organizationTable    = "organization"
+	memberTable          = "organization_member"
+	rateLimitTable       = "rate_limit"
+)
+
+// NewSQLDataStorage returns relational storage implementation for the driver, sqlite3 or postgres.
+// Entity fields are kept in columns, times as milliseconds since epoch, while structured fields
+// like values, variants or delivery attempts are kept in JSON columns, JSONB in Postgres.
+// Children reference their parents with foreign keys removing them together, except for
+// evaluation counters, which may be written after the environment deletion.
+// Schema is created and upgraded by migrations, so Migrate must be called before the storage is used.
+// Changes are not reported, Subscribe returns ErrChangesNotSupported.
+func NewSQLDataStorage(ctx context.Context, driver, dsn string, log zerolog.Logger) (storage.DataStorage, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("Unknown SQL driver %s", driver)
+	}
+	return &sqlStorage{
+		ctx: ctx,
+		dsn: dsn,
+		db:  &database{dialect: d},
+		log: log,
+	}, nil
+}
+
+type sqlStorage struct {
+	ctx context.Context
+	dsn string
+	db  *database
+	log zerolog.Logger
+}
+
+func (s *sqlStorage) Connect() error {
+	d := s.db.dialect
+	db, err := sql.Open(d.driver, d.dsn(s.dsn))
+	if err != nil {
+		return err
+	}
+	if d.singleWriter {
+		db.SetMaxOpenConns(1)
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+	if err = db.PingContext(ctxT); err != nil {
+		db.Close()
+		return err
+	}
+	s.db.DB = db
+	if err = s.ensureMigrationTables(ctxT); err != nil {
+		db.Close()
+		return err
+	}
+	go func() {
+		<-s.ctx.Done()
+		if err := db.Close(); err != nil {
+			s.log.Error().Err(err).Msg("Can't close SQL storage")
+		}
+		s.log.Info().Msg("SQL storage closed")
+	}()
+	s.log.Debug().Str("driver", d.driver).Msg("SQL storage connected")
+	return nil
+}
+
+func (s *sqlStorage) ForOwner(owner string) storage.OwnerStorage {
+	return &sqlOwnerStorage{
+		log:   s.log,
+		owner: owner,
+		ctx:   s.ctx,
+		db:    s.db,
+	}
+}
+
+func (s *sqlStorage) ScheduledChanges() storage.ScheduledChangeQueue {
+	return &sqlScheduledChangeQueue{
+		log: s.log,
+		ctx: s.ctx,
+		db:  s.db,
+	}
+}
+
+func (s *sqlStorage) WebhookDeliveries() storage.WebhookDeliveryQueue {
+	return &sqlWebhookDeliveryQueue{
+		log: s.log,
+		ctx: s.ctx,
+		db:  s.db,
+	}
+}
+
+func (s *sqlStorage) EvaluationCounts() storage.EvaluationCountSink {
+	return &sqlEvaluationCountSink{
+		log: s.log,
+		ctx: s.ctx,
+		db:  s.db,
+	}
+}
+
+func (s *sqlStorage) RateLimits() storage.RateLimitStorage {
+	return &sqlRateLimitStorage{
+		log: s.log,
+		ctx: s.ctx,
+		db:  s.db,
+	}
+}
+
+func (s *sqlStorage) Organizations() storage.OrganizationStorage {
+	return &sqlOrganizationStorage{
+		log: s.log,
+		ctx: s.ctx,
+		db:  s.db,
+	}
+}
+
+func (s *sqlStorage) Subscribe(name string, fn func(c *storage.Change)) error {
+	return storage.ErrChangesNotSupported
+}
+
+type sqlOwnerStorage struct {
+	log   zerolog.Logger
+	owner string
+	ctx   context.Context
+	db    *database
+}
+
+func (s *sqlOwnerStorage) Projects() storage.ProjectStorage {
+	return &sqlProjectStorage{
+		log:   s.log,
+		owner: s.owner,
+		ctx:   s.ctx,
+		db:    s.db,
+	}
+}
+
+// database is a connection pool speaking the dialect. Queries are written with ? placeholders.
+type database struct {
+	*sql.DB
+	dialect *dialect
+}
+
+// querier is implemented by both database and transaction
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// inTransaction runs fn in a transaction committed if fn succeeds
+func (db *database) inTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *database) exec(ctx context.Context, q querier, query string, args ...interface{}) (int64, error) {
+	res, err := q.ExecContext(ctx, db.dialect.rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// scanner is implemented by both row and rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// list calls scan for every row selected by the query
+func (db *database) list(ctx context.Context, q querier, scan func(row scanner) error, query string, args ...interface{}) error {
+	rows, err := q.QueryContext(ctx, db.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// column is a named column value
+type column struct {
+	name  string
+	value interface{}
+}
+
+func col(name string, value interface{}) *column {
+	return &column{name: name, value: value}
+}
+
+// record is an entity row. Key columns identify the row and fields keep the other entity fields,
+// structured ones encoded in JSON columns.
+type record struct {
+	table  string
+	key    []*column
+	fields []*column
+}
+
+func (r *record) where() (string, []interface{}) {
+	conds := make([]string, 0, len(r.key))
+	args := make([]interface{}, 0, len(r.key))
+	for _, c := range r.key {
+		conds = append(conds, c.name+" = ?")
+		args = append(args, c.value)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// get selects the columns of the row and passes it to scan
+func (db *database) get(ctx context.Context, q querier, r *record, columns string, scan func(row scanner) error) error {
+	where, args := r.where()
+	err := scan(q.QueryRowContext(ctx, db.dialect.rebind("SELECT "+columns+" FROM "+r.table+" WHERE "+where), args...))
+	if err == sql.ErrNoRows {
+		return storage.ErrNotFound
+	}
+	return err
+}
+
+// version returns version of the entity stored in the row
+func (db *database) version(ctx context.Context, q querier, r *record) (int, error) {
+	where, args := r.where()
+	var version int
+	err := q.QueryRowContext(ctx, db.dialect.rebind("SELECT version FROM "+r.table+" WHERE "+where), args...).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, storage.ErrNotFound
+	}
+	return version, err
+}
+
+// insert stores a new entity row. Unique key violations are reported as *storage.ErrUniqueIndex
+// of the type and code, missing parents as storage.ErrEntityRelationsBroken.
+func (db *database) insert(ctx context.Context, q querier, r *record, version int, typ, code string) error {
+	columns := append(append([]*column{}, r.key...), r.fields...)
+	columns = append(columns, col("version", version))
+	names := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns))
+	for _, c := range columns {
+		names = append(names, c.name)
+		args = append(args, c.value)
+	}
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	query := "INSERT INTO " + r.table + " (" + strings.Join(names, ", ") + ") VALUES (" + marks + ")"
+	if _, err := db.exec(ctx, q, query, args...); err != nil {
+		return db.dialect.writeError(err, typ, code)
+	}
+	return nil
+}
+
+// update replaces the entity fields if the stored row has the expected version
+func (db *database) update(ctx context.Context, q querier, r *record, version int) error {
+	sets := make([]string, 0, len(r.fields)+1)
+	args := make([]interface{}, 0, len(r.fields)+len(r.key)+2)
+	for _, c := range r.fields {
+		sets = append(sets, c.name+" = ?")
+		args = append(args, c.value)
+	}
+	sets = append(sets, "version = ?")
+	args = append(args, version+1)
+	where, keyArgs := r.where()
+	args = append(append(args, keyArgs...), version)
+	n, err := db.exec(ctx, q, "UPDATE "+r.table+" SET "+strings.Join(sets, ", ")+" WHERE "+where+" AND version = ?", args...)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return db.versionError(ctx, q, r)
+	}
+	return nil
+}
+
+// remove deletes the entity if it has the expected version. Zero version is unconditional.
+func (db *database) remove(ctx context.Context, q querier, r *record, version int) error {
+	where, args := r.where()
+	if version > 0 {
+		where += " AND version = ?"
+		args = append(args, version)
+	}
+	n, err := db.exec(ctx, q, "DELETE FROM "+r.table+" WHERE "+where, args...)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return db.versionError(ctx, q, r)
+	}
+	return nil
+}
+
+// versionError explains why a conditional write matched nothing
+func (db *database) versionError(ctx context.Context, q querier, r *record) error {
+	if _, err := db.version(ctx, q, r); err != nil {
+		return err
+	}
+	return storage.ErrVersionConflict
+}
+
+// millis converts time to milliseconds since epoch, the precision Mongo storage keeps times with
+func millis(t time.Time) int64 {
+	return t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)
+}
+
+// fromMillis converts milliseconds since epoch to local time, the same way Mongo storage reads times.
+// Zero time converted by millis is converted back to zero time.
+func fromMillis(ms int64) time.Time {
+	return time.Unix(ms/1000, ms%1000*int64(time.Millisecond))
+}
+
+// localTime converts time decoded from a JSON column the way fromMillis converts column times
+func localTime(t time.Time) time.Time {
+	return fromMillis(millis(t))
+}
+
+// jsonColumn keeps a structured entity field in a JSON column, nil values as NULL.
+// Written values are encoded on execution, scanned ones are decoded into the pointer
+// the column holds keeping numbers as json.Number, so large integers stay exact.
+type jsonColumn struct {
+	v interface{}
+}
+
+// Value implements driver.Valuer
+func (c jsonColumn) Value() (driver.Value, error) {
+	data, err := json.Marshal(c.v)
+	if err != nil || string(data) == "null" {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (c jsonColumn) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("Can't decode %T from JSON column", src)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(c.v)
+}
@@ -0,0 +1,105 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/rs/zerolog"
+)
+
+// Evaluation counters are kept in columns only, as they are incremented in place
+type sqlEvaluationCountStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	ctx     context.Context
+	db      *database
+}
+
+func (s *sqlEvaluationCountStorage) List(param string, from, to time.Time) ([]*domain.EvaluationCount, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctxT, s.db.dialect.rebind("SELECT variant, hour, count, last FROM "+evaluationCountTable+
+		" WHERE owner = ? AND project = ? AND environment = ? AND parameter = ? AND hour >= ? AND hour < ? ORDER BY hour, variant"),
+		s.owner, s.project, s.env, param, millis(from), millis(to))
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]*domain.EvaluationCount, 0)
+	for rows.Next() {
+		item := &domain.EvaluationCount{Owner: s.owner, Project: s.project, Environment: s.env, Parameter: param}
+		var hour, last int64
+		if err := rows.Scan(&item.Variant, &hour, &item.Count, &last); err != nil {
+			return nil, err
+		}
+		item.Hour, item.Last = fromMillis(hour), fromMillis(last)
+		list = append(list, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *sqlEvaluationCountStorage) LastEvaluated() (map[string]time.Time, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctxT, s.db.dialect.rebind("SELECT parameter, MAX(last) FROM "+evaluationCountTable+
+		" WHERE owner = ? AND project = ? AND environment = ? GROUP BY parameter"), s.owner, s.project, s.env)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[string]time.Time{}
+	for rows.Next() {
+		var param string
+		var last int64
+		if err := rows.Scan(&param, &last); err != nil {
+			return nil, err
+		}
+		res[param] = fromMillis(last)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+type sqlEvaluationCountSink struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *database
+}
+
+func (s *sqlEvaluationCountSink) Add(counts []*domain.EvaluationCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err := s.db.inTransaction(ctxT, func(tx *sql.Tx) error {
+		for _, c := range counts {
+			_, err := s.db.exec(ctxT, tx, "INSERT INTO "+evaluationCountTable+
+				" (owner, project, environment, parameter, hour, variant, count, last) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"+
+				" ON CONFLICT (owner, project, environment, parameter, hour, variant) DO UPDATE SET"+
+				" count = "+evaluationCountTable+".count + excluded.count,"+
+				" last = CASE WHEN excluded.last > "+evaluationCountTable+".last THEN excluded.last ELSE "+evaluationCountTable+".last END",
+				c.Owner, c.Project, c.Environment, c.Parameter, millis(c.Hour), c.Variant, c.Count, millis(c.Last))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Int("count", len(counts)).Msg("Evaluation counters added")
+	return nil
+}
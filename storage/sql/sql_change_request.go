@@ -0,0 +1,141 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/rs/zerolog"
+)
+
+type sqlChangeRequestStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *database
+}
+
+// changeRequestColumns lists columns scanChangeRequest reads
+const changeRequestColumns = "owner, project, id, environment, action, parameter_code, parameter, base_version, changeset," +
+	" environment_state, requester, status, error, approvals, comments, reg_date, version"
+
+func changeRequestRecord(cr *domain.ChangeRequest) *record {
+	return &record{
+		table: changeRequestTable,
+		key:   []*column{col("owner", cr.Owner), col("project", cr.Project), col("id", cr.ID)},
+		fields: []*column{
+			col("environment", cr.Environment),
+			col("action", cr.Action),
+			col("parameter_code", cr.ParameterCode),
+			col("parameter", jsonColumn{cr.Parameter}),
+			col("base_version", cr.BaseVersion),
+			col("changeset", jsonColumn{cr.Changeset}),
+			col("environment_state", jsonColumn{cr.EnvironmentState}),
+			col("requester", cr.Requester),
+			col("status", cr.Status),
+			col("error", cr.Error),
+			col("approvals", jsonColumn{cr.Approvals}),
+			col("comments", jsonColumn{cr.Comments}),
+			col("reg_date", millis(cr.RegDate)),
+		},
+	}
+}
+
+func scanChangeRequest(row scanner) (*domain.ChangeRequest, error) {
+	var cr domain.ChangeRequest
+	var regDate int64
+	err := row.Scan(&cr.Owner, &cr.Project, &cr.ID, &cr.Environment, &cr.Action, &cr.ParameterCode, jsonColumn{&cr.Parameter},
+		&cr.BaseVersion, jsonColumn{&cr.Changeset}, jsonColumn{&cr.EnvironmentState}, &cr.Requester, &cr.Status, &cr.Error,
+		jsonColumn{&cr.Approvals}, jsonColumn{&cr.Comments}, &regDate, &cr.Version)
+	if err != nil {
+		return nil, err
+	}
+	cr.RegDate = fromMillis(regDate)
+	canonicalParameter(cr.Parameter)
+	canonicalChangeset(cr.Changeset)
+	if cr.EnvironmentState != nil {
+		cr.EnvironmentState.RegDate = localTime(cr.EnvironmentState.RegDate)
+	}
+	for _, c := range append(append([]*domain.ChangeComment{}, cr.Approvals...), cr.Comments...) {
+		c.Date = localTime(c.Date)
+	}
+	return &cr, nil
+}
+
+func (s *sqlChangeRequestStorage) List(status string) ([]*domain.ChangeRequest, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	query := "SELECT " + changeRequestColumns + " FROM " + changeRequestTable + " WHERE owner = ? AND project = ?"
+	args := []interface{}{s.owner, s.project}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	list := make([]*domain.ChangeRequest, 0)
+	err := s.db.list(ctxT, s.db, func(row scanner) error {
+		item, err := scanChangeRequest(row)
+		if err != nil {
+			return err
+		}
+		list = append(list, item)
+		return nil
+	}, query+" ORDER BY id", args...)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *sqlChangeRequestStorage) Get(id string) (cr *domain.ChangeRequest, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	r := changeRequestRecord(&domain.ChangeRequest{Owner: s.owner, Project: s.project, ID: id})
+	err = s.db.get(ctxT, s.db, r, changeRequestColumns, func(row scanner) error {
+		cr, err = scanChangeRequest(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+func (s *sqlChangeRequestStorage) checkRelations(cr *domain.ChangeRequest) error {
+	if s.owner != cr.Owner || s.project != cr.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, cr.Owner, cr.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *sqlChangeRequestStorage) Save(cr *domain.ChangeRequest) error {
+	if err := s.checkRelations(cr); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	cr.Version = 1
+	if err := s.db.insert(ctxT, s.db, changeRequestRecord(cr), 1, "change request", cr.ID); err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", cr.ID).Msg("Change request inserted")
+	return nil
+}
+
+func (s *sqlChangeRequestStorage) Update(cr *domain.ChangeRequest) error {
+	if err := s.checkRelations(cr); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := cr.Version
+	cr.Version = version + 1
+	if err := s.db.update(ctxT, s.db, changeRequestRecord(cr), version); err != nil {
+		cr.Version = version
+		return err
+	}
+	return nil
+}
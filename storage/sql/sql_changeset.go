@@ -0,0 +1,163 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/rs/zerolog"
+)
+
+type sqlChangesetStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	ctx     context.Context
+	db      *database
+}
+
+// changesetColumns lists columns scanChangeset reads
+const changesetColumns = "owner, project, environment, id, description, principal, changes, reg_date"
+
+func changesetRecord(cs *domain.Changeset) *record {
+	return &record{
+		table: changesetTable,
+		key: []*column{
+			col("owner", cs.Owner), col("project", cs.Project), col("environment", cs.Environment), col("id", cs.ID),
+		},
+		fields: []*column{
+			col("description", cs.Description),
+			col("principal", cs.Principal),
+			col("changes", jsonColumn{cs.Changes}),
+			col("reg_date", millis(cs.RegDate)),
+		},
+	}
+}
+
+func scanChangeset(row scanner) (*domain.Changeset, error) {
+	var cs domain.Changeset
+	var regDate int64
+	err := row.Scan(&cs.Owner, &cs.Project, &cs.Environment, &cs.ID, &cs.Description, &cs.Principal, jsonColumn{&cs.Changes}, &regDate)
+	if err != nil {
+		return nil, err
+	}
+	cs.RegDate = fromMillis(regDate)
+	canonicalChangeset(&cs)
+	return &cs, nil
+}
+
+// canonicalChangeset converts changeset decoded from JSON to canonical form
+func canonicalChangeset(cs *domain.Changeset) {
+	if cs == nil {
+		return
+	}
+	cs.RegDate = localTime(cs.RegDate)
+	for _, item := range cs.Changes {
+		canonicalParameter(item.Parameter)
+	}
+}
+
+func (s *sqlChangesetStorage) List(limit int) ([]*domain.Changeset, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	list := make([]*domain.Changeset, 0)
+	err := s.db.list(ctxT, s.db, func(row scanner) error {
+		item, err := scanChangeset(row)
+		if err != nil {
+			return err
+		}
+		list = append(list, item)
+		return nil
+	}, "SELECT "+changesetColumns+" FROM "+changesetTable+" WHERE owner = ? AND project = ? AND environment = ? ORDER BY reg_date DESC LIMIT ?",
+		s.owner, s.project, s.env, limit)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *sqlChangesetStorage) Get(id string) (cs *domain.Changeset, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	r := changesetRecord(&domain.Changeset{Owner: s.owner, Project: s.project, Environment: s.env, ID: id})
+	err = s.db.get(ctxT, s.db, r, changesetColumns, func(row scanner) error {
+		cs, err = scanChangeset(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+func (s *sqlChangesetStorage) checkRelations(cs *domain.Changeset) error {
+	if s.owner != cs.Owner || s.project != cs.Project || s.env != cs.Environment {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s/%s, got: %s/%s/%s", s.owner, s.project, s.env, cs.Owner, cs.Project, cs.Environment)
+		return storage.ErrEntityRelationsBroken
+	}
+	for _, item := range cs.Changes {
+		p := item.Parameter
+		if p != nil && (s.owner != p.Owner || s.project != p.Project || s.env != p.Environment || item.ParameterCode != p.Code) {
+			s.log.Error().Str("param", item.ParameterCode).Msg("Wrong changeset parameter relations")
+			return storage.ErrEntityRelationsBroken
+		}
+	}
+	return nil
+}
+
+// Apply writes all changes in a single transaction, so nothing is written if any change fails
+func (s *sqlChangesetStorage) Apply(cs *domain.Changeset) error {
+	if err := s.checkRelations(cs); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err := s.db.inTransaction(ctxT, func(tx *sql.Tx) error {
+		for _, item := range cs.Changes {
+			if err := s.write(ctxT, tx, item); err != nil {
+				return err
+			}
+		}
+		err := s.db.insert(ctxT, tx, changesetRecord(cs), 1, "changeset", cs.ID)
+		if _, ok := err.(*storage.ErrUniqueIndex); ok {
+			return storage.ErrVersionConflict
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", cs.ID).Int("changes", len(cs.Changes)).Msg("Changeset applied")
+	return nil
+}
+
+// write applies a single parameter change
+func (s *sqlChangesetStorage) write(ctx context.Context, tx *sql.Tx, item *domain.ChangesetItem) error {
+	r := parameterRecord(&domain.Parameter{Owner: s.owner, Project: s.project, Environment: s.env, Code: item.ParameterCode})
+	if item.Parameter != nil {
+		r = parameterRecord(item.Parameter)
+	}
+	version, err := s.db.version(ctx, tx, r)
+	if err != nil && err != storage.ErrNotFound {
+		return err
+	}
+	switch {
+	case item.Action == domain.ChangeActionCreate && err == nil:
+		return storage.ErrVersionConflict
+	case item.Action == domain.ChangeActionCreate:
+		item.Parameter.Version = 1
+		return s.db.insert(ctx, tx, r, 1, "parameter", item.ParameterCode)
+	case err != nil:
+		return err
+	case item.BaseVersion > 0 && item.BaseVersion != version:
+		return storage.ErrVersionConflict
+	case item.Action == domain.ChangeActionDelete:
+		return s.db.remove(ctx, tx, r, version)
+	}
+	item.Parameter.Version = version + 1
+	return s.db.update(ctx, tx, r, version)
+}
@@ -0,0 +1,107 @@
+package sql
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Toggly/core/storage"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// dialect describes differences between supported databases
+type dialect struct {
+	driver string
+	// json is the JSON column type
+	json string
+	// numbered placeholders are $1, $2 and so on instead of ?
+	numbered bool
+	// skipLocked is appended to queue queries selecting rows to claim
+	skipLocked string
+	// rowLock is appended to queries selecting rows to lock until the transaction ends
+	rowLock string
+	// singleWriter databases are accessed with a single connection
+	singleWriter bool
+	// options are appended to data source names missing them
+	options map[string]string
+	// uniqueViolation and foreignKeyViolation classify write errors
+	uniqueViolation     func(err error) bool
+	foreignKeyViolation func(err error) bool
+}
+
+var dialects = map[string]*dialect{
+	"sqlite3": {
+		driver:       "sqlite3",
+		json:         "TEXT",
+		singleWriter: true,
+		options:      map[string]string{"_foreign_keys": "1"},
+		uniqueViolation: func(err error) bool {
+			e, ok := err.(sqlite3.Error)
+			return ok && (e.ExtendedCode == sqlite3.ErrConstraintUnique || e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+		},
+		foreignKeyViolation: func(err error) bool {
+			e, ok := err.(sqlite3.Error)
+			return ok && e.ExtendedCode == sqlite3.ErrConstraintForeignKey
+		},
+	},
+	"postgres": {
+		driver:     "postgres",
+		json:       "JSONB",
+		numbered:   true,
+		skipLocked: " FOR UPDATE SKIP LOCKED",
+		rowLock:    " FOR UPDATE",
+		uniqueViolation: func(err error) bool {
+			e, ok := err.(*pq.Error)
+			return ok && e.Code == "23505"
+		},
+		foreignKeyViolation: func(err error) bool {
+			e, ok := err.(*pq.Error)
+			return ok && e.Code == "23503"
+		},
+	},
+}
+
+// dsn appends missing dialect options to the data source name
+func (d *dialect) dsn(dsn string) string {
+	for name, value := range d.options {
+		if strings.Contains(dsn, name+"=") {
+			continue
+		}
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + name + "=" + value
+	}
+	return dsn
+}
+
+// rebind replaces ? placeholders with numbered ones if the dialect needs them
+func (d *dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// writeError converts constraint violations of the entity type to storage errors.
+// Other errors are returned as is.
+func (d *dialect) writeError(err error, typ, key string) error {
+	switch {
+	case d.uniqueViolation(err):
+		return &storage.ErrUniqueIndex{Type: typ, Key: key}
+	case d.foreignKeyViolation(err):
+		return storage.ErrEntityRelationsBroken
+	}
+	return err
+}
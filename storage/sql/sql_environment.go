@@ -0,0 +1,189 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/rs/zerolog"
+)
+
+type sqlEnvironmentStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *database
+}
+
+// environmentColumns lists columns scanEnvironment reads
+const environmentColumns = "owner, project, code, description, protected, disabled, reg_date, version"
+
+func environmentRecord(env *domain.Environment) *record {
+	return &record{
+		table: environmentTable,
+		key:   []*column{col("owner", env.Owner), col("project", env.Project), col("code", env.Code)},
+		fields: []*column{
+			col("description", env.Description), col("protected", env.Protected), col("disabled", env.Disabled),
+			col("reg_date", millis(env.RegDate)),
+		},
+	}
+}
+
+func scanEnvironment(row scanner) (*domain.Environment, error) {
+	var env domain.Environment
+	var regDate int64
+	err := row.Scan(&env.Owner, &env.Project, &env.Code, &env.Description, &env.Protected, &env.Disabled, &regDate, &env.Version)
+	if err != nil {
+		return nil, err
+	}
+	env.RegDate = fromMillis(regDate)
+	return &env, nil
+}
+
+func (s *sqlEnvironmentStorage) record(code string) *record {
+	return environmentRecord(&domain.Environment{Owner: s.owner, Project: s.project, Code: code})
+}
+
+func (s *sqlEnvironmentStorage) List() ([]*domain.Environment, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	list := make([]*domain.Environment, 0)
+	err := s.db.list(ctxT, s.db, func(row scanner) error {
+		item, err := scanEnvironment(row)
+		if err != nil {
+			return err
+		}
+		list = append(list, item)
+		return nil
+	}, "SELECT "+environmentColumns+" FROM "+environmentTable+" WHERE owner = ? AND project = ? ORDER BY code", s.owner, s.project)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *sqlEnvironmentStorage) Get(code string) (env *domain.Environment, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.db.get(ctxT, s.db, s.record(code), environmentColumns, func(row scanner) error {
+		env, err = scanEnvironment(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// Delete removes children by foreign key cascades, except for evaluation counters
+func (s *sqlEnvironmentStorage) Delete(code string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	return s.db.inTransaction(ctxT, func(tx *sql.Tx) error {
+		if err := s.db.remove(ctxT, tx, s.record(code), version); err != nil {
+			return err
+		}
+		s.log.Debug().Str("code", code).Msg("Environment deleted")
+		n, err := s.db.exec(ctxT, tx, "DELETE FROM "+evaluationCountTable+" WHERE owner = ? AND project = ? AND environment = ?",
+			s.owner, s.project, code)
+		if err != nil {
+			return err
+		}
+		s.log.Debug().Int64("count", n).Msg("Environment evaluation counters deleted")
+		return nil
+	})
+}
+
+func (s *sqlEnvironmentStorage) checkRelations(env *domain.Environment) error {
+	if s.owner != env.Owner || s.project != env.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, env.Owner, env.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *sqlEnvironmentStorage) Save(env *domain.Environment) error {
+	if err := s.checkRelations(env); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	env.Version = 1
+	if err := s.db.insert(ctxT, s.db, environmentRecord(env), 1, "environment", env.Code); err != nil {
+		return err
+	}
+	s.log.Debug().Str("code", env.Code).Msg("Environment inserted")
+	return nil
+}
+
+func (s *sqlEnvironmentStorage) Update(env *domain.Environment) error {
+	if err := s.checkRelations(env); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := env.Version
+	env.Version = version + 1
+	if err := s.db.update(ctxT, s.db, environmentRecord(env), version); err != nil {
+		env.Version = version
+		return err
+	}
+	return nil
+}
+
+func (s *sqlEnvironmentStorage) For(env string) storage.ForEnvironment {
+	return &sqlForEnvironmentStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     env,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+type sqlForEnvironmentStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	ctx     context.Context
+	db      *database
+}
+
+func (s *sqlForEnvironmentStorage) Parameters() storage.ParameterStorage {
+	return &sqlParameterStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     s.env,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+func (s *sqlForEnvironmentStorage) EvaluationCounts() storage.EvaluationCountStorage {
+	return &sqlEvaluationCountStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     s.env,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+func (s *sqlForEnvironmentStorage) Changesets() storage.ChangesetStorage {
+	return &sqlChangesetStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		env:     s.env,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
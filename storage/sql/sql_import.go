@@ -0,0 +1,85 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Toggly/core/domain"
+)
+
+func (s *sqlStorage) Owners() ([]string, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctxT, "SELECT owner FROM "+projectTable+" UNION SELECT id FROM "+organizationTable+
+		" UNION SELECT owner FROM "+rateLimitTable+" ORDER BY 1")
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	defer rows.Close()
+	owners := make([]string, 0)
+	for rows.Next() {
+		var owner string
+		if err := rows.Scan(&owner); err != nil {
+			return nil, err
+		}
+		owners = append(owners, owner)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return owners, nil
+}
+
+// Import keeps changesets, which have no version, with version 1 like Apply does
+func (s *sqlStorage) Import(entity interface{}) error {
+	var r *record
+	var typ, code string
+	var version int
+	switch e := entity.(type) {
+	case *domain.Organization:
+		orgs := &sqlOrganizationStorage{log: s.log, ctx: s.ctx, db: s.db}
+		ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+		defer cancel()
+		return s.db.inTransaction(ctxT, func(tx *sql.Tx) error {
+			if err := s.db.insert(ctxT, tx, organizationRecord(e), e.Version, "organization", e.ID); err != nil {
+				return err
+			}
+			return orgs.writeMembers(ctxT, tx, e)
+		})
+	case *domain.RateLimit:
+		r = rateLimitRecord(e)
+		typ, code, version = "rate limit", e.Owner, e.Version
+	case *domain.Project:
+		r = projectRecord(e)
+		typ, code, version = "project", e.Code, e.Version
+	case *domain.Environment:
+		r = environmentRecord(e)
+		typ, code, version = "environment", e.Code, e.Version
+	case *domain.Parameter:
+		r = parameterRecord(e)
+		typ, code, version = "parameter", e.Code, e.Version
+	case *domain.Changeset:
+		r = changesetRecord(e)
+		typ, code, version = "changeset", e.ID, 1
+	case *domain.ChangeRequest:
+		r = changeRequestRecord(e)
+		typ, code, version = "change request", e.ID, e.Version
+	case *domain.ScheduledChange:
+		r = scheduledChangeRecord(e)
+		typ, code, version = "scheduled change", e.ID, e.Version
+	case *domain.Webhook:
+		r = webhookRecord(e)
+		typ, code, version = "webhook", e.ID, e.Version
+	case *domain.WebhookDelivery:
+		r = webhookDeliveryRecord(e)
+		typ, code, version = "webhook delivery", e.ID, e.Version
+	default:
+		return fmt.Errorf("Can't import %T", entity)
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	return s.db.insert(ctxT, s.db, r, version, typ, code)
+}
@@ -0,0 +1,342 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/Toggly/core/storage"
+)
+
+const (
+	migrationTable     = "schema_migration"
+	migrationLockTable = "migration_lock"
+	// migrationLockID identifies the single migration lock row
+	migrationLockID = "migration"
+	// migrationLease is the time the lock is held for before another worker may take it over
+	migrationLease = 10 * time.Minute
+	// migrationWait is the interval between attempts to take the lock held by another worker
+	migrationWait = time.Second
+)
+
+// migration changes the database schema. Migrations are applied in version order, each one once
+// and in its own transaction, and must not be changed after they are released.
+// Statements may refer to the dialect JSON column type as {json}.
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+// migrations lists schema migrations in version order
+var migrations = []*migration{
+	{1, "Create schema", []string{
+		`CREATE TABLE project (
+			owner TEXT NOT NULL,
+			code TEXT NOT NULL,
+			status TEXT NOT NULL,
+			description TEXT NOT NULL,
+			reg_date BIGINT NOT NULL,
+			version INTEGER NOT NULL,
+			PRIMARY KEY (owner, code)
+		)`,
+		`CREATE TABLE environment (
+			owner TEXT NOT NULL,
+			project TEXT NOT NULL,
+			code TEXT NOT NULL,
+			description TEXT NOT NULL,
+			protected BOOLEAN NOT NULL,
+			disabled BOOLEAN NOT NULL,
+			reg_date BIGINT NOT NULL,
+			version INTEGER NOT NULL,
+			PRIMARY KEY (owner, project, code),
+			FOREIGN KEY (owner, project) REFERENCES project (owner, code) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE parameter (
+			owner TEXT NOT NULL,
+			project TEXT NOT NULL,
+			environment TEXT NOT NULL,
+			code TEXT NOT NULL,
+			description TEXT NOT NULL,
+			type TEXT NOT NULL,
+			value {json},
+			off_value {json},
+			allowed_values {json},
+			constraints {json},
+			variants {json},
+			prerequisites {json},
+			expires BIGINT,
+			modified BIGINT NOT NULL,
+			version INTEGER NOT NULL,
+			PRIMARY KEY (owner, project, environment, code),
+			FOREIGN KEY (owner, project, environment) REFERENCES environment (owner, project, code) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE change_request (
+			owner TEXT NOT NULL,
+			project TEXT NOT NULL,
+			id TEXT NOT NULL,
+			environment TEXT NOT NULL,
+			action TEXT NOT NULL,
+			parameter_code TEXT NOT NULL,
+			parameter {json},
+			base_version INTEGER NOT NULL,
+			changeset {json},
+			environment_state {json},
+			requester TEXT NOT NULL,
+			status TEXT NOT NULL,
+			error TEXT NOT NULL,
+			approvals {json},
+			comments {json},
+			reg_date BIGINT NOT NULL,
+			version INTEGER NOT NULL,
+			PRIMARY KEY (owner, project, id),
+			FOREIGN KEY (owner, project) REFERENCES project (owner, code) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX change_request_status ON change_request (owner, project, status)`,
+		`CREATE TABLE scheduled_change (
+			owner TEXT NOT NULL,
+			project TEXT NOT NULL,
+			id TEXT NOT NULL,
+			environment TEXT NOT NULL,
+			parameter TEXT NOT NULL,
+			value {json},
+			at BIGINT NOT NULL,
+			time_zone TEXT NOT NULL,
+			revert_after TEXT NOT NULL,
+			revert_of TEXT NOT NULL,
+			requester TEXT NOT NULL,
+			status TEXT NOT NULL,
+			locked_by TEXT NOT NULL,
+			locked_until BIGINT NOT NULL,
+			executed_at BIGINT NOT NULL,
+			previous {json},
+			base_version INTEGER NOT NULL,
+			change_request TEXT NOT NULL,
+			error TEXT NOT NULL,
+			reg_date BIGINT NOT NULL,
+			version INTEGER NOT NULL,
+			PRIMARY KEY (owner, project, id),
+			FOREIGN KEY (owner, project) REFERENCES project (owner, code) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX scheduled_change_due ON scheduled_change (status, at)`,
+		`CREATE TABLE webhook (
+			owner TEXT NOT NULL,
+			project TEXT NOT NULL,
+			id TEXT NOT NULL,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			events {json},
+			active BOOLEAN NOT NULL,
+			reg_date BIGINT NOT NULL,
+			version INTEGER NOT NULL,
+			PRIMARY KEY (owner, project, id),
+			FOREIGN KEY (owner, project) REFERENCES project (owner, code) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE webhook_delivery (
+			owner TEXT NOT NULL,
+			project TEXT NOT NULL,
+			id TEXT NOT NULL,
+			webhook TEXT NOT NULL,
+			event TEXT NOT NULL,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL,
+			attempts {json},
+			next_attempt BIGINT NOT NULL,
+			locked_by TEXT NOT NULL,
+			locked_until BIGINT NOT NULL,
+			reg_date BIGINT NOT NULL,
+			version INTEGER NOT NULL,
+			PRIMARY KEY (owner, project, id),
+			FOREIGN KEY (owner, project) REFERENCES project (owner, code) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX webhook_delivery_webhook ON webhook_delivery (owner, project, webhook, reg_date)`,
+		`CREATE INDEX webhook_delivery_due ON webhook_delivery (status, next_attempt)`,
+		`CREATE TABLE evaluation_count (
+			owner TEXT NOT NULL,
+			project TEXT NOT NULL,
+			environment TEXT NOT NULL,
+			parameter TEXT NOT NULL,
+			hour BIGINT NOT NULL,
+			variant TEXT NOT NULL,
+			count BIGINT NOT NULL,
+			last BIGINT NOT NULL,
+			PRIMARY KEY (owner, project, environment, parameter, hour, variant)
+		)`,
+		`CREATE TABLE changeset (
+			owner TEXT NOT NULL,
+			project TEXT NOT NULL,
+			environment TEXT NOT NULL,
+			id TEXT NOT NULL,
+			description TEXT NOT NULL,
+			principal TEXT NOT NULL,
+			changes {json},
+			reg_date BIGINT NOT NULL,
+			version INTEGER NOT NULL,
+			PRIMARY KEY (owner, project, environment, id),
+			FOREIGN KEY (owner, project, environment) REFERENCES environment (owner, project, code) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX changeset_reg_date ON changeset (owner, project, environment, reg_date)`,
+		`CREATE TABLE organization (
+			id TEXT NOT NULL PRIMARY KEY,
+			name TEXT NOT NULL,
+			description TEXT NOT NULL,
+			teams {json},
+			reg_date BIGINT NOT NULL,
+			version INTEGER NOT NULL
+		)`,
+		`CREATE TABLE organization_member (
+			organization TEXT NOT NULL REFERENCES organization (id) ON DELETE CASCADE,
+			principal TEXT NOT NULL,
+			role TEXT NOT NULL,
+			sort_order INTEGER NOT NULL,
+			PRIMARY KEY (organization, principal)
+		)`,
+		`CREATE INDEX organization_member_principal ON organization_member (principal)`,
+	}},
+	{2, "Create rate limit table", []string{
+		`CREATE TABLE rate_limit (
+			owner TEXT NOT NULL PRIMARY KEY,
+			management_per_minute INTEGER NOT NULL,
+			management_burst INTEGER NOT NULL,
+			evaluation_per_minute INTEGER NOT NULL,
+			evaluation_burst INTEGER NOT NULL,
+			modified BIGINT NOT NULL,
+			version INTEGER NOT NULL
+		)`,
+	}},
+}
+
+// ensureMigrationTables creates tables keeping applied migrations and the migration lock
+func (s *sqlStorage) ensureMigrationTables(ctx context.Context) error {
+	for _, stmt := range []string{
+		`CREATE TABLE IF NOT EXISTS ` + migrationTable + ` (
+			version INTEGER NOT NULL PRIMARY KEY,
+			description TEXT NOT NULL,
+			worker TEXT NOT NULL,
+			applied BIGINT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS ` + migrationLockTable + ` (
+			id TEXT NOT NULL PRIMARY KEY,
+			worker TEXT NOT NULL,
+			expires BIGINT NOT NULL
+		)`,
+	} {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applied returns application times of applied migrations by version
+func (s *sqlStorage) applied(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT version, applied FROM "+migrationTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var applied int64
+		if err := rows.Scan(&version, &applied); err != nil {
+			return nil, err
+		}
+		res[version] = fromMillis(applied).UTC()
+	}
+	return res, rows.Err()
+}
+
+func (s *sqlStorage) Migrations() ([]*storage.Migration, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	applied, err := s.applied(ctxT)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*storage.Migration, 0, len(migrations))
+	for _, m := range migrations {
+		item := &storage.Migration{Version: m.version, Description: m.description}
+		if date, ok := applied[m.version]; ok {
+			item.Applied = &date
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+func (s *sqlStorage) Migrate(worker string) error {
+	if err := s.lock(worker, migrationLease); err != nil {
+		return err
+	}
+	defer s.unlock(worker)
+	applied, err := s.applied(s.ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		// every migration gets a full lease
+		if err := s.lock(worker, migrationLease); err != nil {
+			return err
+		}
+		s.log.Info().Int("version", m.version).Str("description", m.description).Msg("Applying migration")
+		if err := s.apply(m, worker); err != nil {
+			s.log.Error().Err(err).Int("version", m.version).Msg("Migration failed")
+			return err
+		}
+	}
+	s.log.Debug().Int("version", len(migrations)).Msg("Storage schema is up to date")
+	return nil
+}
+
+// apply runs migration statements and records the migration in one transaction
+func (s *sqlStorage) apply(m *migration, worker string) error {
+	return s.db.inTransaction(s.ctx, func(tx *sql.Tx) error {
+		for _, stmt := range m.statements {
+			if _, err := tx.ExecContext(s.ctx, strings.Replace(stmt, "{json}", s.db.dialect.json, -1)); err != nil {
+				return err
+			}
+		}
+		_, err := s.db.exec(s.ctx, tx, "INSERT INTO "+migrationTable+" (version, description, worker, applied) VALUES (?, ?, ?, ?)",
+			m.version, m.description, worker, millis(time.Now()))
+		return err
+	})
+}
+
+// lock takes the migration lock for the lease or extends the lease of the lock held by the worker.
+// It waits while the lock is held by another worker, but not longer than the lease.
+func (s *sqlStorage) lock(worker string, lease time.Duration) error {
+	deadline := time.Now().Add(lease)
+	for {
+		now := time.Now()
+		n, err := s.db.exec(s.ctx, s.db, "INSERT INTO "+migrationLockTable+" (id, worker, expires) VALUES (?, ?, ?) "+
+			"ON CONFLICT (id) DO UPDATE SET worker = excluded.worker, expires = excluded.expires "+
+			"WHERE "+migrationLockTable+".worker = excluded.worker OR "+migrationLockTable+".expires < ?",
+			migrationLockID, worker, millis(now.Add(lease)), millis(now))
+		if err != nil || n > 0 {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return storage.ErrMigrationLocked
+		}
+		s.log.Info().Msg("Waiting for migration lock")
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-time.After(migrationWait):
+		}
+	}
+}
+
+func (s *sqlStorage) unlock(worker string) {
+	_, err := s.db.exec(s.ctx, s.db, "DELETE FROM "+migrationLockTable+" WHERE id = ? AND worker = ?", migrationLockID, worker)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Can't release migration lock")
+	}
+}
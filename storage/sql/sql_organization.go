@@ -0,0 +1,160 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/rs/zerolog"
+)
+
+// organizationColumns lists columns scanOrganization reads
+const organizationColumns = "id, name, description, teams, reg_date, version"
+
+func organizationRecord(org *domain.Organization) *record {
+	return &record{
+		table: organizationTable,
+		key:   []*column{col("id", org.ID)},
+		fields: []*column{
+			col("name", org.Name), col("description", org.Description), col("teams", jsonColumn{org.Teams}),
+			col("reg_date", millis(org.RegDate)),
+		},
+	}
+}
+
+func scanOrganization(row scanner) (*domain.Organization, error) {
+	var org domain.Organization
+	var regDate int64
+	if err := row.Scan(&org.ID, &org.Name, &org.Description, jsonColumn{&org.Teams}, &regDate, &org.Version); err != nil {
+		return nil, err
+	}
+	org.RegDate = fromMillis(regDate)
+	return &org, nil
+}
+
+// Organization members are kept in the member table in their order, a principal once
+type sqlOrganizationStorage struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *database
+}
+
+func (s *sqlOrganizationStorage) List(principal string) ([]*domain.Organization, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	list := make([]*domain.Organization, 0)
+	err := s.db.list(ctxT, s.db, func(row scanner) error {
+		item, err := scanOrganization(row)
+		if err != nil {
+			return err
+		}
+		list = append(list, item)
+		return nil
+	}, "SELECT o.id, o.name, o.description, o.teams, o.reg_date, o.version FROM "+organizationTable+" o JOIN "+memberTable+
+		" m ON m.organization = o.id WHERE m.principal = ? ORDER BY o.name", principal)
+	if err == nil {
+		for _, org := range list {
+			if err = s.readMembers(ctxT, s.db, org); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *sqlOrganizationStorage) Get(id string) (org *domain.Organization, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.db.get(ctxT, s.db, organizationRecord(&domain.Organization{ID: id}), organizationColumns, func(row scanner) error {
+		org, err = scanOrganization(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = s.readMembers(ctxT, s.db, org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (s *sqlOrganizationStorage) Delete(id string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	if err := s.db.remove(ctxT, s.db, organizationRecord(&domain.Organization{ID: id}), version); err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", id).Msg("Organization deleted")
+	return nil
+}
+
+func (s *sqlOrganizationStorage) Save(org *domain.Organization) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	org.Version = 1
+	err := s.db.inTransaction(ctxT, func(tx *sql.Tx) error {
+		if err := s.db.insert(ctxT, tx, organizationRecord(org), 1, "organization", org.ID); err != nil {
+			return err
+		}
+		return s.writeMembers(ctxT, tx, org)
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", org.ID).Msg("Organization inserted")
+	return nil
+}
+
+func (s *sqlOrganizationStorage) Update(org *domain.Organization) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := org.Version
+	org.Version = version + 1
+	err := s.db.inTransaction(ctxT, func(tx *sql.Tx) error {
+		if err := s.db.update(ctxT, tx, organizationRecord(org), version); err != nil {
+			return err
+		}
+		if _, err := s.db.exec(ctxT, tx, "DELETE FROM "+memberTable+" WHERE organization = ?", org.ID); err != nil {
+			return err
+		}
+		return s.writeMembers(ctxT, tx, org)
+	})
+	if err != nil {
+		org.Version = version
+		return err
+	}
+	return nil
+}
+
+func (s *sqlOrganizationStorage) writeMembers(ctx context.Context, tx *sql.Tx, org *domain.Organization) error {
+	written := map[string]bool{}
+	for i, m := range org.Members {
+		if written[m.Principal] {
+			continue
+		}
+		written[m.Principal] = true
+		_, err := s.db.exec(ctx, tx, "INSERT INTO "+memberTable+" (organization, principal, role, sort_order) VALUES (?, ?, ?, ?)",
+			org.ID, m.Principal, m.Role, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlOrganizationStorage) readMembers(ctx context.Context, q querier, org *domain.Organization) error {
+	org.Members = make([]*domain.Member, 0)
+	return s.db.list(ctx, q, func(row scanner) error {
+		var m domain.Member
+		if err := row.Scan(&m.Principal, &m.Role); err != nil {
+			return err
+		}
+		org.Members = append(org.Members, &m)
+		return nil
+	}, "SELECT principal, role FROM "+memberTable+" WHERE organization = ? ORDER BY sort_order", org.ID)
+}
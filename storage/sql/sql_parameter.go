@@ -0,0 +1,241 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/rs/zerolog"
+)
+
+// parameterColumns lists columns scanParameter reads
+const parameterColumns = "owner, project, environment, code, description, type, value, off_value, allowed_values," +
+	" constraints, variants, prerequisites, expires, modified, version"
+
+// parameterRecord is shared by parameter and changeset storages
+func parameterRecord(p *domain.Parameter) *record {
+	var expires interface{}
+	if p.Expires != nil {
+		expires = millis(*p.Expires)
+	}
+	return &record{
+		table: parameterTable,
+		key: []*column{
+			col("owner", p.Owner), col("project", p.Project), col("environment", p.Environment), col("code", p.Code),
+		},
+		fields: []*column{
+			col("description", p.Description),
+			col("type", p.Type),
+			col("value", jsonColumn{p.Value}),
+			col("off_value", jsonColumn{p.OffValue}),
+			col("allowed_values", jsonColumn{p.AllowedValues}),
+			col("constraints", jsonColumn{p.Constraints}),
+			col("variants", jsonColumn{p.Variants}),
+			col("prerequisites", jsonColumn{p.Prerequisites}),
+			col("expires", expires),
+			col("modified", millis(p.Modified)),
+		},
+	}
+}
+
+func scanParameter(row scanner) (*domain.Parameter, error) {
+	var p domain.Parameter
+	var expires sql.NullInt64
+	var modified int64
+	err := row.Scan(&p.Owner, &p.Project, &p.Environment, &p.Code, &p.Description, &p.Type,
+		jsonColumn{&p.Value}, jsonColumn{&p.OffValue}, jsonColumn{&p.AllowedValues}, jsonColumn{&p.Constraints},
+		jsonColumn{&p.Variants}, jsonColumn{&p.Prerequisites}, &expires, &modified, &p.Version)
+	if err != nil {
+		return nil, err
+	}
+	if expires.Valid {
+		t := fromMillis(expires.Int64)
+		p.Expires = &t
+	}
+	p.Modified = fromMillis(modified)
+	canonicalParameter(&p)
+	return &p, nil
+}
+
+// canonicalParameter converts parameter decoded from JSON to the canonical form: values
+// of the parameter type and local times
+func canonicalParameter(p *domain.Parameter) {
+	if p == nil {
+		return
+	}
+	if p.Expires != nil {
+		t := localTime(*p.Expires)
+		p.Expires = &t
+	}
+	p.Modified = localTime(p.Modified)
+	p.Value = canonicalValue(p.Type, p.Value)
+	p.OffValue = canonicalValue(p.Type, p.OffValue)
+	for i, v := range p.AllowedValues {
+		p.AllowedValues[i] = canonicalValue(p.Type, v)
+	}
+	for _, v := range p.Variants {
+		v.Value = canonicalValue(p.Type, v.Value)
+	}
+	for _, pr := range p.Prerequisites {
+		pr.Value = plainValue(pr.Value)
+	}
+}
+
+// canonicalValue converts the value decoded from JSON to the canonical form of the parameter type.
+// Values not matching the type are converted by plainValue.
+func canonicalValue(typ string, v interface{}) interface{} {
+	switch typ {
+	case domain.ParameterTypeInt:
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				return i
+			}
+		}
+	case domain.ParameterTypeFloat:
+		if n, ok := v.(json.Number); ok {
+			if f, err := n.Float64(); err == nil {
+				return f
+			}
+		}
+	case domain.ParameterTypeJSON:
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			// Marshal sorts object keys and keeps numbers as they were written
+			if data, err := json.Marshal(v); err == nil {
+				return domain.JSONValue(data)
+			}
+		}
+	case domain.ParameterTypeStringList:
+		if items, ok := v.([]interface{}); ok {
+			list := make([]string, 0, len(items))
+			for _, item := range items {
+				s, ok := item.(string)
+				if !ok {
+					return plainValue(v)
+				}
+				list = append(list, s)
+			}
+			return list
+		}
+	}
+	return plainValue(v)
+}
+
+// plainValue converts numbers of the value decoded from JSON to int64 if they are integers
+// and to float64 otherwise, the types of values of unknown parameter type
+func plainValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = plainValue(item)
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = plainValue(item)
+		}
+	}
+	return v
+}
+
+type sqlParameterStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	env     string
+	ctx     context.Context
+	db      *database
+}
+
+func (s *sqlParameterStorage) record(code string) *record {
+	return parameterRecord(&domain.Parameter{Owner: s.owner, Project: s.project, Environment: s.env, Code: code})
+}
+
+func (s *sqlParameterStorage) List() ([]*domain.Parameter, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	list := make([]*domain.Parameter, 0)
+	err := s.db.list(ctxT, s.db, func(row scanner) error {
+		item, err := scanParameter(row)
+		if err != nil {
+			return err
+		}
+		list = append(list, item)
+		return nil
+	}, "SELECT "+parameterColumns+" FROM "+parameterTable+" WHERE owner = ? AND project = ? AND environment = ? ORDER BY code",
+		s.owner, s.project, s.env)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *sqlParameterStorage) Get(code string) (param *domain.Parameter, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.db.get(ctxT, s.db, s.record(code), parameterColumns, func(row scanner) error {
+		param, err = scanParameter(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return param, nil
+}
+
+func (s *sqlParameterStorage) Delete(code string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	if err := s.db.remove(ctxT, s.db, s.record(code), version); err != nil {
+		return err
+	}
+	s.log.Debug().Str("code", code).Msg("Parameter deleted")
+	return nil
+}
+
+func (s *sqlParameterStorage) checkRelations(param *domain.Parameter) error {
+	if s.owner != param.Owner || s.project != param.Project || s.env != param.Environment {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s/%s, got: %s/%s/%s",
+			s.owner, s.project, s.env, param.Owner, param.Project, param.Environment)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *sqlParameterStorage) Save(param *domain.Parameter) error {
+	if err := s.checkRelations(param); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	param.Version = 1
+	if err := s.db.insert(ctxT, s.db, parameterRecord(param), 1, "parameter", param.Code); err != nil {
+		return err
+	}
+	s.log.Debug().Str("code", param.Code).Msg("Parameter inserted")
+	return nil
+}
+
+func (s *sqlParameterStorage) Update(param *domain.Parameter) error {
+	if err := s.checkRelations(param); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := param.Version
+	param.Version = version + 1
+	if err := s.db.update(ctxT, s.db, parameterRecord(param), version); err != nil {
+		param.Version = version
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/rs/zerolog"
+)
+
+type sqlProjectStorage struct {
+	log   zerolog.Logger
+	owner string
+	ctx   context.Context
+	db    *database
+}
+
+// projectColumns lists columns scanProject reads
+const projectColumns = "owner, code, status, description, reg_date, version"
+
+func projectRecord(p *domain.Project) *record {
+	return &record{
+		table: projectTable,
+		key:   []*column{col("owner", p.Owner), col("code", p.Code)},
+		fields: []*column{
+			col("status", p.Status), col("description", p.Description), col("reg_date", millis(p.RegDate)),
+		},
+	}
+}
+
+func scanProject(row scanner) (*domain.Project, error) {
+	var p domain.Project
+	var regDate int64
+	if err := row.Scan(&p.Owner, &p.Code, &p.Status, &p.Description, &regDate, &p.Version); err != nil {
+		return nil, err
+	}
+	p.RegDate = fromMillis(regDate)
+	return &p, nil
+}
+
+func (s *sqlProjectStorage) record(code string) *record {
+	return projectRecord(&domain.Project{Owner: s.owner, Code: code})
+}
+
+func (s *sqlProjectStorage) List() ([]*domain.Project, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	list := make([]*domain.Project, 0)
+	err := s.db.list(ctxT, s.db, func(row scanner) error {
+		item, err := scanProject(row)
+		if err != nil {
+			return err
+		}
+		list = append(list, item)
+		return nil
+	}, "SELECT "+projectColumns+" FROM "+projectTable+" WHERE owner = ? ORDER BY code", s.owner)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *sqlProjectStorage) Get(code string) (project *domain.Project, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.db.get(ctxT, s.db, s.record(code), projectColumns, func(row scanner) error {
+		project, err = scanProject(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// Delete removes children by foreign key cascades, except for evaluation counters.
+// Without cascade the project row is locked first, so environments can't be added
+// between the check and the removal.
+func (s *sqlProjectStorage) Delete(code string, version int, cascade bool) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	return s.db.inTransaction(ctxT, func(tx *sql.Tx) error {
+		if !cascade {
+			if err := s.checkEmpty(ctxT, tx, code); err != nil {
+				return err
+			}
+		}
+		if err := s.db.remove(ctxT, tx, s.record(code), version); err != nil {
+			return err
+		}
+		s.log.Debug().Str("code", code).Msg("Project deleted")
+		n, err := s.db.exec(ctxT, tx, "DELETE FROM "+evaluationCountTable+" WHERE owner = ? AND project = ?", s.owner, code)
+		if err != nil {
+			return err
+		}
+		s.log.Debug().Int64("count", n).Msg("Project evaluation counters deleted")
+		return nil
+	})
+}
+
+// checkEmpty locks the project row and returns storage.ErrNotEmpty if the project has environments
+func (s *sqlProjectStorage) checkEmpty(ctx context.Context, tx *sql.Tx, code string) error {
+	r := s.record(code)
+	where, args := r.where()
+	var version int
+	err := tx.QueryRowContext(ctx, s.db.dialect.rebind("SELECT version FROM "+r.table+" WHERE "+where+s.db.dialect.rowLock), args...).Scan(&version)
+	if err == sql.ErrNoRows {
+		return storage.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	var n int
+	err = tx.QueryRowContext(ctx, s.db.dialect.rebind("SELECT COUNT(*) FROM "+environmentTable+" WHERE owner = ? AND project = ?"), s.owner, code).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return storage.ErrNotEmpty
+	}
+	return nil
+}
+
+func (s *sqlProjectStorage) Save(project *domain.Project) error {
+	if s.owner != project.Owner {
+		s.log.Error().Msgf("Wrong owner. Expected: %s, got: %s", s.owner, project.Owner)
+		return storage.ErrEntityRelationsBroken
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	project.Version = 1
+	if err := s.db.insert(ctxT, s.db, projectRecord(project), 1, "project", project.Code); err != nil {
+		return err
+	}
+	s.log.Debug().Str("code", project.Code).Msg("Project inserted")
+	return nil
+}
+
+func (s *sqlProjectStorage) Update(project *domain.Project) error {
+	if s.owner != project.Owner {
+		s.log.Error().Msgf("Wrong owner. Expected: %s, got: %s", s.owner, project.Owner)
+		return storage.ErrEntityRelationsBroken
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := project.Version
+	project.Version = version + 1
+	if err := s.db.update(ctxT, s.db, projectRecord(project), version); err != nil {
+		project.Version = version
+		return err
+	}
+	return nil
+}
+
+func (s *sqlProjectStorage) For(project string) storage.ForProject {
+	return &sqlForProjectStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: project,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+type sqlForProjectStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *database
+}
+
+func (s *sqlForProjectStorage) Environments() storage.EnvironmentStorage {
+	return &sqlEnvironmentStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+func (s *sqlForProjectStorage) ChangeRequests() storage.ChangeRequestStorage {
+	return &sqlChangeRequestStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+func (s *sqlForProjectStorage) ScheduledChanges() storage.ScheduledChangeStorage {
+	return &sqlScheduledChangeStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+func (s *sqlForProjectStorage) Webhooks() storage.WebhookStorage {
+	return &sqlWebhookStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
+
+func (s *sqlForProjectStorage) WebhookDeliveries() storage.WebhookDeliveryStorage {
+	return &sqlWebhookDeliveryStorage{
+		log:     s.log,
+		owner:   s.owner,
+		project: s.project,
+		ctx:     s.ctx,
+		db:      s.db,
+	}
+}
@@ -0,0 +1,109 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/rs/zerolog"
+)
+
+type sqlRateLimitStorage struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *database
+}
+
+// rateLimitColumns lists columns scanRateLimit reads
+const rateLimitColumns = "owner, management_per_minute, management_burst, evaluation_per_minute, evaluation_burst, modified, version"
+
+func rateLimitRecord(limit *domain.RateLimit) *record {
+	return &record{
+		table: rateLimitTable,
+		key:   []*column{col("owner", limit.Owner)},
+		fields: []*column{
+			col("management_per_minute", limit.Management.PerMinute),
+			col("management_burst", limit.Management.Burst),
+			col("evaluation_per_minute", limit.Evaluation.PerMinute),
+			col("evaluation_burst", limit.Evaluation.Burst),
+			col("modified", millis(limit.Modified)),
+		},
+	}
+}
+
+func scanRateLimit(row scanner) (*domain.RateLimit, error) {
+	var limit domain.RateLimit
+	var modified int64
+	err := row.Scan(&limit.Owner, &limit.Management.PerMinute, &limit.Management.Burst,
+		&limit.Evaluation.PerMinute, &limit.Evaluation.Burst, &modified, &limit.Version)
+	if err != nil {
+		return nil, err
+	}
+	limit.Modified = fromMillis(modified)
+	return &limit, nil
+}
+
+func (s *sqlRateLimitStorage) List() ([]*domain.RateLimit, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	list := make([]*domain.RateLimit, 0)
+	err := s.db.list(ctxT, s.db, func(row scanner) error {
+		item, err := scanRateLimit(row)
+		if err != nil {
+			return err
+		}
+		list = append(list, item)
+		return nil
+	}, "SELECT "+rateLimitColumns+" FROM "+rateLimitTable+" ORDER BY owner")
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *sqlRateLimitStorage) Get(owner string) (limit *domain.RateLimit, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.db.get(ctxT, s.db, rateLimitRecord(&domain.RateLimit{Owner: owner}), rateLimitColumns, func(row scanner) error {
+		limit, err = scanRateLimit(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return limit, nil
+}
+
+func (s *sqlRateLimitStorage) Delete(owner string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	if err := s.db.remove(ctxT, s.db, rateLimitRecord(&domain.RateLimit{Owner: owner}), version); err != nil {
+		return err
+	}
+	s.log.Debug().Str("owner", owner).Msg("Rate limit deleted")
+	return nil
+}
+
+func (s *sqlRateLimitStorage) Save(limit *domain.RateLimit) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	limit.Version = 1
+	if err := s.db.insert(ctxT, s.db, rateLimitRecord(limit), 1, "rate limit", limit.Owner); err != nil {
+		return err
+	}
+	s.log.Debug().Str("owner", limit.Owner).Msg("Rate limit inserted")
+	return nil
+}
+
+func (s *sqlRateLimitStorage) Update(limit *domain.RateLimit) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := limit.Version
+	limit.Version = version + 1
+	if err := s.db.update(ctxT, s.db, rateLimitRecord(limit), version); err != nil {
+		limit.Version = version
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/rs/zerolog"
+)
+
+// scheduledChangeColumns lists columns scanScheduledChange reads
+const scheduledChangeColumns = "owner, project, id, environment, parameter, value, at, time_zone, revert_after, revert_of," +
+	" requester, status, locked_by, locked_until, executed_at, previous, base_version, change_request, error, reg_date, version"
+
+func scheduledChangeRecord(sc *domain.ScheduledChange) *record {
+	return &record{
+		table: scheduledChangeTable,
+		key:   []*column{col("owner", sc.Owner), col("project", sc.Project), col("id", sc.ID)},
+		fields: []*column{
+			col("environment", sc.Environment),
+			col("parameter", sc.Parameter),
+			col("value", jsonColumn{sc.Value}),
+			col("at", millis(sc.At)),
+			col("time_zone", sc.TimeZone),
+			col("revert_after", sc.RevertAfter),
+			col("revert_of", sc.RevertOf),
+			col("requester", sc.Requester),
+			col("status", sc.Status),
+			col("locked_by", sc.LockedBy),
+			col("locked_until", millis(sc.LockedUntil)),
+			col("executed_at", millis(sc.ExecutedAt)),
+			col("previous", jsonColumn{sc.Previous}),
+			col("base_version", sc.BaseVersion),
+			col("change_request", sc.ChangeRequest),
+			col("error", sc.Error),
+			col("reg_date", millis(sc.RegDate)),
+		},
+	}
+}
+
+// scanScheduledChange keeps numbers of values as json.Number, as the parameter type is unknown
+func scanScheduledChange(row scanner) (*domain.ScheduledChange, error) {
+	var sc domain.ScheduledChange
+	var at, lockedUntil, executedAt, regDate int64
+	err := row.Scan(&sc.Owner, &sc.Project, &sc.ID, &sc.Environment, &sc.Parameter, jsonColumn{&sc.Value}, &at, &sc.TimeZone,
+		&sc.RevertAfter, &sc.RevertOf, &sc.Requester, &sc.Status, &sc.LockedBy, &lockedUntil, &executedAt,
+		jsonColumn{&sc.Previous}, &sc.BaseVersion, &sc.ChangeRequest, &sc.Error, &regDate, &sc.Version)
+	if err != nil {
+		return nil, err
+	}
+	sc.At, sc.LockedUntil, sc.ExecutedAt, sc.RegDate = fromMillis(at), fromMillis(lockedUntil), fromMillis(executedAt), fromMillis(regDate)
+	return &sc, nil
+}
+
+type sqlScheduledChangeStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *database
+}
+
+func (s *sqlScheduledChangeStorage) List(status string) ([]*domain.ScheduledChange, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	query := "SELECT " + scheduledChangeColumns + " FROM " + scheduledChangeTable + " WHERE owner = ? AND project = ?"
+	args := []interface{}{s.owner, s.project}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	list := make([]*domain.ScheduledChange, 0)
+	err := s.db.list(ctxT, s.db, func(row scanner) error {
+		item, err := scanScheduledChange(row)
+		if err != nil {
+			return err
+		}
+		list = append(list, item)
+		return nil
+	}, query+" ORDER BY id", args...)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *sqlScheduledChangeStorage) Get(id string) (sc *domain.ScheduledChange, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	r := scheduledChangeRecord(&domain.ScheduledChange{Owner: s.owner, Project: s.project, ID: id})
+	err = s.db.get(ctxT, s.db, r, scheduledChangeColumns, func(row scanner) error {
+		sc, err = scanScheduledChange(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+func (s *sqlScheduledChangeStorage) checkRelations(sc *domain.ScheduledChange) error {
+	if s.owner != sc.Owner || s.project != sc.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, sc.Owner, sc.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *sqlScheduledChangeStorage) Save(sc *domain.ScheduledChange) error {
+	if err := s.checkRelations(sc); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	sc.Version = 1
+	if err := s.db.insert(ctxT, s.db, scheduledChangeRecord(sc), 1, "scheduled change", sc.ID); err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", sc.ID).Msg("Scheduled change inserted")
+	return nil
+}
+
+func (s *sqlScheduledChangeStorage) Update(sc *domain.ScheduledChange) error {
+	if err := s.checkRelations(sc); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := sc.Version
+	sc.Version = version + 1
+	if err := s.db.update(ctxT, s.db, scheduledChangeRecord(sc), version); err != nil {
+		sc.Version = version
+		return err
+	}
+	return nil
+}
+
+type sqlScheduledChangeQueue struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *database
+}
+
+// Claim selects the change with row locking where the dialect supports it
+func (s *sqlScheduledChangeQueue) Claim(now time.Time, worker string, lease time.Duration) (*domain.ScheduledChange, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	var sc *domain.ScheduledChange
+	err := s.db.inTransaction(ctxT, func(tx *sql.Tx) error {
+		var err error
+		sc, err = scanScheduledChange(tx.QueryRowContext(ctxT, s.db.dialect.rebind("SELECT "+scheduledChangeColumns+" FROM "+scheduledChangeTable+
+			" WHERE status = ? AND at <= ? OR status = ? AND locked_until < ? ORDER BY at LIMIT 1"+s.db.dialect.skipLocked),
+			domain.ScheduledChangeStatusPending, millis(now), domain.ScheduledChangeStatusRunning, millis(now)))
+		if err == sql.ErrNoRows {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		version := sc.Version
+		sc.Status = domain.ScheduledChangeStatusRunning
+		sc.LockedBy = worker
+		sc.LockedUntil = now.Add(lease)
+		sc.Version = version + 1
+		return s.db.update(ctxT, tx, scheduledChangeRecord(sc), version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log.Debug().Str("id", sc.ID).Str("worker", worker).Msg("Scheduled change claimed")
+	return sc, nil
+}
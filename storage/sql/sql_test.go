@@ -0,0 +1,169 @@
+package sql_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	asserts "github.com/stretchr/testify/assert"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/storage/sql"
+	"github.com/Toggly/core/storage/storagetest"
+)
+
+var logger = log.Output(zerolog.ConsoleWriter{
+	Out:     os.Stdout,
+	NoColor: true,
+}).Level(zerolog.DebugLevel)
+
+var (
+	dir         string
+	dataStorage storage.DataStorage
+	closeDB     context.CancelFunc
+)
+
+// openDB opens SQLite storage in the file and migrates it
+func openDB(path string) (storage.DataStorage, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := sql.NewSQLDataStorage(ctx, "sqlite3", path, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't create storage")
+	}
+	if err = s.Connect(); err != nil {
+		logger.Fatal().Err(err).Msg("Can't connect")
+	}
+	if err = s.(storage.Migrator).Migrate("test"); err != nil {
+		logger.Fatal().Err(err).Msg("Can't migrate")
+	}
+	return s, cancel
+}
+
+// getDB returns storage opened in the test directory, the same one until afterTest
+func getDB() storage.DataStorage {
+	if dataStorage == nil {
+		dataStorage, closeDB = openDB(filepath.Join(dir, "toggly.sqlite"))
+	}
+	return dataStorage
+}
+
+func beforeTest() {
+	var err error
+	dir, err = ioutil.TempDir("", "toggly_storage_test")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't create test directory")
+	}
+}
+
+func afterTest() {
+	if closeDB != nil {
+		closeDB()
+	}
+	dataStorage, closeDB = nil, nil
+	os.RemoveAll(dir)
+}
+
+// saveParents saves the project and its environments children are stored in
+func saveParents(db storage.DataStorage, owner, project string, envs ...string) {
+	pdb := db.ForOwner(owner).Projects()
+	if err := pdb.Save(&domain.Project{Code: project, Owner: owner, Status: domain.ProjectStatusActive}); err != nil {
+		logger.Fatal().Err(err).Msg("Can't save project")
+	}
+	for _, env := range envs {
+		if err := pdb.For(project).Environments().Save(&domain.Environment{Code: env, Owner: owner, Project: project}); err != nil {
+			logger.Fatal().Err(err).Msg("Can't save environment")
+		}
+	}
+}
+
+func TestSQLStorage(t *testing.T) {
+	assert := asserts.New(t)
+
+	beforeTest()
+
+	path := filepath.Join(dir, "toggly.sqlite")
+	db, cancel := openDB(path)
+
+	t.Run("unknown driver", func(t *testing.T) {
+		_, err := sql.NewSQLDataStorage(context.Background(), "oracle", "", logger)
+		assert.EqualError(err, "Unknown SQL driver oracle")
+	})
+
+	t.Run("migrations", func(t *testing.T) {
+		list, err := db.(storage.Migrator).Migrations()
+		assert.Nil(err)
+		assert.NotEmpty(list)
+		for _, m := range list {
+			assert.NotNil(m.Applied)
+		}
+		assert.Nil(db.(storage.Migrator).Migrate("other"))
+	})
+
+	pdb := db.ForOwner("ow1").Projects()
+
+	t.Run("missing parent", func(t *testing.T) {
+		err := pdb.For("proj1").Environments().Save(&domain.Environment{Code: "env1", Owner: "ow1", Project: "proj1"})
+		assert.Equal(storage.ErrEntityRelationsBroken, err)
+	})
+
+	saveParents(db, "ow1", "proj1", "env1")
+
+	t.Run("owner isolation", func(t *testing.T) {
+		_, err := db.ForOwner("ow2").Projects().Get("proj1")
+		assert.Equal(storage.ErrNotFound, err)
+		list, err := db.ForOwner("ow2").Projects().List()
+		assert.Nil(err)
+		assert.Len(list, 0)
+	})
+
+	t.Run("subscribe", func(t *testing.T) {
+		assert.Equal(storage.ErrChangesNotSupported, db.Subscribe("test", func(c *storage.Change) {}))
+	})
+
+	t.Run("values", func(t *testing.T) {
+		params := db.ForOwner("ow1").Projects().For("proj1").Environments().For("env1").Parameters()
+		for _, p := range []*domain.Parameter{
+			{Code: "int", Type: domain.ParameterTypeInt, Value: int64(9007199254740993),
+				Variants: []*domain.Variant{{Name: "big", Value: int64(-9007199254740993), Weight: 1}}},
+			{Code: "float", Type: domain.ParameterTypeFloat, Value: float64(1), AllowedValues: []interface{}{float64(1), 2.5}},
+			{Code: "json", Type: domain.ParameterTypeJSON, Value: domain.JSONValue(`{"a":[1,{"b":12345678901234567890}]}`)},
+			{Code: "list", Type: domain.ParameterTypeStringList, Value: []string{}, OffValue: []string{"a"}},
+		} {
+			p.Owner, p.Project, p.Environment = "ow1", "proj1", "env1"
+			assert.Nil(params.Save(p))
+			res, err := params.Get(p.Code)
+			if assert.Nil(err, p.Code) {
+				assert.Equal(p.Value, res.Value, p.Code)
+				assert.Equal(p.OffValue, res.OffValue, p.Code)
+				assert.Equal(p.AllowedValues, res.AllowedValues, p.Code)
+				assert.Equal(p.Variants, res.Variants, p.Code)
+			}
+		}
+	})
+
+	t.Run("reopen", func(t *testing.T) {
+		cancel()
+		db, cancel = openDB(path)
+		res, err := db.ForOwner("ow1").Projects().For("proj1").Environments().Get("env1")
+		assert.Nil(err)
+		assert.Equal(1, res.Version)
+	})
+
+	cancel()
+	afterTest()
+}
+
+func TestSQLConformance(t *testing.T) {
+	storagetest.Run(t, &storagetest.Backend{
+		Open: func() storage.DataStorage {
+			beforeTest()
+			return getDB()
+		},
+		Close: afterTest,
+	})
+}
@@ -0,0 +1,285 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/rs/zerolog"
+)
+
+type sqlWebhookStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *database
+}
+
+// webhookColumns lists columns scanWebhook reads
+const webhookColumns = "owner, project, id, url, secret, events, active, reg_date, version"
+
+func webhookRecord(hook *domain.Webhook) *record {
+	return &record{
+		table: webhookTable,
+		key:   []*column{col("owner", hook.Owner), col("project", hook.Project), col("id", hook.ID)},
+		fields: []*column{
+			col("url", hook.URL), col("secret", hook.Secret), col("events", jsonColumn{hook.Events}),
+			col("active", hook.Active), col("reg_date", millis(hook.RegDate)),
+		},
+	}
+}
+
+func scanWebhook(row scanner) (*domain.Webhook, error) {
+	var hook domain.Webhook
+	var regDate int64
+	err := row.Scan(&hook.Owner, &hook.Project, &hook.ID, &hook.URL, &hook.Secret, jsonColumn{&hook.Events}, &hook.Active, &regDate, &hook.Version)
+	if err != nil {
+		return nil, err
+	}
+	hook.RegDate = fromMillis(regDate)
+	return &hook, nil
+}
+
+func (s *sqlWebhookStorage) record(id string) *record {
+	return webhookRecord(&domain.Webhook{Owner: s.owner, Project: s.project, ID: id})
+}
+
+func (s *sqlWebhookStorage) List() ([]*domain.Webhook, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	list := make([]*domain.Webhook, 0)
+	err := s.db.list(ctxT, s.db, func(row scanner) error {
+		item, err := scanWebhook(row)
+		if err != nil {
+			return err
+		}
+		list = append(list, item)
+		return nil
+	}, "SELECT "+webhookColumns+" FROM "+webhookTable+" WHERE owner = ? AND project = ? ORDER BY id", s.owner, s.project)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *sqlWebhookStorage) Get(id string) (hook *domain.Webhook, err error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	err = s.db.get(ctxT, s.db, s.record(id), webhookColumns, func(row scanner) error {
+		hook, err = scanWebhook(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (s *sqlWebhookStorage) Delete(id string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	return s.db.inTransaction(ctxT, func(tx *sql.Tx) error {
+		if err := s.db.remove(ctxT, tx, s.record(id), version); err != nil {
+			return err
+		}
+		s.log.Debug().Str("id", id).Msg("Webhook deleted")
+		n, err := s.db.exec(ctxT, tx, "DELETE FROM "+webhookDeliveryTable+" WHERE owner = ? AND project = ? AND webhook = ?", s.owner, s.project, id)
+		if err != nil {
+			return err
+		}
+		s.log.Debug().Int64("count", n).Msg("Webhook deliveries deleted")
+		return nil
+	})
+}
+
+func (s *sqlWebhookStorage) checkRelations(hook *domain.Webhook) error {
+	if s.owner != hook.Owner || s.project != hook.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, hook.Owner, hook.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *sqlWebhookStorage) Save(hook *domain.Webhook) error {
+	if err := s.checkRelations(hook); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	hook.Version = 1
+	if err := s.db.insert(ctxT, s.db, webhookRecord(hook), 1, "webhook", hook.ID); err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", hook.ID).Msg("Webhook inserted")
+	return nil
+}
+
+func (s *sqlWebhookStorage) Update(hook *domain.Webhook) error {
+	if err := s.checkRelations(hook); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := hook.Version
+	hook.Version = version + 1
+	if err := s.db.update(ctxT, s.db, webhookRecord(hook), version); err != nil {
+		hook.Version = version
+		return err
+	}
+	return nil
+}
+
+// webhookDeliveryColumns lists columns scanWebhookDelivery reads
+const webhookDeliveryColumns = "owner, project, id, webhook, event, url, secret, payload, status, attempts, next_attempt," +
+	" locked_by, locked_until, reg_date, version"
+
+func webhookDeliveryRecord(d *domain.WebhookDelivery) *record {
+	return &record{
+		table: webhookDeliveryTable,
+		key:   []*column{col("owner", d.Owner), col("project", d.Project), col("id", d.ID)},
+		fields: []*column{
+			col("webhook", d.Webhook),
+			col("event", d.Event),
+			col("url", d.URL),
+			col("secret", d.Secret),
+			col("payload", d.Payload),
+			col("status", d.Status),
+			col("attempts", jsonColumn{d.Attempts}),
+			col("next_attempt", millis(d.NextAttempt)),
+			col("locked_by", d.LockedBy),
+			col("locked_until", millis(d.LockedUntil)),
+			col("reg_date", millis(d.RegDate)),
+		},
+	}
+}
+
+func scanWebhookDelivery(row scanner) (*domain.WebhookDelivery, error) {
+	var d domain.WebhookDelivery
+	var nextAttempt, lockedUntil, regDate int64
+	err := row.Scan(&d.Owner, &d.Project, &d.ID, &d.Webhook, &d.Event, &d.URL, &d.Secret, &d.Payload, &d.Status,
+		jsonColumn{&d.Attempts}, &nextAttempt, &d.LockedBy, &lockedUntil, &regDate, &d.Version)
+	if err != nil {
+		return nil, err
+	}
+	d.NextAttempt, d.LockedUntil, d.RegDate = fromMillis(nextAttempt), fromMillis(lockedUntil), fromMillis(regDate)
+	for _, a := range d.Attempts {
+		a.Date = localTime(a.Date)
+	}
+	return &d, nil
+}
+
+type sqlWebhookDeliveryStorage struct {
+	log     zerolog.Logger
+	owner   string
+	project string
+	ctx     context.Context
+	db      *database
+}
+
+func (s *sqlWebhookDeliveryStorage) List(webhook string, limit int) ([]*domain.WebhookDelivery, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	list := make([]*domain.WebhookDelivery, 0)
+	err := s.db.list(ctxT, s.db, func(row scanner) error {
+		item, err := scanWebhookDelivery(row)
+		if err != nil {
+			return err
+		}
+		list = append(list, item)
+		return nil
+	}, "SELECT "+webhookDeliveryColumns+" FROM "+webhookDeliveryTable+" WHERE owner = ? AND project = ? AND webhook = ? ORDER BY reg_date DESC LIMIT ?",
+		s.owner, s.project, webhook, limit)
+	if err != nil {
+		s.log.Error().Err(err).Msg("DB error")
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *sqlWebhookDeliveryStorage) checkRelations(d *domain.WebhookDelivery) error {
+	if s.owner != d.Owner || s.project != d.Project {
+		s.log.Error().Msgf("Wrong relations. Expected: %s/%s, got: %s/%s", s.owner, s.project, d.Owner, d.Project)
+		return storage.ErrEntityRelationsBroken
+	}
+	return nil
+}
+
+func (s *sqlWebhookDeliveryStorage) Save(d *domain.WebhookDelivery) error {
+	if err := s.checkRelations(d); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	d.Version = 1
+	if err := s.db.insert(ctxT, s.db, webhookDeliveryRecord(d), 1, "webhook delivery", d.ID); err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", d.ID).Msg("Webhook delivery inserted")
+	return nil
+}
+
+func (s *sqlWebhookDeliveryStorage) Update(d *domain.WebhookDelivery) error {
+	if err := s.checkRelations(d); err != nil {
+		return err
+	}
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	version := d.Version
+	d.Version = version + 1
+	if err := s.db.update(ctxT, s.db, webhookDeliveryRecord(d), version); err != nil {
+		d.Version = version
+		return err
+	}
+	return nil
+}
+
+func (s *sqlWebhookDeliveryStorage) Delete(id string, version int) error {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	r := webhookDeliveryRecord(&domain.WebhookDelivery{Owner: s.owner, Project: s.project, ID: id})
+	if err := s.db.remove(ctxT, s.db, r, version); err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", id).Msg("Webhook delivery deleted")
+	return nil
+}
+
+type sqlWebhookDeliveryQueue struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *database
+}
+
+// Claim selects the delivery with row locking where the dialect supports it
+func (s *sqlWebhookDeliveryQueue) Claim(now time.Time, worker string, lease time.Duration) (*domain.WebhookDelivery, error) {
+	ctxT, cancel := context.WithTimeout(s.ctx, 3*time.Second)
+	defer cancel()
+	var d *domain.WebhookDelivery
+	err := s.db.inTransaction(ctxT, func(tx *sql.Tx) error {
+		var err error
+		d, err = scanWebhookDelivery(tx.QueryRowContext(ctxT, s.db.dialect.rebind("SELECT "+webhookDeliveryColumns+" FROM "+webhookDeliveryTable+
+			" WHERE status = ? AND next_attempt <= ? OR status = ? AND locked_until < ? ORDER BY next_attempt LIMIT 1"+s.db.dialect.skipLocked),
+			domain.WebhookDeliveryStatusPending, millis(now), domain.WebhookDeliveryStatusRunning, millis(now)))
+		if err == sql.ErrNoRows {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		version := d.Version
+		d.Status = domain.WebhookDeliveryStatusRunning
+		d.LockedBy = worker
+		d.LockedUntil = now.Add(lease)
+		d.Version = version + 1
+		return s.db.update(ctxT, tx, webhookDeliveryRecord(d), version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log.Debug().Str("id", d.ID).Str("worker", worker).Msg("Webhook delivery claimed")
+	return d, nil
+}
@@ -3,6 +3,8 @@ package storage
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Toggly/core/domain"
 )
@@ -17,60 +19,260 @@ func (e *ErrUniqueIndex) Error() string {
 	return fmt.Sprintf("Unique index error: %s [%s]", e.Type, e.Key)
 }
 
+// ErrPartialChangeset is returned when a changeset failed on a deployment without transactions
+// and reverting the changes written before the failure failed too. Parameters lists codes
+// of parameters which may be left changed.
+type ErrPartialChangeset struct {
+	Err        error
+	Revert     error
+	Parameters []string
+}
+
+func (e *ErrPartialChangeset) Error() string {
+	return fmt.Sprintf("Changeset partially applied: %v, revert failed: %v, parameters left changed: %s",
+		e.Err, e.Revert, strings.Join(e.Parameters, ", "))
+}
+
 var (
 	// ErrNotFound error
 	ErrNotFound = errors.New("not found")
 	// ErrEntityRelationsBroken error
 	ErrEntityRelationsBroken = errors.New("entity relations broken")
+	// ErrVersionConflict error
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrMigrationLocked error
+	ErrMigrationLocked = errors.New("migration locked by another worker")
+	// ErrChangesNotSupported error
+	ErrChangesNotSupported = errors.New("change subscriptions not supported")
+	// ErrNotEmpty error
+	ErrNotEmpty = errors.New("not empty")
+)
+
+// Change operations enum
+const (
+	ChangeInsert = "insert"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
 )
 
-// DataStorage defines storage interface
+// Change describes an entity change written by any server instance. Entity is the
+// entity type: project, environment, parameter, webhook, organization or rate_limit. Key is
+// the entity code or ID. Owner is empty if the storage can't tell whose entity was deleted,
+// consumers treat such a change as a change of any owner.
+type Change struct {
+	Entity      string
+	Operation   string
+	Owner       string
+	Project     string
+	Environment string
+	Key         string
+}
+
+// Migration describes a storage schema migration. Applied is nil for pending migrations.
+type Migration struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	Applied     *time.Time `json:"applied,omitempty"`
+}
+
+// Migrator is implemented by storages having versioned schema. Migrate applies pending
+// migrations in version order holding a lock, so only one worker migrates the storage at a time;
+// it returns ErrMigrationLocked if the lock is not released in time. Migrations lists all
+// known migrations.
+type Migrator interface {
+	Migrate(worker string) error
+	Migrations() ([]*Migration, error)
+}
+
+// Importer is implemented by storages able to restore entities as they are, keeping their versions.
+// Import stores an organization, rate limit, project, environment, parameter, changeset, change request,
+// scheduled change, webhook or webhook delivery; children must be imported after their parents.
+// Evaluation counters are restored with EvaluationCountSink.
+type Importer interface {
+	Import(entity interface{}) error
+}
+
+// DataStorage defines storage interface. Owners returns owners having projects, rate limits
+// or registered as organizations in ascending order.
+// Subscribe calls fn for every entity change until the storage context is done.
+// The subscription position is stored under its name after fn returns, so a subscription
+// with the same name resumes after the last handled change unless the storage no longer
+// keeps it. Every server instance needs its own name: instances sharing a name overwrite
+// each other's position and may skip changes on resume. It returns ErrChangesNotSupported
+// if the deployment can't report changes.
 type DataStorage interface {
 	ForOwner(ownerID string) OwnerStorage
+	ScheduledChanges() ScheduledChangeQueue
+	WebhookDeliveries() WebhookDeliveryQueue
+	EvaluationCounts() EvaluationCountSink
+	Organizations() OrganizationStorage
+	RateLimits() RateLimitStorage
+	Owners() ([]string, error)
+	Subscribe(name string, fn func(c *Change)) error
 	Connect() error
 }
 
+// OrganizationStorage defines organizations storage interface.
+// List returns organizations having the principal as a member.
+// Versions are handled the same way as in ProjectStorage.
+type OrganizationStorage interface {
+	List(principal string) ([]*domain.Organization, error)
+	Get(id string) (*domain.Organization, error)
+	Delete(id string, version int) error
+	Save(org *domain.Organization) error
+	Update(org *domain.Organization) error
+}
+
+// RateLimitStorage defines owner rate limits storage interface.
+// Versions are handled the same way as in ProjectStorage.
+type RateLimitStorage interface {
+	List() ([]*domain.RateLimit, error)
+	Get(owner string) (*domain.RateLimit, error)
+	Delete(owner string, version int) error
+	Save(limit *domain.RateLimit) error
+	Update(limit *domain.RateLimit) error
+}
+
+// ScheduledChangeQueue defines cross-owner access to due scheduled changes.
+// Claim atomically leases the earliest due pending change (or a running change with
+// an expired lease) to the worker and returns ErrNotFound if there is nothing to run.
+type ScheduledChangeQueue interface {
+	Claim(now time.Time, worker string, lease time.Duration) (*domain.ScheduledChange, error)
+}
+
+// WebhookDeliveryQueue defines cross-owner access to webhook deliveries.
+// Claim atomically leases the earliest pending delivery due by now (or a running delivery
+// with an expired lease) to the worker and returns ErrNotFound if there is nothing to send.
+type WebhookDeliveryQueue interface {
+	Claim(now time.Time, worker string, lease time.Duration) (*domain.WebhookDelivery, error)
+}
+
+// EvaluationCountSink defines cross-owner access to evaluation counters.
+// Add increments stored counters by the given counts, creating missing ones.
+type EvaluationCountSink interface {
+	Add(counts []*domain.EvaluationCount) error
+}
+
 // OwnerStorage defines owner storage interface
 type OwnerStorage interface {
 	Projects() ProjectStorage
 }
 
-// ProjectStorage defines projects storage interface
+// ProjectStorage defines projects storage interface. Save returns *ErrUniqueIndex
+// if an entity with the same key exists; it applies to other storages as well.
+// Update replaces the project only if the stored version equals project.Version
+// and increments the version on success. Delete with zero version is unconditional
+// and removes the project together with all its environments, parameters, change requests,
+// scheduled changes, evaluation counters, changesets, webhooks and webhook deliveries.
+// Without cascade Delete returns ErrNotEmpty if the project has environments and never removes
+// environments. Deployments with transactions check and remove at once; without them the check
+// is repeated after the removal and the project is put back if environments were created
+// meanwhile, yet an environment created concurrently with the repeated check may be left orphaned.
 type ProjectStorage interface {
 	List() ([]*domain.Project, error)
 	Get(code string) (*domain.Project, error)
-	Delete(code string) error
+	Delete(code string, version int, cascade bool) error
 	Save(project *domain.Project) error
 	Update(project *domain.Project) error
-	// For(project string) ForProject
-}
-
-// // ForProject defines project dependencies interface
-// type ForProject interface {
-// 	Environments() EnvironmentStorage
-// }
-
-// // EnvironmentStorage defines environment storage interface
-// type EnvironmentStorage interface {
-// 	List() ([]*domain.Environment, error)
-// 	Get(code domain.EnvironmentCode) (*domain.Environment, error)
-// 	Delete(code domain.EnvironmentCode) error
-// 	Save(env *domain.Environment) error
-// 	Update(env *domain.Environment) error
-// 	For(domain.EnvironmentCode) ForEnvironment
-// }
-
-// // ForEnvironment defines environment dependencies interface
-// type ForEnvironment interface {
-// 	Objects() ObjectStorage
-// }
-
-// // ObjectStorage defines object structure storage interface
-// type ObjectStorage interface {
-// 	List() ([]*domain.Object, error)
-// 	Get(code domain.ObjectCode) (*domain.Object, error)
-// 	ListInheritors(code domain.ObjectCode) ([]*domain.Object, error)
-// 	Delete(code domain.ObjectCode) error
-// 	Save(object *domain.Object) error
-// 	Update(object *domain.Object) error
-// }
+	For(project string) ForProject
+}
+
+// ForProject defines project dependencies interface
+type ForProject interface {
+	Environments() EnvironmentStorage
+	ChangeRequests() ChangeRequestStorage
+	ScheduledChanges() ScheduledChangeStorage
+	Webhooks() WebhookStorage
+	WebhookDeliveries() WebhookDeliveryStorage
+}
+
+// EnvironmentStorage defines environment storage interface.
+// Versions are handled the same way as in ProjectStorage.
+// Delete removes the environment together with its parameters, evaluation counters and changesets.
+type EnvironmentStorage interface {
+	List() ([]*domain.Environment, error)
+	Get(code string) (*domain.Environment, error)
+	Delete(code string, version int) error
+	Save(env *domain.Environment) error
+	Update(env *domain.Environment) error
+	For(env string) ForEnvironment
+}
+
+// ForEnvironment defines environment dependencies interface
+type ForEnvironment interface {
+	Parameters() ParameterStorage
+	EvaluationCounts() EvaluationCountStorage
+	Changesets() ChangesetStorage
+}
+
+// ParameterStorage defines parameter storage interface.
+// Versions are handled the same way as in ProjectStorage.
+type ParameterStorage interface {
+	List() ([]*domain.Parameter, error)
+	Get(code string) (*domain.Parameter, error)
+	Delete(code string, version int) error
+	Save(param *domain.Parameter) error
+	Update(param *domain.Parameter) error
+}
+
+// ChangesetStorage defines environment changeset storage interface.
+// Apply writes all changeset parameter changes and the changeset itself in one transaction
+// if the deployment supports transactions; otherwise changes already written are reverted
+// when a later one fails, and *ErrPartialChangeset is returned if the revert fails. Without
+// transactions other readers may see changes of a changeset before it is complete. Parameter versions are checked against changes base versions,
+// where zero means any version, and are set the same way as in ParameterStorage.
+// Creating an existing parameter is a version conflict.
+// List returns at most limit latest changesets, newest first.
+type ChangesetStorage interface {
+	List(limit int) ([]*domain.Changeset, error)
+	Get(id string) (*domain.Changeset, error)
+	Apply(cs *domain.Changeset) error
+}
+
+// EvaluationCountStorage defines environment evaluation counters interface.
+// List returns parameter counters for hours in [from, to) ordered by hour and variant.
+// LastEvaluated returns the latest evaluation time of every counted environment parameter.
+type EvaluationCountStorage interface {
+	List(param string, from, to time.Time) ([]*domain.EvaluationCount, error)
+	LastEvaluated() (map[string]time.Time, error)
+}
+
+// ChangeRequestStorage defines change request storage interface.
+// List with empty status returns all project change requests.
+// Versions are handled the same way as in ProjectStorage.
+type ChangeRequestStorage interface {
+	List(status string) ([]*domain.ChangeRequest, error)
+	Get(id string) (*domain.ChangeRequest, error)
+	Save(cr *domain.ChangeRequest) error
+	Update(cr *domain.ChangeRequest) error
+}
+
+// ScheduledChangeStorage defines scheduled change storage interface.
+// List with empty status returns all project scheduled changes.
+// Versions are handled the same way as in ProjectStorage.
+type ScheduledChangeStorage interface {
+	List(status string) ([]*domain.ScheduledChange, error)
+	Get(id string) (*domain.ScheduledChange, error)
+	Save(sc *domain.ScheduledChange) error
+	Update(sc *domain.ScheduledChange) error
+}
+
+// WebhookStorage defines webhook storage interface.
+// Versions are handled the same way as in ProjectStorage.
+type WebhookStorage interface {
+	List() ([]*domain.Webhook, error)
+	Get(id string) (*domain.Webhook, error)
+	Delete(id string, version int) error
+	Save(hook *domain.Webhook) error
+	Update(hook *domain.Webhook) error
+}
+
+// WebhookDeliveryStorage defines webhook delivery storage interface.
+// List returns at most limit latest deliveries of the webhook, newest first.
+// Versions are handled the same way as in ProjectStorage.
+type WebhookDeliveryStorage interface {
+	List(webhook string, limit int) ([]*domain.WebhookDelivery, error)
+	Save(d *domain.WebhookDelivery) error
+	Update(d *domain.WebhookDelivery) error
+	Delete(id string, version int) error
+}
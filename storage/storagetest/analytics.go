@@ -0,0 +1,74 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func testEvaluationCount(t *testing.T, dataStorage storage.DataStorage) {
+	assert := asserts.New(t)
+
+	saveParents(t, dataStorage, "ow1", "proj1")
+	sink := dataStorage.EvaluationCounts()
+	envs := dataStorage.ForOwner("ow1").Projects().For("proj1").Environments()
+	db := envs.For("prod").EvaluationCounts()
+	hour := util.Now().UTC().Truncate(time.Hour)
+
+	count := func(variant string, hour time.Time, n int64) *domain.EvaluationCount {
+		return &domain.EvaluationCount{
+			Owner:       "ow1",
+			Project:     "proj1",
+			Environment: "prod",
+			Parameter:   "color",
+			Variant:     variant,
+			Hour:        hour,
+			Count:       n,
+		}
+	}
+
+	t.Run("add", func(t *testing.T) {
+		assert.Nil(sink.Add([]*domain.EvaluationCount{count("red", hour, 2), count("blue", hour, 1)}))
+		assert.Nil(sink.Add([]*domain.EvaluationCount{count("red", hour, 3), count("red", hour.Add(-time.Hour), 4)}))
+		assert.Nil(sink.Add(nil))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list, err := db.List("color", hour.Add(-time.Hour), hour.Add(time.Hour))
+		assert.Nil(err)
+		assert.Equal(3, len(list))
+		assert.Equal(int64(4), list[0].Count)
+		assert.Equal("blue", list[1].Variant)
+		assert.Equal(int64(1), list[1].Count)
+		assert.Equal("red", list[2].Variant)
+		assert.Equal(int64(5), list[2].Count)
+		list, err = db.List("color", hour, hour.Add(time.Hour))
+		assert.Nil(err)
+		assert.Equal(2, len(list))
+	})
+
+	t.Run("last evaluated", func(t *testing.T) {
+		last := hour.Add(10 * time.Minute)
+		c := count("red", hour, 1)
+		c.Last = last
+		assert.Nil(sink.Add([]*domain.EvaluationCount{c}))
+		c.Last = hour.Add(5 * time.Minute)
+		assert.Nil(sink.Add([]*domain.EvaluationCount{c}))
+		res, err := db.LastEvaluated()
+		assert.Nil(err)
+		assert.Equal(1, len(res))
+		assert.True(last.Equal(res["color"]))
+	})
+
+	t.Run("environment delete", func(t *testing.T) {
+		assert.Nil(envs.Save(&domain.Environment{Code: "prod", Owner: "ow1", Project: "proj1"}))
+		assert.Nil(envs.Delete("prod", 0))
+		list, err := db.List("color", hour.Add(-time.Hour), hour.Add(time.Hour))
+		assert.Nil(err)
+		assert.Equal(0, len(list))
+	})
+}
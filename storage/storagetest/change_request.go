@@ -0,0 +1,59 @@
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func testChangeRequest(t *testing.T, dataStorage storage.DataStorage) {
+	assert := asserts.New(t)
+
+	saveParents(t, dataStorage, "ow1", "proj1")
+	db := dataStorage.ForOwner("ow1").Projects().For("proj1").ChangeRequests()
+
+	cr := &domain.ChangeRequest{
+		ID:            util.NewID(),
+		Owner:         "ow1",
+		Project:       "proj1",
+		Environment:   "prod",
+		Action:        domain.ChangeActionDelete,
+		ParameterCode: "p1",
+		Requester:     "alice",
+		Status:        domain.ChangeRequestStatusPending,
+		RegDate:       util.Now(),
+	}
+
+	t.Run("get not found", func(t *testing.T) {
+		_, err := db.Get(cr.ID)
+		assert.Equal(storage.ErrNotFound, err)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		assert.Nil(db.Save(cr))
+		res, err := db.Get(cr.ID)
+		assert.Nil(err)
+		assert.Equal(cr.ParameterCode, res.ParameterCode)
+		assert.Equal(1, res.Version)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		cr.Status = domain.ChangeRequestStatusRejected
+		assert.Nil(db.Update(cr))
+		assert.Equal(2, cr.Version)
+		cr.Version = 1
+		assert.Equal(storage.ErrVersionConflict, db.Update(cr))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list, err := db.List("")
+		assert.Nil(err)
+		assert.Len(list, 1)
+		list, err = db.List(domain.ChangeRequestStatusPending)
+		assert.Nil(err)
+		assert.Len(list, 0)
+	})
+}
@@ -0,0 +1,71 @@
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func testChangeset(t *testing.T, dataStorage storage.DataStorage) {
+	assert := asserts.New(t)
+
+	saveParents(t, dataStorage, "ow1", "proj1", "dev")
+	env := dataStorage.ForOwner("ow1").Projects().For("proj1").Environments().For("dev")
+	params := env.Parameters()
+	db := env.Changesets()
+
+	param := func(code string, value interface{}) *domain.Parameter {
+		return &domain.Parameter{Code: code, Owner: "ow1", Project: "proj1", Environment: "dev", Type: domain.ParameterTypeBool, Value: value}
+	}
+	changeset := func(items ...*domain.ChangesetItem) *domain.Changeset {
+		return &domain.Changeset{ID: util.NewID(), Owner: "ow1", Project: "proj1", Environment: "dev", Changes: items, RegDate: util.Now()}
+	}
+
+	assert.Nil(params.Save(param("a", true)))
+
+	t.Run("apply", func(t *testing.T) {
+		cs := changeset(
+			&domain.ChangesetItem{Action: domain.ChangeActionCreate, ParameterCode: "b", Parameter: param("b", true)},
+			&domain.ChangesetItem{Action: domain.ChangeActionUpdate, ParameterCode: "a", Parameter: param("a", false), BaseVersion: 1},
+		)
+		assert.Nil(db.Apply(cs))
+		a, err := params.Get("a")
+		assert.Nil(err)
+		assert.Equal(false, a.Value)
+		assert.Equal(2, a.Version)
+		res, err := db.Get(cs.ID)
+		assert.Nil(err)
+		assert.Equal(2, len(res.Changes))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		cs := changeset(
+			&domain.ChangesetItem{Action: domain.ChangeActionCreate, ParameterCode: "c", Parameter: param("c", true)},
+			&domain.ChangesetItem{Action: domain.ChangeActionDelete, ParameterCode: "b", BaseVersion: 1},
+			&domain.ChangesetItem{Action: domain.ChangeActionUpdate, ParameterCode: "a", Parameter: param("a", true), BaseVersion: 1},
+		)
+		assert.Equal(storage.ErrVersionConflict, db.Apply(cs))
+		_, err := params.Get("c")
+		assert.Equal(storage.ErrNotFound, err)
+		_, err = params.Get("b")
+		assert.Nil(err)
+		_, err = db.Get(cs.ID)
+		assert.Equal(storage.ErrNotFound, err)
+	})
+
+	t.Run("wrong relations", func(t *testing.T) {
+		p := param("d", true)
+		p.Environment = "prod"
+		cs := changeset(&domain.ChangesetItem{Action: domain.ChangeActionCreate, ParameterCode: "d", Parameter: p})
+		assert.Equal(storage.ErrEntityRelationsBroken, db.Apply(cs))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list, err := db.List(10)
+		assert.Nil(err)
+		assert.Equal(1, len(list))
+	})
+}
@@ -0,0 +1,106 @@
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func testEnvironment(t *testing.T, dataStorage storage.DataStorage) {
+	assert := asserts.New(t)
+
+	var err error
+
+	saveParents(t, dataStorage, "ow1", "proj1")
+	pdb := dataStorage.ForOwner("ow1").Projects()
+	db := pdb.For("proj1").Environments()
+
+	t.Run("get not found", func(t *testing.T) {
+		env, err := db.Get("env1")
+		assert.Nil(env)
+		assert.Equal(storage.ErrNotFound, err)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		e := &domain.Environment{
+			Code:    "env1",
+			Owner:   "ow1",
+			Project: "proj2",
+			RegDate: util.Now(),
+		}
+
+		t.Run("wrong project", func(t *testing.T) {
+			err = db.Save(e)
+			assert.Equal(storage.ErrEntityRelationsBroken, err)
+		})
+
+		t.Run("ok", func(t *testing.T) {
+			e.Project = "proj1"
+			err = db.Save(e)
+			assert.Nil(err)
+
+			env, err := db.Get("env1")
+			assert.Nil(err)
+			assert.Equal(e.Code, env.Code)
+			assert.Equal(e.RegDate, env.RegDate)
+			assert.Equal(1, env.Version)
+		})
+	})
+
+	t.Run("update", func(t *testing.T) {
+		e := &domain.Environment{
+			Code:      "env1",
+			Owner:     "ow1",
+			Project:   "proj1",
+			Protected: true,
+			Version:   1,
+		}
+		err = db.Update(e)
+		assert.Nil(err)
+		assert.Equal(2, e.Version)
+
+		e.Version = 1
+		err = db.Update(e)
+		assert.Equal(storage.ErrVersionConflict, err)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list, err := db.List()
+		assert.Nil(err)
+		assert.Len(list, 1)
+		list, err = pdb.For("proj2").Environments().List()
+		assert.Nil(err)
+		assert.Len(list, 0)
+	})
+
+	t.Run("project not empty", func(t *testing.T) {
+		assert.Equal(storage.ErrNotEmpty, pdb.Delete("proj1", 0, false))
+		assert.Equal(storage.ErrNotFound, pdb.Delete("proj2", 0, false))
+		list, err := db.List()
+		assert.Nil(err)
+		assert.Len(list, 1)
+	})
+
+	t.Run("project cascade delete", func(t *testing.T) {
+		hook := &domain.Webhook{ID: util.NewID(), Owner: "ow1", Project: "proj1", URL: "https://example.com/hook"}
+		assert.Nil(pdb.For("proj1").Webhooks().Save(hook))
+		assert.Nil(pdb.For("proj1").WebhookDeliveries().Save(&domain.WebhookDelivery{
+			ID: util.NewID(), Owner: "ow1", Project: "proj1", Webhook: hook.ID,
+			Status: domain.WebhookDeliveryStatusPending, Attempts: []*domain.DeliveryAttempt{}, RegDate: util.Now(),
+		}))
+		err = pdb.Delete("proj1", 0, true)
+		assert.Nil(err)
+		list, err := db.List()
+		assert.Nil(err)
+		assert.Len(list, 0)
+		hooks, err := pdb.For("proj1").Webhooks().List()
+		assert.Nil(err)
+		assert.Empty(hooks)
+		deliveries, err := pdb.For("proj1").WebhookDeliveries().List(hook.ID, 10)
+		assert.Nil(err)
+		assert.Empty(deliveries)
+	})
+}
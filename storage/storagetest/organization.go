@@ -0,0 +1,59 @@
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func testOrganization(t *testing.T, dataStorage storage.DataStorage) {
+	assert := asserts.New(t)
+
+	db := dataStorage.Organizations()
+	org := &domain.Organization{
+		ID:      util.NewID(),
+		Name:    "Acme",
+		Members: []*domain.Member{{Principal: "alice", Role: domain.MemberRoleOwner}},
+		RegDate: util.Now(),
+	}
+
+	t.Run("save", func(t *testing.T) {
+		assert.Nil(db.Save(org))
+		assert.Equal(1, org.Version)
+		res, err := db.Get(org.ID)
+		assert.Nil(err)
+		assert.Equal("Acme", res.Name)
+		_, err = db.Get("missing")
+		assert.Equal(storage.ErrNotFound, err)
+	})
+
+	t.Run("list by member", func(t *testing.T) {
+		list, err := db.List("alice")
+		assert.Nil(err)
+		assert.Equal(1, len(list))
+		list, err = db.List("bob")
+		assert.Nil(err)
+		assert.Equal(0, len(list))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		org.Members = append(org.Members, &domain.Member{Principal: "bob", Role: domain.MemberRoleMember})
+		assert.Nil(db.Update(org))
+		assert.Equal(2, org.Version)
+		stale := *org
+		stale.Version = 1
+		assert.Equal(storage.ErrVersionConflict, db.Update(&stale))
+		list, err := db.List("bob")
+		assert.Nil(err)
+		assert.Equal(1, len(list))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(storage.ErrVersionConflict, db.Delete(org.ID, 1))
+		assert.Nil(db.Delete(org.ID, 2))
+		assert.Equal(storage.ErrNotFound, db.Delete(org.ID, 0))
+	})
+}
@@ -0,0 +1,89 @@
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func testParameter(t *testing.T, dataStorage storage.DataStorage) {
+	assert := asserts.New(t)
+
+	var err error
+
+	saveParents(t, dataStorage, "ow1", "proj1", "env1")
+	edb := dataStorage.ForOwner("ow1").Projects().For("proj1").Environments()
+	db := edb.For("env1").Parameters()
+
+	t.Run("get not found", func(t *testing.T) {
+		param, err := db.Get("p1")
+		assert.Nil(param)
+		assert.Equal(storage.ErrNotFound, err)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		p := &domain.Parameter{
+			Code:        "p1",
+			Owner:       "ow1",
+			Project:     "proj1",
+			Environment: "env2",
+			Type:        domain.ParameterTypeBool,
+			Value:       true,
+		}
+
+		t.Run("wrong environment", func(t *testing.T) {
+			err = db.Save(p)
+			assert.Equal(storage.ErrEntityRelationsBroken, err)
+		})
+
+		t.Run("ok", func(t *testing.T) {
+			p.Environment = "env1"
+			err = db.Save(p)
+			assert.Nil(err)
+
+			param, err := db.Get("p1")
+			assert.Nil(err)
+			assert.Equal(p.Code, param.Code)
+			assert.Equal(true, param.Value)
+			assert.Equal(1, param.Version)
+		})
+	})
+
+	t.Run("update", func(t *testing.T) {
+		p := &domain.Parameter{
+			Code:        "p1",
+			Owner:       "ow1",
+			Project:     "proj1",
+			Environment: "env1",
+			Type:        domain.ParameterTypeBool,
+			Value:       false,
+			Version:     1,
+		}
+		err = db.Update(p)
+		assert.Nil(err)
+		assert.Equal(2, p.Version)
+
+		p.Version = 1
+		err = db.Update(p)
+		assert.Equal(storage.ErrVersionConflict, err)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list, err := db.List()
+		assert.Nil(err)
+		assert.Len(list, 1)
+		list, err = edb.For("env2").Parameters().List()
+		assert.Nil(err)
+		assert.Len(list, 0)
+	})
+
+	t.Run("environment cascade delete", func(t *testing.T) {
+		err = edb.Delete("env1", 0)
+		assert.Nil(err)
+		list, err := db.List()
+		assert.Nil(err)
+		assert.Len(list, 0)
+	})
+}
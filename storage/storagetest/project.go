@@ -1,4 +1,4 @@
-package mongo_test
+package storagetest
 
 import (
 	"testing"
@@ -10,14 +10,12 @@ import (
 	asserts "github.com/stretchr/testify/assert"
 )
 
-func TestMongoProject(t *testing.T) {
+func testProject(t *testing.T, dataStorage storage.DataStorage) {
 	assert := asserts.New(t)
 
-	beforeTest()
-
 	var err error
 
-	db := getDB().ForOwner("ow1").Projects()
+	db := dataStorage.ForOwner("ow1").Projects()
 
 	t.Run("get not found", func(t *testing.T) {
 		proj, err := db.Get("proj1")
@@ -59,6 +57,12 @@ func TestMongoProject(t *testing.T) {
 			assert.Equal(p.RegDate, proj.RegDate)
 			assert.Equal(p.Owner, proj.Owner)
 			assert.Equal(p.Status, proj.Status)
+			assert.Equal(1, proj.Version)
+		})
+
+		t.Run("duplicate", func(t *testing.T) {
+			err = db.Save(p)
+			assert.Equal(&storage.ErrUniqueIndex{Type: "project", Key: "proj1"}, err)
 		})
 	})
 
@@ -76,6 +80,7 @@ func TestMongoProject(t *testing.T) {
 			Owner:       "ow1",
 			Status:      domain.ProjectStatusDisabled,
 			RegDate:     util.Now(),
+			Version:     1,
 		}
 
 		t.Run("wrong owner", func(t *testing.T) {
@@ -96,15 +101,24 @@ func TestMongoProject(t *testing.T) {
 			assert.Equal(p.RegDate, proj.RegDate)
 			assert.Equal(p.Owner, proj.Owner)
 			assert.Equal(p.Status, proj.Status)
+			assert.Equal(2, proj.Version)
+		})
+
+		t.Run("version conflict", func(t *testing.T) {
+			p.Version = 1
+			err = db.Update(p)
+			assert.Equal(storage.ErrVersionConflict, err)
+			assert.Equal(1, p.Version)
 		})
 	})
 
 	t.Run("delete", func(t *testing.T) {
-		db.Delete("proj1")
+		err = db.Delete("proj1", 1, false)
+		assert.Equal(storage.ErrVersionConflict, err)
+		err = db.Delete("proj1", 2, false)
+		assert.Nil(err)
 		proj, err := db.Get("proj1")
 		assert.Nil(proj)
 		assert.Equal(storage.ErrNotFound, err)
 	})
-
-	afterTest()
 }
@@ -0,0 +1,60 @@
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func testRateLimit(t *testing.T, dataStorage storage.DataStorage) {
+	assert := asserts.New(t)
+
+	db := dataStorage.RateLimits()
+	limit := &domain.RateLimit{
+		Owner:      "ow1",
+		Evaluation: domain.Rate{PerMinute: 100, Burst: 10},
+		Modified:   util.Now(),
+	}
+
+	t.Run("save", func(t *testing.T) {
+		assert.Nil(db.Save(limit))
+		assert.Equal(1, limit.Version)
+		res, err := db.Get("ow1")
+		assert.Nil(err)
+		assert.Equal(domain.Rate{PerMinute: 100, Burst: 10}, res.Evaluation)
+		_, err = db.Get("missing")
+		assert.Equal(storage.ErrNotFound, err)
+		assert.Equal(&storage.ErrUniqueIndex{Type: "rate limit", Key: "ow1"}, db.Save(&domain.RateLimit{Owner: "ow1"}))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		assert.Nil(db.Save(&domain.RateLimit{Owner: "ow0"}))
+		list, err := db.List()
+		assert.Nil(err)
+		if assert.Equal(2, len(list)) {
+			assert.Equal("ow0", list[0].Owner)
+			assert.Equal("ow1", list[1].Owner)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		limit.Management = domain.Rate{PerMinute: -1}
+		assert.Nil(db.Update(limit))
+		assert.Equal(2, limit.Version)
+		stale := *limit
+		stale.Version = 1
+		assert.Equal(storage.ErrVersionConflict, db.Update(&stale))
+		res, err := db.Get("ow1")
+		assert.Nil(err)
+		assert.Equal(-1, res.Management.PerMinute)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(storage.ErrVersionConflict, db.Delete("ow1", 1))
+		assert.Nil(db.Delete("ow1", 2))
+		assert.Equal(storage.ErrNotFound, db.Delete("ow1", 0))
+	})
+}
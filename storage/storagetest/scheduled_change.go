@@ -0,0 +1,66 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func testScheduledChange(t *testing.T, dataStorage storage.DataStorage) {
+	assert := asserts.New(t)
+
+	saveParents(t, dataStorage, "ow1", "proj1")
+	db := dataStorage.ForOwner("ow1").Projects().For("proj1").ScheduledChanges()
+	queue := dataStorage.ScheduledChanges()
+	now := util.Now()
+
+	sc := &domain.ScheduledChange{
+		ID:          util.NewID(),
+		Owner:       "ow1",
+		Project:     "proj1",
+		Environment: "prod",
+		Parameter:   "flag",
+		Value:       true,
+		At:          now.Add(time.Minute),
+		Status:      domain.ScheduledChangeStatusPending,
+		RegDate:     now,
+	}
+
+	t.Run("create", func(t *testing.T) {
+		assert.Nil(db.Save(sc))
+		res, err := db.Get(sc.ID)
+		assert.Nil(err)
+		assert.Equal(sc.At, res.At)
+	})
+
+	t.Run("claim not due", func(t *testing.T) {
+		_, err := queue.Claim(now, "w1", time.Minute)
+		assert.Equal(storage.ErrNotFound, err)
+	})
+
+	t.Run("claim", func(t *testing.T) {
+		res, err := queue.Claim(now.Add(2*time.Minute), "w1", time.Minute)
+		assert.Nil(err)
+		assert.Equal(sc.ID, res.ID)
+		assert.Equal(domain.ScheduledChangeStatusRunning, res.Status)
+		assert.Equal("w1", res.LockedBy)
+		assert.Equal(2, res.Version)
+		_, err = queue.Claim(now.Add(2*time.Minute), "w2", time.Minute)
+		assert.Equal(storage.ErrNotFound, err)
+	})
+
+	t.Run("claim expired lease", func(t *testing.T) {
+		res, err := queue.Claim(now.Add(5*time.Minute), "w2", time.Minute)
+		assert.Nil(err)
+		assert.Equal("w2", res.LockedBy)
+	})
+
+	t.Run("stale update", func(t *testing.T) {
+		sc.Status = domain.ScheduledChangeStatusCancelled
+		assert.Equal(storage.ErrVersionConflict, db.Update(sc))
+	})
+}
@@ -0,0 +1,59 @@
+// Package storagetest is the conformance suite every storage backend runs in its tests.
+// Backend specific behavior, such as migrations or change subscriptions, is tested
+// in the backend packages.
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+)
+
+// Backend opens empty storage for each suite test and releases it after the test
+type Backend struct {
+	Open  func() storage.DataStorage
+	Close func()
+}
+
+var suite = []struct {
+	name string
+	test func(t *testing.T, dataStorage storage.DataStorage)
+}{
+	{"Project", testProject},
+	{"Environment", testEnvironment},
+	{"Parameter", testParameter},
+	{"ChangeRequest", testChangeRequest},
+	{"ScheduledChange", testScheduledChange},
+	{"Webhook", testWebhook},
+	{"WebhookDelivery", testWebhookDelivery},
+	{"EvaluationCount", testEvaluationCount},
+	{"Changeset", testChangeset},
+	{"Organization", testOrganization},
+	{"RateLimit", testRateLimit},
+}
+
+// Run runs the suite against the backend
+func Run(t *testing.T, backend *Backend) {
+	for _, s := range suite {
+		t.Run(s.name, func(t *testing.T) {
+			dataStorage := backend.Open()
+			defer backend.Close()
+			s.test(t, dataStorage)
+		})
+	}
+}
+
+// saveParents saves the project and its environments children are stored in.
+// Backends with foreign keys refuse children of missing parents.
+func saveParents(t *testing.T, db storage.DataStorage, owner, project string, envs ...string) {
+	pdb := db.ForOwner(owner).Projects()
+	if err := pdb.Save(&domain.Project{Code: project, Owner: owner, Status: domain.ProjectStatusActive}); err != nil {
+		t.Fatalf("Can't save project: %s", err)
+	}
+	for _, env := range envs {
+		if err := pdb.For(project).Environments().Save(&domain.Environment{Code: env, Owner: owner, Project: project}); err != nil {
+			t.Fatalf("Can't save environment: %s", err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+func testWebhook(t *testing.T, dataStorage storage.DataStorage) {
+	assert := asserts.New(t)
+
+	saveParents(t, dataStorage, "ow1", "proj1")
+	db := dataStorage.ForOwner("ow1").Projects().For("proj1").Webhooks()
+
+	hook := &domain.Webhook{
+		ID:      util.NewID(),
+		Owner:   "ow1",
+		Project: "proj1",
+		URL:     "https://example.com/hook",
+		Secret:  "secret",
+		Events:  []string{domain.EventParameterUpdated},
+		Active:  true,
+		RegDate: util.Now(),
+	}
+
+	t.Run("create", func(t *testing.T) {
+		assert.Nil(db.Save(hook))
+		res, err := db.Get(hook.ID)
+		assert.Nil(err)
+		assert.Equal(hook.Secret, res.Secret)
+		assert.Equal(1, res.Version)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		hook.Active = false
+		assert.Nil(db.Update(hook))
+		assert.Equal(2, hook.Version)
+		stale := *hook
+		stale.Version = 1
+		assert.Equal(storage.ErrVersionConflict, db.Update(&stale))
+	})
+
+	t.Run("wrong relations", func(t *testing.T) {
+		other := *hook
+		other.Project = "proj2"
+		assert.Equal(storage.ErrEntityRelationsBroken, db.Save(&other))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(storage.ErrVersionConflict, db.Delete(hook.ID, 1))
+		assert.Nil(db.Delete(hook.ID, 2))
+		_, err := db.Get(hook.ID)
+		assert.Equal(storage.ErrNotFound, err)
+	})
+}
+
+func testWebhookDelivery(t *testing.T, dataStorage storage.DataStorage) {
+	assert := asserts.New(t)
+
+	saveParents(t, dataStorage, "ow1", "proj1")
+	db := dataStorage.ForOwner("ow1").Projects().For("proj1").WebhookDeliveries()
+	queue := dataStorage.WebhookDeliveries()
+	now := util.Now()
+
+	dl := &domain.WebhookDelivery{
+		ID:          util.NewID(),
+		Owner:       "ow1",
+		Project:     "proj1",
+		Webhook:     "hook1",
+		Event:       domain.EventParameterUpdated,
+		URL:         "https://example.com/hook",
+		Payload:     "{}",
+		Status:      domain.WebhookDeliveryStatusPending,
+		Attempts:    []*domain.DeliveryAttempt{},
+		NextAttempt: now.Add(time.Minute),
+		RegDate:     now,
+	}
+
+	t.Run("create", func(t *testing.T) {
+		assert.Nil(db.Save(dl))
+		list, err := db.List("hook1", 10)
+		assert.Nil(err)
+		assert.Equal(1, len(list))
+		list, err = db.List("hook2", 10)
+		assert.Nil(err)
+		assert.Empty(list)
+	})
+
+	t.Run("claim not due", func(t *testing.T) {
+		_, err := queue.Claim(now, "w1", time.Minute)
+		assert.Equal(storage.ErrNotFound, err)
+	})
+
+	t.Run("claim", func(t *testing.T) {
+		res, err := queue.Claim(now.Add(2*time.Minute), "w1", time.Minute)
+		assert.Nil(err)
+		assert.Equal(dl.ID, res.ID)
+		assert.Equal(domain.WebhookDeliveryStatusRunning, res.Status)
+		assert.Equal(2, res.Version)
+		_, err = queue.Claim(now.Add(2*time.Minute), "w2", time.Minute)
+		assert.Equal(storage.ErrNotFound, err)
+		res.Status = domain.WebhookDeliveryStatusSuccess
+		assert.Nil(db.Update(res))
+	})
+
+	t.Run("stale update", func(t *testing.T) {
+		dl.Status = domain.WebhookDeliveryStatusFailed
+		assert.Equal(storage.ErrVersionConflict, db.Update(dl))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(storage.ErrVersionConflict, db.Delete(dl.ID, 1))
+		assert.Nil(db.Delete(dl.ID, 3))
+		assert.Equal(storage.ErrNotFound, db.Delete(dl.ID, 0))
+		list, err := db.List("hook1", 10)
+		assert.Nil(err)
+		assert.Empty(list)
+	})
+
+	t.Run("webhook delete", func(t *testing.T) {
+		hooks := dataStorage.ForOwner("ow1").Projects().For("proj1").Webhooks()
+		hook := &domain.Webhook{ID: util.NewID(), Owner: "ow1", Project: "proj1", URL: "https://example.com/hook"}
+		assert.Nil(hooks.Save(hook))
+		for _, webhook := range []string{hook.ID, "other"} {
+			assert.Nil(db.Save(&domain.WebhookDelivery{
+				ID: util.NewID(), Owner: "ow1", Project: "proj1", Webhook: webhook,
+				Status: domain.WebhookDeliveryStatusPending, Attempts: []*domain.DeliveryAttempt{}, RegDate: now,
+			}))
+		}
+		assert.Nil(hooks.Delete(hook.ID, 0))
+		list, err := db.List(hook.ID, 10)
+		assert.Nil(err)
+		assert.Empty(list)
+		list, err = db.List("other", 10)
+		assert.Nil(err)
+		assert.Len(list, 1)
+	})
+}
@@ -1,8 +1,29 @@
 package util
 
-import "time"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"time"
+)
 
 // Now returns time with microseconds
 func Now() time.Time {
 	return time.Unix(0, time.Now().UnixNano()/1e6*1e6)
 }
+
+// NewID returns random hex identifier
+func NewID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// PublicIP reports whether the address can be reached from outside, that is it isn't
+// loopback, private, link-local, multicast or unspecified one
+func PublicIP(ip net.IP) bool {
+	return ip != nil && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
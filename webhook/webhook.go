@@ -0,0 +1,309 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/util"
+	"github.com/rs/zerolog"
+)
+
+// Delivery request headers
+const (
+	HeaderEvent     = "X-Toggly-Event"
+	HeaderDelivery  = "X-Toggly-Delivery"
+	HeaderSignature = "X-Toggly-Signature"
+)
+
+const (
+	// maxBackoff limits the delay between delivery attempts
+	maxBackoff = time.Hour
+	// queueSize is the number of events waiting for the worker
+	queueSize = 1024
+)
+
+// ErrPrivateAddress error
+var ErrPrivateAddress = errors.New("Webhook address is not public")
+
+// Dispatcher turns configuration change events into webhook deliveries and sends them.
+// Published events are queued for the worker running the dispatcher, which stores their
+// deliveries and sends them, so API requests don't wait for webhooks. Events published
+// while the queue is full are stored right away. Deliveries are stored before sending,
+// so they survive restarts, and every delivery is leased to a single worker when several
+// instances run against the same storage. Failed deliveries are retried with exponential
+// backoff up to MaxAttempts times.
+type Dispatcher struct {
+	Storage storage.DataStorage
+	// Client sends deliveries, NewClient refusing private addresses is used if unset
+	Client      *http.Client
+	Worker      string
+	Interval    time.Duration
+	Lease       time.Duration
+	MaxAttempts int
+	// Backoff is the delay after the first failed attempt, doubled after every next one
+	Backoff time.Duration
+	Log     zerolog.Logger
+
+	once   sync.Once
+	queue  chan *domain.Event
+	client *http.Client
+}
+
+// NewClient returns HTTP client for deliveries. Unless private addresses are allowed,
+// the client refuses to connect to loopback, private and link-local addresses, which
+// covers host names resolved to such addresses and redirects to them.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !util.PublicIP(net.ParseIP(host)) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
+
+func (d *Dispatcher) init() {
+	d.once.Do(func() {
+		d.queue = make(chan *domain.Event, queueSize)
+		d.client = d.Client
+		if d.client == nil {
+			d.client = NewClient(0, false)
+		}
+	})
+}
+
+func (d *Dispatcher) events() chan *domain.Event {
+	d.init()
+	return d.queue
+}
+
+// Sign returns signature of the payload sent in X-Toggly-Signature header
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish queues the event for the worker
+func (d *Dispatcher) Publish(e *domain.Event) {
+	select {
+	case d.events() <- e:
+	default:
+		d.Log.Warn().Str("event", e.ID).Msg("Webhook queue is full, event stored by publisher")
+		d.store(e)
+	}
+}
+
+// store saves deliveries of the event for every matching active webhook of the event project
+func (d *Dispatcher) store(e *domain.Event) {
+	log := d.Log.With().Str("event", e.ID).Str("type", e.Type).Str("owner", e.Owner).Str("project", e.Project).Logger()
+	db := d.Storage.ForOwner(e.Owner).Projects().For(e.Project)
+	hooks, err := db.Webhooks().List()
+	if err != nil {
+		log.Error().Err(err).Msg("Can't list webhooks")
+		return
+	}
+	var payload []byte
+	for _, hook := range hooks {
+		if !hook.Active || !subscribed(hook, e.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(e); err != nil {
+				log.Error().Err(err).Msg("Can't encode event")
+				return
+			}
+		}
+		now := util.Now()
+		dl := &domain.WebhookDelivery{
+			ID:          util.NewID(),
+			Owner:       e.Owner,
+			Project:     e.Project,
+			Webhook:     hook.ID,
+			Event:       e.Type,
+			URL:         hook.URL,
+			Secret:      hook.Secret,
+			Payload:     string(payload),
+			Status:      domain.WebhookDeliveryStatusPending,
+			Attempts:    []*domain.DeliveryAttempt{},
+			NextAttempt: now,
+			RegDate:     now,
+		}
+		if err = db.WebhookDeliveries().Save(dl); err != nil {
+			log.Error().Err(err).Str("webhook", hook.ID).Msg("Can't save webhook delivery")
+		}
+	}
+}
+
+func subscribed(hook *domain.Webhook, typ string) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, t := range hook.Events {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Run sends due deliveries every interval and as soon as events are published until
+// context is done. Queued events are stored before stopping.
+func (d *Dispatcher) Run(ctx context.Context) {
+	log := d.Log.With().Str("worker", d.Worker).Logger()
+	log.Info().Str("interval", d.Interval.String()).Msg("Webhook dispatcher started")
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	for {
+		d.RunDue()
+		select {
+		case <-ctx.Done():
+			d.storeQueued()
+			log.Info().Msg("Webhook dispatcher stopped")
+			return
+		case e := <-d.events():
+			d.store(e)
+		case <-ticker.C:
+		}
+	}
+}
+
+// storeQueued stores deliveries of all queued events
+func (d *Dispatcher) storeQueued() {
+	for {
+		select {
+		case e := <-d.events():
+			d.store(e)
+		default:
+			return
+		}
+	}
+}
+
+// RunDue stores queued events and sends all deliveries due by now.
+// Retries scheduled during the run wait for the next one.
+func (d *Dispatcher) RunDue() {
+	d.storeQueued()
+	now := util.Now()
+	for {
+		dl, err := d.Storage.WebhookDeliveries().Claim(now, d.Worker, d.Lease)
+		if err == storage.ErrNotFound {
+			return
+		}
+		if err != nil {
+			d.Log.Error().Err(err).Msg("Can't claim webhook delivery")
+			return
+		}
+		d.deliver(dl)
+	}
+}
+
+// deliver sends the claimed delivery. Deliveries of removed webhooks are dropped:
+// storage removes them with the webhook, except those saved concurrently with the removal.
+func (d *Dispatcher) deliver(dl *domain.WebhookDelivery) {
+	log := d.Log.With().Str("id", dl.ID).Str("owner", dl.Owner).Str("project", dl.Project).
+		Str("webhook", dl.Webhook).Str("event", dl.Event).Logger()
+	db := d.Storage.ForOwner(dl.Owner).Projects().For(dl.Project)
+	_, err := db.Webhooks().Get(dl.Webhook)
+	if err == storage.ErrNotFound {
+		log.Info().Msg("Webhook removed, delivery dropped")
+		if err = db.WebhookDeliveries().Delete(dl.ID, dl.Version); err != nil {
+			log.Error().Err(err).Msg("Can't drop webhook delivery")
+		}
+		return
+	}
+	if err != nil {
+		// The delivery is retried once its lease expires
+		log.Error().Err(err).Msg("Can't get webhook")
+		return
+	}
+	attempt := d.send(dl)
+	dl.Attempts = append(dl.Attempts, attempt)
+	dl.LockedBy = ""
+	switch {
+	case attempt.Error == "":
+		log.Info().Int("code", attempt.Code).Msg("Webhook delivered")
+		dl.Status = domain.WebhookDeliveryStatusSuccess
+	case len(dl.Attempts) >= d.MaxAttempts:
+		log.Error().Int("code", attempt.Code).Str("error", attempt.Error).Msg("Webhook delivery failed")
+		dl.Status = domain.WebhookDeliveryStatusFailed
+	default:
+		dl.Status = domain.WebhookDeliveryStatusPending
+		dl.NextAttempt = attempt.Date.Add(Backoff(d.Backoff, len(dl.Attempts)))
+		log.Warn().Int("code", attempt.Code).Str("error", attempt.Error).Time("next", dl.NextAttempt).Msg("Webhook delivery will be retried")
+	}
+	if err := db.WebhookDeliveries().Update(dl); err != nil {
+		log.Error().Err(err).Msg("Can't save webhook delivery result")
+	}
+}
+
+// send posts the payload once and describes the attempt. Any non-2xx response is an error.
+func (d *Dispatcher) send(dl *domain.WebhookDelivery) *domain.DeliveryAttempt {
+	attempt := &domain.DeliveryAttempt{Date: util.Now()}
+	defer func() {
+		attempt.Duration = time.Since(attempt.Date).String()
+	}()
+	body := []byte(dl.Payload)
+	req, err := http.NewRequest(http.MethodPost, dl.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Toggly-Webhook")
+	req.Header.Set(HeaderEvent, dl.Event)
+	req.Header.Set(HeaderDelivery, dl.ID)
+	req.Header.Set(HeaderSignature, Sign(dl.Secret, body))
+	d.init()
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	resp.Body.Close()
+	attempt.Code = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("Unexpected response status %s", resp.Status)
+	}
+	return attempt
+}
+
+// Backoff returns delay before the next attempt after the given number of failed attempts
+func Backoff(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,195 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Toggly/core/api"
+	"github.com/Toggly/core/api/engine"
+	"github.com/Toggly/core/domain"
+	"github.com/Toggly/core/storage"
+	"github.com/Toggly/core/storage/mongo"
+	"github.com/Toggly/core/util"
+	"github.com/Toggly/core/webhook"
+	driver "github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	asserts "github.com/stretchr/testify/assert"
+)
+
+var logger = log.Output(zerolog.ConsoleWriter{
+	Out:     os.Stdout,
+	NoColor: true,
+}).Level(zerolog.DebugLevel)
+
+func getDB() storage.DataStorage {
+	ctx := context.Background()
+	dataStorage, err := mongo.NewMongoDataStorage(ctx, "mongodb://localhost:27017", "toggly_webhook_test", logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't create storage")
+	}
+	err = dataStorage.Connect()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't connect")
+	}
+	return dataStorage
+}
+
+func dropDB() {
+	client, err := driver.NewClient("mongodb://localhost:27017")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't connect to mongo")
+	}
+	ctx := context.Background()
+	client.Connect(ctx)
+	err = client.Database("toggly_webhook_test").Drop(ctx)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Can't drop db")
+	}
+}
+
+func TestSign(t *testing.T) {
+	assert := asserts.New(t)
+	sig := webhook.Sign("secret", []byte(`{"type":"parameter.updated"}`))
+	assert.Equal(71, len(sig))
+	assert.Equal(sig, webhook.Sign("secret", []byte(`{"type":"parameter.updated"}`)))
+	assert.NotEqual(sig, webhook.Sign("other", []byte(`{"type":"parameter.updated"}`)))
+}
+
+func TestBackoff(t *testing.T) {
+	assert := asserts.New(t)
+	assert.Equal(10*time.Second, webhook.Backoff(10*time.Second, 1))
+	assert.Equal(20*time.Second, webhook.Backoff(10*time.Second, 2))
+	assert.Equal(80*time.Second, webhook.Backoff(10*time.Second, 4))
+	assert.Equal(time.Hour, webhook.Backoff(10*time.Second, 100))
+}
+
+func TestDispatcher(t *testing.T) {
+	assert := asserts.New(t)
+
+	dropDB()
+
+	fail := true
+	received := make(chan *http.Request, 10)
+	var payload []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ = ioutil.ReadAll(r.Body)
+		received <- r
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	dataStorage := getDB()
+	d := &webhook.Dispatcher{
+		Storage:     dataStorage,
+		Client:      webhook.NewClient(time.Second, true),
+		Worker:      "w1",
+		Interval:    time.Second,
+		Lease:       time.Minute,
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		Log:         logger,
+	}
+	togglyAPI := engine.NewTogglyAPI(dataStorage, &engine.Config{
+		DefaultEnvironments: []*api.EnvironmentInfo{&api.EnvironmentInfo{Code: "prod"}},
+		Events:              d,
+		PrivateWebhooks:     true,
+	}, logger)
+	pApi := togglyAPI.ForOwner("ow1").As("alice").Projects()
+	_, err := pApi.Create(&api.ProjectInfo{Code: "proj1", Status: domain.ProjectStatusActive})
+	assert.Nil(err)
+	hook, err := pApi.For("proj1").Webhooks().Create(&api.WebhookInfo{
+		URL:    srv.URL,
+		Secret: "secret",
+		Events: []string{domain.EventParameterCreated},
+		Active: true,
+	})
+	assert.Nil(err)
+
+	params := pApi.For("proj1").Environments().For("prod").Parameters()
+	_, err = params.Create(&api.ParameterInfo{Code: "flag", Type: domain.ParameterTypeBool, Value: false})
+	assert.Nil(err)
+
+	t.Run("retry", func(t *testing.T) {
+		d.RunDue()
+		r := <-received
+		assert.Equal(domain.EventParameterCreated, r.Header.Get(webhook.HeaderEvent))
+		assert.Equal(webhook.Sign("secret", payload), r.Header.Get(webhook.HeaderSignature))
+		var e domain.Event
+		assert.Nil(json.Unmarshal(payload, &e))
+		assert.Equal("flag", e.Parameter)
+		assert.Equal("alice", e.Principal)
+		list, err := pApi.For("proj1").Webhooks().Deliveries(hook.ID)
+		assert.Nil(err)
+		assert.Equal(1, len(list))
+		assert.Equal(domain.WebhookDeliveryStatusPending, list[0].Status)
+		assert.Equal(http.StatusInternalServerError, list[0].Attempts[0].Code)
+	})
+
+	t.Run("deliver", func(t *testing.T) {
+		fail = false
+		time.Sleep(10 * time.Millisecond)
+		d.RunDue()
+		<-received
+		list, err := pApi.For("proj1").Webhooks().Deliveries(hook.ID)
+		assert.Nil(err)
+		assert.Equal(domain.WebhookDeliveryStatusSuccess, list[0].Status)
+		assert.Equal(2, len(list[0].Attempts))
+	})
+
+	t.Run("filtered events", func(t *testing.T) {
+		_, err = params.Update(&api.ParameterInfo{Code: "flag", Type: domain.ParameterTypeBool, Value: true})
+		assert.Nil(err)
+		d.RunDue()
+		assert.Empty(received)
+		list, err := pApi.For("proj1").Webhooks().Deliveries(hook.ID)
+		assert.Nil(err)
+		assert.Equal(1, len(list))
+	})
+	t.Run("removed webhook", func(t *testing.T) {
+		db := dataStorage.ForOwner("ow1").Projects().For("proj1").WebhookDeliveries()
+		now := util.Now()
+		assert.Nil(db.Save(&domain.WebhookDelivery{
+			ID:          util.NewID(),
+			Owner:       "ow1",
+			Project:     "proj1",
+			Webhook:     "removed",
+			Event:       domain.EventParameterUpdated,
+			URL:         srv.URL,
+			Payload:     "{}",
+			Status:      domain.WebhookDeliveryStatusPending,
+			Attempts:    []*domain.DeliveryAttempt{},
+			NextAttempt: now,
+			RegDate:     now,
+		}))
+		d.RunDue()
+		assert.Empty(received)
+		list, err := db.List("removed", 10)
+		assert.Nil(err)
+		assert.Empty(list)
+	})
+	t.Run("private address", func(t *testing.T) {
+		private := &webhook.Dispatcher{
+			Storage:     dataStorage,
+			Worker:      "w2",
+			Lease:       time.Minute,
+			MaxAttempts: 1,
+			Log:         logger,
+		}
+		private.Publish(&domain.Event{ID: util.NewID(), Type: domain.EventParameterCreated, Owner: "ow1", Project: "proj1"})
+		private.RunDue()
+		assert.Empty(received)
+		list, err := pApi.For("proj1").Webhooks().Deliveries(hook.ID)
+		assert.Nil(err)
+		assert.Equal(domain.WebhookDeliveryStatusFailed, list[0].Status)
+		assert.Contains(list[0].Attempts[0].Error, webhook.ErrPrivateAddress.Error())
+	})
+}